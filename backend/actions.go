@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// Kinds whose pod template can be restarted with a template annotation
+var restartableKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"InstanceSet": true,
+}
+
+// restartResource implements kubectl rollout restart: it stamps the pod template with the current
+// time, which makes the workload controller roll all pods. dryRun=true only validates the patch.
+func restartResource(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace is required for restarting a workload"})
+		return
+	}
+	dryRun, err := dryRunRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Restarting %s/%s in namespace '%s' requested from %s", resourceType, resourceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	resource, gvr, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if !restartableKinds[resource.GetKind()] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s resources cannot be restarted", resource.GetKind())})
+		return
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: restartedAt},
+				},
+			},
+		},
+	})
+
+	patched, err := client.dynamicClient.Resource(gvr).Namespace(namespace).Patch(context.TODO(), resourceName, types.MergePatchType, patch, metav1.PatchOptions{DryRun: dryRunOption(dryRun)})
+	if err != nil {
+		log.Printf("Error restarting %s/%s in namespace %s: %v", resource.GetKind(), resourceName, namespace, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := gin.H{
+		"kind":        resource.GetKind(),
+		"name":        resourceName,
+		"namespace":   namespace,
+		"restartedAt": restartedAt,
+	}
+	if dryRun {
+		respondDryRun(c, resource, patched, result)
+		return
+	}
+	log.Printf("✓ Restarted %s/%s in namespace %s", resource.GetKind(), resourceName, namespace)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Edge types of the v2 tree schema
+const (
+	EdgeTypeOwner = api.EdgeTypeOwner
+)
+
+// TreeV2 is the v2 tree response: a flat list of summary nodes connected by edges
+type TreeV2 = api.TreeV2
+
+// NodeV2 is a summary of one resource in the v2 tree
+type NodeV2 = api.NodeV2
+
+// EdgeV2 connects two nodes of the v2 tree by UID
+type EdgeV2 = api.EdgeV2
+
+// NewTreeV2 converts a built resource tree into the v2 schema
+func NewTreeV2(root *ResourceTreeNode, warnings []string) *TreeV2 {
+	tree := &TreeV2{
+		SchemaVersion: "v2",
+		Root:          string(root.Resource.GetUID()),
+		Health:        rollupHealth(root, nil),
+		Nodes:         []NodeV2{},
+		Edges:         []EdgeV2{},
+		Warnings:      warnings,
+	}
+	if tree.Warnings == nil {
+		tree.Warnings = []string{}
+	}
+	addTreeV2Subtree(tree, root)
+	return tree
+}
+
+// addTreeV2Subtree adds the nodes and edges of a subtree to the tree
+func addTreeV2Subtree(tree *TreeV2, root *ResourceTreeNode) {
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		tree.Nodes = append(tree.Nodes, NodeV2{
+			ResourceNode:   convertToResourceNode(*node.Resource),
+			Health:         computeHealth(node.Resource),
+			DisplayName:    node.DisplayName,
+			Virtual:        node.Virtual,
+			Placement:      node.Placement,
+			Problems:       node.Problems,
+			Endpoints:      node.Endpoints,
+			Storage:        node.Storage,
+			Truncated:      node.Truncated,
+			Deleted:        node.Deleted,
+			GitOpsSource:   node.GitOpsSource,
+			Layout:         node.Layout,
+			DeletedAt:      node.DeletedAt,
+			JobHistory:     node.JobHistory,
+			ServiceAccount: node.ServiceAccount,
+			HiddenSystem:   node.HiddenSystemChildren,
+		})
+		for _, child := range node.Children {
+			tree.Edges = append(tree.Edges, EdgeV2{
+				From: string(node.Resource.GetUID()),
+				To:   string(child.Resource.GetUID()),
+				Type: EdgeTypeOwner,
+			})
+			walk(child)
+		}
+		for _, child := range node.SecondaryChildren {
+			tree.Edges = append(tree.Edges, EdgeV2{
+				From: string(node.Resource.GetUID()),
+				To:   child,
+				Type: EdgeTypeSecondaryOwner,
+			})
+		}
+		for _, reference := range node.References {
+			// Referenced resources are not part of the tree, add them as leaf nodes
+			if tree.Node(reference.Target.UID) == nil {
+				tree.Nodes = append(tree.Nodes, NodeV2{ResourceNode: reference.Target, Health: HealthUnknown})
+			}
+			tree.Edges = append(tree.Edges, EdgeV2{
+				From:           string(node.Resource.GetUID()),
+				To:             reference.Target.UID,
+				Type:           EdgeTypeReference,
+				CrossNamespace: reference.CrossNamespace,
+			})
+		}
+		for _, target := range node.SelectorTargets {
+			tree.Edges = append(tree.Edges, EdgeV2{
+				From: string(node.Resource.GetUID()),
+				To:   target,
+				Type: EdgeTypeSelector,
+			})
+		}
+	}
+	walk(root)
+}
+
+// setTreeV2Continuation marks the tree as partial when the builder left nodes unexpanded
+func setTreeV2Continuation(tree *TreeV2, treeBuilder *ResourceTreeBuilder) {
+	tree.Continue = treeBuilder.ContinuationToken()
+	tree.Partial = tree.Continue != ""
+}
+
+func getResourceTreeV2(c *gin.Context) {
+	resourceType := c.Param("type")
+	rootResourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Building v2 resource tree with %s/%s as root node in namespace '%s' requested from %s", resourceType, rootResourceName, namespace, c.ClientIP())
+
+	// A continuation returns the subtrees of the nodes a partial tree left unexpanded, without a root
+	if token := c.Query("continue"); token != "" {
+		subtrees, treeBuilder, status, err := buildTreeContinuation(c, token)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		tree := &TreeV2{SchemaVersion: "v2", Health: HealthHealthy, Nodes: []NodeV2{}, Edges: []EdgeV2{}}
+		for _, subtree := range subtrees {
+			treeBuilder.DecorateTree(subtree)
+			addTreeV2Subtree(tree, subtree)
+			tree.Health = worseHealth(tree.Health, rollupHealth(subtree, nil))
+		}
+		tree.Warnings = treeBuilder.Warnings()
+		if tree.Warnings == nil {
+			tree.Warnings = []string{}
+		}
+		setTreeV2Continuation(tree, treeBuilder)
+		c.JSON(http.StatusOK, tree)
+		return
+	}
+
+	rootTreeNode, treeBuilder, status, err := buildTreeForRequest(c, resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if rootTreeNode == nil {
+		// Long-poll timed out without changes
+		c.Status(status)
+		return
+	}
+	treeBuilder.DecorateTree(rootTreeNode)
+
+	tree := NewTreeV2(rootTreeNode, treeBuilder.Warnings())
+	setTreeV2Continuation(tree, treeBuilder)
+	log.Printf("Successfully built v2 resource tree with %d nodes, %d edges and health %s", len(tree.Nodes), len(tree.Edges), tree.Health)
+
+	c.JSON(http.StatusOK, tree)
+}
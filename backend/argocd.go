@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Tree format of the Argo CD application resource tree
+const treeFormatArgoCD = "argocd"
+
+// ArgoResourceTree mirrors the ApplicationTree of the Argo CD API, so components and tooling
+// built for Argo CD's resource tree can render trees of this backend
+type ArgoResourceTree struct {
+	Nodes         []ArgoResourceNode `json:"nodes"`
+	OrphanedNodes []ArgoResourceNode `json:"orphanedNodes,omitempty"`
+}
+
+// ArgoResourceRef identifies a resource like Argo CD's ResourceRef
+type ArgoResourceRef struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// ArgoResourceNode mirrors Argo CD's ResourceNode
+type ArgoResourceNode struct {
+	ArgoResourceRef
+	ParentRefs      []ArgoResourceRef   `json:"parentRefs,omitempty"`
+	Info            []ArgoInfoItem      `json:"info,omitempty"`
+	NetworkingInfo  *ArgoNetworkingInfo `json:"networkingInfo,omitempty"`
+	ResourceVersion string              `json:"resourceVersion,omitempty"`
+	Images          []string            `json:"images,omitempty"`
+	Health          *ArgoHealthStatus   `json:"health,omitempty"`
+	CreatedAt       string              `json:"createdAt,omitempty"`
+}
+
+// ArgoInfoItem is a name/value pair shown on the node, e.g. the pod status
+type ArgoInfoItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ArgoNetworkingInfo holds the labels Argo CD uses to draw traffic between Services and pods
+type ArgoNetworkingInfo struct {
+	TargetLabels map[string]string `json:"targetLabels,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// ArgoHealthStatus mirrors Argo CD's HealthStatus, whose states match the health of this backend
+type ArgoHealthStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func argoResourceRef(resource *unstructured.Unstructured) ArgoResourceRef {
+	gv, _ := schema.ParseGroupVersion(resource.GetAPIVersion())
+	return ArgoResourceRef{
+		Group:     gv.Group,
+		Version:   gv.Version,
+		Kind:      resource.GetKind(),
+		Namespace: resource.GetNamespace(),
+		Name:      resource.GetName(),
+		UID:       string(resource.GetUID()),
+	}
+}
+
+// argoResourceNode converts a tree node, the parent refs are set by the caller
+func argoResourceNode(node *ResourceTreeNode) ArgoResourceNode {
+	resource := node.Resource
+	argoNode := ArgoResourceNode{
+		ArgoResourceRef: argoResourceRef(resource),
+		ResourceVersion: resource.GetResourceVersion(),
+		Health:          &ArgoHealthStatus{Status: computeHealth(resource)},
+		CreatedAt:       resource.GetCreationTimestamp().UTC().Format(time.RFC3339),
+	}
+	if len(node.Problems) > 0 {
+		argoNode.Health.Message = node.Problems[0].Message
+	}
+
+	switch resource.GetKind() {
+	case "Pod":
+		argoNode.Info = append(argoNode.Info, ArgoInfoItem{Name: "Status Reason", Value: resourceStatus(resource)})
+		statuses, _, _ := unstructured.NestedSlice(resource.Object, "status", "containerStatuses")
+		ready, restarts := 0, int64(0)
+		for _, item := range statuses {
+			status, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if isReady, _, _ := unstructured.NestedBool(status, "ready"); isReady {
+				ready++
+			}
+			count, _, _ := unstructured.NestedInt64(status, "restartCount")
+			restarts += count
+		}
+		containers, _, _ := unstructured.NestedSlice(resource.Object, "spec", "containers")
+		argoNode.Info = append(argoNode.Info,
+			ArgoInfoItem{Name: "Containers", Value: fmt.Sprintf("%d/%d", ready, len(containers))},
+			ArgoInfoItem{Name: "Restart Count", Value: fmt.Sprintf("%d", restarts)},
+		)
+		for _, item := range containers {
+			if container, ok := item.(map[string]interface{}); ok {
+				if image, _ := container["image"].(string); image != "" {
+					argoNode.Images = appendUnique(argoNode.Images, image)
+				}
+			}
+		}
+		argoNode.NetworkingInfo = &ArgoNetworkingInfo{Labels: resource.GetLabels()}
+	case "Service":
+		if selector, found, _ := unstructured.NestedStringMap(resource.Object, "spec", "selector"); found && len(selector) > 0 {
+			argoNode.NetworkingInfo = &ArgoNetworkingInfo{TargetLabels: selector}
+		}
+	}
+	return argoNode
+}
+
+// NewArgoResourceTree flattens trees into Argo CD's node list, where each node points to its owners
+// through parentRefs. Virtual nodes have no counterpart in Argo CD, their children point to the
+// virtual node's parent instead.
+func NewArgoResourceTree(roots ...*ResourceTreeNode) *ArgoResourceTree {
+	tree := &ArgoResourceTree{Nodes: []ArgoResourceNode{}}
+	index := map[string]int{}
+	var walk func(node *ResourceTreeNode, parent *ArgoResourceRef)
+	walk = func(node *ResourceTreeNode, parent *ArgoResourceRef) {
+		if node.Virtual {
+			for _, child := range node.Children {
+				walk(child, parent)
+			}
+			return
+		}
+		uid := string(node.Resource.GetUID())
+		if _, seen := index[uid]; !seen {
+			argoNode := argoResourceNode(node)
+			if parent != nil {
+				argoNode.ParentRefs = append(argoNode.ParentRefs, *parent)
+			}
+			index[uid] = len(tree.Nodes)
+			tree.Nodes = append(tree.Nodes, argoNode)
+		}
+		ref := tree.Nodes[index[uid]].ArgoResourceRef
+		for _, child := range node.Children {
+			walk(child, &ref)
+		}
+	}
+	for _, root := range roots {
+		walk(root, nil)
+	}
+
+	// Co-owned resources point to their secondary owners as well
+	var link func(node *ResourceTreeNode)
+	link = func(node *ResourceTreeNode) {
+		if owner, found := index[string(node.Resource.GetUID())]; found && !node.Virtual {
+			for _, childUID := range node.SecondaryChildren {
+				if child, found := index[childUID]; found {
+					tree.Nodes[child].ParentRefs = append(tree.Nodes[child].ParentRefs, tree.Nodes[owner].ArgoResourceRef)
+				}
+			}
+		}
+		for _, child := range node.Children {
+			link(child)
+		}
+	}
+	for _, root := range roots {
+		link(root)
+	}
+	return tree
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AuthorizationWebhookConfig points to an external policy engine (OPA, etc.) consulted with a
+// SubjectAccessReview before resource data is served
+type AuthorizationWebhookConfig struct {
+	// URL receives a POST of an authorization.k8s.io/v1 SubjectAccessReview; empty disables the webhook
+	URL string `json:"url"`
+	// BearerTokenFile is sent as the bearer token of the webhook requests
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	TimeoutSeconds  int    `json:"timeoutSeconds"`
+	// CacheTTLSeconds caches decisions per subject and resource
+	CacheTTLSeconds int `json:"cacheTTLSeconds"`
+	// FailOpen allows requests when the webhook cannot be reached; by default they are denied
+	FailOpen bool `json:"failOpen"`
+	// UserHeader and GroupsHeader carry the identity set by the authenticating proxy in front of the server
+	UserHeader   string `json:"userHeader"`
+	GroupsHeader string `json:"groupsHeader"`
+}
+
+// authzDecision is a cached webhook answer
+type authzDecision struct {
+	allowed bool
+	reason  string
+	expires time.Time
+}
+
+// authzMaxDecisions bounds the cached decisions, keyed by headers the clients choose
+const authzMaxDecisions = 10000
+
+var authzDecisions = struct {
+	mu        sync.Mutex
+	decisions map[string]authzDecision
+}{decisions: map[string]authzDecision{}}
+
+// authzExemptPaths are served without consulting the webhook, they hold no resource data
+var authzExemptPaths = map[string]bool{
+	"/health": true,
+	"/leader": true,
+}
+
+// authzRoute is the resource a route without a :type parameter acts on
+type authzRoute struct {
+	gvr         schema.GroupVersionResource
+	subresource string
+	// verb overrides the verb of the HTTP method, e.g. create for exec like kubectl
+	verb string
+	// unnamed routes act on all resources of the kind in the namespace, e.g. the logs of all pods of a cluster
+	unnamed bool
+}
+
+// authzTarget is a resource a handler reviews with resourceAccessAllowed
+type authzTarget struct {
+	gvr       schema.GroupVersionResource
+	name      string
+	namespace string
+}
+
+var (
+	namespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	eventGVR     = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+)
+
+// authzResourceRoutes describe the routes on a named resource without a :type parameter, so a
+// policy can scope them by namespace, resource and subresource like the resource routes
+var authzResourceRoutes = map[string]authzRoute{
+	"/pods/:name/probes":                      {gvr: podGVR},
+	"/pods/:name/datapath":                    {gvr: podGVR},
+	"/pods/:name/debug":                       {gvr: podGVR, subresource: "ephemeralcontainers", verb: "update"},
+	"/pods/:name/exec":                        {gvr: podGVR, subresource: "exec", verb: "create"},
+	"/cronjobs/:name/jobs":                    {gvr: cronJobGVR},
+	"/kubeblocks/instances/:name":             {gvr: instanceGVR},
+	"/kubeblocks/components/:name/parameters": {gvr: componentGVR},
+	"/clusters/:name/placement":               {gvr: clusterGVR},
+	"/clusters/:name/scheduling-report":       {gvr: clusterGVR},
+	"/clusters/:name/simulate-disruption":     {gvr: clusterGVR, verb: "get"},
+	"/clusters/:name/history":                 {gvr: clusterGVR},
+	"/clusters/:name/stats/history":           {gvr: clusterGVR},
+	"/clusters/:name/wait":                    {gvr: clusterGVR, verb: "get"},
+	"/clusters/:name/events/stream":           {gvr: eventGVR, verb: "watch", unnamed: true},
+	"/clusters/:name/logs/stream":             {gvr: podGVR, subresource: "log", unnamed: true},
+	"/namespaces/:ns/stuck-deletions":         {gvr: namespaceGVR, verb: "get"},
+	"/namespaces/:ns/report":                  {gvr: namespaceGVR, verb: "get"},
+}
+
+// authzRoutePath is the route of the request without the API version prefix
+func authzRoutePath(c *gin.Context) string {
+	path := c.FullPath()
+	for _, prefix := range []string{"/api/v1", "/api/v2", "/api"} {
+		if strings.HasPrefix(path, prefix+"/") {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	return path
+}
+
+// authzVerb maps the HTTP method to a Kubernetes verb, listing endpoints are "list"
+func authzVerb(c *gin.Context) string {
+	switch c.Request.Method {
+	case http.MethodPost:
+		if strings.HasSuffix(c.FullPath(), "/diff") || strings.HasSuffix(c.FullPath(), "/preview") || strings.HasSuffix(c.FullPath(), "/export") {
+			// Read-only endpoints that take a body
+			return "get"
+		}
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	}
+	if c.Param("root") == "" && c.Param("name") == "" && c.Param("uid") == "" {
+		return "list"
+	}
+	return "get"
+}
+
+// requestUser returns the user set by the authenticating proxy, empty for anonymous requests
+func requestUser(c *gin.Context) string {
+	return c.GetHeader(defaultString(appConfig.Authorization.UserHeader, "X-Forwarded-User"))
+}
+
+// subjectAccessReviewFor describes the request as a SubjectAccessReview. Requests on a resource type
+// or on a named resource of authzResourceRoutes become resource attributes, everything else is
+// checked as a non-resource path.
+func subjectAccessReviewFor(c *gin.Context, webhook AuthorizationWebhookConfig) *authorizationv1.SubjectAccessReview {
+	review := &authorizationv1.SubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "authorization.k8s.io/v1", Kind: "SubjectAccessReview"},
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   c.GetHeader(defaultString(webhook.UserHeader, "X-Forwarded-User")),
+			Groups: splitAndTrim(c.GetHeader(defaultString(webhook.GroupsHeader, "X-Forwarded-Groups"))),
+		},
+	}
+	verb := authzVerb(c)
+
+	if resourceType := c.Param("type"); resourceType != "" {
+		if gvr, err := getGVRForResourceType(resourceType); err == nil {
+			name := c.Param("root")
+			if name == "" {
+				name = c.Param("name")
+			}
+			review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+				Namespace: c.Query("namespace"),
+				Verb:      verb,
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Resource:  gvr.Resource,
+				Name:      name,
+			}
+			return review
+		}
+	}
+	if route, found := authzResourceRoutes[authzRoutePath(c)]; found {
+		attributes := &authorizationv1.ResourceAttributes{
+			Namespace:   c.Query("namespace"),
+			Verb:        defaultString(route.verb, verb),
+			Group:       route.gvr.Group,
+			Version:     route.gvr.Version,
+			Resource:    route.gvr.Resource,
+			Subresource: route.subresource,
+		}
+		if namespace := c.Param("ns"); namespace != "" {
+			attributes.Namespace = namespace
+			attributes.Name = namespace
+		} else if !route.unnamed {
+			attributes.Name = c.Param("name")
+		}
+		review.Spec.ResourceAttributes = attributes
+		return review
+	}
+	review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{Path: c.Request.URL.Path, Verb: verb}
+	return review
+}
+
+// resourceAccessAllowed asks the authorization webhook whether the user may act on a resource the
+// handler read from the query or the body, which the review of the route itself cannot describe
+func resourceAccessAllowed(c *gin.Context, verb string, gvr schema.GroupVersionResource, name, namespace string) error {
+	webhook := appConfig.Authorization
+	if webhook.URL == "" || c.GetBool(sharedRequestKey) {
+		return nil
+	}
+	review := subjectAccessReviewFor(c, webhook)
+	review.Spec.NonResourceAttributes = nil
+	review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      verb,
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Resource:  gvr.Resource,
+		Name:      name,
+	}
+	allowed, reason, err := reviewAccess(webhook, review)
+	if err != nil {
+		log.Printf("⚠️  Authorization webhook failed for %s %s in namespace '%s': %v", verb, gvr.Resource, namespace, err)
+		if webhook.FailOpen {
+			return nil
+		}
+		return fmt.Errorf("Authorization webhook unavailable")
+	}
+	if !allowed {
+		log.Printf("Authorization webhook denied %s %s/%s in namespace '%s' for user '%s': %s", verb, gvr.Resource, name, namespace, review.Spec.User, reason)
+		return fmt.Errorf("%s", defaultString(reason, "Forbidden by authorization policy"))
+	}
+	return nil
+}
+
+// objectAccessAllowed reviews reading a resource found by UID, whose type comes from its kind.
+// Kinds that do not resolve are denied while the webhook is configured.
+func objectAccessAllowed(c *gin.Context, apiVersion, kind, name, namespace string) error {
+	if appConfig.Authorization.URL == "" || c.GetBool(sharedRequestKey) {
+		return nil
+	}
+	gvr, found := gvrForKind(clientFor(c), apiVersion, kind)
+	if !found {
+		return fmt.Errorf("Unable to authorize %s %s, its resource type is unknown", kind, name)
+	}
+	return resourceAccessAllowed(c, "get", gvr, name, namespace)
+}
+
+// reviewAccess asks the webhook, or the decision cache, whether the review is allowed
+func reviewAccess(webhook AuthorizationWebhookConfig, review *authorizationv1.SubjectAccessReview) (bool, string, error) {
+	spec, _ := json.Marshal(review.Spec)
+	key := string(spec)
+	authzDecisions.mu.Lock()
+	if decision, ok := authzDecisions.decisions[key]; ok && time.Now().Before(decision.expires) {
+		authzDecisions.mu.Unlock()
+		return decision.allowed, decision.reason, nil
+	}
+	authzDecisions.mu.Unlock()
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return false, "", err
+	}
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if webhook.BearerTokenFile != "" {
+		token, err := os.ReadFile(webhook.BearerTokenFile)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to read bearer token: %v", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	client := &http.Client{Timeout: time.Duration(webhook.TimeoutSeconds) * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return false, "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("webhook answered %s", response.Status)
+	}
+	var result authorizationv1.SubjectAccessReview
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return false, "", fmt.Errorf("invalid webhook response: %v", err)
+	}
+
+	allowed := result.Status.Allowed && !result.Status.Denied
+	if webhook.CacheTTLSeconds > 0 {
+		cacheAuthzDecision(key, authzDecision{
+			allowed: allowed,
+			reason:  result.Status.Reason,
+			expires: time.Now().Add(time.Duration(webhook.CacheTTLSeconds) * time.Second),
+		})
+	}
+	return allowed, result.Status.Reason, nil
+}
+
+// cacheAuthzDecision caches a decision, dropping expired decisions and, once the cache is full,
+// the decision expiring first
+func cacheAuthzDecision(key string, decision authzDecision) {
+	authzDecisions.mu.Lock()
+	defer authzDecisions.mu.Unlock()
+	now := time.Now()
+	for cached, existing := range authzDecisions.decisions {
+		if !now.Before(existing.expires) {
+			delete(authzDecisions.decisions, cached)
+		}
+	}
+	if _, found := authzDecisions.decisions[key]; !found && len(authzDecisions.decisions) >= authzMaxDecisions {
+		oldest := ""
+		for cached, existing := range authzDecisions.decisions {
+			if oldest == "" || existing.expires.Before(authzDecisions.decisions[oldest].expires) {
+				oldest = cached
+			}
+		}
+		delete(authzDecisions.decisions, oldest)
+	}
+	authzDecisions.decisions[key] = decision
+}
+
+// authorizationWebhookMiddleware consults the configured authorization webhook before serving a request
+func authorizationWebhookMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook := appConfig.Authorization
+		if webhook.URL == "" || c.GetBool(sharedRequestKey) || authzExemptPaths[authzRoutePath(c)] {
+			c.Next()
+			return
+		}
+
+		review := subjectAccessReviewFor(c, webhook)
+		allowed, reason, err := reviewAccess(webhook, review)
+		if err != nil {
+			log.Printf("⚠️  Authorization webhook failed for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			if !webhook.FailOpen {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Authorization webhook unavailable"})
+				return
+			}
+			c.Next()
+			return
+		}
+		if !allowed {
+			log.Printf("Authorization webhook denied %s %s for user '%s': %s", c.Request.Method, c.Request.URL.Path, review.Spec.User, reason)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": defaultString(reason, "Forbidden by authorization policy")})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubeBlocks data protection GVRs of backup repositories
+var (
+	backupRepoGVR      = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuprepos"}
+	storageProviderGVR = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "storageproviders"}
+	backupPolicyGVR    = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"}
+)
+
+const (
+	// defaultBackupRepoAnnotation marks the BackupRepo used by BackupPolicies that name none
+	defaultBackupRepoAnnotation = "dataprotection.kubeblocks.io/is-default-repo"
+	// backupRepoLabel names the BackupRepo of the PVCs and Secrets KubeBlocks creates for it
+	backupRepoLabel = "dataprotection.kubeblocks.io/backup-repo-name"
+	// backupRepoReady is the phase of a usable BackupRepo, and of a usable StorageProvider
+	backupRepoReady = "Ready"
+)
+
+// BackupRepoDependency is a resource a BackupRepo needs to store backups
+type BackupRepoDependency struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Found     bool   `json:"found"`
+	// Phase is set for StorageProviders and PVCs
+	Phase string `json:"phase,omitempty"`
+}
+
+// BackupRepoSummary is a BackupRepo with the health of the resources it depends on
+type BackupRepoSummary struct {
+	Name    string `json:"name"`
+	Phase   string `json:"phase"`
+	Default bool   `json:"default"`
+	// AccessMethod is Mount (through a PVC) or Tool (through a datasafed config Secret)
+	AccessMethod    string                `json:"accessMethod,omitempty"`
+	StorageProvider BackupRepoDependency  `json:"storageProvider"`
+	StorageClass    string                `json:"storageClass,omitempty"`
+	Credential      *BackupRepoDependency `json:"credential,omitempty"`
+	// PVCs are the backup PVCs created for the repo in the namespaces running backups
+	PVCs []BackupRepoDependency `json:"pvcs"`
+	// BackupPolicies are the namespace/name of the BackupPolicies storing backups in the repo
+	BackupPolicies []string `json:"backupPolicies"`
+	Healthy        bool     `json:"healthy"`
+	Issues         []string `json:"issues"`
+	CreationTime   string   `json:"creationTime"`
+}
+
+// backupRepoLookup is a BackupRepo read by the tree builder, repo is nil when it cannot be read
+type backupRepoLookup struct {
+	repo *unstructured.Unstructured
+	err  error
+}
+
+// isDefaultBackupRepo reports whether the repo is the default one
+func isDefaultBackupRepo(repo *unstructured.Unstructured) bool {
+	return repo.GetAnnotations()[defaultBackupRepoAnnotation] == "true"
+}
+
+// backupRepoRef returns the BackupRepo a BackupPolicy or Backup stores backups in, with the field
+// naming it. BackupPolicies without one use the default repo, returned as an empty name.
+func backupRepoRef(resource *unstructured.Unstructured) (string, string, bool) {
+	switch resource.GetKind() {
+	case "BackupPolicy":
+		name, _, _ := unstructured.NestedString(resource.Object, "spec", "backupRepoName")
+		return name, "spec.backupRepoName", true
+	case "Backup":
+		name, _, _ := unstructured.NestedString(resource.Object, "status", "backupRepoName")
+		return name, "status.backupRepoName", name != ""
+	}
+	return "", "", false
+}
+
+// backupRepo reads a BackupRepo once per tree, the default one when name is empty
+func (rtb *ResourceTreeBuilder) backupRepo(name string) backupRepoLookup {
+	if rtb.backupRepos == nil {
+		rtb.backupRepos = map[string]backupRepoLookup{}
+	}
+	if lookup, found := rtb.backupRepos[name]; found {
+		return lookup
+	}
+
+	var lookup backupRepoLookup
+	if name == "" {
+		list, err := rtb.client.dynamicClient.Resource(backupRepoGVR).List(context.TODO(), metav1.ListOptions{})
+		lookup.err = err
+		if err == nil {
+			lookup.err = fmt.Errorf("no default BackupRepo")
+			for i := range list.Items {
+				if isDefaultBackupRepo(&list.Items[i]) {
+					lookup = backupRepoLookup{repo: &list.Items[i]}
+					break
+				}
+			}
+		}
+	} else {
+		lookup.repo, lookup.err = rtb.client.dynamicClient.Resource(backupRepoGVR).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	if lookup.err != nil && !errors.IsNotFound(lookup.err) && name != "" {
+		rtb.addWarning("Unable to read BackupRepo %s: %v", name, lookup.err)
+	}
+	rtb.backupRepos[name] = lookup
+	return lookup
+}
+
+// detectBackupRepoProblems reports a missing or not ready BackupRepo on a BackupPolicy or Backup
+func (rtb *ResourceTreeBuilder) detectBackupRepoProblems(resource *unstructured.Unstructured) []Problem {
+	name, _, found := backupRepoRef(resource)
+	if !found {
+		return nil
+	}
+	lookup := rtb.backupRepo(name)
+	switch {
+	case lookup.repo == nil && name == "":
+		return []Problem{{Type: ProblemBackupRepoNotReady, Severity: SeverityCritical,
+			Message: localize(rtb.language, "No BackupRepo is named and there is no default BackupRepo")}}
+	case lookup.repo == nil && errors.IsNotFound(lookup.err):
+		return []Problem{{Type: ProblemBackupRepoNotReady, Severity: SeverityCritical,
+			Message: localize(rtb.language, "BackupRepo %s does not exist", name)}}
+	case lookup.repo == nil:
+		return nil
+	}
+
+	phase, _, _ := unstructured.NestedString(lookup.repo.Object, "status", "phase")
+	if phase == backupRepoReady {
+		return nil
+	}
+	severity := SeverityWarning
+	if phase == "Failed" {
+		severity = SeverityCritical
+	}
+	return []Problem{{Type: ProblemBackupRepoNotReady, Severity: severity,
+		Message: localize(rtb.language, "BackupRepo %s is %s", lookup.repo.GetName(), defaultString(phase, "Unknown"))}}
+}
+
+// LinkBackupRepos references from BackupPolicies and Backups the BackupRepo they store backups in.
+// BackupRepos are cluster-scoped, so they are never part of the tree itself.
+func (rtb *ResourceTreeBuilder) LinkBackupRepos(root *ResourceTreeNode) {
+	var link func(node *ResourceTreeNode)
+	link = func(node *ResourceTreeNode) {
+		if name, path, found := backupRepoRef(node.Resource); found {
+			if lookup := rtb.backupRepo(name); lookup.repo != nil {
+				node.References = append(node.References, ResourceReference{Target: convertToResourceNode(*lookup.repo), Path: path})
+			}
+		}
+		for _, child := range node.Children {
+			link(child)
+		}
+	}
+	link(root)
+}
+
+// summarizeBackupRepo checks the StorageProvider, credential Secret and backup PVCs of a repo
+func summarizeBackupRepo(client *K8sClient, repo *unstructured.Unstructured, providers map[string]*unstructured.Unstructured, pvcs []unstructured.Unstructured, language string) BackupRepoSummary {
+	summary := BackupRepoSummary{
+		Name:           repo.GetName(),
+		Default:        isDefaultBackupRepo(repo),
+		PVCs:           []BackupRepoDependency{},
+		BackupPolicies: []string{},
+		Issues:         []string{},
+		CreationTime:   repo.GetCreationTimestamp().Format(time.RFC3339),
+	}
+	summary.Phase, _, _ = unstructured.NestedString(repo.Object, "status", "phase")
+	summary.AccessMethod, _, _ = unstructured.NestedString(repo.Object, "spec", "accessMethod")
+	summary.StorageClass, _, _ = unstructured.NestedString(repo.Object, "status", "generatedStorageClassName")
+
+	if summary.Phase != backupRepoReady {
+		summary.Issues = append(summary.Issues, localize(language, "BackupRepo %s is %s", summary.Name, defaultString(summary.Phase, "Unknown")))
+	}
+	conditions, _, _ := unstructured.NestedSlice(repo.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, _ := item.(map[string]interface{})
+		if status, _ := condition["status"].(string); status == "False" {
+			conditionType, _ := condition["type"].(string)
+			message, _ := condition["message"].(string)
+			summary.Issues = append(summary.Issues, localize(language, "Condition %s is False: %s", conditionType, message))
+		}
+	}
+
+	providerName, _, _ := unstructured.NestedString(repo.Object, "spec", "storageProviderRef")
+	summary.StorageProvider = BackupRepoDependency{Kind: "StorageProvider", Name: providerName}
+	if provider := providers[providerName]; provider != nil {
+		summary.StorageProvider.Found = true
+		summary.StorageProvider.Phase, _, _ = unstructured.NestedString(provider.Object, "status", "phase")
+		if summary.StorageProvider.Phase != backupRepoReady {
+			summary.Issues = append(summary.Issues, localize(language, "StorageProvider %s is %s", providerName, defaultString(summary.StorageProvider.Phase, "Unknown")))
+		}
+	} else {
+		summary.Issues = append(summary.Issues, localize(language, "StorageProvider %s does not exist", providerName))
+	}
+
+	if name, _, _ := unstructured.NestedString(repo.Object, "spec", "credential", "name"); name != "" {
+		namespace, _, _ := unstructured.NestedString(repo.Object, "spec", "credential", "namespace")
+		summary.Credential = &BackupRepoDependency{Kind: "Secret", Name: name, Namespace: namespace}
+		// Only the existence of the Secret is checked, its data is never read into the response
+		_, err := client.dynamicClient.Resource(secretGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			summary.Credential.Found = true
+		case errors.IsNotFound(err):
+			summary.Issues = append(summary.Issues, localize(language, "Credential Secret %s/%s does not exist", namespace, name))
+		default:
+			summary.Issues = append(summary.Issues, localize(language, "Credential Secret %s/%s cannot be read: %v", namespace, name, err))
+		}
+	}
+
+	for _, pvc := range pvcs {
+		if pvc.GetLabels()[backupRepoLabel] != summary.Name {
+			continue
+		}
+		phase, _, _ := unstructured.NestedString(pvc.Object, "status", "phase")
+		summary.PVCs = append(summary.PVCs, BackupRepoDependency{Kind: "PersistentVolumeClaim", Name: pvc.GetName(), Namespace: pvc.GetNamespace(), Found: true, Phase: phase})
+		if phase != "Bound" {
+			summary.Issues = append(summary.Issues, localize(language, "PVC %s/%s is %s", pvc.GetNamespace(), pvc.GetName(), defaultString(phase, "Unknown")))
+		}
+	}
+
+	summary.Healthy = len(summary.Issues) == 0
+	return summary
+}
+
+// getBackupRepos lists the BackupRepos with their phase, StorageProvider, credential Secret and
+// backup PVCs, and the BackupPolicies storing backups in each. PVCs and BackupPolicies are listed in
+// namespace=, or in the allowed namespaces.
+func getBackupRepos(c *gin.Context) {
+	namespace := c.Query("namespace")
+	log.Printf("Listing BackupRepos requested from %s", c.ClientIP())
+
+	client := clientFor(c)
+	repos, err := client.dynamicClient.Resource(backupRepoGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing BackupRepos: %v", err)
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	providers := map[string]*unstructured.Unstructured{}
+	if list, err := client.dynamicClient.Resource(storageProviderGVR).List(context.TODO(), metav1.ListOptions{}); err == nil {
+		for i := range list.Items {
+			providers[list.Items[i].GetName()] = &list.Items[i]
+		}
+	} else {
+		log.Printf("    ⚠️  Unable to list StorageProviders: %v", err)
+	}
+	pvcs, err := listInAllowedNamespacesWith(client, pvcGVR, namespace, metav1.ListOptions{LabelSelector: backupRepoLabel})
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list backup PVCs: %v", err)
+	}
+	policies, err := listInAllowedNamespaces(client, backupPolicyGVR, namespace)
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list BackupPolicies: %v", err)
+	}
+
+	language := requestLanguage(c)
+	summaries := make([]BackupRepoSummary, 0, len(repos.Items))
+	byName := map[string]*BackupRepoSummary{}
+	defaultRepo := ""
+	for i := range repos.Items {
+		summaries = append(summaries, summarizeBackupRepo(client, &repos.Items[i], providers, pvcs, language))
+		if summaries[i].Default {
+			defaultRepo = summaries[i].Name
+		}
+	}
+	for i := range summaries {
+		byName[summaries[i].Name] = &summaries[i]
+	}
+	for _, policy := range policies {
+		name, _, _ := backupRepoRef(&policy)
+		if summary := byName[defaultString(name, defaultRepo)]; summary != nil {
+			summary.BackupPolicies = append(summary.BackupPolicies, policy.GetNamespace()+"/"+policy.GetName())
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	unhealthy := 0
+	for _, summary := range summaries {
+		if !summary.Healthy {
+			unhealthy++
+		}
+	}
+	log.Printf("Found %d BackupRepos, %d unhealthy", len(summaries), unhealthy)
+	c.JSON(http.StatusOK, gin.H{"backupRepos": summaries})
+}
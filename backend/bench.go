@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// benchStorageClass is not expected to exist, so PVCs created against a real API server stay Pending
+const benchStorageClass = "kb-viz-bench"
+
+// benchSchedulingGate keeps the pods created against a real API server from being scheduled
+const benchSchedulingGate = "kb-viz.io/bench"
+
+var (
+	instanceSetGVR = schema.GroupVersionResource{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"}
+	serviceGVR     = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}
+	secretGVR      = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}
+)
+
+// benchOptions are the flags of the bench subcommand
+type benchOptions struct {
+	clusters   int
+	pods       int
+	iterations int
+	namespace  string
+	real       bool
+	keep       bool
+	cold       bool
+	json       bool
+}
+
+// benchObject is a generated resource, created after its owner so the owner reference carries its UID
+type benchObject struct {
+	gvr    schema.GroupVersionResource
+	object *unstructured.Unstructured
+	owner  *unstructured.Unstructured
+}
+
+// BenchResult summarizes the tree builds of a bench run
+type BenchResult struct {
+	Mode           string  `json:"mode"`
+	Namespace      string  `json:"namespace"`
+	Clusters       int     `json:"clusters"`
+	PodsPerCluster int     `json:"podsPerCluster"`
+	Builds         int     `json:"builds"`
+	NodesPerTree   int     `json:"nodesPerTree"`
+	LatencyP50Ms   float64 `json:"latencyP50Ms"`
+	LatencyP95Ms   float64 `json:"latencyP95Ms"`
+	LatencyMaxMs   float64 `json:"latencyMaxMs"`
+	AllocsPerBuild uint64  `json:"allocsPerBuild"`
+	BytesPerBuild  uint64  `json:"bytesPerBuild"`
+}
+
+// runBench implements `kb-viz bench`: it generates a namespace of synthetic KubeBlocks clusters,
+// builds and decorates the tree of every cluster a number of times, and reports the latency and
+// allocations of the builds. It runs against an in-memory fake API server unless -real is set.
+func runBench(args []string) int {
+	opts := benchOptions{}
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.IntVar(&opts.clusters, "clusters", 10, "Number of synthetic clusters")
+	flags.IntVar(&opts.pods, "pods", 3, "Number of pods per cluster")
+	flags.IntVar(&opts.iterations, "iterations", 5, "Number of builds of each cluster tree")
+	flags.StringVar(&opts.namespace, "namespace", "kb-viz-bench", "Namespace of the synthetic clusters")
+	flags.BoolVar(&opts.real, "real", false, "Create the clusters on the API server of the kubeconfig instead of a fake one")
+	flags.BoolVar(&opts.keep, "keep", false, "Keep the namespace created with -real")
+	flags.BoolVar(&opts.cold, "cold", false, "Forget the detected resource types before every build")
+	flags.BoolVar(&opts.json, "json", false, "Print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if opts.clusters < 1 || opts.pods < 0 || opts.iterations < 1 {
+		log.Printf("-clusters and -iterations must be positive and -pods must not be negative")
+		return 2
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+		return 1
+	}
+	appConfig = config
+	if config.TreeTypesFile != "" {
+		if err := loadTreeTypes(config.TreeTypesFile); err != nil {
+			log.Printf("Failed to load tree resource types: %v", err)
+			return 1
+		}
+	}
+
+	objects := generateBenchObjects(opts.namespace, opts.clusters, opts.pods, !opts.real)
+	mode := "fake"
+	var client *K8sClient
+	if opts.real {
+		mode = "real"
+		if client, err = initK8sClient(); err != nil {
+			log.Printf("Failed to initialize Kubernetes client: %v", err)
+			return 1
+		}
+		if err := createBenchNamespace(client, opts.namespace); err != nil {
+			log.Printf("Failed to create namespace %s: %v", opts.namespace, err)
+			return 1
+		}
+		if !opts.keep {
+			defer deleteBenchNamespace(client, opts.namespace)
+		}
+	} else {
+		client = newBenchFakeClient(objects)
+	}
+
+	log.Printf("Creating %d clusters with %d pods each in namespace '%s' (%s API server)", opts.clusters, opts.pods, opts.namespace, mode)
+	if err := createBenchObjects(client, objects); err != nil {
+		log.Printf("Failed to create the synthetic clusters: %v", err)
+		return 1
+	}
+
+	result, err := measureTreeBuilds(client, opts)
+	if err != nil {
+		log.Printf("Failed to build a tree: %v", err)
+		return 1
+	}
+	result.Mode = mode
+
+	if opts.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Printf("Failed to write the result: %v", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("mode:           %s\n", result.Mode)
+	fmt.Printf("clusters:       %d x %d pods\n", result.Clusters, result.PodsPerCluster)
+	fmt.Printf("builds:         %d\n", result.Builds)
+	fmt.Printf("nodes/tree:     %d\n", result.NodesPerTree)
+	fmt.Printf("latency:        p50 %.2fms  p95 %.2fms  max %.2fms\n", result.LatencyP50Ms, result.LatencyP95Ms, result.LatencyMaxMs)
+	fmt.Printf("allocs/build:   %d\n", result.AllocsPerBuild)
+	fmt.Printf("bytes/build:    %d\n", result.BytesPerBuild)
+	return 0
+}
+
+// measureTreeBuilds builds and decorates the tree of every synthetic cluster, like the tree endpoint
+// does. The builder's logs and pool summaries are discarded while measuring.
+func measureTreeBuilds(client *K8sClient, opts benchOptions) (*BenchResult, error) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	log.SetOutput(io.Discard)
+	os.Stdout = devNull
+	defer func() {
+		log.SetOutput(os.Stderr)
+		os.Stdout = stdout
+	}()
+
+	result := &BenchResult{Namespace: opts.namespace, Clusters: opts.clusters, PodsPerCluster: opts.pods}
+	var latencies []time.Duration
+	var allocs, bytes uint64
+	var before, after runtime.MemStats
+	for i := 0; i < opts.iterations; i++ {
+		for cluster := 0; cluster < opts.clusters; cluster++ {
+			if opts.cold {
+				resourceTypeDetection.reset()
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			root, treeBuilder, _, err := buildTreeForRoot(client, "cluster", benchClusterName(cluster), opts.namespace)
+			if err != nil {
+				return nil, err
+			}
+			treeBuilder.DecorateTree(root)
+			latencies = append(latencies, time.Since(start))
+			runtime.ReadMemStats(&after)
+
+			allocs += after.Mallocs - before.Mallocs
+			bytes += after.TotalAlloc - before.TotalAlloc
+			result.NodesPerTree = treeBuilder.CountNodes(root)
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) float64 {
+		return float64(latencies[int(p*float64(len(latencies)-1))].Microseconds()) / 1000
+	}
+	result.Builds = len(latencies)
+	result.LatencyP50Ms = percentile(0.50)
+	result.LatencyP95Ms = percentile(0.95)
+	result.LatencyMaxMs = percentile(1)
+	result.AllocsPerBuild = allocs / uint64(result.Builds)
+	result.BytesPerBuild = bytes / uint64(result.Builds)
+	return result, nil
+}
+
+func benchClusterName(index int) string {
+	return fmt.Sprintf("bench-%04d", index)
+}
+
+// generateBenchObjects generates the synthetic clusters: a Cluster owning a Component, a Service, a
+// Secret and a ConfigMap, the Component owning an InstanceSet, and the InstanceSet owning the pods and
+// their PVCs. Scheduled pods are placed on the fake nodes and report Running; otherwise a scheduling
+// gate keeps them Pending.
+func generateBenchObjects(namespace string, clusters, pods int, scheduled bool) []benchObject {
+	var objects []benchObject
+	newObject := func(gvr schema.GroupVersionResource, kind, name, cluster string, owner *unstructured.Unstructured, fields map[string]interface{}) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{Object: fields}
+		object.SetAPIVersion(gvr.GroupVersion().String())
+		object.SetKind(kind)
+		object.SetName(name)
+		object.SetNamespace(namespace)
+		object.SetUID(types.UID(fmt.Sprintf("%s-%s-%s", namespace, gvr.Resource, name)))
+		object.SetCreationTimestamp(metav1.Now())
+		object.SetLabels(map[string]string{"app.kubernetes.io/instance": cluster, componentNameLabel: "mysql"})
+		objects = append(objects, benchObject{gvr: gvr, object: object, owner: owner})
+		return object
+	}
+
+	for i := 0; i < clusters; i++ {
+		name := benchClusterName(i)
+		component := name + "-mysql"
+		cluster := newObject(clusterGVR, "Cluster", name, name, nil, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"terminationPolicy": "Delete",
+				"componentSpecs": []interface{}{
+					map[string]interface{}{"name": "mysql", "componentDef": "mysql-8.0", "replicas": int64(pods)},
+				},
+			},
+			"status": map[string]interface{}{"phase": "Running"},
+		})
+		newObject(serviceGVR, "Service", component, name, cluster, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"app.kubernetes.io/instance": name},
+				"ports":    []interface{}{map[string]interface{}{"name": "mysql", "port": int64(3306)}},
+			},
+		})
+		newObject(secretGVR, "Secret", component+"-account-root", name, cluster, map[string]interface{}{
+			"stringData": map[string]interface{}{"username": "root", "password": "bench"},
+		})
+		newObject(configMapGVR, "ConfigMap", component+"-config", name, cluster, map[string]interface{}{
+			"data": map[string]interface{}{"my.cnf": "[mysqld]\n"},
+		})
+		componentObject := newObject(componentGVR, "Component", component, name, cluster, map[string]interface{}{
+			"spec":   map[string]interface{}{"compDef": "mysql-8.0", "replicas": int64(pods)},
+			"status": map[string]interface{}{"phase": "Running"},
+		})
+		instanceSet := newObject(instanceSetGVR, "InstanceSet", component, name, componentObject, map[string]interface{}{
+			"spec":   map[string]interface{}{"replicas": int64(pods)},
+			"status": map[string]interface{}{"replicas": int64(pods), "readyReplicas": int64(pods)},
+		})
+
+		for p := 0; p < pods; p++ {
+			podName := fmt.Sprintf("%s-%d", component, p)
+			pvcName := "data-" + podName
+			newObject(pvcGVR, "PersistentVolumeClaim", pvcName, name, instanceSet, map[string]interface{}{
+				"spec": map[string]interface{}{
+					"storageClassName": benchStorageClass,
+					"accessModes":      []interface{}{"ReadWriteOnce"},
+					"resources":        map[string]interface{}{"requests": map[string]interface{}{"storage": "1Gi"}},
+				},
+			})
+			spec := map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "mysql", "image": "mysql:8.0"}},
+				"volumes": []interface{}{map[string]interface{}{
+					"name":                  "data",
+					"persistentVolumeClaim": map[string]interface{}{"claimName": pvcName},
+				}},
+			}
+			fields := map[string]interface{}{"spec": spec}
+			if scheduled {
+				spec["nodeName"] = fmt.Sprintf("bench-node-%d", p%3)
+				fields["status"] = map[string]interface{}{
+					"phase":             "Running",
+					"conditions":        []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+					"containerStatuses": []interface{}{map[string]interface{}{"name": "mysql", "ready": true, "restartCount": int64(0)}},
+				}
+			} else {
+				spec["schedulingGates"] = []interface{}{map[string]interface{}{"name": benchSchedulingGate}}
+			}
+			newObject(podGVR, "Pod", podName, name, instanceSet, fields)
+		}
+	}
+	return objects
+}
+
+// newBenchFakeClient creates a client backed by in-memory fakes serving the candidate resource
+// types of the tree builder, and three nodes in three zones for the generated pods
+func newBenchFakeClient(objects []benchObject) *K8sClient {
+	client := newFakeClient("bench", objects)
+	createFakeNodes(client, "bench")
+	return client
+}
+
+// newFakeClient creates a fake client named name serving the resource types of the objects besides
+// the candidate types of the tree builder
+func newFakeClient(name string, objects []benchObject) *K8sClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	var served [][]schema.GroupVersionResource
+	served = append(served, currentTreeResourceTypes(), rbacResourceTypes, systemResourceTypes, extraNamespacedReadResources, clusterScopedReadResources)
+	for _, gvr := range resourceMappings {
+		served = append(served, []schema.GroupVersionResource{gvr})
+	}
+	for _, gvrs := range served {
+		for _, gvr := range gvrs {
+			listKinds[gvr] = "List"
+		}
+	}
+	for _, generated := range objects {
+		listKinds[generated.gvr] = generated.object.GetKind() + "List"
+	}
+
+	clientset := kubernetesfake.NewSimpleClientset()
+	return &K8sClient{
+		name:            name,
+		clientset:       clientset,
+		dynamicClient:   dynamicfake.NewSimpleDynamicClientWithCustomListKinds(k8sruntime.NewScheme(), listKinds),
+		discoveryClient: clientset.Discovery(),
+		apiDiscovery:    &discoveryCache{client: clientset.Discovery()},
+	}
+}
+
+// createFakeNodes creates the nodes <prefix>-node-0 to 2 in the zones <prefix>-zone-0 to 2
+func createFakeNodes(client *K8sClient, prefix string) {
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("%s-node-%d", prefix, i)
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{hostnameLabel: name, zoneLabel: fmt.Sprintf("%s-zone-%d", prefix, i)},
+		}}
+		if _, err := client.clientset.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+			log.Printf("⚠️  Unable to create fake node %s: %v", name, err)
+		}
+	}
+}
+
+// createBenchObjects creates the generated resources in order, pointing owner references at the UIDs
+// the API server assigned to their owners
+func createBenchObjects(client *K8sClient, objects []benchObject) error {
+	for _, generated := range objects {
+		object := generated.object
+		if owner := generated.owner; owner != nil {
+			controller := true
+			object.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: owner.GetAPIVersion(),
+				Kind:       owner.GetKind(),
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: &controller,
+			}})
+		}
+		created, err := client.dynamicClient.Resource(generated.gvr).Namespace(object.GetNamespace()).Create(context.TODO(), object, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("%s %s: %v", object.GetKind(), object.GetName(), err)
+		}
+		object.SetUID(created.GetUID())
+	}
+	return nil
+}
+
+// createBenchNamespace creates the namespace of a run against a real API server, refusing to reuse
+// an existing one so the measured trees only contain generated resources
+func createBenchNamespace(client *K8sClient, namespace string) error {
+	_, err := client.clientset.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("namespace already exists, delete it or pick another one with -namespace")
+	}
+	return err
+}
+
+func deleteBenchNamespace(client *K8sClient, namespace string) {
+	if err := client.clientset.CoreV1().Namespaces().Delete(context.TODO(), namespace, metav1.DeleteOptions{}); err != nil {
+		log.Printf("⚠️  Unable to delete namespace %s: %v", namespace, err)
+		return
+	}
+	log.Printf("Deleted namespace %s", namespace)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBookmarksPerUser bounds the bookmarks kept for a user
+const maxBookmarksPerUser = 100
+
+// Bookmark is a tree root saved by a user for quick access
+type Bookmark struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Context is the cluster of the root, as selected with the cluster query parameter
+	Context   string    `json:"context"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkRequest is the body of POST /api/bookmarks
+type BookmarkRequest struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Context   string `json:"context"`
+}
+
+// bookmarkStore keeps the bookmarks by user, written to the bookmarks file on every change
+type bookmarkStore struct {
+	mu     sync.Mutex
+	once   sync.Once
+	path   string
+	byUser map[string][]Bookmark
+}
+
+var bookmarks = &bookmarkStore{}
+
+// load reads the bookmarks file once. Without a file bookmarks are only kept until the server restarts.
+func (store *bookmarkStore) load() {
+	store.once.Do(func() {
+		store.path = appConfig.BookmarksFile
+		store.byUser = map[string][]Bookmark{}
+		if store.path == "" {
+			log.Printf("⚠️  No bookmarksFile configured, bookmarks are only kept until the server restarts")
+			return
+		}
+		data, err := os.ReadFile(store.path)
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Unable to read bookmarks file %s: %v", store.path, err)
+			return
+		}
+		if err := json.Unmarshal(data, &store.byUser); err != nil {
+			log.Printf("⚠️  Unable to parse bookmarks file %s: %v", store.path, err)
+			store.byUser = map[string][]Bookmark{}
+			return
+		}
+		log.Printf("Loaded bookmarks of %d users from %s", len(store.byUser), store.path)
+	})
+}
+
+// save writes the bookmarks file through a temporary file, so a crash never leaves it truncated
+func (store *bookmarkStore) save() error {
+	if store.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(store.byUser, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(store.path), ".bookmarks-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), store.path)
+}
+
+func (store *bookmarkStore) list(user string) []Bookmark {
+	store.load()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return append([]Bookmark{}, store.byUser[user]...)
+}
+
+// add saves a bookmark, or returns the existing one for the same root, with the status to answer
+func (store *bookmarkStore) add(user string, bookmark Bookmark) (Bookmark, int, error) {
+	store.load()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, existing := range store.byUser[user] {
+		if existing.Type == bookmark.Type && existing.Name == bookmark.Name && existing.Namespace == bookmark.Namespace && existing.Context == bookmark.Context {
+			return existing, http.StatusOK, nil
+		}
+	}
+	if len(store.byUser[user]) >= maxBookmarksPerUser {
+		return Bookmark{}, http.StatusConflict, fmt.Errorf("At most %d bookmarks can be saved", maxBookmarksPerUser)
+	}
+	store.byUser[user] = append(store.byUser[user], bookmark)
+	if err := store.save(); err != nil {
+		store.byUser[user] = store.byUser[user][:len(store.byUser[user])-1]
+		return Bookmark{}, http.StatusInternalServerError, err
+	}
+	return bookmark, http.StatusCreated, nil
+}
+
+// remove deletes a bookmark, reporting whether it existed
+func (store *bookmarkStore) remove(user, id string) (bool, error) {
+	store.load()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	previous := store.byUser[user]
+	for i, existing := range previous {
+		if existing.ID != id {
+			continue
+		}
+		remaining := append(append([]Bookmark{}, previous[:i]...), previous[i+1:]...)
+		if len(remaining) == 0 {
+			delete(store.byUser, user)
+		} else {
+			store.byUser[user] = remaining
+		}
+		if err := store.save(); err != nil {
+			store.byUser[user] = previous
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func newBookmarkID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// bookmarkUser returns the authenticated user of the request, answering 401 when there is none
+func bookmarkUser(c *gin.Context) (string, bool) {
+	user := requestUser(c)
+	if user == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Bookmarks require an authenticated user, set by the proxy in the user header"})
+		return "", false
+	}
+	return user, true
+}
+
+// getBookmarks lists the bookmarks of the authenticated user
+func getBookmarks(c *gin.Context) {
+	user, ok := bookmarkUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookmarks": bookmarks.list(user)})
+}
+
+// createBookmark saves a tree root for the authenticated user
+func createBookmark(c *gin.Context) {
+	user, ok := bookmarkUser(c)
+	if !ok {
+		return
+	}
+	var request BookmarkRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Type == "" || request.Name == "" || request.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be {\"type\": ..., \"name\": ..., \"namespace\": ..., \"context\": ...}"})
+		return
+	}
+	if _, err := getGVRForResourceType(request.Type); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !appConfig.namespaceAllowed(request.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", request.Namespace)})
+		return
+	}
+	request.Context = defaultString(request.Context, defaultClusterName)
+	if _, found := clusterClients.clients[request.Context]; !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown cluster %s", request.Context)})
+		return
+	}
+
+	id, err := newBookmarkID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	bookmark, status, err := bookmarks.add(user, Bookmark{
+		ID:        id,
+		Type:      request.Type,
+		Name:      request.Name,
+		Namespace: request.Namespace,
+		Context:   request.Context,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Error saving bookmark of user '%s': %v", user, err)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status == http.StatusCreated {
+		log.Printf("User '%s' bookmarked %s/%s in namespace %s of cluster %s", user, bookmark.Type, bookmark.Name, bookmark.Namespace, bookmark.Context)
+	}
+	c.JSON(status, bookmark)
+}
+
+// deleteBookmark removes a bookmark of the authenticated user
+func deleteBookmark(c *gin.Context) {
+	user, ok := bookmarkUser(c)
+	if !ok {
+		return
+	}
+	removed, err := bookmarks.remove(user, c.Param("id"))
+	if err != nil {
+		log.Printf("Error removing bookmark of user '%s': %v", user, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bookmark %s not found", c.Param("id"))})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// captureOptions are the flags of the capture subcommand
+type captureOptions struct {
+	namespace    string
+	resourceType string
+	name         string
+	output       string
+	reveal       bool
+}
+
+// TreeFixture is a capture of the resources a tree was built from, replayed with --replay
+type TreeFixture struct {
+	CapturedAt time.Time `json:"capturedAt"`
+	// Version is the version of the visualizer that captured the fixture
+	Version   string `json:"version"`
+	Namespace string `json:"namespace"`
+	// RootType and RootName identify the captured tree, e.g. cluster and mycluster
+	RootType string `json:"rootType"`
+	RootName string `json:"rootName"`
+	// TreeVersion is the version of the tree when captured, the replayed tree has the same
+	TreeVersion string            `json:"treeVersion"`
+	Resources   []FixtureResource `json:"resources"`
+	// Nodes are the nodes the pods of the tree run on
+	Nodes []corev1.Node `json:"nodes,omitempty"`
+	// Events are the Warning events of the resources of the tree
+	Events []corev1.Event `json:"events,omitempty"`
+}
+
+// FixtureResource is a captured resource with its resource type
+type FixtureResource struct {
+	Group    string                 `json:"group"`
+	Version  string                 `json:"version"`
+	Resource string                 `json:"resource"`
+	Object   map[string]interface{} `json:"object"`
+}
+
+func (resource FixtureResource) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: resource.Group, Version: resource.Version, Resource: resource.Resource}
+}
+
+// runCapture implements `kb-viz capture`: it builds the tree of a resource on the cluster of the
+// kubeconfig and records every resource the tree builder read, with the nodes and Warning events
+// of the tree, into a fixture. Secret data and sensitive fields are redacted unless -reveal is set.
+func runCapture(args []string) int {
+	opts := captureOptions{}
+	flags := flag.NewFlagSet("capture", flag.ContinueOnError)
+	flags.StringVar(&opts.namespace, "n", "", "Namespace of the root resource")
+	flags.StringVar(&opts.namespace, "namespace", "", "Namespace of the root resource")
+	flags.StringVar(&opts.name, "cluster", "", "Name of the root resource, a KubeBlocks Cluster unless -type is set")
+	flags.StringVar(&opts.resourceType, "type", "cluster", "Resource type of the root resource")
+	flags.StringVar(&opts.output, "o", "", "Fixture file to write, <namespace>-<name>.fixture.json by default")
+	flags.BoolVar(&opts.reveal, "reveal", false, "Keep Secret data and sensitive fields in the fixture")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if opts.namespace == "" || opts.name == "" {
+		log.Printf("-n and --cluster are required")
+		return 2
+	}
+	if opts.output == "" {
+		opts.output = fmt.Sprintf("%s-%s.fixture.json", opts.namespace, opts.name)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+		return 1
+	}
+	appConfig = config
+	if config.TreeTypesFile != "" {
+		if err := loadTreeTypes(config.TreeTypesFile); err != nil {
+			log.Printf("Failed to load tree resource types: %v", err)
+			return 1
+		}
+	}
+	client, err := initK8sClient()
+	if err != nil {
+		log.Printf("Failed to initialize Kubernetes client: %v", err)
+		return 1
+	}
+
+	fixture, err := captureTree(client, opts)
+	if err != nil {
+		log.Printf("Failed to capture the tree of %s/%s: %v", opts.resourceType, opts.name, err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode the fixture: %v", err)
+		return 1
+	}
+	if err := os.WriteFile(opts.output, append(data, '\n'), 0o644); err != nil {
+		log.Printf("Failed to write the fixture: %v", err)
+		return 1
+	}
+	log.Printf("✓ Captured %d resources, %d nodes and %d events of %s/%s into %s",
+		len(fixture.Resources), len(fixture.Nodes), len(fixture.Events), opts.resourceType, opts.name, opts.output)
+	return 0
+}
+
+// captureTree builds the tree and collects the resources of its pool, in a stable order so
+// fixtures of the same tree diff cleanly
+func captureTree(client *K8sClient, opts captureOptions) (*TreeFixture, error) {
+	root, treeBuilder, _, err := buildTreeForRoot(client, opts.resourceType, opts.name, opts.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &TreeFixture{
+		CapturedAt:  time.Now().UTC().Truncate(time.Second),
+		Version:     version,
+		Namespace:   opts.namespace,
+		RootType:    opts.resourceType,
+		RootName:    opts.name,
+		TreeVersion: treeVersion(root),
+		Resources:   []FixtureResource{},
+	}
+
+	resources := []*unstructured.Unstructured{root.Resource}
+	if treeBuilder.pool != nil {
+		resources = append(resources, treeBuilder.pool.GetAllResources()...)
+	}
+	sortResources(resources)
+
+	uids := map[types.UID]bool{}
+	nodeNames := map[string]bool{}
+	for _, resource := range resources {
+		if uids[resource.GetUID()] {
+			continue
+		}
+		uids[resource.GetUID()] = true
+		gvr, found := gvrForKind(client, resource.GetAPIVersion(), resource.GetKind())
+		if !found {
+			log.Printf("⚠️  Skipping %s/%s, its resource type is unknown", resource.GetKind(), resource.GetName())
+			continue
+		}
+		object := resource.DeepCopy()
+		object.SetManagedFields(nil)
+		if !opts.reveal {
+			redactObject(object.Object)
+		}
+		fixture.Resources = append(fixture.Resources, FixtureResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource, Object: object.Object})
+		if nodeName, _, _ := unstructured.NestedString(object.Object, "spec", "nodeName"); gvr == podGVR && nodeName != "" {
+			nodeNames[nodeName] = true
+		}
+	}
+
+	for nodeName := range nodeNames {
+		node, err := client.clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("⚠️  Unable to capture node %s: %v", nodeName, err)
+			continue
+		}
+		node.ManagedFields = nil
+		fixture.Nodes = append(fixture.Nodes, *node)
+	}
+	sort.Slice(fixture.Nodes, func(i, j int) bool { return fixture.Nodes[i].Name < fixture.Nodes[j].Name })
+
+	events, err := client.clientset.CoreV1().Events(opts.namespace).List(context.TODO(), metav1.ListOptions{FieldSelector: "type=Warning"})
+	if err != nil {
+		log.Printf("⚠️  Unable to capture events of namespace %s: %v", opts.namespace, err)
+	} else {
+		for _, event := range events.Items {
+			if uids[event.InvolvedObject.UID] {
+				event.ManagedFields = nil
+				fixture.Events = append(fixture.Events, event)
+			}
+		}
+	}
+	return fixture, nil
+}
+
+// newReplayClient creates the client of replay mode: an in-memory fake API server holding the
+// resources of fixture files written by `kb-viz capture`. Nothing is read from a real cluster.
+func newReplayClient(paths []string) (*K8sClient, error) {
+	var fixtures []*TreeFixture
+	var objects []benchObject
+	seen := map[types.UID]bool{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fixture := &TreeFixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+		}
+		for _, resource := range fixture.Resources {
+			object := &unstructured.Unstructured{Object: resource.Object}
+			if resource.Resource == "" || seen[object.GetUID()] {
+				continue
+			}
+			seen[object.GetUID()] = true
+			objects = append(objects, benchObject{gvr: resource.GVR(), object: object})
+		}
+		fixtures = append(fixtures, fixture)
+		log.Printf("Replaying %s/%s in namespace %s captured %s (%d resources)",
+			fixture.RootType, fixture.RootName, fixture.Namespace, fixture.CapturedAt.Format(time.RFC3339), len(fixture.Resources))
+	}
+
+	client := newFakeClient("replay", objects)
+	if err := createBenchObjects(client, objects); err != nil {
+		return nil, err
+	}
+
+	namespaces := map[string]bool{}
+	nodes := map[string]bool{}
+	for _, fixture := range fixtures {
+		if !namespaces[fixture.Namespace] {
+			namespaces[fixture.Namespace] = true
+			if _, err := client.clientset.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fixture.Namespace}}, metav1.CreateOptions{}); err != nil {
+				return nil, err
+			}
+		}
+		for i := range fixture.Nodes {
+			if nodes[fixture.Nodes[i].Name] {
+				continue
+			}
+			nodes[fixture.Nodes[i].Name] = true
+			if _, err := client.clientset.CoreV1().Nodes().Create(context.TODO(), &fixture.Nodes[i], metav1.CreateOptions{}); err != nil {
+				return nil, err
+			}
+		}
+		for i := range fixture.Events {
+			if _, err := client.clientset.CoreV1().Events(fixture.Events[i].Namespace).Create(context.TODO(), &fixture.Events[i], metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return nil, err
+			}
+		}
+	}
+
+	log.Printf("✓ Replay mode: %d resources of %d fixtures", len(objects), len(fixtures))
+	return client, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CompareEntry is a field that differs between the two compared clusters
+type CompareEntry struct {
+	Path string      `json:"path"`
+	A    interface{} `json:"a"`
+	B    interface{} `json:"b"`
+}
+
+// ComponentComparison compares a component of the same name in both clusters
+type ComponentComparison struct {
+	Name    string         `json:"name"`
+	OnlyIn  string         `json:"onlyIn,omitempty"` // a or b when the component exists in one cluster only
+	Entries []CompareEntry `json:"entries"`
+}
+
+// KindCountComparison compares how many resources of a kind each cluster tree holds
+type KindCountComparison struct {
+	Kind string `json:"kind"`
+	A    int    `json:"a"`
+	B    int    `json:"b"`
+}
+
+// ClusterComparison is the structural diff of two KubeBlocks clusters
+type ClusterComparison struct {
+	A           string                `json:"a"`
+	B           string                `json:"b"`
+	Cluster     []CompareEntry        `json:"cluster"`
+	Components  []ComponentComparison `json:"components"`
+	KindCounts  []KindCountComparison `json:"kindCounts"`
+	Differences int                   `json:"differences"`
+	Identical   bool                  `json:"identical"`
+	Warnings    []string              `json:"warnings,omitempty"`
+}
+
+// comparedCluster holds the normalized view of one side of the comparison
+type comparedCluster struct {
+	spec       map[string]interface{}
+	components map[string]map[string]interface{}
+	kindCounts map[string]int
+}
+
+// parseClusterRef splits a namespace/name reference
+func parseClusterRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid cluster reference %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// loadComparedCluster reads the cluster, its components with their effective parameters and its tree
+func loadComparedCluster(client *K8sClient, namespace, name string, warnings *[]string) (*comparedCluster, int, error) {
+	cluster, err := client.dynamicClient.Resource(clusterGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Cluster not found: %s/%s", namespace, name)
+	}
+
+	compared := &comparedCluster{
+		spec:       map[string]interface{}{},
+		components: map[string]map[string]interface{}{},
+		kindCounts: map[string]int{},
+	}
+	for _, field := range []string{"clusterDef", "topology", "terminationPolicy"} {
+		if value, found, _ := unstructured.NestedFieldCopy(cluster.Object, "spec", field); found {
+			compared.spec[field] = value
+		}
+	}
+
+	componentList, err := client.dynamicClient.Resource(componentGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", instanceLabel, name),
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to list components of %s/%s: %v", namespace, name, err)
+	}
+	for i := range componentList.Items {
+		component := &componentList.Items[i]
+		shortName := component.GetLabels()[componentNameLabel]
+		if shortName == "" {
+			shortName = strings.TrimPrefix(component.GetName(), name+"-")
+		}
+
+		fields := map[string]interface{}{}
+		for _, field := range []string{"compDef", "serviceVersion", "replicas", "resources", "volumeClaimTemplates"} {
+			if value, found, _ := unstructured.NestedFieldCopy(component.Object, "spec", field); found {
+				fields[field] = value
+			}
+		}
+
+		// Parameters are compared by their effective value, wherever it was set
+		view, err := buildComponentParametersView(client, component)
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("Parameters of component %s/%s not compared: %v", namespace, component.GetName(), err))
+		} else {
+			parameters := map[string]interface{}{}
+			for j := range view.Entries {
+				if value := effectiveValue(&view.Entries[j]); value != nil {
+					parameters[view.Entries[j].Name] = *value
+				}
+			}
+			fields["parameters"] = parameters
+		}
+		compared.components[shortName] = fields
+	}
+
+	root, _, status, err := buildTreeForRoot(client, "cluster", name, namespace)
+	if err != nil {
+		return nil, status, err
+	}
+	var countKinds func(node *ResourceTreeNode)
+	countKinds = func(node *ResourceTreeNode) {
+		compared.kindCounts[node.Resource.GetKind()]++
+		for _, child := range node.Children {
+			countKinds(child)
+		}
+	}
+	countKinds(root)
+
+	return compared, http.StatusOK, nil
+}
+
+// compareValues reports the differences between two values, walking the keys of both sides
+func compareValues(path string, a, b interface{}) []CompareEntry {
+	aMap, aIsMap := a.(map[string]interface{})
+	bMap, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := sortedKeys(aMap)
+		for _, key := range sortedKeys(bMap) {
+			if _, ok := aMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var entries []CompareEntry
+		for _, key := range keys {
+			entries = append(entries, compareValues(joinDiffPath(path, key), aMap[key], bMap[key])...)
+		}
+		return entries
+	}
+
+	aSlice, aIsSlice := a.([]interface{})
+	bSlice, bIsSlice := b.([]interface{})
+	if aIsSlice && bIsSlice {
+		if len(aSlice) != len(bSlice) {
+			return []CompareEntry{{Path: path, A: a, B: b}}
+		}
+		var entries []CompareEntry
+		for i := range aSlice {
+			entries = append(entries, compareValues(fmt.Sprintf("%s[%d]", path, i), aSlice[i], bSlice[i])...)
+		}
+		return entries
+	}
+
+	if !scalarsEqual(a, b) {
+		return []CompareEntry{{Path: path, A: a, B: b}}
+	}
+	return nil
+}
+
+// compareClusters builds the structural diff of two loaded clusters
+func compareClusters(a, b *comparedCluster) *ClusterComparison {
+	comparison := &ClusterComparison{
+		Cluster:    compareValues("", a.spec, b.spec),
+		Components: []ComponentComparison{},
+		KindCounts: []KindCountComparison{},
+	}
+	if comparison.Cluster == nil {
+		comparison.Cluster = []CompareEntry{}
+	}
+	comparison.Differences = len(comparison.Cluster)
+
+	names := map[string]bool{}
+	for name := range a.components {
+		names[name] = true
+	}
+	for name := range b.components {
+		names[name] = true
+	}
+	var sortedNames []string
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		aFields, inA := a.components[name]
+		bFields, inB := b.components[name]
+		componentComparison := ComponentComparison{Name: name, Entries: []CompareEntry{}}
+		switch {
+		case !inB:
+			componentComparison.OnlyIn = "a"
+			comparison.Differences++
+		case !inA:
+			componentComparison.OnlyIn = "b"
+			comparison.Differences++
+		default:
+			if entries := compareValues("", aFields, bFields); entries != nil {
+				componentComparison.Entries = entries
+				comparison.Differences += len(entries)
+			}
+		}
+		comparison.Components = append(comparison.Components, componentComparison)
+	}
+
+	kinds := map[string]bool{}
+	for kind := range a.kindCounts {
+		kinds[kind] = true
+	}
+	for kind := range b.kindCounts {
+		kinds[kind] = true
+	}
+	for kind := range kinds {
+		comparison.KindCounts = append(comparison.KindCounts, KindCountComparison{Kind: kind, A: a.kindCounts[kind], B: b.kindCounts[kind]})
+		if a.kindCounts[kind] != b.kindCounts[kind] {
+			comparison.Differences++
+		}
+	}
+	sort.Slice(comparison.KindCounts, func(i, j int) bool {
+		return comparison.KindCounts[i].Kind < comparison.KindCounts[j].Kind
+	})
+
+	comparison.Identical = comparison.Differences == 0
+	return comparison
+}
+
+func compareKubeBlocksClusters(c *gin.Context) {
+	aRef, bRef := c.Query("a"), c.Query("b")
+	aNamespace, aName, err := parseClusterRef(aRef)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bNamespace, bName, err := parseClusterRef(bRef)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The namespace allowlist middleware and the webhook only see ?namespace=, the compared clusters
+	// name their own
+	for _, ref := range [][2]string{{aNamespace, aName}, {bNamespace, bName}} {
+		if !appConfig.namespaceAllowed(ref[0]) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", ref[0])})
+			return
+		}
+		if err := resourceAccessAllowed(c, "get", clusterGVR, ref[1], ref[0]); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	log.Printf("Comparing cluster %s with %s requested from %s", aRef, bRef, c.ClientIP())
+
+	client := clientFor(c)
+	var warnings []string
+	a, status, err := loadComparedCluster(client, aNamespace, aName, &warnings)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	b, status, err := loadComparedCluster(client, bNamespace, bName, &warnings)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison := compareClusters(a, b)
+	comparison.A = aRef
+	comparison.B = bRef
+	comparison.Warnings = warnings
+
+	log.Printf("Clusters %s and %s have %d differences", aRef, bRef, comparison.Differences)
+	c.JSON(http.StatusOK, comparison)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Per-cluster gauges describing the shape and health of KubeBlocks cluster trees
+var clusterGauges = []struct {
+	name string
+	help string
+}{
+	{"kbviz_cluster_pods", "Number of pods in the tree of a KubeBlocks cluster"},
+	{"kbviz_cluster_unhealthy_nodes", "Number of degraded nodes in the tree of a KubeBlocks cluster"},
+	{"kbviz_cluster_backups", "Number of backups in the tree of a KubeBlocks cluster"},
+	{"kbviz_cluster_tree_depth", "Depth of the tree of a KubeBlocks cluster"},
+}
+
+// collectClusterMetrics periodically builds the tree of every KubeBlocks cluster and
+// exports its shape as gauges, until the context is cancelled
+func collectClusterMetrics(ctx context.Context) {
+	interval := time.Duration(appConfig.ClusterMetricsIntervalSeconds) * time.Second
+	log.Printf("📈 Collecting per-cluster metrics every %s", interval)
+
+	exported := map[string]map[string]string{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		exported = refreshClusterMetrics(exported)
+
+		select {
+		case <-ctx.Done():
+			// Another instance takes over, stop exporting stale values
+			for key, labels := range exported {
+				deleteClusterGauges(labels)
+				clusterStats.remove(key)
+			}
+			log.Printf("Stopped collecting per-cluster metrics")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// listKubeBlocksClusters lists the clusters in the allowed namespaces
+func listKubeBlocksClusters() ([]unstructured.Unstructured, error) {
+	return listInAllowedNamespaces(k8sClient, clusterGVR, "")
+}
+
+// refreshClusterMetrics updates the gauges of all clusters and removes those of deleted clusters.
+// It returns the label sets currently exported, keyed by namespace/name.
+func refreshClusterMetrics(previous map[string]map[string]string) map[string]map[string]string {
+	clusters, err := listKubeBlocksClusters()
+	if err != nil {
+		log.Printf("⚠️  Unable to list KubeBlocks clusters for metrics: %v", err)
+		return previous
+	}
+
+	current := map[string]map[string]string{}
+	for _, cluster := range clusters {
+		labels := map[string]string{"cluster": cluster.GetName(), "namespace": cluster.GetNamespace()}
+		rootTreeNode, treeBuilder, _, err := buildTreeForRoot(k8sClient, "cluster", cluster.GetName(), cluster.GetNamespace())
+		if err != nil {
+			log.Printf("⚠️  Unable to build tree of cluster %s/%s for metrics: %v", cluster.GetNamespace(), cluster.GetName(), err)
+			continue
+		}
+
+		pods, unhealthy, backups := 0, 0, 0
+		var walk func(node *ResourceTreeNode)
+		walk = func(node *ResourceTreeNode) {
+			switch node.Resource.GetKind() {
+			case "Pod":
+				pods++
+			case "Backup":
+				backups++
+			}
+			if !node.Virtual && computeHealth(node.Resource) == HealthDegraded {
+				unhealthy++
+			}
+			for _, child := range node.Children {
+				walk(child)
+			}
+		}
+		walk(rootTreeNode)
+
+		metrics.SetGauge(clusterGauges[0].name, clusterGauges[0].help, labels, float64(pods))
+		metrics.SetGauge(clusterGauges[1].name, clusterGauges[1].help, labels, float64(unhealthy))
+		metrics.SetGauge(clusterGauges[2].name, clusterGauges[2].help, labels, float64(backups))
+		depth := treeBuilder.GetDepth(rootTreeNode)
+		metrics.SetGauge(clusterGauges[3].name, clusterGauges[3].help, labels, float64(depth))
+		key := cluster.GetNamespace() + "/" + cluster.GetName()
+		clusterStats.add(key, StatsSample{Time: time.Now(), Pods: pods, Unhealthy: unhealthy, Backups: backups, Depth: depth})
+		current[key] = labels
+	}
+
+	for key, labels := range previous {
+		if _, exists := current[key]; !exists {
+			deleteClusterGauges(labels)
+			clusterStats.remove(key)
+		}
+	}
+	log.Printf("📈 Refreshed metrics of %d KubeBlocks clusters", len(current))
+	return current
+}
+
+func deleteClusterGauges(labels map[string]string) {
+	for _, gauge := range clusterGauges {
+		metrics.DeleteSeries(gauge.name, labels)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// statsRetention bounds how far back the tree stats of a cluster are kept
+	statsRetention     = 24 * time.Hour
+	statsDefaultWindow = 6 * time.Hour
+)
+
+// StatsSample is one sample of the tree stats of a KubeBlocks cluster
+type StatsSample struct {
+	Time      time.Time `json:"time"`
+	Pods      int       `json:"pods"`
+	Unhealthy int       `json:"unhealthy"`
+	Backups   int       `json:"backups"`
+	Depth     int       `json:"depth"`
+}
+
+// clusterStatsHistory keeps the samples of every cluster in time order, dropping those past the retention
+type clusterStatsHistory struct {
+	mu      sync.Mutex
+	samples map[string][]StatsSample // namespace/name -> samples
+}
+
+var clusterStats = &clusterStatsHistory{samples: map[string][]StatsSample{}}
+
+func (csh *clusterStatsHistory) add(key string, sample StatsSample) {
+	csh.mu.Lock()
+	defer csh.mu.Unlock()
+	samples := append(csh.samples[key], sample)
+	cutoff := sample.Time.Add(-statsRetention)
+	first := 0
+	for first < len(samples) && samples[first].Time.Before(cutoff) {
+		first++
+	}
+	csh.samples[key] = append([]StatsSample(nil), samples[first:]...)
+}
+
+func (csh *clusterStatsHistory) remove(key string) {
+	csh.mu.Lock()
+	defer csh.mu.Unlock()
+	delete(csh.samples, key)
+}
+
+// since returns the samples of a cluster taken after the given time
+func (csh *clusterStatsHistory) since(key string, start time.Time) []StatsSample {
+	csh.mu.Lock()
+	defer csh.mu.Unlock()
+	result := []StatsSample{}
+	for _, sample := range csh.samples[key] {
+		if !sample.Time.Before(start) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+func getClusterStatsHistory(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching cluster stats"})
+		return
+	}
+	params, err := queryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window := statsDefaultWindow
+	if params.Window != nil {
+		window = *params.Window
+	}
+
+	log.Printf("Fetching stats history of cluster %s in namespace '%s' for the last %s requested from %s", clusterName, namespace, window, c.ClientIP())
+
+	switch {
+	case appConfig.ClusterMetricsIntervalSeconds <= 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster stats are not collected, set KB_VIZ_CLUSTER_METRICS_INTERVAL to enable them"})
+		return
+	case clientFor(c).name != k8sClient.name:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cluster stats are only collected for the default cluster"})
+		return
+	case !leaderState.IsLeader():
+		proxyToLeader(c, "Cluster stats are")
+		return
+	}
+
+	samples := clusterStats.since(namespace+"/"+clusterName, time.Now().Add(-window))
+	c.JSON(http.StatusOK, gin.H{
+		"cluster":         clusterName,
+		"namespace":       namespace,
+		"window":          window.String(),
+		"intervalSeconds": appConfig.ClusterMetricsIntervalSeconds,
+		"samples":         samples,
+	})
+}
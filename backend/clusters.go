@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultClusterName = "default"
+	clientContextKey   = "k8sClient"
+)
+
+// ClusterConfig is an additional cluster reached through its own kubeconfig,
+// read either from a file or from a Secret in the default cluster
+type ClusterConfig struct {
+	Name string `json:"name"`
+	// Kubeconfig is the path of the kubeconfig file
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// KubeconfigSecret references a Secret holding the kubeconfig
+	KubeconfigSecret *SecretKeyRef `json:"kubeconfigSecret,omitempty"`
+	// Context selects a context of the kubeconfig instead of its current context
+	Context string `json:"context,omitempty"`
+}
+
+// SecretKeyRef references a key of a Secret
+type SecretKeyRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"` // defaults to "kubeconfig"
+}
+
+// ClusterInfo describes a configured cluster in /api/clusters-config
+type ClusterInfo struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	Source  string `json:"source"` // default, kubeconfig or secret
+	Context string `json:"context,omitempty"`
+	Server  string `json:"server,omitempty"`
+}
+
+// clusterRegistry holds the clients of all configured clusters
+type clusterRegistry struct {
+	clients map[string]*K8sClient
+	infos   []ClusterInfo
+}
+
+var clusterClients = &clusterRegistry{clients: map[string]*K8sClient{}}
+
+// initClusterClients registers the default client and creates the clients of the configured clusters.
+// A cluster that cannot be reached is logged and skipped so the others keep working.
+func initClusterClients(defaultClient *K8sClient, configs []ClusterConfig) {
+	clusterClients.clients[defaultClusterName] = defaultClient
+	clusterClients.infos = []ClusterInfo{{Name: defaultClusterName, Default: true, Source: defaultClusterName}}
+
+	for _, clusterConfig := range configs {
+		if clusterConfig.Name == "" || clusterClients.clients[clusterConfig.Name] != nil {
+			log.Printf("⚠️  Skipping cluster with empty or duplicate name %q", clusterConfig.Name)
+			continue
+		}
+		restConfig, source, err := loadClusterRESTConfig(defaultClient, clusterConfig)
+		if err != nil {
+			log.Printf("⚠️  Skipping cluster %s: %v", clusterConfig.Name, err)
+			continue
+		}
+		client, err := newK8sClient(clusterConfig.Name, restConfig)
+		if err != nil {
+			log.Printf("⚠️  Skipping cluster %s: %v", clusterConfig.Name, err)
+			continue
+		}
+
+		clusterClients.clients[clusterConfig.Name] = client
+		clusterClients.infos = append(clusterClients.infos, ClusterInfo{
+			Name:    clusterConfig.Name,
+			Source:  source,
+			Context: clusterConfig.Context,
+			Server:  restConfig.Host,
+		})
+		log.Printf("✓ Cluster %s configured from %s (%s)", clusterConfig.Name, source, restConfig.Host)
+	}
+}
+
+// loadClusterRESTConfig builds the REST config of a cluster from its kubeconfig file or Secret
+func loadClusterRESTConfig(defaultClient *K8sClient, clusterConfig ClusterConfig) (*rest.Config, string, error) {
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: clusterConfig.Context}
+
+	if ref := clusterConfig.KubeconfigSecret; ref != nil {
+		secret, err := defaultClient.clientset.CoreV1().Secrets(ref.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read kubeconfig secret %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+		key := defaultString(ref.Key, "kubeconfig")
+		data, ok := secret.Data[key]
+		if !ok {
+			return nil, "", fmt.Errorf("secret %s/%s has no key %s", ref.Namespace, ref.Name, key)
+		}
+		rawConfig, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid kubeconfig in secret %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+		restConfig, err := clientcmd.NewDefaultClientConfig(*rawConfig, overrides).ClientConfig()
+		return restConfig, "secret", err
+	}
+
+	if clusterConfig.Kubeconfig == "" {
+		return nil, "", fmt.Errorf("either kubeconfig or kubeconfigSecret is required")
+	}
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: clusterConfig.Kubeconfig}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	return restConfig, "kubeconfig", err
+}
+
+// clusterSelectionMiddleware resolves the cluster query parameter, or the kubeconfig session of the
+// request, to the client used by the handlers
+func clusterSelectionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client, found, err := kubeconfigSessionClient(c); found {
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(clientContextKey, client.withRequestID(requestIDFor(c)))
+			c.Next()
+			return
+		}
+		name := defaultString(c.Query("cluster"), defaultClusterName)
+		client := clusterClients.clients[name]
+		if client == nil {
+			log.Printf("Rejecting request for unknown cluster '%s' from %s", name, c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown cluster: %s", name)})
+			return
+		}
+		// API calls made for the request carry its request ID
+		c.Set(clientContextKey, client.withRequestID(requestIDFor(c)))
+		c.Next()
+	}
+}
+
+// clientFor returns the client of the cluster selected by the request
+func clientFor(c *gin.Context) *K8sClient {
+	if client, ok := c.Get(clientContextKey); ok {
+		return client.(*K8sClient)
+	}
+	return k8sClient
+}
+
+func getClustersConfig(c *gin.Context) {
+	log.Printf("Listing configured clusters requested from %s", c.ClientIP())
+	c.JSON(http.StatusOK, clusterClients.infos)
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds the runtime configuration of the server.
+// It is loaded from the YAML file pointed to by KB_VIZ_CONFIG, and
+// individual settings can be overridden by environment variables.
+type Config struct {
+	// ClientQPS and ClientBurst configure the client-side rate limiter shared by all Kubernetes clients
+	ClientQPS   float32 `json:"clientQPS"`
+	ClientBurst int     `json:"clientBurst"`
+	// MaxBackoffSeconds caps the adaptive backoff applied after the API server answers 429
+	MaxBackoffSeconds int `json:"maxBackoffSeconds"`
+	// RetryMaxAttempts bounds the attempts of idempotent API calls failing transiently (1 disables retries)
+	RetryMaxAttempts int `json:"retryMaxAttempts"`
+	// CallTimeoutSeconds bounds each attempt of a non-streaming API call (0 disables the deadline)
+	CallTimeoutSeconds int `json:"callTimeoutSeconds"`
+	// WatchNamespaces restricts the server to an explicit set of namespaces (empty means all)
+	WatchNamespaces []string `json:"watchNamespaces"`
+	// WarmupNamespaces are warmed at startup before /readyz reports ready (defaults to WatchNamespaces)
+	WarmupNamespaces []string `json:"warmupNamespaces"`
+	// LeaderElection makes only one of several replicas own caches and recorders
+	LeaderElection LeaderElectionConfig `json:"leaderElection"`
+	// CustomResourceTypes adds resolvable resource types beyond the builtin aliases
+	CustomResourceTypes []CustomResourceType `json:"customResourceTypes"`
+	// TreeTypesFile adds or removes the resource types searched for tree children (see TreeTypesFile),
+	// it is reloaded when it changes so it can be mounted from a ConfigMap
+	TreeTypesFile string `json:"treeTypesFile"`
+	// StatusExtractors define per-kind status and health rules (JSONPath)
+	StatusExtractors []StatusExtractor `json:"statusExtractors"`
+	// HealthRules derive the health of a kind from conditions on its status, replacing the builtin rule of the kind
+	HealthRules []HealthRule `json:"healthRules"`
+	// ClusterMetricsIntervalSeconds is how often the per-cluster tree gauges are refreshed (0 disables them)
+	ClusterMetricsIntervalSeconds int `json:"clusterMetricsIntervalSeconds"`
+	// Clusters are additional clusters selectable with the cluster query parameter
+	Clusters []ClusterConfig `json:"clusters"`
+	// CrossNamespaceReferences allowlists references the relationship resolver may follow into other namespaces
+	CrossNamespaceReferences []CrossNamespaceReference `json:"crossNamespaceReferences"`
+	// RecordHistory lists KubeBlocks clusters (namespace/name) whose changes are recorded from startup
+	RecordHistory []string `json:"recordHistory"`
+	// Authorization configures an external authorization webhook
+	Authorization AuthorizationWebhookConfig `json:"authorization"`
+	// DeletedNodeGraceSeconds keeps nodes deleted during a live tree session, flagged as deleted (0 disables it)
+	DeletedNodeGraceSeconds int `json:"deletedNodeGraceSeconds"`
+	// MaxTreeNodes caps the nodes of a tree, further nodes are left unexpanded (0 disables the cap)
+	MaxTreeNodes int `json:"maxTreeNodes"`
+	// TreeBuildWorkers resolves large child sets with this many workers per kind (1 builds sequentially)
+	TreeBuildWorkers int `json:"treeBuildWorkers"`
+	// ShowSystem includes ControllerRevisions, Succeeded pods and old completed Jobs in trees by default (?showSystem= overrides it)
+	ShowSystem bool `json:"showSystem"`
+	// CompletedJobMaxAgeHours hides Jobs completed longer ago than this unless system resources are shown (0 keeps them)
+	CompletedJobMaxAgeHours int `json:"completedJobMaxAgeHours"`
+	// ShowRBAC includes ServiceAccounts, Roles and RoleBindings in trees by default (?rbac= overrides it)
+	ShowRBAC bool `json:"showRBAC"`
+	// AllowedOrigins are the browser origins allowed by CORS and for non-GET requests (empty allows
+	// every origin to read, and only the server's own host to write)
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// CSRFProtection requires a CSRF token on write endpoints
+	CSRFProtection bool `json:"csrfProtection"`
+	// BookmarksFile persists the bookmarks of all users; without it they are kept in memory only
+	BookmarksFile string `json:"bookmarksFile"`
+	// ShareTokenSecret signs share tokens; without it tokens are only valid on the replica that issued them
+	ShareTokenSecret string `json:"shareTokenSecret,omitempty"`
+	// ExportStorage is the S3 or GCS bucket asynchronous exports are uploaded to
+	ExportStorage ExportStorageConfig `json:"exportStorage"`
+	// Redaction masks Secret data, credential env vars and annotations in responses
+	Redaction RedactionConfig `json:"redaction"`
+	// Server tunes HTTP/2, TLS and the timeouts of the HTTP server
+	Server ServerConfig `json:"server"`
+	// PoolCache bounds the resource pools kept between requests, see pool_cache.go
+	PoolCache PoolCacheConfig `json:"poolCache"`
+	// DebugImage is the image of ephemeral debug containers when the request names none
+	DebugImage string `json:"debugImage"`
+	// WriteEnabled allows the endpoints that modify cluster resources; the server is read-only by default
+	WriteEnabled bool `json:"writeEnabled"`
+	// KubeconfigUpload lets users upload a kubeconfig and select it per request, see kubeconfig_sessions.go
+	KubeconfigUpload KubeconfigUploadConfig `json:"kubeconfigUpload"`
+	// TreePipeline are the post-processors applied to trees without ?pipeline=, see tree_pipeline.go
+	TreePipeline []string `json:"treePipeline"`
+}
+
+// KubeconfigUploadConfig configures the sessions of uploaded kubeconfigs
+type KubeconfigUploadConfig struct {
+	// Enabled allows uploads; off by default since the server then reaches any API server a user names
+	Enabled bool `json:"enabled"`
+	// SessionTTLSeconds drops a session once it was not used for this long
+	SessionTTLSeconds int `json:"sessionTTLSeconds"`
+}
+
+var appConfig = defaultConfig()
+
+func defaultConfig() *Config {
+	return &Config{
+		ClientQPS:                     20,
+		ClientBurst:                   40,
+		MaxBackoffSeconds:             30,
+		RetryMaxAttempts:              4,
+		CallTimeoutSeconds:            30,
+		ClusterMetricsIntervalSeconds: 60,
+		MaxTreeNodes:                  5000,
+		TreeBuildWorkers:              4,
+		CompletedJobMaxAgeHours:       24,
+		DeletedNodeGraceSeconds:       60,
+		CSRFProtection:                true,
+		DebugImage:                    "busybox:1.36",
+		Redaction:                     defaultRedactionConfig(),
+		Server:                        defaultServerConfig(),
+		PoolCache:                     defaultPoolCacheConfig(),
+		Authorization: AuthorizationWebhookConfig{
+			TimeoutSeconds:  5,
+			CacheTTLSeconds: 30,
+		},
+		LeaderElection: LeaderElectionConfig{
+			LeaseName:      "kb-viz-leader",
+			LeaseNamespace: "default",
+		},
+		ExportStorage: ExportStorageConfig{
+			URLExpirySeconds: 3600,
+		},
+		KubeconfigUpload: KubeconfigUploadConfig{
+			SessionTTLSeconds: 8 * 3600,
+		},
+		TreePipeline: defaultTreePipeline,
+	}
+}
+
+// loadConfig reads the configuration file (if any) and applies environment overrides
+func loadConfig() (*Config, error) {
+	config := defaultConfig()
+
+	if path := os.Getenv("KB_VIZ_CONFIG"); path != "" {
+		log.Printf("Loading configuration from %s", path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+		if err := validateStatusExtractors(config.StatusExtractors); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		if err := validateHealthRules(config.HealthRules); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		if err := validateTreePipeline(config.TreePipeline); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+
+	if value := os.Getenv("KB_VIZ_CLIENT_QPS"); value != "" {
+		qps, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_CLIENT_QPS %q: %v", value, err)
+		}
+		config.ClientQPS = float32(qps)
+	}
+	if value := os.Getenv("KB_VIZ_CLIENT_BURST"); value != "" {
+		burst, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_CLIENT_BURST %q: %v", value, err)
+		}
+		config.ClientBurst = burst
+	}
+
+	if value := os.Getenv("KB_VIZ_WATCH_NAMESPACES"); value != "" {
+		config.WatchNamespaces = splitAndTrim(value)
+	}
+	if value := os.Getenv("KB_VIZ_WARMUP_NAMESPACES"); value != "" {
+		config.WarmupNamespaces = splitAndTrim(value)
+	}
+
+	if value := os.Getenv("KB_VIZ_LEADER_ELECT"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_LEADER_ELECT %q: %v", value, err)
+		}
+		config.LeaderElection.Enabled = enabled
+	}
+	if value := os.Getenv("KB_VIZ_ADVERTISE_ADDRESS"); value != "" {
+		config.LeaderElection.AdvertiseAddress = value
+	}
+	if value := os.Getenv("KB_VIZ_DELETED_NODE_GRACE"); value != "" {
+		grace, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_DELETED_NODE_GRACE %q: %v", value, err)
+		}
+		config.DeletedNodeGraceSeconds = grace
+	}
+	if value := os.Getenv("KB_VIZ_CLUSTER_METRICS_INTERVAL"); value != "" {
+		interval, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_CLUSTER_METRICS_INTERVAL %q: %v", value, err)
+		}
+		config.ClusterMetricsIntervalSeconds = interval
+	}
+	if value := os.Getenv("KB_VIZ_WRITE_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_WRITE_ENABLED %q: %v", value, err)
+		}
+		config.WriteEnabled = enabled
+	}
+	if value := os.Getenv("KB_VIZ_KUBECONFIG_UPLOAD"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_KUBECONFIG_UPLOAD %q: %v", value, err)
+		}
+		config.KubeconfigUpload.Enabled = enabled
+	}
+	if value := os.Getenv("KB_VIZ_KUBECONFIG_SESSION_TTL"); value != "" {
+		ttl, err := strconv.Atoi(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid KB_VIZ_KUBECONFIG_SESSION_TTL %q", value)
+		}
+		config.KubeconfigUpload.SessionTTLSeconds = ttl
+	}
+	if value := os.Getenv("KB_VIZ_ALLOWED_ORIGINS"); value != "" {
+		config.AllowedOrigins = splitAndTrim(value)
+	}
+	if value := os.Getenv("KB_VIZ_CSRF_PROTECTION"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_CSRF_PROTECTION %q: %v", value, err)
+		}
+		config.CSRFProtection = enabled
+	}
+	if value := os.Getenv("KB_VIZ_SHARE_TOKEN_SECRET"); value != "" {
+		config.ShareTokenSecret = value
+	}
+	if value := os.Getenv("KB_VIZ_DEBUG_IMAGE"); value != "" {
+		config.DebugImage = value
+	}
+	if value := os.Getenv("KB_VIZ_BOOKMARKS_FILE"); value != "" {
+		config.BookmarksFile = value
+	}
+	if value := os.Getenv("KB_VIZ_TREE_TYPES_FILE"); value != "" {
+		config.TreeTypesFile = value
+	}
+	if value := os.Getenv("KB_VIZ_TREE_PIPELINE"); value != "" {
+		config.TreePipeline = splitAndTrim(value)
+		if err := validateTreePipeline(config.TreePipeline); err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_TREE_PIPELINE %q: %v", value, err)
+		}
+	}
+	if value := os.Getenv("KB_VIZ_ALLOW_REVEAL"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_ALLOW_REVEAL %q: %v", value, err)
+		}
+		config.Redaction.AllowReveal = enabled
+	}
+	if value := os.Getenv("KB_VIZ_REDACT_ENV"); value != "" {
+		config.Redaction.EnvDenylist = splitAndTrim(value)
+	}
+	if value := os.Getenv("KB_VIZ_REDACT_ANNOTATIONS"); value != "" {
+		config.Redaction.AnnotationDenylist = splitAndTrim(value)
+	}
+	if value := os.Getenv("KB_VIZ_SHOW_RBAC"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_SHOW_RBAC %q: %v", value, err)
+		}
+		config.ShowRBAC = enabled
+	}
+	if value := os.Getenv("KB_VIZ_SHOW_SYSTEM"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_SHOW_SYSTEM %q: %v", value, err)
+		}
+		config.ShowSystem = enabled
+	}
+	if value := os.Getenv("KB_VIZ_MAX_TREE_NODES"); value != "" {
+		maxNodes, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_MAX_TREE_NODES %q: %v", value, err)
+		}
+		config.MaxTreeNodes = maxNodes
+	}
+	if value := os.Getenv("KB_VIZ_TREE_BUILD_WORKERS"); value != "" {
+		workers, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_TREE_BUILD_WORKERS %q: %v", value, err)
+		}
+		config.TreeBuildWorkers = workers
+	}
+	if value := os.Getenv("KB_VIZ_EXPORT_STORAGE_PROVIDER"); value != "" {
+		config.ExportStorage.Provider = value
+	}
+	if value := os.Getenv("KB_VIZ_EXPORT_STORAGE_BUCKET"); value != "" {
+		config.ExportStorage.Bucket = value
+	}
+	if value := os.Getenv("KB_VIZ_EXPORT_STORAGE_ENDPOINT"); value != "" {
+		config.ExportStorage.Endpoint = value
+	}
+	if value := os.Getenv("KB_VIZ_EXPORT_STORAGE_ACCESS_KEY_ID"); value != "" {
+		config.ExportStorage.AccessKeyID = value
+	}
+	if value := os.Getenv("KB_VIZ_EXPORT_STORAGE_SECRET_ACCESS_KEY"); value != "" {
+		config.ExportStorage.SecretAccessKey = value
+	}
+	if value := os.Getenv("KB_VIZ_LISTEN_ADDR"); value != "" {
+		config.Server.Addr = value
+	}
+	if value := os.Getenv("KB_VIZ_TLS_CERT_FILE"); value != "" {
+		config.Server.TLSCertFile = value
+	}
+	if value := os.Getenv("KB_VIZ_TLS_KEY_FILE"); value != "" {
+		config.Server.TLSKeyFile = value
+	}
+	if value := os.Getenv("KB_VIZ_H2C"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_H2C %q: %v", value, err)
+		}
+		config.Server.H2C = enabled
+	}
+	if value := os.Getenv("KB_VIZ_WRITE_TIMEOUT"); value != "" {
+		timeout, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_WRITE_TIMEOUT %q: %v", value, err)
+		}
+		config.Server.WriteTimeoutSeconds = timeout
+	}
+	if value := os.Getenv("KB_VIZ_IDLE_TIMEOUT"); value != "" {
+		timeout, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_IDLE_TIMEOUT %q: %v", value, err)
+		}
+		config.Server.IdleTimeoutSeconds = timeout
+	}
+	if value := os.Getenv("KB_VIZ_POOL_CACHE_TTL"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_POOL_CACHE_TTL %q: %v", value, err)
+		}
+		config.PoolCache.TTLSeconds = limit
+	}
+	if value := os.Getenv("KB_VIZ_POOL_CACHE_MAX_MB"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_POOL_CACHE_MAX_MB %q: %v", value, err)
+		}
+		config.PoolCache.MaxMB = limit
+	}
+	if value := os.Getenv("KB_VIZ_POOL_CACHE_MAX_NAMESPACES"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KB_VIZ_POOL_CACHE_MAX_NAMESPACES %q: %v", value, err)
+		}
+		config.PoolCache.MaxNamespaces = limit
+	}
+	if value := os.Getenv("KB_VIZ_AUTHZ_WEBHOOK_URL"); value != "" {
+		config.Authorization.URL = value
+	}
+	if value := os.Getenv("POD_NAMESPACE"); value != "" {
+		config.LeaderElection.LeaseNamespace = value
+	}
+
+	return config, nil
+}
+
+// namespaceAllowed reports whether the namespace is within the configured allowlist
+func (c *Config) namespaceAllowed(namespace string) bool {
+	if len(c.WatchNamespaces) == 0 {
+		return true
+	}
+	for _, allowed := range c.WatchNamespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAndTrim splits a comma-separated list and drops empty entries
+func splitAndTrim(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const crdWatchRetryBackoff = 10 * time.Second
+
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// crdAlias is a name of a CRD resolving to its preferred version
+type crdAlias struct {
+	crd    string // Name of the CRD registering the alias
+	gvr    schema.GroupVersionResource
+	served map[string]bool
+}
+
+// crdAliasRegistry holds the plural, singular, kind and short names of the installed CRDs. Several
+// CRDs may register the same short name; the alias resolves to the CRD that registered it last and
+// stays resolvable until every CRD registering it is gone.
+type crdAliasRegistry struct {
+	mu      sync.RWMutex
+	byCRD   map[string][]string   // CRD name -> aliases it registered
+	aliases map[string][]crdAlias // Alias -> CRDs registering it, latest last
+}
+
+var crdAliases = &crdAliasRegistry{byCRD: map[string][]string{}, aliases: map[string][]crdAlias{}}
+
+// crdAliasesOf returns the aliases of a CRD and the version they resolve to, no aliases when the
+// CRD serves no version
+func crdAliasesOf(crd *unstructured.Unstructured) (crdAlias, []string) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	singular, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "singular")
+	kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	shortNames, _, _ := unstructured.NestedStringSlice(crd.Object, "spec", "names", "shortNames")
+
+	// Resolve to the storage version when it is served, otherwise to the first served version
+	alias := crdAlias{crd: crd.GetName(), served: map[string]bool{}}
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, version := range versions {
+		versionMap, ok := version.(map[string]interface{})
+		if !ok || versionMap["served"] != true {
+			continue
+		}
+		name, _ := versionMap["name"].(string)
+		alias.served[name] = true
+		if alias.gvr.Version == "" || versionMap["storage"] == true {
+			alias.gvr = schema.GroupVersionResource{Group: group, Version: name, Resource: plural}
+		}
+	}
+	if alias.gvr.Version == "" {
+		return alias, nil
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range append([]string{plural, singular, kind}, shortNames...) {
+		name = strings.ToLower(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return alias, names
+}
+
+// set replaces the aliases registered by a CRD
+func (r *crdAliasRegistry) set(crd *unstructured.Unstructured) {
+	alias, names := crdAliasesOf(crd)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(crd.GetName())
+	r.addLocked(alias, names)
+}
+
+// replace rebuilds the registry from a full list of the CRDs, dropping the aliases of CRDs deleted
+// while they were not watched
+func (r *crdAliasRegistry) replace(crds []unstructured.Unstructured) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byCRD = map[string][]string{}
+	r.aliases = map[string][]crdAlias{}
+	for i := range crds {
+		r.addLocked(crdAliasesOf(&crds[i]))
+	}
+}
+
+// remove drops the aliases of a deleted CRD
+func (r *crdAliasRegistry) remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(name)
+}
+
+func (r *crdAliasRegistry) addLocked(alias crdAlias, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	for _, name := range names {
+		r.aliases[name] = append(r.aliases[name], alias)
+	}
+	r.byCRD[alias.crd] = names
+}
+
+// removeLocked drops the registrations of a CRD, aliases other CRDs also registered stay
+func (r *crdAliasRegistry) removeLocked(crd string) {
+	for _, name := range r.byCRD[crd] {
+		var remaining []crdAlias
+		for _, alias := range r.aliases[name] {
+			if alias.crd != crd {
+				remaining = append(remaining, alias)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(r.aliases, name)
+		} else {
+			r.aliases[name] = remaining
+		}
+	}
+	delete(r.byCRD, crd)
+}
+
+// resolve looks up an alias. A builtin mapping of the same resource keeps its version while the CRD
+// still serves it, since the code reading those resources expects that version's fields.
+func (r *crdAliasRegistry) resolve(alias string) (schema.GroupVersionResource, bool) {
+	r.mu.RLock()
+	registered := r.aliases[alias]
+	r.mu.RUnlock()
+	if len(registered) == 0 {
+		return schema.GroupVersionResource{}, false
+	}
+	found := registered[len(registered)-1]
+	if builtin, exists := resourceMappings[alias]; exists {
+		if builtin.Group != found.gvr.Group {
+			// A CRD claiming the alias of another group does not shadow the builtin one
+			return builtin, true
+		}
+		if builtin.Resource == found.gvr.Resource && found.served[builtin.Version] {
+			return builtin, true
+		}
+	}
+	return found.gvr, true
+}
+
+// watchCRDAliases loads the aliases of all CRDs and keeps them current with a watch. Every relist
+// replaces the whole registry, since deletions are missed while the watch is down.
+func watchCRDAliases(ctx context.Context, client *K8sClient) {
+	crds := client.dynamicClient.Resource(crdGVR)
+	for ctx.Err() == nil {
+		list, err := crds.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Printf("⚠️  Unable to list CRDs for resource aliases, using builtin aliases: %v", err)
+			sleepContext(ctx, crdWatchRetryBackoff)
+			continue
+		}
+		crdAliases.replace(list.Items)
+		log.Printf("✓ Loaded resource aliases of %d CRDs", len(list.Items))
+
+		watcher, err := crds.Watch(ctx, metav1.ListOptions{ResourceVersion: list.GetResourceVersion()})
+		if err != nil {
+			log.Printf("⚠️  Unable to watch CRDs for resource aliases: %v", err)
+			sleepContext(ctx, crdWatchRetryBackoff)
+			continue
+		}
+		for result := range watcher.ResultChan() {
+			crd, ok := result.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			switch result.Type {
+			case watch.Added, watch.Modified:
+				crdAliases.set(crd)
+				log.Printf("🔄 Resource aliases of CRD %s updated", crd.GetName())
+			case watch.Deleted:
+				crdAliases.remove(crd.GetName())
+				log.Printf("🔄 Resource aliases of CRD %s removed", crd.GetName())
+			}
+		}
+		watcher.Stop()
+		// The watch ended or expired, list again to resync
+	}
+}
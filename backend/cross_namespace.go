@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Edge type for references to resources outside the tree
+const EdgeTypeReference = api.EdgeTypeReference
+
+// CrossNamespaceReference allows the relationship resolver to follow references into another namespace
+type CrossNamespaceReference struct {
+	// From is the namespace of the referencing resources, "*" for any
+	From string `json:"from"`
+	// To is the namespace the references may point into
+	To string `json:"to"`
+	// Kinds restricts the referenced kinds, e.g. Secret or Backup (empty means all)
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// ResourceReference is a resource referenced by a tree node through a namespaced object reference
+type ResourceReference struct {
+	Target         ResourceNode `json:"target"`
+	Path           string       `json:"path"`
+	CrossNamespace bool         `json:"crossNamespace"`
+}
+
+// namespacedRefPath is a field holding a {name, namespace} reference, "*" walks list items
+type namespacedRefPath struct {
+	kind   string
+	path   []string
+	target string
+	gvr    schema.GroupVersionResource
+}
+
+var namespacedRefPaths = []namespacedRefPath{
+	{kind: "BackupRepo", path: []string{"spec", "credential"}, target: "Secret", gvr: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}},
+	{kind: "Restore", path: []string{"spec", "backup"}, target: "Backup", gvr: schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"}},
+	{kind: "Cluster", path: []string{"spec", "componentSpecs", "*", "systemAccounts", "*", "secretRef"}, target: "Secret", gvr: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}},
+}
+
+// crossNamespaceAllowed reports whether a reference from one namespace into another may be followed
+func (c *Config) crossNamespaceAllowed(from, to, kind string) bool {
+	if !c.namespaceAllowed(to) {
+		return false
+	}
+	for _, rule := range c.CrossNamespaceReferences {
+		if rule.From != "*" && rule.From != from {
+			continue
+		}
+		if rule.To != to {
+			continue
+		}
+		if len(rule.Kinds) == 0 || containsFold(rule.Kinds, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectNamespacedRefs returns the {name, namespace} maps found at the path, with their concrete paths
+func collectNamespacedRefs(value interface{}, path []string, prefix string) map[string]map[string]interface{} {
+	refs := map[string]map[string]interface{}{}
+	if len(path) == 0 {
+		if ref, ok := value.(map[string]interface{}); ok {
+			refs[prefix] = ref
+		}
+		return refs
+	}
+
+	if path[0] == "*" {
+		items, ok := value.([]interface{})
+		if !ok {
+			return refs
+		}
+		for i, item := range items {
+			itemPrefix := fmt.Sprintf("%s[%d]", prefix, i)
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				if name, ok := itemMap["name"].(string); ok {
+					itemPrefix = fmt.Sprintf("%s[name=%s]", prefix, name)
+				}
+			}
+			for refPath, ref := range collectNamespacedRefs(item, path[1:], itemPrefix) {
+				refs[refPath] = ref
+			}
+		}
+		return refs
+	}
+
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return refs
+	}
+	return collectNamespacedRefs(valueMap[path[0]], path[1:], joinDiffPath(prefix, path[0]))
+}
+
+// ResolveCrossNamespaceRefs follows the allowlisted references of tree nodes into other namespaces
+func (rtb *ResourceTreeBuilder) ResolveCrossNamespaceRefs(root *ResourceTreeNode) {
+	if len(appConfig.CrossNamespaceReferences) == 0 {
+		return
+	}
+
+	var resolve func(node *ResourceTreeNode)
+	resolve = func(node *ResourceTreeNode) {
+		for _, refPath := range namespacedRefPaths {
+			if node.Resource.GetKind() != refPath.kind {
+				continue
+			}
+			from := node.Resource.GetNamespace()
+			for path, ref := range collectNamespacedRefs(node.Resource.Object, refPath.path, "") {
+				name, _ := ref["name"].(string)
+				namespace, _ := ref["namespace"].(string)
+				if name == "" || namespace == "" || namespace == from {
+					continue
+				}
+				if !appConfig.crossNamespaceAllowed(from, namespace, refPath.target) {
+					log.Printf("    ⏭️  Not following %s reference %s/%s of %s/%s, namespace is not allowlisted", refPath.target, namespace, name, node.Resource.GetKind(), node.Resource.GetName())
+					continue
+				}
+
+				target, err := rtb.client.dynamicClient.Resource(refPath.gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					rtb.addWarning("%s/%s references %s %s/%s which cannot be read: %v", node.Resource.GetKind(), node.Resource.GetName(), refPath.target, namespace, name, err)
+					continue
+				}
+				targetNode := convertToResourceNode(*redactResource(target))
+				node.References = append(node.References, ResourceReference{Target: targetNode, Path: path, CrossNamespace: true})
+				log.Printf("🔗 %s/%s references %s %s/%s across namespaces", node.Resource.GetKind(), node.Resource.GetName(), refPath.target, namespace, name)
+			}
+		}
+		for _, child := range node.Children {
+			resolve(child)
+		}
+	}
+	resolve(root)
+}
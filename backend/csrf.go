@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csrfHeader = "X-CSRF-Token"
+	csrfCookie = "kbviz_csrf"
+)
+
+// safeMethod reports whether the HTTP method does not modify anything
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// originAllowed reports whether a request origin may call non-GET endpoints: an allowed
+// origin from the configuration, or the server's own host
+func originAllowed(origin, host string) bool {
+	for _, allowed := range appConfig.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == host
+}
+
+// originCheckMiddleware rejects non-GET requests sent by pages of other origins. Requests
+// without an Origin header come from non-browser clients and are let through.
+func originCheckMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if safeMethod(c.Request.Method) || origin == "" || originAllowed(origin, c.Request.Host) {
+			c.Next()
+			return
+		}
+		log.Printf("Rejecting %s %s from origin %s (%s)", c.Request.Method, c.FullPath(), origin, c.ClientIP())
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Origin %s is not allowed", origin)})
+	}
+}
+
+// csrfMiddleware rejects requests with an unsafe method, whether or not they modify the cluster,
+// unless they carry a valid CSRF token
+func csrfMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if safeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if err := verifyCSRF(c); err != nil {
+			log.Printf("Rejecting %s %s from %s: %v", c.Request.Method, c.FullPath(), c.ClientIP(), err)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// getCSRFToken issues a token as a SameSite cookie and in the body. Mutating requests send it
+// back in the X-CSRF-Token header, which a page of another site can neither read nor forge.
+func getCSRFToken(c *gin.Context) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(nonce)
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	c.JSON(http.StatusOK, gin.H{"token": token, "header": csrfHeader})
+}
+
+// verifyCSRF checks that the CSRF header matches the cookie issued by getCSRFToken
+func verifyCSRF(c *gin.Context) error {
+	if !appConfig.CSRFProtection {
+		return nil
+	}
+	cookie, err := c.Cookie(csrfCookie)
+	token := c.GetHeader(csrfHeader)
+	if err != nil || cookie == "" || token == "" {
+		return fmt.Errorf("Missing CSRF token, fetch one from /api/csrf-token and send it in the %s header", csrfHeader)
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(token)) != 1 {
+		return fmt.Errorf("Invalid CSRF token")
+	}
+	return nil
+}
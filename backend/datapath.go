@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// dataPathMaxBackups bounds the backups listed per pod, newest first
+const dataPathMaxBackups = 20
+
+// Scopes of a backup in the data path of a pod
+const (
+	// DataPathScopePod backups were taken from the pod itself
+	DataPathScopePod = "pod"
+	// DataPathScopeVolume backups hold a snapshot of a volume of the pod
+	DataPathScopeVolume = "volume"
+	// DataPathScopeComponent backups were taken from another replica of the pod's component
+	DataPathScopeComponent = "component"
+)
+
+var volumeSnapshotGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+
+// DataPathSnapshot is a VolumeSnapshot of a claim of the pod
+type DataPathSnapshot struct {
+	Name         string `json:"name"`
+	ReadyToUse   bool   `json:"readyToUse"`
+	Size         string `json:"size,omitempty"`
+	CreationTime string `json:"creationTime"`
+	// Backup is the Backup the snapshot was taken for, if any
+	Backup string `json:"backup,omitempty"`
+}
+
+// DataPathVolume is a PersistentVolumeClaim mounted by the pod with its snapshots
+type DataPathVolume struct {
+	// Volume is the name of the volume in the pod spec, e.g. data
+	Volume           string             `json:"volume"`
+	Claim            string             `json:"claim"`
+	Phase            string             `json:"phase,omitempty"`
+	Capacity         string             `json:"capacity,omitempty"`
+	StorageClass     string             `json:"storageClass,omitempty"`
+	PersistentVolume string             `json:"persistentVolume,omitempty"`
+	Snapshots        []DataPathSnapshot `json:"snapshots"`
+}
+
+// DataPathBackup is a Backup protecting the data of the pod
+type DataPathBackup struct {
+	Name           string `json:"name"`
+	Phase          string `json:"phase,omitempty"`
+	Method         string `json:"method,omitempty"`
+	Scope          string `json:"scope"`
+	TotalSize      string `json:"totalSize,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// PodDataPath is the data path of a database pod: its claims, their snapshots and the backups
+type PodDataPath struct {
+	Pod       string           `json:"pod"`
+	Namespace string           `json:"namespace"`
+	Cluster   string           `json:"cluster,omitempty"`
+	Component string           `json:"component,omitempty"`
+	Role      string           `json:"role,omitempty"`
+	Volumes   []DataPathVolume `json:"volumes"`
+	Backups   []DataPathBackup `json:"backups"`
+	// Coverage is the narrowest scope of a completed backup or ready snapshot: pod, volume,
+	// component or none
+	Coverage string `json:"coverage"`
+	// LastBackup is the completion time of the newest completed backup of any scope
+	LastBackup string   `json:"lastBackup,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// backupTargetPods are the pods the actions of a Backup ran against
+func backupTargetPods(backup *unstructured.Unstructured) map[string]bool {
+	pods := map[string]bool{}
+	actions, _, _ := unstructured.NestedSlice(backup.Object, "status", "actions")
+	for _, item := range actions {
+		if action, ok := item.(map[string]interface{}); ok {
+			if pod, _, _ := unstructured.NestedString(action, "targetPodName"); pod != "" {
+				pods[pod] = true
+			}
+		}
+	}
+	return pods
+}
+
+// backupSnapshotNames are the VolumeSnapshots the actions of a Backup created
+func backupSnapshotNames(backup *unstructured.Unstructured) map[string]bool {
+	names := map[string]bool{}
+	actions, _, _ := unstructured.NestedSlice(backup.Object, "status", "actions")
+	for _, item := range actions {
+		action, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		snapshots, _, _ := unstructured.NestedSlice(action, "volumeSnapshots")
+		for _, snapshot := range snapshots {
+			if fields, ok := snapshot.(map[string]interface{}); ok {
+				if name, _ := fields["name"].(string); name != "" {
+					names[name] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// buildPodDataPath follows the claims of the pod to their VolumeSnapshots, and finds the Backups taken
+// from the pod, of its volumes, or of its component. Missing snapshot or backup CRDs leave the
+// sections empty with a warning.
+func buildPodDataPath(client *K8sClient, pod *corev1.Pod) *PodDataPath {
+	dataPath := &PodDataPath{
+		Pod:       pod.Name,
+		Namespace: pod.Namespace,
+		Cluster:   pod.Labels[instanceLabel],
+		Component: pod.Labels[componentNameLabel],
+		Role:      pod.Labels[roleLabel],
+		Volumes:   []DataPathVolume{},
+		Backups:   []DataPathBackup{},
+		Coverage:  "none",
+	}
+
+	claims := map[string]int{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		entry := DataPathVolume{Volume: volume.Name, Claim: volume.PersistentVolumeClaim.ClaimName, Snapshots: []DataPathSnapshot{}}
+		pvc, err := client.dynamicClient.Resource(pvcGVR).Namespace(pod.Namespace).Get(context.TODO(), entry.Claim, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("    ⚠️  Unable to get claim %s of pod %s: %v", entry.Claim, pod.Name, err)
+			entry.Phase = "Missing"
+		} else {
+			entry.Phase, _, _ = unstructured.NestedString(pvc.Object, "status", "phase")
+			entry.PersistentVolume, _, _ = unstructured.NestedString(pvc.Object, "spec", "volumeName")
+			entry.StorageClass, _, _ = unstructured.NestedString(pvc.Object, "spec", "storageClassName")
+			entry.Capacity, _, _ = unstructured.NestedString(pvc.Object, "status", "capacity", "storage")
+		}
+		claims[entry.Claim] = len(dataPath.Volumes)
+		dataPath.Volumes = append(dataPath.Volumes, entry)
+	}
+
+	volumeSnapshots := map[string]string{}
+	if len(claims) > 0 {
+		snapshots, err := client.dynamicClient.Resource(volumeSnapshotGVR).Namespace(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			dataPath.Warnings = append(dataPath.Warnings, "Unable to list VolumeSnapshots: "+err.Error())
+		} else {
+			for i := range snapshots.Items {
+				snapshot := &snapshots.Items[i]
+				claim, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName")
+				index, found := claims[claim]
+				if !found {
+					continue
+				}
+				entry := DataPathSnapshot{Name: snapshot.GetName(), CreationTime: snapshot.GetCreationTimestamp().UTC().Format(time.RFC3339)}
+				entry.ReadyToUse, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+				entry.Size, _, _ = unstructured.NestedString(snapshot.Object, "status", "restoreSize")
+				for _, owner := range snapshot.GetOwnerReferences() {
+					if owner.Kind == "Backup" {
+						entry.Backup = owner.Name
+					}
+				}
+				volumeSnapshots[entry.Name] = entry.Backup
+				if entry.ReadyToUse {
+					dataPath.Coverage = DataPathScopeVolume
+				}
+				dataPath.Volumes[index].Snapshots = append(dataPath.Volumes[index].Snapshots, entry)
+			}
+		}
+		for i := range dataPath.Volumes {
+			snapshots := dataPath.Volumes[i].Snapshots
+			sort.Slice(snapshots, func(a, b int) bool { return snapshots[a].CreationTime > snapshots[b].CreationTime })
+		}
+	}
+
+	if dataPath.Cluster != "" {
+		selector := labels.Set{instanceLabel: dataPath.Cluster}
+		if dataPath.Component != "" {
+			selector[componentNameLabel] = dataPath.Component
+		}
+		backups, err := client.dynamicClient.Resource(backupGVR).Namespace(pod.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			dataPath.Warnings = append(dataPath.Warnings, "Unable to list Backups: "+err.Error())
+		} else {
+			for i := range backups.Items {
+				dataPath.Backups = append(dataPath.Backups, newDataPathBackup(&backups.Items[i], pod.Name, volumeSnapshots))
+			}
+		}
+	}
+
+	scopeRank := map[string]int{"none": 0, DataPathScopeComponent: 1, DataPathScopeVolume: 2, DataPathScopePod: 3}
+	for _, backup := range dataPath.Backups {
+		if backup.Phase != "Completed" {
+			continue
+		}
+		if scopeRank[backup.Scope] > scopeRank[dataPath.Coverage] {
+			dataPath.Coverage = backup.Scope
+		}
+		if backup.CompletionTime > dataPath.LastBackup {
+			dataPath.LastBackup = backup.CompletionTime
+		}
+	}
+	sort.Slice(dataPath.Backups, func(i, j int) bool { return dataPath.Backups[i].CompletionTime > dataPath.Backups[j].CompletionTime })
+	if len(dataPath.Backups) > dataPathMaxBackups {
+		dataPath.Backups = dataPath.Backups[:dataPathMaxBackups]
+	}
+	return dataPath
+}
+
+// newDataPathBackup scopes a Backup of the pod's component to the pod, the volumes of the pod
+// through their snapshots, or the component
+func newDataPathBackup(backup *unstructured.Unstructured, podName string, volumeSnapshots map[string]string) DataPathBackup {
+	entry := DataPathBackup{Name: backup.GetName(), Scope: DataPathScopeComponent}
+	entry.Phase, _, _ = unstructured.NestedString(backup.Object, "status", "phase")
+	entry.Method, _, _ = unstructured.NestedString(backup.Object, "spec", "backupMethod")
+	entry.TotalSize, _, _ = unstructured.NestedString(backup.Object, "status", "totalSize")
+	entry.CompletionTime, _, _ = unstructured.NestedString(backup.Object, "status", "completionTimestamp")
+
+	if backupTargetPods(backup)[podName] {
+		entry.Scope = DataPathScopePod
+		return entry
+	}
+	snapshots := backupSnapshotNames(backup)
+	for snapshot, owner := range volumeSnapshots {
+		if snapshots[snapshot] || owner == backup.GetName() {
+			entry.Scope = DataPathScopeVolume
+			break
+		}
+	}
+	return entry
+}
+
+// getPodDataPath shows the data protection of one replica: the claims the pod mounts, the
+// VolumeSnapshots of those claims, and the Backups taken from the pod, its volumes or its component
+func getPodDataPath(c *gin.Context) {
+	podName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching the data path of a pod"})
+		return
+	}
+
+	log.Printf("Computing data path of pod %s in namespace '%s' requested from %s", podName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	resource, err := client.dynamicClient.Resource(podGVR).Namespace(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(resource.Object, pod); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dataPath := buildPodDataPath(client, pod)
+	log.Printf("Pod %s mounts %d claims and is covered by %d backups (coverage %s)", podName, len(dataPath.Volumes), len(dataPath.Backups), dataPath.Coverage)
+	c.JSON(http.StatusOK, dataPath)
+}
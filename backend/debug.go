@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	execTokenVersion    = "exec1"
+	execTokenTTL        = 5 * time.Minute
+	debugContainerStart = time.Minute
+	debugContainerName  = "debugger-"
+	// Close reasons of WebSocket frames are limited to 123 bytes
+	maxCloseReason = 120
+)
+
+// DebugRequest is the body of POST /api/pods/:name/debug
+type DebugRequest struct {
+	// Image of the debug container, debugImage of the configuration when empty
+	Image string `json:"image"`
+	// TargetContainer shares its process namespace with the debug container, the first container when empty
+	TargetContainer string `json:"targetContainer"`
+	// Command overrides the entrypoint of the image
+	Command []string `json:"command,omitempty"`
+}
+
+// DebugSession is an ephemeral debug container attached to a pod and the WebSocket URL of a shell in it
+type DebugSession struct {
+	Pod             string   `json:"pod"`
+	Namespace       string   `json:"namespace"`
+	Container       string   `json:"container"`
+	Image           string   `json:"image"`
+	TargetContainer string   `json:"targetContainer"`
+	Command         []string `json:"command,omitempty"`
+	// ExecURL opens a shell in the container over a WebSocket until ExecURLExpiresAt
+	ExecURL          string `json:"execURL,omitempty"`
+	ExecURLExpiresAt string `json:"execURLExpiresAt,omitempty"`
+}
+
+// ExecClaims scope an exec token to one container of a pod and to the user who launched it
+type ExecClaims struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	User      string `json:"user,omitempty"`
+	Expires   int64  `json:"exp"`
+}
+
+// execMessage is a message of the browser on the exec WebSocket: stdin data or a terminal resize
+type execMessage struct {
+	Op   string `json:"op"` // stdin or resize
+	Data string `json:"data,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+var execUpgrader = websocket.Upgrader{
+	// Pages of other origins must not open shells with the cookies of the user
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || originAllowed(origin, r.Host)
+	},
+}
+
+// createDebugContainer implements kubectl debug: it adds an ephemeral container to the pod,
+// sharing the process namespace of the target container, and returns the exec URL of a shell in it.
+// dryRun=true only validates the update of the pod.
+func createDebugContainer(c *gin.Context) {
+	podName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace is required for debugging a pod"})
+		return
+	}
+	dryRun, err := dryRunRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var request DebugRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid debug request: %v", err)})
+			return
+		}
+	}
+	image := defaultString(request.Image, appConfig.DebugImage)
+
+	log.Printf("Attaching debug container (%s) to pod %s in namespace '%s' requested from %s", image, podName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	pod, err := client.clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	target := request.TargetContainer
+	if target == "" && len(pod.Spec.Containers) > 0 {
+		target = pod.Spec.Containers[0].Name
+	}
+	if !podHasContainer(pod, target) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Pod %s has no container %s", podName, target)})
+		return
+	}
+
+	container := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     debugContainerName + utilrand.String(5),
+			Image:                    image,
+			Command:                  request.Command,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: target,
+	}
+	before := pod.DeepCopy()
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, container)
+	updated, err := client.clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{DryRun: dryRunOption(dryRun)})
+	if err != nil {
+		log.Printf("Error attaching debug container to pod %s in namespace %s: %v", podName, namespace, err)
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	session := DebugSession{
+		Pod:             podName,
+		Namespace:       namespace,
+		Container:       container.Name,
+		Image:           image,
+		TargetContainer: target,
+		Command:         request.Command,
+	}
+	if dryRun {
+		// A dry-run container cannot be opened, so no exec URL is signed
+		beforeObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(before)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		updatedObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(updated)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		beforePod, updatedPod := &unstructured.Unstructured{Object: beforeObject}, &unstructured.Unstructured{Object: updatedObject}
+		beforePod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		updatedPod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		respondDryRun(c, beforePod, updatedPod, session)
+		return
+	}
+
+	// The token is bound to the cluster the container was attached in; sessions of uploaded
+	// kubeconfigs have random names, so it is only valid within the same session
+	expires := time.Now().Add(execTokenTTL)
+	token, err := signToken(execTokenVersion, ExecClaims{
+		Cluster:   clientFor(c).name,
+		Namespace: namespace,
+		Pod:       podName,
+		Container: container.Name,
+		User:      requestUser(c),
+		Expires:   expires.Unix(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	query := url.Values{"namespace": {namespace}, "container": {container.Name}, "token": {token}}
+	if cluster := c.Query("cluster"); cluster != "" {
+		query.Set("cluster", cluster)
+	}
+
+	session.ExecURL = strings.TrimSuffix(c.Request.URL.Path, "/debug") + "/exec?" + query.Encode()
+	session.ExecURLExpiresAt = expires.UTC().Format(time.RFC3339)
+	log.Printf("✓ Attached debug container %s to pod %s in namespace %s", container.Name, podName, namespace)
+	c.JSON(http.StatusCreated, session)
+}
+
+// podHasContainer reports whether the pod has a regular or init container with the name
+func podHasContainer(pod *corev1.Pod, name string) bool {
+	for _, containers := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, container := range containers {
+			if container.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// execInContainer opens a shell in a debug container over a WebSocket. It needs the token of the exec
+// URL returned by createDebugContainer, since browsers cannot send the CSRF header on WebSockets.
+func execInContainer(c *gin.Context) {
+	podName := c.Param("name")
+	namespace := c.Query("namespace")
+	container := c.Query("container")
+	if !appConfig.WriteEnabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Write actions are disabled, set KB_VIZ_WRITE_ENABLED=true to enable them"})
+		return
+	}
+	var claims ExecClaims
+	if err := verifyToken(c.Query("token"), execTokenVersion, "exec", &claims); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if time.Now().Unix() > claims.Expires {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Exec token expired, launch a new debug container"})
+		return
+	}
+	if claims.Pod != podName || claims.Namespace != namespace || claims.Container != container ||
+		claims.Cluster != clientFor(c).name || claims.User != requestUser(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Exec token does not cover this container"})
+		return
+	}
+	command := c.QueryArray("command")
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+
+	log.Printf("Opening shell in container %s of pod %s in namespace '%s' requested from %s", container, podName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	if client.config == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Exec is not supported by this cluster client"})
+		return
+	}
+	if status, err := waitForContainerRunning(c.Request.Context(), client, namespace, podName, container); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	execRequest := client.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(podName).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     true,
+			Stdout:    true,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(client.config, http.MethodPost, execRequest.URL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := execUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already answered the request
+		log.Printf("Unable to upgrade exec request of pod %s to a WebSocket: %v", podName, err)
+		return
+	}
+	session := newExecSession(conn)
+	err = session.run(c.Request.Context(), executor)
+	reason := "exited"
+	if err != nil {
+		reason = err.Error()
+		log.Printf("Shell in container %s of pod %s ended: %v", container, podName, err)
+	} else {
+		log.Printf("✓ Shell in container %s of pod %s exited", container, podName)
+	}
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+	session.close(reason)
+}
+
+// waitForContainerRunning waits until the container of the pod runs, ephemeral containers take a moment to start
+func waitForContainerRunning(ctx context.Context, client *K8sClient, namespace, podName, container string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, debugContainerStart)
+	defer cancel()
+	for {
+		pod, err := client.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return apiErrorStatusCode(err), err
+		}
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.EphemeralContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.Name != container {
+					continue
+				}
+				if status.State.Running != nil {
+					return http.StatusOK, nil
+				}
+				if terminated := status.State.Terminated; terminated != nil {
+					return http.StatusConflict, fmt.Errorf("Container %s terminated: %s", container, defaultString(terminated.Message, terminated.Reason))
+				}
+				if waiting := status.State.Waiting; waiting != nil && degradedWaitingReasons[waiting.Reason] {
+					return http.StatusConflict, fmt.Errorf("Container %s cannot start: %s %s", container, waiting.Reason, waiting.Message)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return http.StatusGatewayTimeout, fmt.Errorf("Container %s of pod %s is not running after %s", container, podName, debugContainerStart)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// execSession bridges a WebSocket and the streams of an exec: stdin and resize messages in, terminal output out
+type execSession struct {
+	conn    *websocket.Conn
+	stdin   *io.PipeReader
+	stdinW  *io.PipeWriter
+	sizes   chan remotecommand.TerminalSize
+	writeMu sync.Mutex
+}
+
+func newExecSession(conn *websocket.Conn) *execSession {
+	stdin, stdinW := io.Pipe()
+	return &execSession{conn: conn, stdin: stdin, stdinW: stdinW, sizes: make(chan remotecommand.TerminalSize, 1)}
+}
+
+// Write sends terminal output to the browser as a binary message
+func (session *execSession) Write(data []byte) (int, error) {
+	session.writeMu.Lock()
+	defer session.writeMu.Unlock()
+	if err := session.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Next returns the next terminal size, nil once the browser is gone
+func (session *execSession) Next() *remotecommand.TerminalSize {
+	size, ok := <-session.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+// run streams the exec until the shell exits or the browser goes away
+func (session *execSession) run(ctx context.Context, executor remotecommand.Executor) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The browser's messages feed stdin and the terminal size
+	go func() {
+		defer close(session.sizes)
+		defer cancel()
+		for {
+			_, data, err := session.conn.ReadMessage()
+			if err != nil {
+				session.stdinW.CloseWithError(err)
+				return
+			}
+			var message execMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				continue
+			}
+			switch message.Op {
+			case "stdin":
+				if _, err := session.stdinW.Write([]byte(message.Data)); err != nil {
+					return
+				}
+			case "resize":
+				// Only the latest size matters
+				select {
+				case <-session.sizes:
+				default:
+				}
+				session.sizes <- remotecommand.TerminalSize{Width: message.Cols, Height: message.Rows}
+			}
+		}
+	}()
+
+	// Pings keep proxies from closing an idle shell
+	go func() {
+		ticker := time.NewTicker(streamHeartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				session.writeMu.Lock()
+				err := session.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+				session.writeMu.Unlock()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             session.stdin,
+		Stdout:            session,
+		Tty:               true,
+		TerminalSizeQueue: session,
+	})
+}
+
+// close ends the WebSocket with the reason the shell ended
+func (session *execSession) close(reason string) {
+	session.stdin.Close()
+	session.writeMu.Lock()
+	session.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(time.Second))
+	session.writeMu.Unlock()
+	session.conn.Close()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Actions the garbage collector takes on a dependent
+const (
+	GCActionDelete = "delete"
+	GCActionOrphan = "orphan"
+)
+
+// DeletionImpactEntry is a resource affected by deleting the root
+type DeletionImpactEntry struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	UID       string `json:"uid"`
+	Owner     string `json:"owner"` // kind/name of the owner through which it is affected
+	Depth     int    `json:"depth"`
+	Action    string `json:"action"`
+	// BlockOwnerDeletion marks dependents that hold up a foreground deletion of their owner
+	BlockOwnerDeletion bool `json:"blockOwnerDeletion,omitempty"`
+}
+
+// DeletionImpact lists everything the garbage collector would remove after deleting a resource
+type DeletionImpact struct {
+	Kind              string                `json:"kind"`
+	Name              string                `json:"name"`
+	Namespace         string                `json:"namespace,omitempty"`
+	PropagationPolicy string                `json:"propagationPolicy"`
+	Deleted           []DeletionImpactEntry `json:"deleted"`
+	Orphaned          []DeletionImpactEntry `json:"orphaned"`
+	Warnings          []string              `json:"warnings,omitempty"`
+}
+
+// orphansDependents reports whether deleting the owner leaves its dependents in place
+func orphansDependents(owner *unstructured.Unstructured, isRoot bool, policy metav1.DeletionPropagation) bool {
+	if isRoot && policy == metav1.DeletePropagationOrphan {
+		return true
+	}
+	for _, finalizer := range owner.GetFinalizers() {
+		if finalizer == metav1.FinalizerOrphanDependents {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeDeletionImpact simulates the garbage collector over the pool. A dependent is deleted once
+// all of its owners are deleted and at least one of them propagates the deletion; dependents of
+// owners that orphan lose the owner reference instead.
+func analyzeDeletionImpact(root *unstructured.Unstructured, pool *ResourcePool, policy metav1.DeletionPropagation) *DeletionImpact {
+	impact := &DeletionImpact{
+		Kind:              root.GetKind(),
+		Name:              root.GetName(),
+		Namespace:         root.GetNamespace(),
+		PropagationPolicy: string(policy),
+		Deleted:           []DeletionImpactEntry{},
+		Orphaned:          []DeletionImpactEntry{},
+	}
+
+	deleted := map[types.UID]*DeletionImpactEntry{root.GetUID(): {Depth: 0}}
+	orphaning := map[types.UID]bool{root.GetUID(): orphansDependents(root, true, policy)}
+	resourceOf := func(uid types.UID) *unstructured.Unstructured {
+		if uid == root.GetUID() {
+			return root
+		}
+		return pool.GetResource(uid)
+	}
+
+	// Iterate to a fixpoint, since a dependent may have several owners deleted at different depths
+	for changed := true; changed; {
+		changed = false
+		for _, resource := range pool.GetAllResources() {
+			if _, done := deleted[resource.GetUID()]; done {
+				continue
+			}
+
+			var cause *metav1.OwnerReference
+			allOwnersDeleted := true
+			for i, ref := range resource.GetOwnerReferences() {
+				if _, ok := deleted[ref.UID]; !ok {
+					allOwnersDeleted = false
+					continue
+				}
+				if !orphaning[ref.UID] && cause == nil {
+					cause = &resource.GetOwnerReferences()[i]
+				}
+			}
+			if cause == nil || !allOwnersDeleted {
+				continue
+			}
+
+			owner := resourceOf(cause.UID)
+			entry := &DeletionImpactEntry{
+				Kind:      resource.GetKind(),
+				Name:      resource.GetName(),
+				Namespace: resource.GetNamespace(),
+				UID:       string(resource.GetUID()),
+				Owner:     fmt.Sprintf("%s/%s", owner.GetKind(), owner.GetName()),
+				Depth:     deleted[cause.UID].Depth + 1,
+				Action:    GCActionDelete,
+				BlockOwnerDeletion: policy == metav1.DeletePropagationForeground &&
+					cause.BlockOwnerDeletion != nil && *cause.BlockOwnerDeletion,
+			}
+			deleted[resource.GetUID()] = entry
+			orphaning[resource.GetUID()] = orphansDependents(resource, false, policy)
+			impact.Deleted = append(impact.Deleted, *entry)
+			changed = true
+		}
+	}
+
+	// Report what survives: dependents of orphaning owners, and dependents with owners outside the deletion
+	for _, resource := range pool.GetAllResources() {
+		if _, done := deleted[resource.GetUID()]; done {
+			continue
+		}
+		for _, ref := range resource.GetOwnerReferences() {
+			ownerEntry, ok := deleted[ref.UID]
+			if !ok {
+				continue
+			}
+			owner := resourceOf(ref.UID)
+			if !orphaning[ref.UID] {
+				impact.Warnings = append(impact.Warnings, fmt.Sprintf("%s/%s is kept because it has owners that are not deleted", resource.GetKind(), resource.GetName()))
+				break
+			}
+			impact.Orphaned = append(impact.Orphaned, DeletionImpactEntry{
+				Kind:      resource.GetKind(),
+				Name:      resource.GetName(),
+				Namespace: resource.GetNamespace(),
+				UID:       string(resource.GetUID()),
+				Owner:     fmt.Sprintf("%s/%s", owner.GetKind(), owner.GetName()),
+				Depth:     ownerEntry.Depth + 1,
+				Action:    GCActionOrphan,
+			})
+			break
+		}
+	}
+
+	for _, entries := range [][]DeletionImpactEntry{impact.Deleted, impact.Orphaned} {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Depth != entries[j].Depth {
+				return entries[i].Depth < entries[j].Depth
+			}
+			if entries[i].Kind != entries[j].Kind {
+				return entries[i].Kind < entries[j].Kind
+			}
+			return entries[i].Name < entries[j].Name
+		})
+	}
+
+	// KubeBlocks clusters delete their data according to their termination policy
+	if root.GetKind() == "Cluster" {
+		if terminationPolicy, found, _ := unstructured.NestedString(root.Object, "spec", "terminationPolicy"); found {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("Cluster terminationPolicy is %s, which decides whether PVCs and backups are removed", terminationPolicy))
+		}
+	}
+	return impact
+}
+
+func getDeletionImpact(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	params, err := queryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy := metav1.DeletionPropagation(defaultString(params.PropagationPolicy, string(metav1.DeletePropagationBackground)))
+
+	log.Printf("Analyzing deletion impact of %s/%s in namespace '%s' (%s) requested from %s", resourceType, resourceName, namespace, policy, c.ClientIP())
+
+	client := clientFor(c)
+	root, _, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// KubeBlocks resources share the instance label of their cluster, which narrows the pool
+	listOptions := metav1.ListOptions{}
+	if instance := root.GetLabels()[instanceLabel]; instance != "" {
+		listOptions.LabelSelector = fmt.Sprintf("%s=%s", instanceLabel, instance)
+	}
+	treeBuilder := NewResourceTreeBuilder(client, namespace, listOptions)
+	if err := treeBuilder.buildResourcePool(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	impact := analyzeDeletionImpact(root, treeBuilder.pool, policy)
+	log.Printf("Deleting %s/%s would delete %d and orphan %d resources", root.GetKind(), resourceName, len(impact.Deleted), len(impact.Orphaned))
+	c.JSON(http.StatusOK, impact)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// Default age after which a terminating resource is considered stuck
+const defaultStuckDeletionMinutes = 10
+
+// StuckDeletion is a resource that has been terminating for longer than the threshold,
+// usually because a finalizer is never removed
+type StuckDeletion struct {
+	Kind              string   `json:"kind"`
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	UID               string   `json:"uid"`
+	DeletionTimestamp string   `json:"deletionTimestamp"`
+	TerminatingFor    string   `json:"terminatingFor"`
+	Finalizers        []string `json:"finalizers,omitempty"`
+	Owner             string   `json:"owner,omitempty"`
+}
+
+// deletionTimestamp formats the deletion timestamp of a resource as RFC3339, empty when it is not terminating
+func deletionTimestamp(resource *unstructured.Unstructured) string {
+	deletion := resource.GetDeletionTimestamp()
+	if deletion == nil {
+		return ""
+	}
+	return deletion.UTC().Format(time.RFC3339)
+}
+
+// AnnotateDeletions sets the deletion timestamp and finalizers of every node
+func (rtb *ResourceTreeBuilder) AnnotateDeletions(root *ResourceTreeNode) {
+	var annotate func(node *ResourceTreeNode)
+	annotate = func(node *ResourceTreeNode) {
+		node.DeletionTimestamp = deletionTimestamp(node.Resource)
+		node.Finalizers = node.Resource.GetFinalizers()
+		for _, child := range node.Children {
+			annotate(child)
+		}
+	}
+	annotate(root)
+}
+
+// getStuckDeletions lists the resources of a namespace terminating for more than minutes= (default 10), longest first
+func getStuckDeletions(c *gin.Context) {
+	namespace := c.Param("ns")
+	threshold := defaultStuckDeletionMinutes
+	params, err := queryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Minutes != nil {
+		threshold = *params.Minutes
+	}
+
+	log.Printf("Listing deletions stuck for more than %d minutes in namespace '%s' requested from %s", threshold, namespace, c.ClientIP())
+
+	treeBuilder := NewResourceTreeBuilder(clientFor(c), namespace, metav1.ListOptions{})
+	treeBuilder.language = requestLanguage(c)
+	if err := treeBuilder.buildResourcePool(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	stuck := []StuckDeletion{}
+	for _, resource := range treeBuilder.pool.GetAllResources() {
+		deletion := resource.GetDeletionTimestamp()
+		if deletion == nil || now.Sub(deletion.Time) < time.Duration(threshold)*time.Minute {
+			continue
+		}
+		entry := StuckDeletion{
+			Kind:              resource.GetKind(),
+			Name:              resource.GetName(),
+			Namespace:         resource.GetNamespace(),
+			UID:               string(resource.GetUID()),
+			DeletionTimestamp: deletionTimestamp(resource),
+			TerminatingFor:    duration.HumanDuration(now.Sub(deletion.Time)),
+			Finalizers:        resource.GetFinalizers(),
+		}
+		if owner := treeBuilder.pool.GetResource(treeBuilder.pool.PrimaryOwner(resource)); owner != nil {
+			entry.Owner = owner.GetKind() + "/" + owner.GetName()
+		}
+		stuck = append(stuck, entry)
+	}
+	sort.Slice(stuck, func(i, j int) bool {
+		return stuck[i].DeletionTimestamp < stuck[j].DeletionTimestamp
+	})
+
+	log.Printf("Found %d stuck deletions in namespace %s", len(stuck), namespace)
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":        namespace,
+		"thresholdMinutes": threshold,
+		"stuck":            stuck,
+		"warnings":         treeBuilder.Warnings(),
+	})
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// demoNamespace is the namespace of the clusters served in demo mode
+const demoNamespace = "demo"
+
+var backupScheduleGVR = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"}
+
+// demoCluster describes a KubeBlocks cluster of the demo, with a single component
+type demoCluster struct {
+	name      string
+	component string
+	compDef   string
+	image     string
+	port      int64
+	phase     string
+	// roles of the pods, by ordinal
+	roles []string
+	// crashing is the ordinal of a pod in CrashLoopBackOff, or -1
+	crashing int
+	age      time.Duration
+}
+
+var demoClusters = []demoCluster{
+	{name: "orders-db", component: "mysql", compDef: "apecloud-mysql-8.0", image: "apecloud/apecloud-mysql-server:8.0.30", port: 3306,
+		phase: "Running", roles: []string{"leader", "follower", "follower"}, crashing: -1, age: 72 * time.Hour},
+	{name: "session-cache", component: "redis", compDef: "redis-7", image: "redis:7.2.4", port: 6379,
+		phase: "Abnormal", roles: []string{"primary", "secondary"}, crashing: 1, age: 26 * time.Hour},
+	{name: "analytics-pg", component: "postgresql", compDef: "postgresql-16", image: "postgres:16.2", port: 5432,
+		phase: "Updating", roles: []string{"primary", "secondary"}, crashing: -1, age: 5 * time.Hour},
+}
+
+// demoObjects collects the resources of the demo, created in order like the bench resources
+type demoObjects struct {
+	now     time.Time
+	objects []benchObject
+}
+
+func (demo *demoObjects) add(gvr schema.GroupVersionResource, kind, name, instance string, owner *unstructured.Unstructured, age time.Duration, labels map[string]string, fields map[string]interface{}) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: fields}
+	object.SetAPIVersion(gvr.GroupVersion().String())
+	object.SetKind(kind)
+	object.SetName(name)
+	object.SetNamespace(demoNamespace)
+	object.SetUID(types.UID(fmt.Sprintf("%s-%s-%s", demoNamespace, gvr.Resource, name)))
+	object.SetCreationTimestamp(metav1.NewTime(demo.now.Add(-age)))
+	objectLabels := map[string]string{"app.kubernetes.io/instance": instance, "app.kubernetes.io/managed-by": "kubeblocks"}
+	for key, value := range labels {
+		objectLabels[key] = value
+	}
+	object.SetLabels(objectLabels)
+	demo.objects = append(demo.objects, benchObject{gvr: gvr, object: object, owner: owner})
+	return object
+}
+
+func (demo *demoObjects) timestamp(age time.Duration) string {
+	return demo.now.Add(-age).UTC().Format(time.RFC3339)
+}
+
+// addCluster adds a Cluster with its Component, InstanceSet, pods, PVCs, Services, Secret and
+// ConfigMap, a BackupPolicy with its schedule and backups, and the OpsRequests run on it
+func (demo *demoObjects) addCluster(spec demoCluster) {
+	replicas := int64(len(spec.roles))
+	ready := replicas
+	if spec.crashing >= 0 {
+		ready--
+	}
+	componentName := spec.name + "-" + spec.component
+	componentLabels := map[string]string{componentNameLabel: spec.component}
+
+	cluster := demo.add(clusterGVR, "Cluster", spec.name, spec.name, nil, spec.age, nil, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"terminationPolicy": "Delete",
+			"componentSpecs": []interface{}{map[string]interface{}{
+				"name":         spec.component,
+				"componentDef": spec.compDef,
+				"replicas":     replicas,
+				"resources": map[string]interface{}{
+					"limits":   map[string]interface{}{"cpu": "1", "memory": "2Gi"},
+					"requests": map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+				},
+			}},
+		},
+		"status": map[string]interface{}{
+			"phase":      spec.phase,
+			"components": map[string]interface{}{spec.component: map[string]interface{}{"phase": spec.phase}},
+		},
+	})
+	demo.add(serviceGVR, "Service", componentName, spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"app.kubernetes.io/instance": spec.name, componentNameLabel: spec.component},
+			"ports":    []interface{}{map[string]interface{}{"name": spec.component, "port": spec.port}},
+		},
+	})
+	demo.add(serviceGVR, "Service", componentName+"-headless", spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"clusterIP": "None",
+			"selector":  map[string]interface{}{"app.kubernetes.io/instance": spec.name, componentNameLabel: spec.component},
+			"ports":     []interface{}{map[string]interface{}{"name": spec.component, "port": spec.port}},
+		},
+	})
+	demo.add(secretGVR, "Secret", componentName+"-account-root", spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"stringData": map[string]interface{}{"username": "root", "password": "demo"},
+	})
+	demo.add(configMapGVR, "ConfigMap", componentName+"-config", spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"data": map[string]interface{}{"config": fmt.Sprintf("# %s configuration of %s\n", spec.component, spec.name)},
+	})
+
+	component := demo.add(componentGVR, "Component", componentName, spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"spec":   map[string]interface{}{"compDef": spec.compDef, "replicas": replicas},
+		"status": map[string]interface{}{"phase": spec.phase},
+	})
+	instanceSet := demo.add(instanceSetGVR, "InstanceSet", componentName, spec.name, component, spec.age, componentLabels, map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": replicas},
+		"status": map[string]interface{}{"replicas": replicas, "readyReplicas": ready, "availableReplicas": ready},
+	})
+
+	for ordinal, role := range spec.roles {
+		podName := fmt.Sprintf("%s-%d", componentName, ordinal)
+		pvcName := "data-" + podName
+		demo.add(pvcGVR, "PersistentVolumeClaim", pvcName, spec.name, instanceSet, spec.age, componentLabels, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"accessModes": []interface{}{"ReadWriteOnce"},
+				"resources":   map[string]interface{}{"requests": map[string]interface{}{"storage": "20Gi"}},
+			},
+			"status": map[string]interface{}{"phase": "Bound", "capacity": map[string]interface{}{"storage": "20Gi"}},
+		})
+
+		containerStatus := map[string]interface{}{
+			"name": spec.component, "image": spec.image, "ready": true, "restartCount": int64(0),
+			"state": map[string]interface{}{"running": map[string]interface{}{"startedAt": demo.timestamp(spec.age)}},
+		}
+		readyCondition := "True"
+		if ordinal == spec.crashing {
+			readyCondition = "False"
+			containerStatus["ready"] = false
+			containerStatus["restartCount"] = int64(17)
+			containerStatus["state"] = map[string]interface{}{"waiting": map[string]interface{}{
+				"reason":  "CrashLoopBackOff",
+				"message": "back-off 5m0s restarting failed container",
+			}}
+		}
+		podLabels := map[string]string{componentNameLabel: spec.component, "kubeblocks.io/role": role}
+		demo.add(podGVR, "Pod", podName, spec.name, instanceSet, spec.age, podLabels, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"nodeName":   fmt.Sprintf("demo-node-%d", ordinal%3),
+				"containers": []interface{}{map[string]interface{}{"name": spec.component, "image": spec.image}},
+				"volumes": []interface{}{map[string]interface{}{
+					"name":                  "data",
+					"persistentVolumeClaim": map[string]interface{}{"claimName": pvcName},
+				}},
+			},
+			"status": map[string]interface{}{
+				"phase":             "Running",
+				"podIP":             fmt.Sprintf("10.244.%d.%d", ordinal%3, 10+ordinal),
+				"startTime":         demo.timestamp(spec.age),
+				"conditions":        []interface{}{map[string]interface{}{"type": "Ready", "status": readyCondition}},
+				"containerStatuses": []interface{}{containerStatus},
+			},
+		})
+	}
+
+	policy := demo.add(backupPolicyGVR, "BackupPolicy", componentName+"-backup-policy", spec.name, cluster, spec.age, componentLabels, map[string]interface{}{
+		"spec":   map[string]interface{}{"backupRepoName": "demo-backup-repo"},
+		"status": map[string]interface{}{"phase": "Available"},
+	})
+	demo.add(backupScheduleGVR, "BackupSchedule", componentName+"-backup-schedule", spec.name, policy, spec.age, componentLabels, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"backupPolicyName": policy.GetName(),
+			"schedules":        []interface{}{map[string]interface{}{"backupMethod": "xtrabackup", "cronExpression": "0 18 * * *", "enabled": true}},
+		},
+		"status": map[string]interface{}{"phase": "Available"},
+	})
+	for day := 2; day >= 1; day-- {
+		age := time.Duration(day) * 24 * time.Hour
+		if age > spec.age {
+			continue
+		}
+		phase := "Completed"
+		if spec.crashing >= 0 && day == 1 {
+			phase = "Failed"
+		}
+		demo.add(backupGVR, "Backup", fmt.Sprintf("%s-backup-%s", spec.name, demo.now.Add(-age).Format("20060102")), spec.name, policy, age, componentLabels, map[string]interface{}{
+			"spec": map[string]interface{}{"backupPolicyName": policy.GetName(), "backupMethod": "xtrabackup"},
+			"status": map[string]interface{}{
+				"phase":               phase,
+				"totalSize":           "1.2Gi",
+				"startTimestamp":      demo.timestamp(age),
+				"completionTimestamp": demo.timestamp(age - 10*time.Minute),
+				"actions": []interface{}{map[string]interface{}{
+					"name":          "dp-backup-0",
+					"phase":         phase,
+					"targetPodName": fmt.Sprintf("%s-%s-%d", spec.name, spec.component, replicas-1),
+				}},
+			},
+		})
+	}
+
+	opsLabels := map[string]string{"ops.kubeblocks.io/ops-type": "Restart"}
+	demo.add(opsRequestGVR, "OpsRequest", spec.name+"-restart", spec.name, cluster, spec.age/2, opsLabels, map[string]interface{}{
+		"spec": map[string]interface{}{"clusterName": spec.name, "type": "Restart", "restart": []interface{}{map[string]interface{}{"componentName": spec.component}}},
+		"status": map[string]interface{}{
+			"phase":               "Succeed",
+			"startTimestamp":      demo.timestamp(spec.age / 2),
+			"completionTimestamp": demo.timestamp(spec.age/2 - 3*time.Minute),
+		},
+	})
+	if spec.phase == "Updating" {
+		opsLabels := map[string]string{"ops.kubeblocks.io/ops-type": "VerticalScaling"}
+		demo.add(opsRequestGVR, "OpsRequest", spec.name+"-vscale", spec.name, cluster, 4*time.Minute, opsLabels, map[string]interface{}{
+			"spec": map[string]interface{}{"clusterName": spec.name, "type": "VerticalScaling", "verticalScaling": []interface{}{map[string]interface{}{
+				"componentName": spec.component,
+				"requests":      map[string]interface{}{"cpu": "1", "memory": "2Gi"},
+				"limits":        map[string]interface{}{"cpu": "2", "memory": "4Gi"},
+			}}},
+			"status": map[string]interface{}{"phase": "Running", "progress": "1/2", "startTimestamp": demo.timestamp(4 * time.Minute)},
+		})
+	}
+}
+
+// generateDemoObjects generates the demo clusters: a healthy MySQL cluster, a Redis cluster with a
+// pod in CrashLoopBackOff and a failed backup, and a PostgreSQL cluster being scaled
+func generateDemoObjects() []benchObject {
+	demo := &demoObjects{now: time.Now()}
+	for _, spec := range demoClusters {
+		demo.addCluster(spec)
+	}
+	return demo.objects
+}
+
+// newDemoClient creates the client of demo mode: an in-memory fake API server holding the demo
+// clusters, with Warning events for their failing pods. Nothing is read from a real cluster.
+func newDemoClient() (*K8sClient, error) {
+	objects := generateDemoObjects()
+	client := newFakeClient("demo", objects)
+	createFakeNodes(client, "demo")
+	if err := createBenchObjects(client, objects); err != nil {
+		return nil, err
+	}
+	if _, err := client.clientset.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: demoNamespace}}, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+
+	for _, spec := range demoClusters {
+		if spec.crashing < 0 {
+			continue
+		}
+		podName := fmt.Sprintf("%s-%s-%d", spec.name, spec.component, spec.crashing)
+		var pod *unstructured.Unstructured
+		for _, generated := range objects {
+			if generated.gvr == podGVR && generated.object.GetName() == podName {
+				pod = generated.object
+			}
+		}
+		if pod == nil {
+			continue
+		}
+		now := metav1.Now()
+		event := &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: podName + ".backoff", Namespace: demoNamespace},
+			InvolvedObject: corev1.ObjectReference{
+				APIVersion: "v1", Kind: "Pod", Name: podName, Namespace: demoNamespace, UID: pod.GetUID(),
+			},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        fmt.Sprintf("Back-off restarting failed container %s in pod %s", spec.component, podName),
+			Count:          17,
+			FirstTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+			LastTimestamp:  now,
+			Source:         corev1.EventSource{Component: "kubelet", Host: "demo-node-1"},
+		}
+		if _, err := client.clientset.CoreV1().Events(demoNamespace).Create(context.TODO(), event, metav1.CreateOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("✓ Demo mode: %d resources of %d clusters in namespace '%s'", len(objects), len(demoClusters), demoNamespace)
+	return client, nil
+}
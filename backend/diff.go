@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Diff entry types
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffEntry is a single field-level difference between the desired and the live object
+type DiffEntry struct {
+	Path    string      `json:"path"`
+	Type    string      `json:"type"`
+	Desired interface{} `json:"desired,omitempty"`
+	Live    interface{} `json:"live,omitempty"`
+}
+
+// ResourceDiff is the result of comparing a live resource with a desired manifest
+type ResourceDiff struct {
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Source    string      `json:"source"` // last-applied or request
+	Entries   []DiffEntry `json:"entries"`
+}
+
+// Fields the server owns, which never take part in the diff
+var diffIgnoredPaths = map[string]bool{
+	"status":                     true,
+	"metadata.uid":               true,
+	"metadata.resourceVersion":   true,
+	"metadata.generation":        true,
+	"metadata.creationTimestamp": true,
+	"metadata.managedFields":     true,
+	"metadata.selfLink":          true,
+}
+
+// diffObjects compares the fields set in desired with the live object. Fields that only
+// exist in the live object are defaulted or set by controllers and are not reported.
+func diffObjects(path string, desired, live interface{}) []DiffEntry {
+	var entries []DiffEntry
+	for _, change := range diffValues(path, desired, live, true) {
+		entries = append(entries, DiffEntry{Path: change.Path, Type: change.Type, Desired: change.Before, Live: change.After})
+	}
+	return entries
+}
+
+// diffValues lists the fields that differ between before and after, ignoring the fields the server
+// owns. With setFieldsOnly, object fields only present in after are not reported.
+func diffValues(path string, before, after interface{}, setFieldsOnly bool) []FieldChange {
+	if diffIgnoredPaths[path] {
+		return nil
+	}
+
+	switch beforeValue := before.(type) {
+	case map[string]interface{}:
+		afterMap, ok := after.(map[string]interface{})
+		if !ok {
+			return []FieldChange{newFieldChange(path, before, after)}
+		}
+		keys := beforeValue
+		if !setFieldsOnly {
+			keys = map[string]interface{}{}
+			for key := range beforeValue {
+				keys[key] = nil
+			}
+			for key := range afterMap {
+				keys[key] = nil
+			}
+		}
+		var changes []FieldChange
+		for _, key := range sortedKeys(keys) {
+			changes = append(changes, diffValues(joinDiffPath(path, key), beforeValue[key], afterMap[key], setFieldsOnly)...)
+		}
+		return changes
+	case []interface{}:
+		afterSlice, ok := after.([]interface{})
+		if !ok {
+			return []FieldChange{newFieldChange(path, before, after)}
+		}
+		return diffSlices(path, beforeValue, afterSlice, setFieldsOnly)
+	}
+
+	if !scalarsEqual(before, after) {
+		return []FieldChange{newFieldChange(path, before, after)}
+	}
+	return nil
+}
+
+// diffSlices matches list items by their name when they have one, otherwise by index
+func diffSlices(path string, before, after []interface{}, setFieldsOnly bool) []FieldChange {
+	var changes []FieldChange
+	if afterByName, ok := indexByName(after); ok {
+		if beforeByName, beforeNamed := indexByName(before); beforeNamed {
+			for _, item := range before {
+				name := item.(map[string]interface{})["name"].(string)
+				changes = append(changes, diffValues(fmt.Sprintf("%s[name=%s]", path, name), item, afterByName[name], setFieldsOnly)...)
+			}
+			if !setFieldsOnly {
+				for _, item := range after {
+					name := item.(map[string]interface{})["name"].(string)
+					if _, found := beforeByName[name]; !found {
+						changes = append(changes, FieldChange{Path: fmt.Sprintf("%s[name=%s]", path, name), Type: DiffAdded, After: item})
+					}
+				}
+			}
+			return changes
+		}
+	}
+
+	for i, item := range before {
+		var afterItem interface{}
+		if i < len(after) {
+			afterItem = after[i]
+		}
+		changes = append(changes, diffValues(fmt.Sprintf("%s[%d]", path, i), item, afterItem, setFieldsOnly)...)
+	}
+	for i := len(before); i < len(after); i++ {
+		changes = append(changes, FieldChange{Path: fmt.Sprintf("%s[%d]", path, i), Type: DiffAdded, After: after[i]})
+	}
+	return changes
+}
+
+// indexByName indexes list items by their "name" field, if every item has one
+func indexByName(items []interface{}) (map[string]interface{}, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+	index := map[string]interface{}{}
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := itemMap["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		index[name] = item
+	}
+	return index, true
+}
+
+func newFieldChange(path string, before, after interface{}) FieldChange {
+	switch {
+	case before == nil:
+		return FieldChange{Path: path, Type: DiffAdded, After: after}
+	case after == nil:
+		return FieldChange{Path: path, Type: DiffRemoved, Before: before}
+	}
+	return FieldChange{Path: path, Type: DiffChanged, Before: before, After: after}
+}
+
+// scalarsEqual compares JSON scalars, treating all numbers as float64
+func scalarsEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getResourceDiff(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Diffing %s/%s in namespace '%s' requested from %s", resourceType, resourceName, namespace, c.ClientIP())
+
+	live, _, status, err := fetchResource(clientFor(c), resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	var desired map[string]interface{}
+	source := "last-applied"
+	if c.Request.Method == http.MethodPost {
+		// Compare against the manifest in the request body, YAML or JSON
+		body, err := c.GetRawData()
+		if err == nil {
+			err = yaml.Unmarshal(body, &desired)
+		}
+		if err != nil || desired == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid manifest: %v", err)})
+			return
+		}
+		source = "request"
+	} else {
+		lastApplied, found := live.GetAnnotations()[lastAppliedAnnotation]
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s/%s has no %s annotation", resourceType, resourceName, lastAppliedAnnotation)})
+			return
+		}
+		if err := json.Unmarshal([]byte(lastApplied), &desired); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Invalid last-applied configuration: %v", err)})
+			return
+		}
+	}
+
+	// The annotation describes the previous apply and is not itself part of the desired state
+	if metadata, ok := desired["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, lastAppliedAnnotation)
+		}
+	}
+
+	// Redacted fields are masked on both sides, so they never show up as differences
+	if !revealAllowed(c) {
+		live = redactResource(live)
+		redactObject(desired)
+	}
+
+	diff := ResourceDiff{
+		Kind:      live.GetKind(),
+		Name:      live.GetName(),
+		Namespace: live.GetNamespace(),
+		Source:    source,
+		Entries:   diffObjects("", desired, live.Object),
+	}
+	if diff.Entries == nil {
+		diff.Entries = []DiffEntry{}
+	}
+
+	log.Printf("Found %d differences between %s/%s and its %s configuration", len(diff.Entries), resourceType, resourceName, source)
+	c.JSON(http.StatusOK, diff)
+}
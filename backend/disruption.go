@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Outcomes of a simulated disruption for a component
+const (
+	DisruptionUnaffected  = "unaffected"
+	DisruptionTolerated   = "tolerated"
+	DisruptionFailover    = "failover"
+	DisruptionQuorumLost  = "quorumLost"
+	DisruptionUnavailable = "unavailable"
+)
+
+// leaderRoles are the roles of the replica serving writes, a failover follows its disruption
+var leaderRoles = map[string]bool{"leader": true, "primary": true, "master": true}
+
+// DisruptionRequest is the body of POST /api/clusters/:name/simulate-disruption, naming a node or a zone
+type DisruptionRequest struct {
+	Node string `json:"node"`
+	Zone string `json:"zone"`
+}
+
+// DisruptedPod is a pod of the cluster running on a disrupted node
+type DisruptedPod struct {
+	Name      string   `json:"name"`
+	Component string   `json:"component,omitempty"`
+	Role      string   `json:"role,omitempty"`
+	Node      string   `json:"node"`
+	Zone      string   `json:"zone,omitempty"`
+	Ready     bool     `json:"ready"`
+	PDBs      []string `json:"pdbs,omitempty"`
+}
+
+// ComponentDisruption is the impact of the disruption on one component
+type ComponentDisruption struct {
+	Component      string   `json:"component"`
+	Replicas       int      `json:"replicas"`
+	Disrupted      int      `json:"disrupted"`
+	RemainingReady int      `json:"remainingReady"`
+	DisruptedRoles []string `json:"disruptedRoles,omitempty"`
+	// Quorum is the number of voting replicas a consensus component needs, zero for other components
+	Quorum    int    `json:"quorum,omitempty"`
+	Outcome   string `json:"outcome"`
+	Tolerated bool   `json:"tolerated"`
+	Message   string `json:"message"`
+}
+
+// PDBDisruption is whether a PodDisruptionBudget selecting pods of the cluster allows evicting the disrupted pods
+type PDBDisruption struct {
+	Name               string `json:"name"`
+	Selector           string `json:"selector"`
+	Pods               int    `json:"pods"`
+	Disrupted          int    `json:"disrupted"`
+	CurrentHealthy     int32  `json:"currentHealthy"`
+	DesiredHealthy     int32  `json:"desiredHealthy"`
+	DisruptionsAllowed int32  `json:"disruptionsAllowed"`
+	Allowed            bool   `json:"allowed"`
+	Message            string `json:"message"`
+}
+
+// DisruptionSimulation is the response of /api/clusters/:name/simulate-disruption
+type DisruptionSimulation struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Node      string `json:"node,omitempty"`
+	Zone      string `json:"zone,omitempty"`
+	// Nodes are the disrupted nodes, the node or every node of the zone
+	Nodes      []string              `json:"nodes"`
+	Pods       []DisruptedPod        `json:"pods"`
+	Components []ComponentDisruption `json:"components"`
+	PDBs       []PDBDisruption       `json:"pdbs"`
+	// Tolerated is set when every component stays available, possibly after a failover
+	Tolerated bool `json:"tolerated"`
+	// EvictionsAllowed is set when the PDBs allow evicting every disrupted pod, so a drain would not block
+	EvictionsAllowed bool     `json:"evictionsAllowed"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// podReady reports whether the Ready condition of a pod is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// evaluateComponentDisruption decides whether a component survives losing its disrupted pods.
+// Components with followers are consensus groups that need a majority of their voting replicas,
+// others need one ready replica, with a failover when the leader is disrupted.
+func evaluateComponentDisruption(name string, pods []*corev1.Pod, disrupted map[string]bool, language string) ComponentDisruption {
+	result := ComponentDisruption{Component: name, Replicas: len(pods)}
+	consensus := false
+	voters, remainingVoters := 0, 0
+	var disruptedLeader string
+	for _, pod := range pods {
+		role := pod.Labels[roleLabel]
+		if role == "follower" {
+			consensus = true
+		}
+		voting := role != "learner"
+		if voting {
+			voters++
+		}
+		if disrupted[pod.Name] {
+			result.Disrupted++
+			if role != "" {
+				result.DisruptedRoles = append(result.DisruptedRoles, role)
+			}
+			if leaderRoles[role] {
+				disruptedLeader = pod.Name
+			}
+			continue
+		}
+		if podReady(pod) {
+			result.RemainingReady++
+			if voting {
+				remainingVoters++
+			}
+		}
+	}
+	sort.Strings(result.DisruptedRoles)
+	if consensus {
+		result.Quorum = voters/2 + 1
+	}
+
+	switch {
+	case result.Disrupted == 0:
+		result.Outcome = DisruptionUnaffected
+		result.Message = localize(language, "No replica would be disrupted")
+	case result.RemainingReady == 0:
+		result.Outcome = DisruptionUnavailable
+		result.Message = localize(language, "No ready replica would remain, %d of %d replicas would be disrupted", result.Disrupted, result.Replicas)
+	case consensus && remainingVoters < result.Quorum:
+		result.Outcome = DisruptionQuorumLost
+		result.Message = localize(language, "%d ready voting replicas would remain, below the quorum of %d", remainingVoters, result.Quorum)
+	case disruptedLeader != "":
+		result.Outcome = DisruptionFailover
+		result.Message = localize(language, "Leader %s would be disrupted, one of the %d remaining ready replicas is expected to take over", disruptedLeader, result.RemainingReady)
+	default:
+		result.Outcome = DisruptionTolerated
+		result.Message = localize(language, "%d of %d replicas would remain ready", result.RemainingReady, result.Replicas)
+	}
+	result.Tolerated = result.Outcome != DisruptionUnavailable && result.Outcome != DisruptionQuorumLost
+	return result
+}
+
+// simulateDisruption reports which pods of a KubeBlocks cluster run on a node or in a zone, and
+// whether the cluster would survive losing them: per component from the replica roles, and per
+// PodDisruptionBudget whether a drain could evict them. Nothing is evicted.
+func simulateDisruption(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+
+	var request DisruptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid disruption request: %v", err)})
+		return
+	}
+	if (request.Node == "") == (request.Zone == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of node and zone is required"})
+		return
+	}
+
+	log.Printf("Simulating disruption of node '%s' zone '%s' for cluster %s in namespace '%s' requested from %s", request.Node, request.Zone, clusterName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	rootTreeNode, treeBuilder, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	nodes, err := listNodeInfo(client)
+	if err != nil {
+		log.Printf("Error listing nodes: %v", err)
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": fmt.Sprintf("Failed to list nodes: %v", err)})
+		return
+	}
+
+	simulation := &DisruptionSimulation{Cluster: clusterName, Namespace: namespace, Node: request.Node, Zone: request.Zone,
+		Nodes: []string{}, Pods: []DisruptedPod{}, Components: []ComponentDisruption{}, PDBs: []PDBDisruption{}, Tolerated: true, EvictionsAllowed: true}
+	disruptedNodes := map[string]bool{}
+	for name, info := range nodes {
+		if name == request.Node || (request.Zone != "" && info.zone == request.Zone) {
+			disruptedNodes[name] = true
+			simulation.Nodes = append(simulation.Nodes, name)
+		}
+	}
+	if len(disruptedNodes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No node matches node '%s' zone '%s'", request.Node, request.Zone)})
+		return
+	}
+	sort.Strings(simulation.Nodes)
+
+	var pods []*corev1.Pod
+	byComponent := map[string][]*corev1.Pod{}
+	disrupted := map[string]bool{}
+	for _, resource := range treeBuilder.GetResourcesByKind(rootTreeNode, "Pod") {
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(resource.Object, pod); err != nil {
+			log.Printf("    ⚠️  Unable to convert pod %s: %v", resource.GetName(), err)
+			continue
+		}
+		pods = append(pods, pod)
+		component := pod.Labels[componentNameLabel]
+		byComponent[component] = append(byComponent[component], pod)
+		if disruptedNodes[pod.Spec.NodeName] {
+			disrupted[pod.Name] = true
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	pdbs, err := client.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list PodDisruptionBudgets: %v", err)
+		simulation.Warnings = append(simulation.Warnings, fmt.Sprintf("Unable to list PodDisruptionBudgets, evictions are not checked: %v", err))
+		pdbs = nil
+	}
+	podPDBs := map[string][]string{}
+	language := requestLanguage(c)
+	if pdbs != nil {
+		for _, pdb := range pdbs.Items {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || pdb.Spec.Selector == nil {
+				continue
+			}
+			result := PDBDisruption{Name: pdb.Name, Selector: selector.String(), CurrentHealthy: pdb.Status.CurrentHealthy,
+				DesiredHealthy: pdb.Status.DesiredHealthy, DisruptionsAllowed: pdb.Status.DisruptionsAllowed}
+			for _, pod := range pods {
+				if !selector.Matches(labels.Set(pod.Labels)) {
+					continue
+				}
+				result.Pods++
+				// Only healthy pods consume the disruption budget
+				if disrupted[pod.Name] && podReady(pod) {
+					result.Disrupted++
+					podPDBs[pod.Name] = append(podPDBs[pod.Name], pdb.Name)
+				}
+			}
+			if result.Pods == 0 {
+				continue
+			}
+			result.Allowed = int32(result.Disrupted) <= result.DisruptionsAllowed
+			if result.Allowed {
+				result.Message = localize(language, "Evicting %d healthy pods stays within the %d disruptions allowed", result.Disrupted, result.DisruptionsAllowed)
+			} else {
+				simulation.EvictionsAllowed = false
+				result.Message = localize(language, "Evicting %d healthy pods exceeds the %d disruptions allowed, a drain would wait until replacements are healthy", result.Disrupted, result.DisruptionsAllowed)
+			}
+			simulation.PDBs = append(simulation.PDBs, result)
+		}
+	}
+	sort.Slice(simulation.PDBs, func(i, j int) bool { return simulation.PDBs[i].Name < simulation.PDBs[j].Name })
+
+	for _, pod := range pods {
+		if !disrupted[pod.Name] {
+			continue
+		}
+		simulation.Pods = append(simulation.Pods, DisruptedPod{
+			Name:      pod.Name,
+			Component: pod.Labels[componentNameLabel],
+			Role:      pod.Labels[roleLabel],
+			Node:      pod.Spec.NodeName,
+			Zone:      nodes[pod.Spec.NodeName].zone,
+			Ready:     podReady(pod),
+			PDBs:      podPDBs[pod.Name],
+		})
+	}
+
+	for name, componentPods := range byComponent {
+		result := evaluateComponentDisruption(name, componentPods, disrupted, language)
+		if !result.Tolerated {
+			simulation.Tolerated = false
+		}
+		simulation.Components = append(simulation.Components, result)
+	}
+	sort.Slice(simulation.Components, func(i, j int) bool { return simulation.Components[i].Component < simulation.Components[j].Component })
+
+	log.Printf("Disruption of %d nodes hits %d pods of cluster %s, tolerated: %t, evictions allowed: %t",
+		len(simulation.Nodes), len(simulation.Pods), clusterName, simulation.Tolerated, simulation.EvictionsAllowed)
+	c.JSON(http.StatusOK, simulation)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// FieldChange is a field a mutation changes, Before is unset for added fields and After for removed ones
+type FieldChange struct {
+	// Resource is the kind/name of the changed resource, set when a dry-run changes several
+	Resource string      `json:"resource,omitempty"`
+	Path     string      `json:"path"`
+	Type     string      `json:"type"` // added, removed or changed
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+}
+
+// DryRunResult is the answer of a mutating endpoint called with dryRun=true: the server-side dry-run
+// accepted the mutation, Changes lists what it would change and Result is what the endpoint would return
+type DryRunResult struct {
+	DryRun    bool          `json:"dryRun"`
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace,omitempty"`
+	Changes   []FieldChange `json:"changes"`
+	Result    interface{}   `json:"result"`
+}
+
+// dryRunRequested reports whether the request asks for a dry-run with dryRun=true
+func dryRunRequested(c *gin.Context) (bool, error) {
+	params, err := queryParams(c)
+	if err != nil {
+		return false, err
+	}
+	return params.DryRun != nil && *params.DryRun, nil
+}
+
+// dryRunOption is the DryRun field of the API options of a mutation
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// respondDryRun answers a dry-run with the changes between the object before and as the dry-run returned it
+func respondDryRun(c *gin.Context, before, after *unstructured.Unstructured, result interface{}) {
+	respondDryRunChanges(c, after, dryRunChanges(c, before, after), result)
+}
+
+// dryRunChanges lists the fields that differ between the object before and as the dry-run returned it.
+// Both are redacted first, so masked values never show up as changes.
+func dryRunChanges(c *gin.Context, before, after *unstructured.Unstructured) []FieldChange {
+	if !revealAllowed(c) {
+		before, after = redactResource(before), redactResource(after)
+	}
+	return diffValues("", before.Object, after.Object, false)
+}
+
+// respondDryRunChanges answers a dry-run of a mutation of target, or of the resources below it
+func respondDryRunChanges(c *gin.Context, target *unstructured.Unstructured, changes []FieldChange, result interface{}) {
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	log.Printf("Dry run of %s %s on %s/%s in namespace '%s' by %q would change %d fields", c.Request.Method, c.FullPath(), target.GetKind(), target.GetName(), target.GetNamespace(), requestUser(c), len(changes))
+	c.JSON(http.StatusOK, DryRunResult{
+		DryRun:    true,
+		Kind:      target.GetKind(),
+		Name:      target.GetName(),
+		Namespace: target.GetNamespace(),
+		Changes:   changes,
+		Result:    result,
+	})
+}
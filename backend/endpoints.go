@@ -0,0 +1,54 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// EndpointSummary counts the ready and not-ready backends of a Service
+type EndpointSummary = api.EndpointSummary
+
+// SummarizeEndpoints aggregates the EndpointSlices under every Service node
+func (rtb *ResourceTreeBuilder) SummarizeEndpoints(root *ResourceTreeNode) {
+	var summarize func(node *ResourceTreeNode)
+	summarize = func(node *ResourceTreeNode) {
+		if node.Resource.GetKind() == "Service" {
+			summary := &EndpointSummary{}
+			for _, child := range node.Children {
+				if child.Resource.GetKind() != "EndpointSlice" {
+					continue
+				}
+				summary.Slices++
+				addEndpointSliceToSummary(child.Resource, summary)
+			}
+			node.Endpoints = summary
+		}
+		for _, child := range node.Children {
+			summarize(child)
+		}
+	}
+	summarize(root)
+}
+
+func addEndpointSliceToSummary(slice *unstructured.Unstructured, summary *EndpointSummary) {
+	endpoints, _, _ := unstructured.NestedSlice(slice.Object, "endpoints")
+	for _, endpoint := range endpoints {
+		endpointMap, ok := endpoint.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// A missing ready condition must be interpreted as ready
+		ready, found, _ := unstructured.NestedBool(endpointMap, "conditions", "ready")
+		if !found || ready {
+			summary.Ready++
+			continue
+		}
+		summary.NotReady++
+		if target, found, _ := unstructured.NestedString(endpointMap, "targetRef", "name"); found {
+			summary.NotReadyTargets = append(summary.NotReadyTargets, target)
+		} else if addresses, _, _ := unstructured.NestedStringSlice(endpointMap, "addresses"); len(addresses) > 0 {
+			summary.NotReadyTargets = append(summary.NotReadyTargets, addresses[0])
+		}
+	}
+}
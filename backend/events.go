@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const treeUIDRefreshInterval = 30 * time.Second
+
+// ClusterEvent is a Warning event reported for a resource of a cluster tree
+type ClusterEvent struct {
+	Reason         string                 `json:"reason"`
+	Message        string                 `json:"message"`
+	Count          int32                  `json:"count"`
+	FirstTimestamp string                 `json:"firstTimestamp,omitempty"`
+	LastTimestamp  string                 `json:"lastTimestamp,omitempty"`
+	InvolvedObject corev1.ObjectReference `json:"involvedObject"`
+}
+
+func newClusterEvent(event *corev1.Event) ClusterEvent {
+	clusterEvent := ClusterEvent{
+		Reason:         event.Reason,
+		Message:        event.Message,
+		Count:          event.Count,
+		InvolvedObject: event.InvolvedObject,
+	}
+	if !event.FirstTimestamp.IsZero() {
+		clusterEvent.FirstTimestamp = event.FirstTimestamp.Format(time.RFC3339)
+	}
+	switch {
+	case !event.LastTimestamp.IsZero():
+		clusterEvent.LastTimestamp = event.LastTimestamp.Format(time.RFC3339)
+	case !event.EventTime.IsZero():
+		clusterEvent.LastTimestamp = event.EventTime.Format(time.RFC3339)
+	}
+	return clusterEvent
+}
+
+// collectTreeUIDs returns the set of UIDs of all resources in the tree
+func collectTreeUIDs(root *ResourceTreeNode) map[types.UID]bool {
+	uids := map[types.UID]bool{}
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		uids[node.Resource.GetUID()] = true
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return uids
+}
+
+// clusterTreeUIDs builds the tree of a KubeBlocks cluster and returns its UID set
+func clusterTreeUIDs(client *K8sClient, clusterName, namespace string) (map[types.UID]bool, int, error) {
+	rootTreeNode, _, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		return nil, status, err
+	}
+	return collectTreeUIDs(rootTreeNode), http.StatusOK, nil
+}
+
+// prepareSSE sets the headers of a Server-Sent Events response
+func prepareSSE(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// sendSSE writes one event and flushes it to the client
+func sendSSE(c *gin.Context, name string, data interface{}) {
+	c.SSEvent(name, data)
+	c.Writer.Flush()
+}
+
+func streamClusterEvents(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+
+	log.Printf("Streaming warning events of cluster %s in namespace '%s' requested from %s", clusterName, namespace, c.ClientIP())
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, status, err := openWarningStream(ctx, clientFor(c), clusterName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	defer stream.stop()
+
+	prepareSSE(c)
+	stream.run(ctx, func(name string, data interface{}) { sendSSE(c, name, data) }, true)
+}
+
+// warningStream follows the Warning events of the resources of a cluster tree, for the SSE
+// stream of the cluster and the events topic of /api/ws
+type warningStream struct {
+	client          *K8sClient
+	clusterName     string
+	namespace       string
+	uids            map[types.UID]bool
+	events          typedcorev1.EventInterface
+	listOptions     metav1.ListOptions
+	list            *corev1.EventList
+	resourceVersion string
+	watcher         watch.Interface
+}
+
+// openWarningStream lists the warnings of the cluster tree and starts watching for new ones
+func openWarningStream(ctx context.Context, client *K8sClient, clusterName, namespace string) (*warningStream, int, error) {
+	uids, status, err := clusterTreeUIDs(client, clusterName, namespace)
+	if err != nil {
+		return nil, status, err
+	}
+	stream := &warningStream{
+		client:      client,
+		clusterName: clusterName,
+		namespace:   namespace,
+		uids:        uids,
+		events:      client.clientset.CoreV1().Events(namespace),
+		listOptions: metav1.ListOptions{FieldSelector: "type=Warning"},
+	}
+
+	stream.list, err = stream.events.List(ctx, stream.listOptions)
+	if err != nil {
+		log.Printf("Error listing events in namespace %s: %v", namespace, err)
+		return nil, http.StatusInternalServerError, err
+	}
+	stream.resourceVersion = stream.list.ResourceVersion
+	stream.watcher, err = stream.watchFrom(ctx, stream.resourceVersion)
+	if err != nil {
+		log.Printf("Error watching events in namespace %s: %v", namespace, err)
+		return nil, http.StatusInternalServerError, err
+	}
+	return stream, http.StatusOK, nil
+}
+
+func (stream *warningStream) watchFrom(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+	options := stream.listOptions
+	options.ResourceVersion = resourceVersion
+	options.AllowWatchBookmarks = true
+	return stream.events.Watch(ctx, options)
+}
+
+func (stream *warningStream) stop() {
+	if stream.watcher != nil {
+		stream.watcher.Stop()
+	}
+}
+
+// run sends the warnings already recorded for the cluster, then new ones as warning events, until
+// ctx is done or the watch cannot be re-established. heartbeat adds heartbeat events for SSE.
+func (stream *warningStream) run(ctx context.Context, emit func(name string, data interface{}), heartbeat bool) {
+	clusterName := stream.clusterName
+
+	// replay sends the warnings already recorded for the cluster
+	replay := func(list *corev1.EventList) {
+		for i := range list.Items {
+			if stream.uids[list.Items[i].InvolvedObject.UID] {
+				emit("warning", newClusterEvent(&list.Items[i]))
+			}
+		}
+	}
+	replay(stream.list)
+
+	// reconnect re-establishes the watch, resuming from the last seen resourceVersion when possible.
+	// After a relist, events may have been missed: clients get a resync marker followed by the
+	// current warnings and should replace what they show.
+	reconnect := func(relist bool) (watch.Interface, error) {
+		var err error
+		for attempt := 0; attempt < watchReconnectAttempts && ctx.Err() == nil; attempt++ {
+			if attempt > 0 {
+				sleepContext(ctx, watchReconnectBackoff)
+			}
+			if relist {
+				var list *corev1.EventList
+				if list, err = stream.events.List(ctx, stream.listOptions); err != nil {
+					log.Printf("⚠️  Unable to relist events of cluster %s: %v", clusterName, err)
+					continue
+				}
+				stream.resourceVersion = list.ResourceVersion
+				emit("resync", gin.H{"reason": "watch could not be resumed", "time": time.Now().Format(time.RFC3339)})
+				replay(list)
+			}
+			var watcher watch.Interface
+			if watcher, err = stream.watchFrom(ctx, stream.resourceVersion); err == nil {
+				recordWatchReconnect("events", relist)
+				return watcher, nil
+			}
+			log.Printf("⚠️  Unable to re-establish event watch of cluster %s: %v", clusterName, err)
+			relist = true
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	var heartbeats <-chan time.Time
+	if heartbeat {
+		ticker := time.NewTicker(streamHeartbeatInterval())
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	refresh := time.NewTicker(treeUIDRefreshInterval)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Event stream of cluster %s closed by client", clusterName)
+			return
+		case <-heartbeats:
+			emit("heartbeat", gin.H{"time": time.Now().Format(time.RFC3339)})
+		case <-refresh.C:
+			// Pick up resources created since the stream started
+			if refreshed, _, err := clusterTreeUIDs(stream.client, clusterName, stream.namespace); err == nil {
+				stream.uids = refreshed
+			} else {
+				log.Printf("⚠️  Unable to refresh tree of cluster %s: %v", clusterName, err)
+			}
+		case result, ok := <-stream.watcher.ResultChan():
+			if !ok || result.Type == watch.Error {
+				// A closed watch resumes where it stopped, an error (usually an expired resourceVersion) needs a relist
+				relist := ok
+				if ok && !isWatchExpired(result) {
+					log.Printf("⚠️  Event watch of cluster %s failed: %v", clusterName, errors.FromObject(result.Object))
+				}
+				stream.watcher.Stop()
+				reconnected, err := reconnect(relist)
+				if err != nil {
+					stream.watcher = nil
+					if ctx.Err() == nil {
+						log.Printf("Event watch of cluster %s ended: %v", clusterName, err)
+						emit("end", gin.H{"reason": "watch closed"})
+					}
+					return
+				}
+				stream.watcher = reconnected
+				continue
+			}
+			event, ok := result.Object.(*corev1.Event)
+			if !ok {
+				continue
+			}
+			stream.resourceVersion = event.ResourceVersion
+			if (result.Type != watch.Added && result.Type != watch.Modified) || !stream.uids[event.InvolvedObject.UID] {
+				continue
+			}
+			emit("warning", newClusterEvent(event))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportStorage receives asynchronous exports, nil when no export storage is configured
+var exportStorage artifactStore
+
+// ExportArtifact is the result of an export job, an artifact uploaded to object storage
+type ExportArtifact struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// URL downloads the artifact until URLExpiresAt
+	URL          string `json:"url"`
+	URLExpiresAt string `json:"urlExpiresAt"`
+}
+
+// urlExpiry is how long signed URLs are valid
+func urlExpiry() time.Duration {
+	return time.Duration(appConfig.ExportStorage.URLExpirySeconds) * time.Second
+}
+
+// asyncRequested reports whether the request asks for an asynchronous export with async=true
+func asyncRequested(c *gin.Context) (bool, error) {
+	params, err := queryParams(c)
+	if err != nil {
+		return false, err
+	}
+	return params.Async != nil && *params.Async, nil
+}
+
+// startExport answers 202 with a job rendering the artifact in the background and uploading it to
+// the export storage. render must not use the request, which is done by the time it runs.
+func startExport(c *gin.Context, jobType, filename, contentType string, render func(w io.Writer) error) {
+	if exportStorage == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Asynchronous exports need exportStorage to be configured"})
+		return
+	}
+	job, err := startJob(c, jobType, func(ctx context.Context, progress func(int, string)) (interface{}, error) {
+		return uploadExport(ctx, filename, contentType, render, progress)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondJobStarted(c, job)
+}
+
+// uploadExport renders the artifact to a temporary file, hashing it for the upload signature, then uploads it
+func uploadExport(ctx context.Context, filename, contentType string, render func(w io.Writer) error, progress func(int, string)) (*ExportArtifact, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp("", "kb-viz-export-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	progress(0, "Rendering "+filename)
+	hash := sha256.New()
+	if err := render(io.MultiWriter(file, hash)); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	progress(80, fmt.Sprintf("Uploading %s (%d bytes)", filename, size))
+	key := fmt.Sprintf("%s/%s", hex.EncodeToString(id), filename)
+	if err := exportStorage.Put(ctx, key, contentType, file, size, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return nil, err
+	}
+	url, err := exportStorage.SignedURL(key, urlExpiry())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Export %s uploaded (%d bytes)", key, size)
+	return &ExportArtifact{Filename: filename, ContentType: contentType, Size: size, URL: url,
+		URLExpiresAt: time.Now().Add(urlExpiry()).UTC().Format(time.RFC3339)}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+type ResourceColumns = api.ResourceColumns
+type ResourceRow = api.ResourceRow
+
+// maxSelectedColumns bounds the columns of one request, each is evaluated on every object
+const maxSelectedColumns = 32
+
+// fieldColumn is a named JSONPath selecting the values of one column
+type fieldColumn struct {
+	name string
+	path *jsonpath.JSONPath
+}
+
+// parseFieldSelection reads the columns or jsonpath parameter of a listing endpoint. columns
+// takes kubectl custom-columns, e.g. NAME:.metadata.name,READY:.status.readyReplicas, jsonpath a
+// single expression named after itself. Both are nil when the full list is requested.
+func parseFieldSelection(c *gin.Context) ([]fieldColumn, error) {
+	columnsParam, jsonpathParam := c.Query("columns"), c.Query("jsonpath")
+	switch {
+	case columnsParam != "" && jsonpathParam != "":
+		return nil, fmt.Errorf("columns and jsonpath cannot be combined")
+	case jsonpathParam != "":
+		column, err := newFieldColumn(jsonpathParam, jsonpathParam)
+		if err != nil {
+			return nil, err
+		}
+		return []fieldColumn{column}, nil
+	case columnsParam == "":
+		return nil, nil
+	}
+
+	specs := strings.Split(columnsParam, ",")
+	if len(specs) > maxSelectedColumns {
+		return nil, fmt.Errorf("Too many columns, at most %d are allowed", maxSelectedColumns)
+	}
+	columns := make([]fieldColumn, 0, len(specs))
+	for _, spec := range specs {
+		name, path, found := strings.Cut(spec, ":")
+		if !found || strings.TrimSpace(name) == "" || strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("Invalid column %q, expected NAME:JSONPATH", spec)
+		}
+		column, err := newFieldColumn(strings.TrimSpace(name), strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// newFieldColumn parses the JSONPath of a column, braces are optional like in kubectl custom-columns
+func newFieldColumn(name, expr string) (fieldColumn, error) {
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	path := jsonpath.New(name).AllowMissingKeys(true)
+	if err := path.Parse(expr); err != nil {
+		return fieldColumn{}, fmt.Errorf("Invalid JSONPath of column %s: %v", name, err)
+	}
+	return fieldColumn{name: name, path: path}, nil
+}
+
+// selectFields reduces objects to the values of the columns, keeping the JSON types of the values
+func selectFields(columns []fieldColumn, objects []unstructured.Unstructured) (*ResourceColumns, error) {
+	result := &ResourceColumns{Columns: make([]string, 0, len(columns)), Items: make([]ResourceRow, 0, len(objects))}
+	for _, column := range columns {
+		result.Columns = append(result.Columns, column.name)
+	}
+	for i := range objects {
+		row := ResourceRow{
+			Name:      objects[i].GetName(),
+			Namespace: objects[i].GetNamespace(),
+			UID:       string(objects[i].GetUID()),
+			Values:    make([]interface{}, 0, len(columns)),
+		}
+		for _, column := range columns {
+			results, err := column.path.FindResults(objects[i].Object)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to evaluate column %s on %s: %v", column.name, objects[i].GetName(), err)
+			}
+			var values []interface{}
+			for _, result := range results {
+				for _, value := range result {
+					if value.IsValid() && value.CanInterface() {
+						values = append(values, value.Interface())
+					}
+				}
+			}
+			switch len(values) {
+			case 0:
+				row.Values = append(row.Values, nil)
+			case 1:
+				row.Values = append(row.Values, values[0])
+			default:
+				row.Values = append(row.Values, values)
+			}
+		}
+		result.Items = append(result.Items, row)
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// resourceAge returns the age of a resource in kubectl notation, e.g. 3d4h
+func resourceAge(resource *unstructured.Unstructured, now time.Time) string {
+	created := resource.GetCreationTimestamp()
+	if created.IsZero() {
+		return ""
+	}
+	return duration.HumanDuration(now.Sub(created.Time))
+}
+
+// resourceLastUpdated returns the latest time the resource is known to have changed: the newest
+// managedFields entry or condition transition, falling back to its creation
+func resourceLastUpdated(resource *unstructured.Unstructured) time.Time {
+	latest := resource.GetCreationTimestamp().Time
+	for _, entry := range resource.GetManagedFields() {
+		if entry.Time != nil && entry.Time.After(latest) {
+			latest = entry.Time.Time
+		}
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(resource.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, _ := conditionMap["lastTransitionTime"].(string)
+		if transition, err := time.Parse(time.RFC3339, value); err == nil && transition.After(latest) {
+			latest = transition
+		}
+	}
+	return latest
+}
+
+// formatLastUpdated formats the last update time as RFC3339, empty when unknown
+func formatLastUpdated(resource *unstructured.Unstructured) string {
+	lastUpdated := resourceLastUpdated(resource)
+	if lastUpdated.IsZero() {
+		return ""
+	}
+	return lastUpdated.UTC().Format(time.RFC3339)
+}
+
+// AnnotateFreshness sets the age and last update time of every node
+func (rtb *ResourceTreeBuilder) AnnotateFreshness(root *ResourceTreeNode) {
+	now := time.Now()
+	var annotate func(node *ResourceTreeNode)
+	annotate = func(node *ResourceTreeNode) {
+		node.Age = resourceAge(node.Resource, now)
+		node.LastUpdated = formatLastUpdated(node.Resource)
+		for _, child := range node.Children {
+			annotate(child)
+		}
+	}
+	annotate(root)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// GitOps tools detected from the labels and annotations they put on the resources they apply
+const (
+	GitOpsToolArgoCD        = "argocd"
+	GitOpsToolFluxKustomize = "flux-kustomization"
+	GitOpsToolFluxHelm      = "flux-helmrelease"
+)
+
+const (
+	argoInstanceLabel      = "argocd.argoproj.io/instance"
+	argoTrackingAnnotation = "argocd.argoproj.io/tracking-id"
+	fluxKustomizeNameLabel = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizeNSLabel   = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseLabel   = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNSLabel = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// GitOpsSource is the GitOps application that applied a resource
+type GitOpsSource = api.GitOpsSource
+
+// GitOpsApp is a GitOps application with the resources it manages in a namespace
+type GitOpsApp struct {
+	GitOpsSource
+	Resources int            `json:"resources"`
+	Kinds     map[string]int `json:"kinds"`
+	// Roots are the managed resources whose owner is not managed by the application, e.g. the Cluster
+	Roots []string `json:"roots"`
+}
+
+// gitOpsSourceOf detects the GitOps application of a resource from its labels and annotations
+func gitOpsSourceOf(resource *unstructured.Unstructured) *GitOpsSource {
+	labels := resource.GetLabels()
+	if name := labels[fluxKustomizeNameLabel]; name != "" {
+		return &GitOpsSource{Tool: GitOpsToolFluxKustomize, Name: name, Namespace: labels[fluxKustomizeNSLabel]}
+	}
+	if name := labels[fluxHelmReleaseLabel]; name != "" {
+		return &GitOpsSource{Tool: GitOpsToolFluxHelm, Name: name, Namespace: labels[fluxHelmReleaseNSLabel]}
+	}
+	// Annotation tracking is <app>:<group>/<kind>:<namespace>/<name>, the app may be <namespace>_<name>
+	if tracking := resource.GetAnnotations()[argoTrackingAnnotation]; tracking != "" {
+		app, _, _ := strings.Cut(tracking, ":")
+		if namespace, name, found := strings.Cut(app, "_"); found {
+			return &GitOpsSource{Tool: GitOpsToolArgoCD, Name: name, Namespace: namespace}
+		}
+		return &GitOpsSource{Tool: GitOpsToolArgoCD, Name: app}
+	}
+	if name := labels[argoInstanceLabel]; name != "" {
+		return &GitOpsSource{Tool: GitOpsToolArgoCD, Name: name}
+	}
+	return nil
+}
+
+// AnnotateGitOpsSources sets the GitOps application of every node. Resources without GitOps
+// metadata, e.g. pods of a StatefulSet, inherit the application of their nearest managed ancestor.
+func (rtb *ResourceTreeBuilder) AnnotateGitOpsSources(root *ResourceTreeNode) {
+	var annotate func(node *ResourceTreeNode, inherited *GitOpsSource)
+	annotate = func(node *ResourceTreeNode, inherited *GitOpsSource) {
+		if source := gitOpsSourceOf(node.Resource); source != nil {
+			node.GitOpsSource = source
+		} else if inherited != nil {
+			source := *inherited
+			source.Inherited = true
+			node.GitOpsSource = &source
+		}
+		for _, child := range node.Children {
+			annotate(child, node.GitOpsSource)
+		}
+	}
+	annotate(root, nil)
+}
+
+// getGitOpsApps lists the GitOps applications managing resources of a namespace
+func getGitOpsApps(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for listing GitOps applications"})
+		return
+	}
+
+	log.Printf("Listing GitOps applications in namespace '%s' requested from %s", namespace, c.ClientIP())
+
+	treeBuilder := NewResourceTreeBuilder(clientFor(c), namespace, metav1.ListOptions{})
+	treeBuilder.language = requestLanguage(c)
+	if err := treeBuilder.buildResourcePool(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	apps := map[GitOpsSource]*GitOpsApp{}
+	for _, resource := range treeBuilder.pool.GetAllResources() {
+		source := gitOpsSourceOf(resource)
+		if source == nil {
+			continue
+		}
+		app := apps[*source]
+		if app == nil {
+			app = &GitOpsApp{GitOpsSource: *source, Kinds: map[string]int{}, Roots: []string{}}
+			apps[*source] = app
+		}
+		app.Resources++
+		app.Kinds[resource.GetKind()]++
+
+		owner := treeBuilder.pool.GetResource(treeBuilder.pool.PrimaryOwner(resource))
+		if owner == nil || gitOpsSourceOf(owner) == nil || *gitOpsSourceOf(owner) != *source {
+			app.Roots = append(app.Roots, resource.GetKind()+"/"+resource.GetName())
+		}
+	}
+
+	result := make([]GitOpsApp, 0, len(apps))
+	for _, app := range apps {
+		sort.Strings(app.Roots)
+		result = append(result, *app)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tool != result[j].Tool {
+			return result[i].Tool < result[j].Tool
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	log.Printf("Found %d GitOps applications in namespace %s", len(result), namespace)
+	c.JSON(http.StatusOK, gin.H{"namespace": namespace, "apps": result, "warnings": treeBuilder.Warnings()})
+}
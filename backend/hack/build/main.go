@@ -0,0 +1,71 @@
+// Command build cross-compiles static binaries of the backend with the build information
+// embedded, e.g. `go run ./hack/build -platforms linux/amd64,linux/arm64`
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const binaryName = "k8s-resource-visualizer"
+
+// gitOutput runs a git command and returns its trimmed output, or the fallback when git fails
+func gitOutput(fallback string, args ...string) string {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return fallback
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func main() {
+	platforms := flag.String("platforms", "linux/amd64,linux/arm64,darwin/amd64,darwin/arm64", "Comma-separated os/arch pairs to build")
+	outputDir := flag.String("output", "bin", "Directory receiving the binaries")
+	output := flag.String("o", "", "Output file, only valid with a single platform (default <output>/"+binaryName+"-<os>-<arch>)")
+	version := flag.String("version", gitOutput("dev", "describe", "--tags", "--always", "--dirty"), "Version to embed")
+	commit := flag.String("commit", gitOutput("unknown", "rev-parse", "HEAD"), "Git SHA to embed")
+	flag.Parse()
+
+	targets := strings.Split(*platforms, ",")
+	if *output != "" && len(targets) != 1 {
+		log.Fatalf("-o can only be used with a single platform")
+	}
+
+	ldflags := strings.Join([]string{
+		"-s", "-w",
+		"-X main.version=" + *version,
+		"-X main.gitSHA=" + *commit,
+		"-X main.buildDate=" + time.Now().UTC().Format(time.RFC3339),
+	}, " ")
+
+	for _, target := range targets {
+		goos, goarch, found := strings.Cut(strings.TrimSpace(target), "/")
+		if !found {
+			log.Fatalf("Invalid platform %q, expected os/arch", target)
+		}
+		binary := *output
+		if binary == "" {
+			binary = filepath.Join(*outputDir, fmt.Sprintf("%s-%s-%s", binaryName, goos, goarch))
+			if goos == "windows" {
+				binary += ".exe"
+			}
+		}
+
+		log.Printf("🔨 Building %s for %s/%s", binary, goos, goarch)
+		cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", binary, ".")
+		// Static binaries without cgo
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS="+goos, "GOARCH="+goarch)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Fatalf("Build for %s/%s failed: %v", goos, goarch, err)
+		}
+	}
+	log.Printf("✓ Built %d binaries (version %s)", len(targets), *version)
+}
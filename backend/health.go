@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Health statuses of tree nodes
+const (
+	HealthUnknown     = api.HealthUnknown
+	HealthHealthy     = api.HealthHealthy
+	HealthProgressing = api.HealthProgressing
+	HealthDegraded    = api.HealthDegraded
+)
+
+// healthSeverity orders health statuses for rollup, higher is worse
+var healthSeverity = map[string]int{
+	HealthUnknown:     0,
+	HealthHealthy:     1,
+	HealthProgressing: 2,
+	HealthDegraded:    3,
+}
+
+// Kinds whose "Running" phase means work in progress rather than a steady state
+var operationKinds = map[string]bool{
+	"OpsRequest": true,
+	"Backup":     true,
+	"Restore":    true,
+	"Job":        true,
+}
+
+// Container waiting reasons that indicate a broken pod
+var degradedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"InvalidImageName":           true,
+}
+
+// computeHealth derives the health of a single resource from its status
+func computeHealth(resource *unstructured.Unstructured) string {
+	// Configured health rules and extractors take precedence over the builtin rules
+	if health, ok := ruleHealth(resource, configuredHealthRules()); ok {
+		return health
+	}
+	if health, ok := extractHealth(resource); ok {
+		return health
+	}
+	if health, ok := ruleHealth(resource, builtinHealthRules); ok {
+		return health
+	}
+
+	switch resource.GetKind() {
+	case "Pod":
+		return podHealth(resource)
+	case "Deployment", "StatefulSet", "ReplicaSet", "InstanceSet":
+		return replicaHealth(resource)
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(resource.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(resource.Object, "status", "numberReady")
+		if ready >= desired {
+			return HealthHealthy
+		}
+		return HealthProgressing
+	}
+
+	phase, found, _ := unstructured.NestedString(resource.Object, "status", "phase")
+	if !found || phase == "" {
+		return HealthUnknown
+	}
+	if operationKinds[resource.GetKind()] && strings.EqualFold(phase, "Running") {
+		return HealthProgressing
+	}
+	return phaseHealth(phase)
+}
+
+// phaseHealth maps the common phase vocabulary of Kubernetes and KubeBlocks resources to a health
+func phaseHealth(phase string) string {
+	switch strings.ToLower(phase) {
+	case "running", "completed", "complete", "succeeded", "succeed", "available", "bound", "active", "ready":
+		return HealthHealthy
+	case "pending", "creating", "updating", "provisioning", "starting", "stopping", "deleting", "terminating", "processing", "new", "inprogress", "cancelling":
+		return HealthProgressing
+	case "failed", "abnormal", "error", "lost", "unavailable", "deleted":
+		return HealthDegraded
+	}
+	return HealthUnknown
+}
+
+func podHealth(pod *unstructured.Unstructured) string {
+	statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+	allReady := len(statuses) > 0
+	for _, status := range statuses {
+		statusMap, ok := status.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(statusMap, "state", "waiting", "reason")
+		if degradedWaitingReasons[reason] {
+			return HealthDegraded
+		}
+		if ready, _, _ := unstructured.NestedBool(statusMap, "ready"); !ready {
+			allReady = false
+		}
+	}
+
+	phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+	switch phase {
+	case "Running":
+		if allReady {
+			return HealthHealthy
+		}
+		return HealthProgressing
+	case "Succeeded":
+		return HealthHealthy
+	case "Failed":
+		return HealthDegraded
+	case "Pending":
+		return HealthProgressing
+	}
+	return HealthUnknown
+}
+
+func replicaHealth(resource *unstructured.Unstructured) string {
+	desired, found, _ := unstructured.NestedInt64(resource.Object, "spec", "replicas")
+	if !found {
+		desired = 1
+	}
+	ready, _, _ := unstructured.NestedInt64(resource.Object, "status", "readyReplicas")
+	if ready >= desired {
+		return HealthHealthy
+	}
+	return HealthProgressing
+}
+
+// worseHealth returns the more severe of two health statuses
+func worseHealth(a, b string) string {
+	if healthSeverity[b] > healthSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// rollupHealth returns the worst health found in the subtree. visit, when set, is called with the
+// rolled up health of every node of the subtree, children first.
+func rollupHealth(node *ResourceTreeNode, visit func(node *ResourceTreeNode, health string)) string {
+	if node == nil {
+		return HealthUnknown
+	}
+	health := computeHealth(node.Resource)
+	for _, child := range node.Children {
+		health = worseHealth(health, rollupHealth(child, visit))
+	}
+	if visit != nil {
+		visit(node, health)
+	}
+	return health
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Statuses of the health check components
+const (
+	CheckStatusOK       = "ok"
+	CheckStatusDegraded = "degraded"
+	CheckStatusFailed   = "failed"
+)
+
+const healthCheckTTL = 10 * time.Second
+
+// kubeBlocksAPIGroups are the API groups installed by KubeBlocks
+var kubeBlocksAPIGroups = []string{
+	"apps.kubeblocks.io",
+	"workloads.kubeblocks.io",
+	"dataprotection.kubeblocks.io",
+	"operations.kubeblocks.io",
+	"parameters.kubeblocks.io",
+}
+
+// ComponentCheck is the result of checking one dependency of the server
+type ComponentCheck struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// HealthReport is the response of /api/health
+type HealthReport struct {
+	Status     string                    `json:"status"` // healthy, degraded or unhealthy
+	Message    string                    `json:"message"`
+	Cluster    string                    `json:"cluster"`
+	CheckedAt  time.Time                 `json:"checkedAt"`
+	Components map[string]ComponentCheck `json:"components"`
+}
+
+// healthReports caches the latest report per cluster so probes don't hit the API server every time
+var healthReports = struct {
+	mu      sync.Mutex
+	reports map[string]*HealthReport
+}{reports: map[string]*HealthReport{}}
+
+// checkAPIServer verifies the API server answers and reports its version
+func checkAPIServer(client *K8sClient) ComponentCheck {
+	start := time.Now()
+	version, err := client.discoveryClient.ServerVersion()
+	if err != nil {
+		return ComponentCheck{Status: CheckStatusFailed, Message: err.Error()}
+	}
+	return ComponentCheck{Status: CheckStatusOK, Details: map[string]string{
+		"version": version.GitVersion,
+		"latency": time.Since(start).Round(time.Millisecond).String(),
+	}}
+}
+
+// checkDiscovery reports how fresh the cached API discovery is
+func checkDiscovery(client *K8sClient) ComponentCheck {
+	dc := client.apiDiscovery
+	dc.mu.Lock()
+	fetched, count := dc.fetched, len(dc.types)
+	dc.mu.Unlock()
+
+	if fetched.IsZero() {
+		return ComponentCheck{Status: CheckStatusOK, Message: "Discovery not fetched yet, it is loaded on first use"}
+	}
+	check := ComponentCheck{Status: CheckStatusOK, Details: map[string]string{
+		"age":           time.Since(fetched).Round(time.Second).String(),
+		"resourceTypes": fmt.Sprintf("%d", count),
+	}}
+	// Refreshes happen on use after the TTL, only a refresh that keeps failing leaves it this old
+	if time.Since(fetched) > 2*discoveryCacheTTL {
+		check.Status = CheckStatusDegraded
+		check.Message = "Discovery results are stale"
+	}
+	return check
+}
+
+// checkKubeBlocks reports which KubeBlocks API groups are served and in which versions
+func checkKubeBlocks(client *K8sClient) ComponentCheck {
+	groups, err := client.discoveryClient.ServerGroups()
+	if err != nil {
+		return ComponentCheck{Status: CheckStatusFailed, Message: err.Error()}
+	}
+
+	details := map[string]string{}
+	for _, group := range groups.Groups {
+		for _, kbGroup := range kubeBlocksAPIGroups {
+			if group.Name != kbGroup {
+				continue
+			}
+			var versions []string
+			for _, version := range group.Versions {
+				versions = append(versions, version.Version)
+			}
+			sort.Strings(versions)
+			details[group.Name] = strings.Join(versions, ",")
+		}
+	}
+	if _, ok := details[clusterGVR.Group]; !ok {
+		return ComponentCheck{Status: CheckStatusDegraded, Message: "KubeBlocks CRDs are not installed, only generic resources can be visualized", Details: details}
+	}
+	return ComponentCheck{Status: CheckStatusOK, Details: details}
+}
+
+// checkWatches reports the watch-based history recorders, which only run on the leader
+func checkWatches() ComponentCheck {
+	historyRecorders.mu.Lock()
+	count := len(historyRecorders.recorders)
+	historyRecorders.mu.Unlock()
+
+	return ComponentCheck{Status: CheckStatusOK, Details: map[string]string{
+		"leader":           fmt.Sprintf("%t", leaderState.IsLeader()),
+		"historyRecorders": fmt.Sprintf("%d", count),
+	}}
+}
+
+// buildHealthReport runs the checks against a cluster and rolls them up
+func buildHealthReport(client *K8sClient) *HealthReport {
+	report := &HealthReport{
+		Status:    "healthy",
+		Message:   "K8s Resource Visualizer API is running",
+		Cluster:   client.name,
+		CheckedAt: time.Now(),
+		Components: map[string]ComponentCheck{
+			"apiServer": checkAPIServer(client),
+			"discovery": checkDiscovery(client),
+			"watches":   checkWatches(),
+		},
+	}
+	// KubeBlocks presence can only be checked against a reachable API server
+	if report.Components["apiServer"].Status == CheckStatusOK {
+		report.Components["kubeblocks"] = checkKubeBlocks(client)
+	}
+
+	for name, check := range report.Components {
+		switch {
+		case check.Status == CheckStatusFailed:
+			report.Status = "unhealthy"
+			report.Message = fmt.Sprintf("%s check failed", name)
+		case check.Status == CheckStatusDegraded && report.Status == "healthy":
+			report.Status = "degraded"
+		}
+	}
+	return report
+}
+
+// cachedHealthReport returns the latest report of the cluster, checking again once it is older than the TTL
+func cachedHealthReport(client *K8sClient) *HealthReport {
+	healthReports.mu.Lock()
+	defer healthReports.mu.Unlock()
+
+	if report, ok := healthReports.reports[client.name]; ok && time.Since(report.CheckedAt) < healthCheckTTL {
+		return report
+	}
+	report := buildHealthReport(client)
+	healthReports.reports[client.name] = report
+	if report.Status != "healthy" {
+		log.Printf("⚠️  Health of cluster %s is %s", client.name, report.Status)
+	}
+	return report
+}
+
+// healthStatusCode keeps probes green unless strict checking is requested, so an API server
+// outage does not restart the visualizer
+func healthStatusCode(c *gin.Context, report *HealthReport) int {
+	if c.Query("strict") == "true" && report.Status == "unhealthy" {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Ways a health condition matches the values its path selects
+const (
+	HealthMatchAll = "all"
+	HealthMatchAny = "any"
+)
+
+// HealthCondition holds when the values selected by Path are among Values (case-insensitive).
+// A condition selecting no value does not hold, unless AllowEmpty is set.
+type HealthCondition struct {
+	// Path is a JSONPath selecting one or more values, e.g. "{.status.components.*.phase}"
+	Path   string   `json:"path"`
+	Values []string `json:"values"`
+	// Match is "all" (default) to require every selected value in Values, "any" for at least one
+	Match string `json:"match,omitempty"`
+	// AllowEmpty makes the condition hold when Path selects nothing, e.g. for lists that may be empty
+	AllowEmpty bool `json:"allowEmpty,omitempty"`
+}
+
+// HealthRule derives the health of a kind from conditions on its status, e.g. readiness gates
+// like "phase is Running and all components are Running". A resource is Degraded when any
+// Degraded condition holds, Healthy when all Healthy conditions hold, and Progressing otherwise
+// (Unknown when none of the paths selects a value yet).
+type HealthRule struct {
+	Group    string            `json:"group"`
+	Kind     string            `json:"kind"`
+	Healthy  []HealthCondition `json:"healthy"`
+	Degraded []HealthCondition `json:"degraded,omitempty"`
+}
+
+// builtinHealthRules replace the phase string for KubeBlocks resources whose phase alone is
+// misleading; configured rules and status extractors for the same kind take precedence
+var builtinHealthRules = []HealthRule{
+	{
+		Group: "apps.kubeblocks.io",
+		Kind:  "Cluster",
+		Healthy: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Running"}},
+			{Path: "{.status.components.*.phase}", Values: []string{"Running"}, AllowEmpty: true},
+		},
+		Degraded: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Failed", "Abnormal"}},
+			{Path: "{.status.components.*.phase}", Values: []string{"Failed", "Abnormal"}, Match: HealthMatchAny},
+		},
+	},
+	{
+		Group: "apps.kubeblocks.io",
+		Kind:  "Component",
+		Healthy: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Running"}},
+		},
+		Degraded: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Failed", "Abnormal"}},
+		},
+	},
+	{
+		Group: "dataprotection.kubeblocks.io",
+		Kind:  "Backup",
+		Healthy: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Completed"}},
+		},
+		Degraded: []HealthCondition{
+			{Path: "{.status.phase}", Values: []string{"Failed"}},
+		},
+	},
+}
+
+// validateHealthRules checks that the rules name a kind and that their JSONPath expressions parse
+func validateHealthRules(rules []HealthRule) error {
+	for i, rule := range rules {
+		if rule.Kind == "" {
+			return fmt.Errorf("healthRules[%d]: kind is required", i)
+		}
+		if len(rule.Healthy) == 0 {
+			return fmt.Errorf("healthRules[%d]: at least one healthy condition is required", i)
+		}
+		for _, condition := range append(append([]HealthCondition{}, rule.Healthy...), rule.Degraded...) {
+			if err := jsonpath.New(rule.Kind).Parse(condition.Path); err != nil {
+				return fmt.Errorf("healthRules[%d]: invalid JSONPath %q: %v", i, condition.Path, err)
+			}
+			if condition.Match != "" && condition.Match != HealthMatchAll && condition.Match != HealthMatchAny {
+				return fmt.Errorf("healthRules[%d]: invalid match %q, must be %s or %s", i, condition.Match, HealthMatchAll, HealthMatchAny)
+			}
+		}
+	}
+	return nil
+}
+
+// configuredHealthRules returns the health rules of the configuration
+func configuredHealthRules() []HealthRule {
+	if appConfig == nil {
+		return nil
+	}
+	return appConfig.HealthRules
+}
+
+// findHealthRule returns the rule of the resource's group and kind among the rules
+func findHealthRule(resource *unstructured.Unstructured, rules []HealthRule) *HealthRule {
+	gvk := resource.GroupVersionKind()
+	for i := range rules {
+		if rules[i].Kind == gvk.Kind && rules[i].Group == gvk.Group {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// evaluateHealthCondition reports whether the condition holds, and whether its path selected anything
+func evaluateHealthCondition(resource *unstructured.Unstructured, condition HealthCondition) (holds, found bool) {
+	values, err := evaluateJSONPath(resource, condition.Path)
+	if err != nil || len(values) == 0 {
+		return condition.AllowEmpty, false
+	}
+	if condition.Match == HealthMatchAny {
+		for _, value := range values {
+			if containsFold(condition.Values, value) {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	for _, value := range values {
+		if !containsFold(condition.Values, value) {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// ruleHealth evaluates the health rule of the resource among the rules, if one applies
+func ruleHealth(resource *unstructured.Unstructured, rules []HealthRule) (string, bool) {
+	rule := findHealthRule(resource, rules)
+	if rule == nil {
+		return "", false
+	}
+
+	anyFound := false
+	for _, condition := range rule.Degraded {
+		holds, found := evaluateHealthCondition(resource, condition)
+		if holds {
+			return HealthDegraded, true
+		}
+		anyFound = anyFound || found
+	}
+	allHold := true
+	for _, condition := range rule.Healthy {
+		holds, found := evaluateHealthCondition(resource, condition)
+		allHold = allHold && holds
+		anyFound = anyFound || found
+	}
+	switch {
+	case allHold:
+		return HealthHealthy, true
+	case !anyFound:
+		return HealthUnknown, true
+	}
+	return HealthProgressing, true
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	historyRingSize          = 5000
+	historyDefaultWindow     = time.Hour
+	historyRedetectInterval  = 5 * time.Minute
+	historyWatchRetryBackoff = 5 * time.Second
+)
+
+// HistoryEvent is one recorded change of a resource of a cluster tree
+type HistoryEvent struct {
+	Time            time.Time `json:"time"`
+	Type            string    `json:"type"` // ADDED, MODIFIED or DELETED
+	Kind            string    `json:"kind"`
+	Name            string    `json:"name"`
+	UID             string    `json:"uid"`
+	ResourceVersion string    `json:"resourceVersion"`
+	Generation      int64     `json:"generation,omitempty"`
+	Status          string    `json:"status,omitempty"`
+	Health          string    `json:"health"`
+}
+
+// ClusterHistory is the response of /api/clusters/:name/history
+type ClusterHistory struct {
+	Cluster        string         `json:"cluster"`
+	Namespace      string         `json:"namespace"`
+	RecordingSince time.Time      `json:"recordingSince"`
+	Window         string         `json:"window"`
+	Events         []HistoryEvent `json:"events"`
+}
+
+// historyRecorder keeps the latest watch events of the resources of one KubeBlocks cluster in a ring buffer
+type historyRecorder struct {
+	client    *K8sClient
+	namespace string
+	cluster   string
+	since     time.Time
+
+	mu     sync.RWMutex
+	events []HistoryEvent
+	next   int
+}
+
+// historyRecorders holds the recorders by cluster client, namespace and cluster name
+var historyRecorders = struct {
+	mu        sync.Mutex
+	recorders map[string]*historyRecorder
+}{recorders: map[string]*historyRecorder{}}
+
+// recorderFor returns the recorder of a cluster, starting it on first use
+func recorderFor(client *K8sClient, namespace, cluster string) *historyRecorder {
+	client = baseClient(client)
+	key := fmt.Sprintf("%s|%s|%s", client.name, namespace, cluster)
+	historyRecorders.mu.Lock()
+	defer historyRecorders.mu.Unlock()
+
+	if recorder, ok := historyRecorders.recorders[key]; ok {
+		return recorder
+	}
+	recorder := &historyRecorder{client: client, namespace: namespace, cluster: cluster, since: time.Now()}
+	historyRecorders.recorders[key] = recorder
+	log.Printf("📼 Recording history of cluster %s/%s", namespace, cluster)
+	// Recorders are leader-only work, they move to the new leader on failover
+	leaderState.RunWhenLeader(recorder.run)
+	return recorder
+}
+
+// startConfiguredRecorders starts recording the clusters listed as namespace/name in the configuration
+func startConfiguredRecorders(client *K8sClient, clusters []string) {
+	for _, ref := range clusters {
+		namespace, name, err := parseClusterRef(ref)
+		if err != nil {
+			log.Printf("⚠️  Skipping history recording: %v", err)
+			continue
+		}
+		recorderFor(client, namespace, name)
+	}
+}
+
+func (hr *historyRecorder) record(eventType watch.EventType, resource *unstructured.Unstructured) {
+	event := HistoryEvent{
+		Time:            time.Now(),
+		Type:            string(eventType),
+		Kind:            resource.GetKind(),
+		Name:            resource.GetName(),
+		UID:             string(resource.GetUID()),
+		ResourceVersion: resource.GetResourceVersion(),
+		Generation:      resource.GetGeneration(),
+		Status:          resourceStatus(resource),
+		Health:          computeHealth(resource),
+	}
+
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if len(hr.events) < historyRingSize {
+		hr.events = append(hr.events, event)
+		return
+	}
+	hr.events[hr.next] = event
+	hr.next = (hr.next + 1) % historyRingSize
+}
+
+// recordingSince returns when this replica started recording
+func (hr *historyRecorder) recordingSince() time.Time {
+	hr.mu.RLock()
+	defer hr.mu.RUnlock()
+	return hr.since
+}
+
+// eventsSince returns the recorded events after the given time, oldest first
+func (hr *historyRecorder) eventsSince(since time.Time) []HistoryEvent {
+	hr.mu.RLock()
+	defer hr.mu.RUnlock()
+
+	events := []HistoryEvent{}
+	for i := 0; i < len(hr.events); i++ {
+		event := hr.events[(hr.next+i)%len(hr.events)]
+		if event.Time.After(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// run watches the cluster object and every resource type holding resources of the cluster,
+// detecting the types again periodically to pick up new ones
+func (hr *historyRecorder) run(ctx context.Context) {
+	hr.mu.Lock()
+	hr.since = time.Now()
+	hr.mu.Unlock()
+
+	listOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", instanceLabel, hr.cluster)}
+	for ctx.Err() == nil {
+		watchCtx, cancel := context.WithTimeout(ctx, historyRedetectInterval)
+
+		builder := NewResourceTreeBuilder(hr.client, hr.namespace, listOptions)
+		resourceTypes, _ := builder.detectResourceTypes(builder.getSupportedResourceTypes())
+
+		var wg sync.WaitGroup
+		watchType := func(gvr schema.GroupVersionResource, options metav1.ListOptions) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				hr.watchType(watchCtx, gvr, options)
+			}()
+		}
+		watchType(clusterGVR, metav1.ListOptions{FieldSelector: "metadata.name=" + hr.cluster})
+		for _, gvr := range resourceTypes {
+			if gvr != clusterGVR {
+				watchType(gvr, listOptions)
+			}
+		}
+		wg.Wait()
+		cancel()
+	}
+	log.Printf("📼 Stopped recording history of cluster %s/%s", hr.namespace, hr.cluster)
+}
+
+// watchType records the events of one resource type, resuming from the last seen resourceVersion
+// and listing again when it expired
+func (hr *historyRecorder) watchType(ctx context.Context, gvr schema.GroupVersionResource, listOptions metav1.ListOptions) {
+	resources := hr.client.dynamicClient.Resource(gvr).Namespace(hr.namespace)
+	resourceVersion := ""
+
+	for ctx.Err() == nil {
+		if resourceVersion == "" {
+			list, err := resources.List(ctx, listOptions)
+			if err != nil {
+				log.Printf("⚠️  History of %s/%s: unable to list %s: %v", hr.namespace, hr.cluster, gvr.Resource, err)
+				sleepContext(ctx, historyWatchRetryBackoff)
+				continue
+			}
+			resourceVersion = list.GetResourceVersion()
+		}
+
+		options := listOptions
+		options.ResourceVersion = resourceVersion
+		options.AllowWatchBookmarks = true
+		watcher, err := resources.Watch(ctx, options)
+		if err != nil {
+			log.Printf("⚠️  History of %s/%s: unable to watch %s: %v", hr.namespace, hr.cluster, gvr.Resource, err)
+			resourceVersion = ""
+			sleepContext(ctx, historyWatchRetryBackoff)
+			continue
+		}
+
+	events:
+		for result := range watcher.ResultChan() {
+			switch result.Type {
+			case watch.Error:
+				// Most likely "too old resource version", start over from a fresh list
+				resourceVersion = ""
+				break events
+			case watch.Bookmark:
+				if resource, ok := result.Object.(*unstructured.Unstructured); ok {
+					resourceVersion = resource.GetResourceVersion()
+				}
+			default:
+				resource, ok := result.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				resourceVersion = resource.GetResourceVersion()
+				hr.record(result.Type, resource)
+			}
+		}
+		watcher.Stop()
+		if ctx.Err() == nil {
+			recordWatchReconnect("history", resourceVersion == "")
+		}
+	}
+}
+
+// sleepContext waits for the duration or until the context is done
+func sleepContext(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func getClusterHistory(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching cluster history"})
+		return
+	}
+	params, err := queryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window := historyDefaultWindow
+	if params.Window != nil {
+		window = *params.Window
+	}
+
+	log.Printf("Fetching history of cluster %s in namespace '%s' for the last %s requested from %s", clusterName, namespace, window, c.ClientIP())
+
+	if !leaderState.IsLeader() {
+		proxyToLeader(c, "History is")
+		return
+	}
+
+	client := clientFor(c)
+	if _, err := client.dynamicClient.Resource(clusterGVR).Namespace(namespace).Get(context.TODO(), clusterName, metav1.GetOptions{}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Cluster not found: %s in namespace %s", clusterName, namespace)})
+		return
+	}
+
+	recorder := recorderFor(client, namespace, clusterName)
+	history := ClusterHistory{
+		Cluster:        clusterName,
+		Namespace:      namespace,
+		RecordingSince: recorder.recordingSince(),
+		Window:         window.String(),
+		Events:         recorder.eventsSince(time.Now().Add(-window)),
+	}
+	log.Printf("Returning %d history events of cluster %s", len(history.Events), clusterName)
+	c.JSON(http.StatusOK, history)
+}
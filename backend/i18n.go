@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Languages of server-generated messages. Messages are written in English, other languages are
+// translated through their catalog.
+const (
+	languageEnglish = "en"
+	languageChinese = "zh"
+)
+
+const languageContextKey = "language"
+
+// messageCatalogs translate problem findings and tree warnings. They are keyed by the English
+// format string, so a message missing from a catalog is served in English.
+var messageCatalogs = map[string]map[string]string{
+	languageChinese: {
+		// Problem detectors
+		"Container %s is crash looping":                               "容器 %s 处于崩溃循环（CrashLoopBackOff）",
+		"Container %s cannot pull its image: %s":                      "容器 %s 无法拉取镜像：%s",
+		"Container %s was killed for exceeding its memory limit":      "容器 %s 因超出内存限制被终止",
+		"Container %s restarted %d times":                             "容器 %s 已重启 %d 次",
+		"Container %s has been running for %s without becoming ready": "容器 %s 已运行 %s，仍未就绪",
+		"Pod is pending because PVC %s is %s":                         "Pod 处于 Pending 状态，因为 PVC %s 的状态为 %s",
+		"Pod cannot be scheduled: %s":                                 "Pod 无法调度：%s",
+
+		"No BackupRepo is named and there is no default BackupRepo": "未指定 BackupRepo，且不存在默认 BackupRepo",
+		"BackupRepo %s does not exist":                              "BackupRepo %s 不存在",
+		"BackupRepo %s is %s":                                       "BackupRepo %s 的状态为 %s",
+
+		// BackupRepo health
+		"Condition %s is False: %s":                  "条件 %s 为 False：%s",
+		"StorageProvider %s is %s":                   "StorageProvider %s 的状态为 %s",
+		"StorageProvider %s does not exist":          "StorageProvider %s 不存在",
+		"Credential Secret %s/%s does not exist":     "凭据 Secret %s/%s 不存在",
+		"Credential Secret %s/%s cannot be read: %v": "无法读取凭据 Secret %s/%s：%v",
+		"PVC %s/%s is %s":                            "PVC %s/%s 的状态为 %s",
+
+		// Scheduling report
+		"Pods %s share %s %s although a required anti-affinity keeps them apart":                                    "Pod %s 位于相同的 %s %s，但必需的反亲和性要求将它们分开",
+		"Pods %s share %s %s although a preferred anti-affinity asks to keep them apart":                            "Pod %s 位于相同的 %s %s，但首选的反亲和性建议将它们分开",
+		"Pods are spread over %s with a skew of %d (%s has %d, %s has %d), more than the allowed %d":                "Pod 在 %s 上的分布偏差为 %d（%s 有 %d 个，%s 有 %d 个），超过了允许的 %d",
+		"Replicas %s run on the same node %s, nothing keeps them apart so losing the node takes them down together": "副本 %s 运行在同一节点 %s 上，没有约束将它们分开，该节点故障时它们会同时不可用",
+		"All %d replicas run in zone %s although the nodes span %d zones":                                           "全部 %d 个副本都运行在可用区 %s 中，而节点分布在 %d 个可用区",
+
+		// Disruption simulation
+		"No replica would be disrupted":                                                                                  "不会有副本受到影响",
+		"No ready replica would remain, %d of %d replicas would be disrupted":                                            "将没有就绪副本剩余，%d/%d 个副本会受到影响",
+		"%d ready voting replicas would remain, below the quorum of %d":                                                  "将只剩 %d 个就绪的投票副本，低于法定人数 %d",
+		"Leader %s would be disrupted, one of the %d remaining ready replicas is expected to take over":                  "Leader %s 会受到影响，预计由剩余 %d 个就绪副本之一接管",
+		"%d of %d replicas would remain ready":                                                                           "%d/%d 个副本将保持就绪",
+		"Evicting %d healthy pods uses %d of the %d disruptions allowed":                                                 "驱逐 %d 个健康 Pod 将占用允许的 %[3]d 次中断中的 %[2]d 次",
+		"Evicting %d healthy pods exceeds the %d disruptions allowed, a drain would wait until replacements are healthy": "驱逐 %d 个健康 Pod 超过了允许的 %d 次中断，排空节点将等待替代 Pod 就绪",
+
+		// Tree builder warnings
+		"%s/%s references %s %s/%s which cannot be read: %v":                                         "%s/%s 引用的 %s %s/%s 无法读取：%v",
+		"Unable to list RoleBindings in namespace %s: %v":                                            "无法列出命名空间 %s 中的 RoleBinding：%v",
+		"Unable to read BackupRepo %s: %v":                                                           "无法读取 BackupRepo %s：%v",
+		"Cycle detected for resource %s/%s (UID: %s)":                                                "资源 %s/%s 存在循环引用（UID：%s）",
+		"Tree truncated at %d nodes, expand the truncated nodes to see the rest":                     "资源树在 %d 个节点处被截断，展开被截断的节点可查看其余部分",
+		"Tree truncated after the time budget was spent, expand the truncated nodes to see the rest": "时间预算已用完，资源树被截断，展开被截断的节点可查看其余部分",
+		"Resource %s of the continuation no longer exists":                                           "续传令牌中的资源 %s 已不存在",
+		"Parent %s of %s/%s not found in the tree":                                                   "在资源树中未找到 %[2]s/%[3]s 的父资源 %[1]s",
+		"Descendants of %s/%s do not carry the label %s (%s), so the namespace was listed without the label selector, which is slower on large namespaces. Label them to restore the fast path": "%s/%s 的下级资源未携带标签 %s（%s），因此在列出命名空间时未使用标签选择器，这在大型命名空间中更慢。为这些资源添加该标签即可恢复快速路径",
+	},
+}
+
+// localize formats a message in the language, falling back to English when it is not translated
+func localize(language, format string, args ...interface{}) string {
+	if translated, found := messageCatalogs[language][format]; found {
+		format = translated
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// negotiateLanguage picks the preferred language of an Accept-Language header that has a catalog,
+// e.g. zh for "zh-CN,zh;q=0.9,en;q=0.8". English is the default.
+func negotiateLanguage(header string) string {
+	type weighted struct {
+		language string
+		quality  float64
+	}
+	var candidates []weighted
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary != "" && quality > 0 {
+			candidates = append(candidates, weighted{language: primary, quality: quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	for _, candidate := range candidates {
+		if candidate.language == languageEnglish {
+			return languageEnglish
+		}
+		if _, found := messageCatalogs[candidate.language]; found {
+			return candidate.language
+		}
+	}
+	return languageEnglish
+}
+
+// languageMiddleware negotiates the language of the messages of a request from its Accept-Language header
+func languageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		language := negotiateLanguage(c.GetHeader("Accept-Language"))
+		c.Set(languageContextKey, language)
+		c.Header("Content-Language", language)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// requestLanguage returns the negotiated language of the request, English outside of languageMiddleware
+func requestLanguage(c *gin.Context) string {
+	return defaultString(c.GetString(languageContextKey), languageEnglish)
+}
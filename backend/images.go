@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ImageUsage is a container image used in a tree with the pods running it
+type ImageUsage struct {
+	Image      string   `json:"image"`
+	ImageIDs   []string `json:"imageIDs,omitempty"` // Resolved digests reported by the kubelet
+	Containers []string `json:"containers"`
+	Pods       []string `json:"pods"`
+}
+
+// ComponentVersion is the service version a KubeBlocks Component runs
+type ComponentVersion struct {
+	Name           string `json:"name"`
+	CompDef        string `json:"compDef,omitempty"`
+	ServiceVersion string `json:"serviceVersion,omitempty"`
+}
+
+// ImageInventory lists the images and component versions of a tree
+type ImageInventory struct {
+	Images     []ImageUsage       `json:"images"`
+	Components []ComponentVersion `json:"components"`
+}
+
+// appendUnique appends the value unless the slice already holds it
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// collectImageInventory walks the tree for pod images and component service versions
+func collectImageInventory(root *ResourceTreeNode) ImageInventory {
+	inventory := ImageInventory{Images: []ImageUsage{}, Components: []ComponentVersion{}}
+	images := map[string]*ImageUsage{}
+
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		resource := node.Resource
+		switch resource.GetKind() {
+		case "Pod":
+			// Digests the kubelet resolved, per container name
+			imageIDs := map[string]string{}
+			for _, field := range []string{"containerStatuses", "initContainerStatuses"} {
+				statuses, _, _ := unstructured.NestedSlice(resource.Object, "status", field)
+				for _, status := range statuses {
+					statusMap, ok := status.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					name, _ := statusMap["name"].(string)
+					imageIDs[name], _ = statusMap["imageID"].(string)
+				}
+			}
+			for _, field := range []string{"containers", "initContainers"} {
+				containers, _, _ := unstructured.NestedSlice(resource.Object, "spec", field)
+				for _, container := range containers {
+					containerMap, ok := container.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					image, _ := containerMap["image"].(string)
+					name, _ := containerMap["name"].(string)
+					if image == "" {
+						continue
+					}
+					usage := images[image]
+					if usage == nil {
+						usage = &ImageUsage{Image: image, Containers: []string{}, Pods: []string{}}
+						images[image] = usage
+					}
+					usage.Containers = appendUnique(usage.Containers, name)
+					usage.Pods = appendUnique(usage.Pods, resource.GetName())
+					if imageID := imageIDs[name]; imageID != "" {
+						usage.ImageIDs = appendUnique(usage.ImageIDs, imageID)
+					}
+				}
+			}
+		case "Component":
+			version := ComponentVersion{Name: resource.GetName()}
+			version.CompDef, _, _ = unstructured.NestedString(resource.Object, "spec", "compDef")
+			version.ServiceVersion, _, _ = unstructured.NestedString(resource.Object, "spec", "serviceVersion")
+			inventory.Components = append(inventory.Components, version)
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	for _, usage := range images {
+		sort.Strings(usage.Pods)
+		sort.Strings(usage.ImageIDs)
+		inventory.Images = append(inventory.Images, *usage)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool {
+		return inventory.Images[i].Image < inventory.Images[j].Image
+	})
+	sort.Slice(inventory.Components, func(i, j int) bool {
+		return inventory.Components[i].Name < inventory.Components[j].Name
+	})
+	return inventory
+}
+
+func getResourceImages(c *gin.Context) {
+	resourceType := c.Param("type")
+	rootResourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Listing images of %s/%s in namespace '%s' requested from %s", resourceType, rootResourceName, namespace, c.ClientIP())
+
+	rootTreeNode, _, status, err := buildTreeForRoot(clientFor(c), resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	inventory := collectImageInventory(rootTreeNode)
+	log.Printf("Found %d images and %d components in the tree of %s/%s", len(inventory.Images), len(inventory.Components), resourceType, rootResourceName)
+	c.JSON(http.StatusOK, inventory)
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const installConfigPath = "/etc/kb-viz/config.yaml"
+
+// installTreeTypesPath is where the tree types file of the ConfigMap is mounted
+const installTreeTypesPath = "/etc/kb-viz/tree-types.yaml"
+
+// installOptions are the flags of the install subcommand
+type installOptions struct {
+	name      string
+	namespace string
+	image     string
+	replicas  int
+	output    string
+}
+
+// Cluster-scoped resources read by the tree decorators and KubeBlocks views
+var clusterScopedReadResources = []schema.GroupVersionResource{
+	{Group: "", Version: "v1", Resource: "namespaces"},
+	{Group: "", Version: "v1", Resource: "nodes"},
+	persistentVolumeGVR,
+	storageClassGVR,
+	parametersDefinitionGVR,
+	paramConfigRendererGVR,
+	crdGVR,
+	clusterRoleGVR,
+	backupRepoGVR,
+	storageProviderGVR,
+}
+
+// Namespaced resources read outside of the tree pool
+var extraNamespacedReadResources = []schema.GroupVersionResource{
+	{Group: "", Version: "v1", Resource: "events"},
+	instanceGVR,
+	serviceAccountGVR,
+	roleGVR,
+	roleBindingGVR,
+	controllerRevisionGVR,
+	{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+	volumeSnapshotGVR,
+}
+
+// runInstall implements `kb-viz install`: it prints the manifests deploying the visualizer
+// in-cluster with a read-only ServiceAccount scoped to the configured resource types
+func runInstall(args []string) int {
+	opts := installOptions{}
+	flags := flag.NewFlagSet("install", flag.ContinueOnError)
+	flags.StringVar(&opts.name, "name", "kb-viz", "Name of the generated resources")
+	flags.StringVar(&opts.namespace, "namespace", "kb-viz", "Namespace to deploy the visualizer into")
+	flags.StringVar(&opts.image, "image", "k8s-resource-visualizer:latest", "Backend container image")
+	flags.IntVar(&opts.replicas, "replicas", 1, "Number of replicas (more than one enables leader election)")
+	flags.StringVar(&opts.output, "output", "", "Write the manifests to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+		return 1
+	}
+	// Types included by the tree types file get read permissions as well
+	if config.TreeTypesFile != "" {
+		if err := loadTreeTypes(config.TreeTypesFile); err != nil {
+			log.Printf("Failed to load tree resource types: %v", err)
+			return 1
+		}
+	}
+	if opts.replicas > 1 {
+		config.LeaderElection.Enabled = true
+		config.LeaderElection.LeaseNamespace = opts.namespace
+	}
+
+	manifests, err := generateInstallManifests(opts, config)
+	if err != nil {
+		log.Printf("Failed to generate manifests: %v", err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if opts.output != "" {
+		file, err := os.Create(opts.output)
+		if err != nil {
+			log.Printf("Failed to create %s: %v", opts.output, err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if _, err := out.Write(manifests); err != nil {
+		log.Printf("Failed to write manifests: %v", err)
+		return 1
+	}
+	if opts.output != "" {
+		log.Printf("✓ Wrote install manifests to %s", opts.output)
+	}
+	return 0
+}
+
+// policyRulesFor groups the resources by API group into rules with the given verbs
+func policyRulesFor(resources []schema.GroupVersionResource, verbs []string) []rbacv1.PolicyRule {
+	byGroup := map[string]map[string]bool{}
+	for _, gvr := range resources {
+		if byGroup[gvr.Group] == nil {
+			byGroup[gvr.Group] = map[string]bool{}
+		}
+		byGroup[gvr.Group][gvr.Resource] = true
+	}
+
+	groups := make([]string, 0, len(byGroup))
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var rules []rbacv1.PolicyRule
+	for _, group := range groups {
+		var names []string
+		for resource := range byGroup[group] {
+			names = append(names, resource)
+		}
+		sort.Strings(names)
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{group}, Resources: names, Verbs: verbs})
+	}
+	return rules
+}
+
+// Verbs granted on the resources the server reads
+var installReadVerbs = []string{"get", "list", "watch"}
+
+// installReadResources returns the namespaced and cluster-scoped resources the server reads.
+// Types added to the tree types file later need their permissions granted separately.
+func installReadResources(config *Config) ([]schema.GroupVersionResource, []schema.GroupVersionResource) {
+	namespaced := append((&ResourceTreeBuilder{}).getSupportedResourceTypes(), extraNamespacedReadResources...)
+	clusterScoped := append([]schema.GroupVersionResource{}, clusterScopedReadResources...)
+	for _, custom := range config.CustomResourceTypes {
+		gvr := schema.GroupVersionResource{Group: custom.Group, Version: custom.Version, Resource: custom.Resource}
+		if custom.Namespaced != nil && !*custom.Namespaced {
+			clusterScoped = append(clusterScoped, gvr)
+		} else {
+			namespaced = append(namespaced, gvr)
+		}
+	}
+	return namespaced, clusterScoped
+}
+
+// namespacedPolicyRules returns the rules the server needs on the namespaced resources it reads
+func namespacedPolicyRules(config *Config, namespaced []schema.GroupVersionResource) []rbacv1.PolicyRule {
+	namespacedRules := policyRulesFor(namespaced, installReadVerbs)
+	// Scale subresources are read by the scale endpoint
+	namespacedRules = append(namespacedRules,
+		rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments/scale", "statefulsets/scale"}, Verbs: []string{"get"}},
+		rbacv1.PolicyRule{APIGroups: []string{instanceGVR.Group}, Resources: []string{"instancesets/scale"}, Verbs: []string{"get"}},
+		// Container logs are read by the log stream
+		rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+	)
+	if config.WriteEnabled {
+		namespacedRules = append(namespacedRules,
+			rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments/scale", "statefulsets/scale"}, Verbs: []string{"update"}},
+			rbacv1.PolicyRule{APIGroups: []string{instanceGVR.Group}, Resources: []string{"instancesets/scale"}, Verbs: []string{"update"}},
+			// Server-side dry-runs of OpsRequest previews need create
+			rbacv1.PolicyRule{APIGroups: []string{opsRequestGVR.Group}, Resources: []string{opsRequestGVR.Resource}, Verbs: []string{"create"}},
+			// Debug containers are added to pods and opened with exec
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/ephemeralcontainers"}, Verbs: []string{"update"}},
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"create"}},
+		)
+		// Restarts and the patch endpoint patch any resource the server reads
+		namespacedRules = append(namespacedRules, policyRulesFor(namespaced, []string{"patch"})...)
+	}
+	return namespacedRules
+}
+
+// generateInstallManifests renders the ServiceAccount, RBAC, config, Deployment and Service
+func generateInstallManifests(opts installOptions, config *Config) ([]byte, error) {
+	labels := map[string]string{"app.kubernetes.io/name": opts.name}
+	meta := func(name, namespace string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}
+	}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: opts.name, Namespace: opts.namespace}}
+	namespacedRole := opts.name + "-reader"
+	clusterRole := opts.name + "-cluster-reader"
+
+	namespaced, clusterScoped := installReadResources(config)
+	namespacedRules := namespacedPolicyRules(config, namespaced)
+
+	// The share token secret and export storage credentials belong in a Secret, not in the ConfigMap
+	published := *config
+	published.ShareTokenSecret = ""
+	published.ExportStorage.AccessKeyID = ""
+	published.ExportStorage.SecretAccessKey = ""
+	// The tree types file is shipped in the ConfigMap, where it can be edited and is reloaded
+	treeTypesData := []byte("include: []\nexclude: []\n")
+	if config.TreeTypesFile != "" {
+		var err error
+		if _, treeTypesData, err = readTreeTypesFile(config.TreeTypesFile); err != nil {
+			return nil, err
+		}
+	}
+	published.TreeTypesFile = installTreeTypesPath
+	configData, err := yaml.Marshal(&published)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []interface{}{
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: opts.namespace},
+		},
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: meta(opts.name, opts.namespace),
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: meta(namespacedRole, ""),
+			Rules:      namespacedRules,
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: meta(clusterRole, ""),
+			Rules:      policyRulesFor(clusterScoped, installReadVerbs),
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: meta(clusterRole, ""),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole},
+			Subjects:   subjects,
+		},
+	}
+
+	// With a namespace allowlist the namespaced permissions are only bound in those namespaces
+	if len(config.WatchNamespaces) == 0 {
+		objects = append(objects, &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: meta(namespacedRole, ""),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: namespacedRole},
+			Subjects:   subjects,
+		})
+	} else {
+		for _, namespace := range config.WatchNamespaces {
+			objects = append(objects, &rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: meta(namespacedRole, namespace),
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: namespacedRole},
+				Subjects:   subjects,
+			})
+		}
+	}
+
+	// Kubeconfigs of additional clusters are read from their Secrets
+	for _, cluster := range config.Clusters {
+		if ref := cluster.KubeconfigSecret; ref != nil {
+			secretRole := fmt.Sprintf("%s-kubeconfig-%s", opts.name, cluster.Name)
+			objects = append(objects,
+				&rbacv1.Role{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+					ObjectMeta: meta(secretRole, ref.Namespace),
+					Rules: []rbacv1.PolicyRule{{
+						APIGroups:     []string{""},
+						Resources:     []string{"secrets"},
+						ResourceNames: []string{ref.Name},
+						Verbs:         []string{"get"},
+					}},
+				},
+				&rbacv1.RoleBinding{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+					ObjectMeta: meta(secretRole, ref.Namespace),
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: secretRole},
+					Subjects:   subjects,
+				},
+			)
+		}
+	}
+
+	// Secrets referenced across namespaces, e.g. BackupRepo credentials, are read in their namespace
+	secretNamespaces := map[string]bool{}
+	for _, rule := range config.CrossNamespaceReferences {
+		if secretNamespaces[rule.To] || (len(rule.Kinds) > 0 && !containsFold(rule.Kinds, "Secret")) {
+			continue
+		}
+		secretNamespaces[rule.To] = true
+		secretRole := opts.name + "-cross-namespace-secrets"
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: meta(secretRole, rule.To),
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{""},
+					Resources: []string{"secrets"},
+					Verbs:     []string{"get"},
+				}},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: meta(secretRole, rule.To),
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: secretRole},
+				Subjects:   subjects,
+			},
+		)
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "KB_VIZ_CONFIG", Value: installConfigPath},
+		{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+	}
+	if config.LeaderElection.Enabled {
+		leaseRole := opts.name + "-leader-election"
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: meta(leaseRole, opts.namespace),
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{"coordination.k8s.io"},
+					Resources: []string{"leases"},
+					Verbs:     []string{"get", "create", "update"},
+				}},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: meta(leaseRole, opts.namespace),
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: leaseRole},
+				Subjects:   subjects,
+			},
+		)
+		env = append(env, corev1.EnvVar{Name: "POD_IP", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"}}})
+	}
+
+	replicas := int32(opts.replicas)
+	healthProbe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/api/health", Port: intstr.FromString("http")}},
+	}
+	readinessProbe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/readyz", Port: intstr.FromString("http")}},
+	}
+	objects = append(objects,
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: meta(opts.name+"-config", opts.namespace),
+			Data:       map[string]string{"config.yaml": string(configData), "tree-types.yaml": string(treeTypesData)},
+		},
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: meta(opts.name, opts.namespace),
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						ServiceAccountName: opts.name,
+						Containers: []corev1.Container{{
+							Name:           "backend",
+							Image:          opts.image,
+							Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+							Env:            env,
+							ReadinessProbe: readinessProbe,
+							LivenessProbe:  healthProbe,
+							VolumeMounts:   []corev1.VolumeMount{{Name: "config", MountPath: "/etc/kb-viz", ReadOnly: true}},
+						}},
+						Volumes: []corev1.Volume{{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: opts.name + "-config"}},
+							},
+						}},
+					},
+				},
+			},
+		},
+		&corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: meta(opts.name, opts.namespace),
+			Spec: corev1.ServiceSpec{
+				Selector: labels,
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 8080, TargetPort: intstr.FromString("http")}},
+			},
+		},
+	)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated by kb-viz install for namespace %s\n", opts.namespace)
+	for i, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// InstanceDetail merges a KubeBlocks Instance with its pod, role and volumes
+type InstanceDetail struct {
+	Instance  ResourceNode   `json:"instance"`
+	Cluster   string         `json:"cluster,omitempty"`
+	Component string         `json:"component,omitempty"`
+	Role      string         `json:"role,omitempty"`
+	Health    string         `json:"health"`
+	Pod       *ResourceNode  `json:"pod,omitempty"`
+	Placement *PodPlacement  `json:"placement,omitempty"`
+	Problems  []Problem      `json:"problems,omitempty"`
+	PVCs      []ResourceNode `json:"pvcs"`
+}
+
+// isOwnedBy reports whether the resource has an owner reference to the given owner
+func isOwnedBy(resource, owner *unstructured.Unstructured) bool {
+	for _, ref := range resource.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// findInstancePod returns the pod backing an Instance, which normally shares its name
+func findInstancePod(client *K8sClient, instance *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	pods := client.dynamicClient.Resource(podGVR).Namespace(instance.GetNamespace())
+	pod, err := pods.Get(context.TODO(), instance.GetName(), metav1.GetOptions{})
+	if err == nil && isOwnedBy(pod, instance) {
+		return pod, nil
+	}
+
+	listOptions := metav1.ListOptions{}
+	if cluster := instance.GetLabels()[instanceLabel]; cluster != "" {
+		listOptions.LabelSelector = fmt.Sprintf("%s=%s", instanceLabel, cluster)
+	}
+	podList, err := pods.List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		if isOwnedBy(&podList.Items[i], instance) {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findInstancePVCs returns the PVCs mounted by the pod, or owned by the Instance when there is no pod
+func findInstancePVCs(client *K8sClient, instance, pod *unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	pvcs := client.dynamicClient.Resource(pvcGVR).Namespace(instance.GetNamespace())
+	var result []unstructured.Unstructured
+
+	if pod != nil {
+		volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+		for _, volume := range volumes {
+			volumeMap, ok := volume.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			claimName, found, _ := unstructured.NestedString(volumeMap, "persistentVolumeClaim", "claimName")
+			if !found {
+				continue
+			}
+			pvc, err := pvcs.Get(context.TODO(), claimName, metav1.GetOptions{})
+			if err != nil {
+				log.Printf("    ⚠️  PVC %s of pod %s not found: %v", claimName, pod.GetName(), err)
+				continue
+			}
+			result = append(result, *pvc)
+		}
+		return result, nil
+	}
+
+	listOptions := metav1.ListOptions{}
+	if cluster := instance.GetLabels()[instanceLabel]; cluster != "" {
+		listOptions.LabelSelector = fmt.Sprintf("%s=%s", instanceLabel, cluster)
+	}
+	pvcList, err := pvcs.List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pvcList.Items {
+		if isOwnedBy(&pvcList.Items[i], instance) {
+			result = append(result, pvcList.Items[i])
+		}
+	}
+	return result, nil
+}
+
+func getInstanceDetail(c *gin.Context) {
+	instanceName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		log.Printf("Namespace is required for fetching instance details")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching instance details"})
+		return
+	}
+
+	log.Printf("Fetching instance %s in namespace '%s' requested from %s", instanceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	instance, err := client.dynamicClient.Resource(instanceGVR).Namespace(namespace).Get(context.TODO(), instanceName, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Instance not found: %s in namespace %s: %v", instanceName, namespace, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Instance not found: %s in namespace %s", instanceName, namespace)})
+		return
+	}
+
+	pod, err := findInstancePod(client, instance)
+	if err != nil {
+		log.Printf("Error finding pod of instance %s: %v", instanceName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pvcs, err := findInstancePVCs(client, instance, pod)
+	if err != nil {
+		log.Printf("Error finding PVCs of instance %s: %v", instanceName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	detail := InstanceDetail{
+		Instance:  resourceNodeFor(c, instance),
+		Cluster:   instance.GetLabels()[instanceLabel],
+		Component: instance.GetLabels()[componentNameLabel],
+		Role:      instance.GetLabels()[roleLabel],
+		Health:    computeHealth(instance),
+		PVCs:      resourceNodesFor(c, pvcs),
+	}
+	if pod != nil {
+		podNode := resourceNodeFor(c, pod)
+		detail.Pod = &podNode
+		// The pod carries the current role, the Instance may lag behind
+		if role := pod.GetLabels()[roleLabel]; role != "" {
+			detail.Role = role
+		}
+		detail.Health = computeHealth(pod)
+
+		pvcsByName := map[string]*unstructured.Unstructured{}
+		for i := range pvcs {
+			pvcsByName[pvcs[i].GetName()] = &pvcs[i]
+		}
+		detail.Problems = detectPodProblems(pod, pvcsByName, requestLanguage(c))
+
+		// Node access is optional, the placement then only has the node name
+		nodes, err := listNodeInfo(client)
+		if err != nil {
+			log.Printf("    ⚠️  Unable to list nodes for placement: %v", err)
+		}
+		detail.Placement = podPlacement(pod, nodes)
+	}
+	if detail.PVCs == nil {
+		detail.PVCs = []ResourceNode{}
+	}
+
+	log.Printf("Instance %s: pod %v, role '%s', %d PVCs", instanceName, pod != nil, detail.Role, len(detail.PVCs))
+	c.JSON(http.StatusOK, detail)
+}
@@ -0,0 +1,55 @@
+// Package retry holds the retry policy shared by the API server client of the backend and the Go
+// client of pkg/api: which failures are transient and how long to wait before the next attempt.
+package retry
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// InitialBackoff is the delay before the first retry, doubled for each following one
+	InitialBackoff = 200 * time.Millisecond
+	// MaxBackoff caps the delay between two attempts
+	MaxBackoff = 5 * time.Second
+)
+
+// TransientError reports whether a transport error is worth retrying
+func TransientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// TransientStatus reports whether a response status is worth retrying
+func TransientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Backoff returns the jittered delay before the given retry (1-based), at least Retry-After
+func Backoff(retry int, retryAfter string) time.Duration {
+	backoff := InitialBackoff << (retry - 1)
+	if backoff > MaxBackoff || backoff <= 0 {
+		backoff = MaxBackoff
+	}
+	// Full jitter over the upper half keeps retries of concurrent calls apart
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && time.Duration(seconds)*time.Second > backoff {
+		backoff = time.Duration(seconds) * time.Second
+	}
+	return backoff
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// States of a Job run
+const (
+	JobStateActive    = "Active"
+	JobStateSucceeded = "Succeeded"
+	JobStateFailed    = "Failed"
+)
+
+var (
+	jobGVR     = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	cronJobGVR = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+)
+
+// JobHistorySummary condenses the Jobs of a CronJob that are not shown as children
+type JobHistorySummary = api.JobHistorySummary
+
+// JobRun is one Job in the full history of a CronJob
+type JobRun struct {
+	ResourceNode
+	State          string       `json:"state"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+}
+
+// jobState derives the state of a Job from its conditions and counters
+func jobState(job *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(job.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok || conditionMap["status"] != "True" {
+			continue
+		}
+		switch conditionMap["type"] {
+		case "Complete":
+			return JobStateSucceeded
+		case "Failed":
+			return JobStateFailed
+		}
+	}
+	return JobStateActive
+}
+
+// jobRunTime returns when the Job started, falling back to its creation
+func jobRunTime(job *unstructured.Unstructured) metav1.Time {
+	if value, found, _ := unstructured.NestedString(job.Object, "status", "startTime"); found {
+		if started, err := time.Parse(time.RFC3339, value); err == nil {
+			return metav1.NewTime(started)
+		}
+	}
+	return job.GetCreationTimestamp()
+}
+
+// collapseJobHistory replaces the Job children of a CronJob with a summary, keeping only
+// running Jobs and the latest run so backup CronJobs do not flood the tree
+func collapseJobHistory(node *ResourceTreeNode) {
+	var jobs, others []*ResourceTreeNode
+	for _, child := range node.Children {
+		if child.Resource.GetKind() == "Job" {
+			jobs = append(jobs, child)
+		} else {
+			others = append(others, child)
+		}
+	}
+	if len(jobs) == 0 {
+		return
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobRunTime(jobs[i].Resource).Time.After(jobRunTime(jobs[j].Resource).Time)
+	})
+
+	summary := &JobHistorySummary{Total: len(jobs)}
+	kept := others
+	for i, job := range jobs {
+		state := jobState(job.Resource)
+		runTime := jobRunTime(job.Resource)
+		switch state {
+		case JobStateActive:
+			summary.Active++
+		case JobStateSucceeded:
+			summary.Succeeded++
+		case JobStateFailed:
+			summary.Failed++
+			if summary.LastFailed == nil {
+				summary.LastFailed = &runTime
+			}
+		}
+		if i == 0 {
+			summary.LastRun = &runTime
+			summary.LastState = state
+		}
+		if i == 0 || state == JobStateActive {
+			kept = append(kept, job)
+		}
+	}
+
+	node.Children = kept
+	node.JobHistory = summary
+}
+
+func getCronJobHistory(c *gin.Context) {
+	cronJobName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching job history"})
+		return
+	}
+
+	log.Printf("Fetching job history of CronJob %s in namespace '%s' requested from %s", cronJobName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	cronJob, err := client.dynamicClient.Resource(cronJobGVR).Namespace(namespace).Get(context.TODO(), cronJobName, metav1.GetOptions{})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("CronJob not found: %s in namespace %s", cronJobName, namespace)})
+		return
+	}
+	jobList, err := client.dynamicClient.Resource(jobGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runs := []JobRun{}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if !isOwnedBy(job, cronJob) {
+			continue
+		}
+		started := jobRunTime(job)
+		run := JobRun{
+			ResourceNode: resourceNodeFor(c, job),
+			State:        jobState(job),
+			StartTime:    &started,
+		}
+		if value, found, _ := unstructured.NestedString(job.Object, "status", "completionTime"); found {
+			if completed, err := time.Parse(time.RFC3339, value); err == nil {
+				completionTime := metav1.NewTime(completed)
+				run.CompletionTime = &completionTime
+				run.Duration = completed.Sub(started.Time).Round(time.Second).String()
+			}
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[j].StartTime.Before(runs[i].StartTime)
+	})
+
+	log.Printf("CronJob %s has %d jobs in its history", cronJobName, len(runs))
+	c.JSON(http.StatusOK, runs)
+}
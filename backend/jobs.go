@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Statuses of background jobs
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// Types of background jobs
+const (
+	// JobTypeNamespaceScan builds every tree of a namespace and detects their problems
+	JobTypeNamespaceScan = "namespaceScan"
+	// JobTypeClusterCompare compares two KubeBlocks clusters
+	JobTypeClusterCompare = "clusterCompare"
+	// JobTypeTreeExport and JobTypeManifestExport upload exports started with async=true
+	JobTypeTreeExport     = "treeExport"
+	JobTypeManifestExport = "manifestExport"
+)
+
+// jobConcurrency bounds the jobs running at the same time, further ones wait pending
+const jobConcurrency = 2
+
+// jobRetention is how long finished jobs can still be polled
+const jobRetention = time.Hour
+
+// Job is a long-running task run in the background and polled with GET /api/jobs/:id
+type Job struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Cluster string `json:"cluster"`
+	Status  string `json:"status"`
+	// Progress is the completed percentage, 100 once the job succeeded
+	Progress int `json:"progress"`
+	// Message describes the step the job is at
+	Message string `json:"message,omitempty"`
+	// Result is the outcome of a succeeded job, its shape depends on the job type
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	CreatedAt   string      `json:"createdAt"`
+	StartedAt   string      `json:"startedAt,omitempty"`
+	CompletedAt string      `json:"completedAt,omitempty"`
+
+	owner    string
+	created  time.Time
+	finished time.Time
+	cancel   context.CancelFunc
+}
+
+// JobRequest is the body of POST /api/jobs
+type JobRequest struct {
+	Type string `json:"type"`
+	// Namespace is scanned by namespaceScan jobs
+	Namespace string `json:"namespace,omitempty"`
+	// A and B are the namespace/name of the clusters compared by clusterCompare jobs
+	A string `json:"a,omitempty"`
+	B string `json:"b,omitempty"`
+}
+
+// jobFunc runs a job, reporting its progress, and returns early with ctx.Err() once ctx is canceled
+type jobFunc func(ctx context.Context, progress func(percent int, message string)) (interface{}, error)
+
+// jobStore keeps the jobs until their retention expires
+type jobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	slots chan struct{}
+}
+
+var jobQueue = &jobStore{jobs: map[string]*Job{}, slots: make(chan struct{}, jobConcurrency)}
+
+// done reports whether the job finished, whatever its outcome
+func (job *Job) done() bool {
+	return job.Status == JobStatusSucceeded || job.Status == JobStatusFailed || job.Status == JobStatusCanceled
+}
+
+// add registers a new job and drops the finished ones past their retention
+func (store *jobStore) add(job *Job) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for id, existing := range store.jobs {
+		if existing.done() && time.Since(existing.finished) > jobRetention {
+			delete(store.jobs, id)
+		}
+	}
+	store.jobs[job.ID] = job
+}
+
+// get returns a copy of the job visible to the owner, so it can be served while the job updates it.
+// Jobs started by an authenticated user or in a kubeconfig session are only visible to their owner.
+func (store *jobStore) get(id, owner string) (Job, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job, found := store.jobs[id]
+	if !found || (job.owner != "" && job.owner != owner) {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// list returns copies of the jobs visible to the owner, newest first
+func (store *jobStore) list(owner string) []Job {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	jobs := []Job{}
+	for _, job := range store.jobs {
+		if job.owner == "" || job.owner == owner {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].created.After(jobs[j].created) })
+	return jobs
+}
+
+// update changes a job under the store lock
+func (store *jobStore) update(job *Job, change func(job *Job)) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	change(job)
+}
+
+// cancel cancels a job that has not finished, the job stops at its next cancellation check
+func (store *jobStore) cancel(id, owner string) (Job, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job, found := store.jobs[id]
+	if !found || (job.owner != "" && job.owner != owner) {
+		return Job{}, false
+	}
+	if !job.done() {
+		job.cancel()
+		job.Message = "Canceling"
+	}
+	return *job, true
+}
+
+// jobOwner identifies who may see the jobs of a request: its user and, in a kubeconfig session, the
+// SHA-256 of the session token, so jobs run against an uploaded kubeconfig never show up outside it
+func jobOwner(c *gin.Context) string {
+	owner := requestUser(c)
+	if inKubeconfigSession(c) {
+		owner += "/" + kubeconfigSessionKey(kubeconfigSessionToken(c))
+	}
+	return owner
+}
+
+// startJob registers a job and runs it in the background once a slot is free. run must not use
+// the request, which is done by the time it runs.
+func startJob(c *gin.Context, jobType string, run jobFunc) (Job, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Job{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	job := &Job{ID: hex.EncodeToString(id), Type: jobType, Cluster: clientFor(c).name, Status: JobStatusPending,
+		CreatedAt: now.UTC().Format(time.RFC3339), owner: jobOwner(c), created: now, cancel: cancel}
+	jobQueue.add(job)
+	// The job is only read under the store lock once it runs, so the response gets a copy taken before
+	started := *job
+	go jobQueue.run(ctx, job, run)
+
+	log.Printf("Started %s job %s requested from %s", jobType, started.ID, c.ClientIP())
+	return started, nil
+}
+
+// respondJobStarted answers 202 with the new job and its status URL
+func respondJobStarted(c *gin.Context, job Job) {
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// run waits for a slot and runs the job, recording its outcome
+func (store *jobStore) run(ctx context.Context, job *Job, run jobFunc) {
+	defer job.cancel()
+	select {
+	case store.slots <- struct{}{}:
+	case <-ctx.Done():
+		store.finish(job, nil, ctx.Err())
+		return
+	}
+	defer func() { <-store.slots }()
+	store.update(job, func(job *Job) {
+		job.Status = JobStatusRunning
+		job.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	})
+
+	result, err := run(ctx, func(percent int, message string) {
+		store.update(job, func(job *Job) {
+			// Progress never goes backwards nor reaches 100 before the job succeeded
+			if percent > job.Progress && percent < 100 {
+				job.Progress = percent
+			}
+			job.Message = message
+		})
+	})
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	store.finish(job, result, err)
+}
+
+// finish records the outcome of a job
+func (store *jobStore) finish(job *Job, result interface{}, err error) {
+	now := time.Now()
+	store.update(job, func(job *Job) {
+		job.finished = now
+		job.CompletedAt = now.UTC().Format(time.RFC3339)
+		job.Message = ""
+		switch {
+		case err == nil:
+			job.Status = JobStatusSucceeded
+			job.Progress = 100
+			job.Result = result
+		case errors.Is(err, context.Canceled):
+			job.Status = JobStatusCanceled
+		default:
+			job.Status = JobStatusFailed
+			job.Error = err.Error()
+		}
+	})
+	switch {
+	case err == nil:
+		log.Printf("✓ %s job %s succeeded", job.Type, job.ID)
+	case errors.Is(err, context.Canceled):
+		log.Printf("%s job %s canceled", job.Type, job.ID)
+	default:
+		log.Printf("❌ %s job %s failed: %v", job.Type, job.ID, err)
+	}
+}
+
+// NamespaceScanTree is a tree found by a namespace scan
+type NamespaceScanTree struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	UID   string `json:"uid"`
+	Nodes int    `json:"nodes"`
+	// Tree is the API path of the tree
+	Tree     string           `json:"tree"`
+	Problems *ProblemsSummary `json:"problems"`
+}
+
+// NamespaceScan is the result of a namespaceScan job
+type NamespaceScan struct {
+	Namespace string              `json:"namespace"`
+	Trees     []NamespaceScanTree `json:"trees"`
+	// Problems is the number of problems detected in all trees
+	Problems int      `json:"problems"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// scanNamespace builds the tree of every root resource of the namespace and detects its problems
+func scanNamespace(ctx context.Context, client *K8sClient, namespace, language string, progress func(int, string)) (*NamespaceScan, error) {
+	progress(0, "Listing resources")
+	treeBuilder := NewResourceTreeBuilder(client, namespace, metav1.ListOptions{})
+	treeBuilder.language = language
+	if err := treeBuilder.buildResourcePool(); err != nil {
+		return nil, fmt.Errorf("failed to build resource pool: %v", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	roots := treeBuilder.pool.GetRootResources()
+	sort.Slice(roots, func(i, j int) bool {
+		if roots[i].GetKind() != roots[j].GetKind() {
+			return roots[i].GetKind() < roots[j].GetKind()
+		}
+		return roots[i].GetName() < roots[j].GetName()
+	})
+
+	scan := &NamespaceScan{Namespace: namespace, Trees: []NamespaceScanTree{}}
+	for i, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Listing is the first tenth of the work, the trees share the rest
+		progress(10+90*i/len(roots), fmt.Sprintf("Building tree %d of %d: %s/%s", i+1, len(roots), root.GetKind(), root.GetName()))
+
+		// Each tree gets the whole node cap
+		treeBuilder.visited = make(map[types.UID]bool)
+		treeBuilder.nodeCount = 0
+		tree, err := treeBuilder.buildTreeFromPool(root)
+		if err != nil {
+			treeBuilder.addWarning("Unable to build the tree of %s/%s: %v", root.GetKind(), root.GetName(), err)
+			continue
+		}
+		treeBuilder.DetectProblems(tree)
+		summary := summarizeProblems(tree)
+		scan.Problems += summary.Total
+		scan.Trees = append(scan.Trees, NamespaceScanTree{
+			Kind:     root.GetKind(),
+			Name:     root.GetName(),
+			UID:      string(root.GetUID()),
+			Nodes:    treeBuilder.CountNodes(tree),
+			Tree:     treePath(root),
+			Problems: summary,
+		})
+	}
+	scan.Warnings = treeBuilder.Warnings()
+	log.Printf("Scanned %d trees in namespace %s, %d problems", len(scan.Trees), namespace, scan.Problems)
+	return scan, nil
+}
+
+// compareClustersJob loads and compares two KubeBlocks clusters
+func compareClustersJob(ctx context.Context, client *K8sClient, aRef, bRef string, progress func(int, string)) (*ClusterComparison, error) {
+	aNamespace, aName, _ := parseClusterRef(aRef)
+	bNamespace, bName, _ := parseClusterRef(bRef)
+	var warnings []string
+
+	progress(0, "Loading cluster "+aRef)
+	a, _, err := loadComparedCluster(client, aNamespace, aName, &warnings)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	progress(45, "Loading cluster "+bRef)
+	b, _, err := loadComparedCluster(client, bNamespace, bName, &warnings)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	progress(90, "Comparing clusters")
+	comparison := compareClusters(a, b)
+	comparison.A = aRef
+	comparison.B = bRef
+	comparison.Warnings = warnings
+	return comparison, nil
+}
+
+// createJob starts a namespaceScan or clusterCompare job. Exports become jobs when started with async=true.
+func createJob(c *gin.Context) {
+	var request JobRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid job request: %v", err)})
+		return
+	}
+
+	var namespaces []string
+	var run jobFunc
+	// reviews are the resources the job reads, checked with the webhook since it only saw POST /jobs
+	var reviews []authzTarget
+	client := clientFor(c)
+	switch request.Type {
+	case JobTypeNamespaceScan:
+		if request.Namespace == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaceScan jobs"})
+			return
+		}
+		namespaces = []string{request.Namespace}
+		reviews = append(reviews, authzTarget{gvr: namespaceGVR, name: request.Namespace, namespace: request.Namespace})
+		language := requestLanguage(c)
+		run = func(ctx context.Context, progress func(int, string)) (interface{}, error) {
+			return scanNamespace(ctx, client, request.Namespace, language, progress)
+		}
+	case JobTypeClusterCompare:
+		for _, ref := range []string{request.A, request.B} {
+			namespace, name, err := parseClusterRef(ref)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			namespaces = append(namespaces, namespace)
+			reviews = append(reviews, authzTarget{gvr: clusterGVR, name: name, namespace: namespace})
+		}
+		run = func(ctx context.Context, progress func(int, string)) (interface{}, error) {
+			return compareClustersJob(ctx, client, request.A, request.B, progress)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported job type %q, use %s or %s", request.Type, JobTypeNamespaceScan, JobTypeClusterCompare)})
+		return
+	}
+	for _, namespace := range namespaces {
+		if !appConfig.namespaceAllowed(namespace) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", namespace)})
+			return
+		}
+	}
+	for _, review := range reviews {
+		if err := resourceAccessAllowed(c, "get", review.gvr, review.name, review.namespace); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	job, err := startJob(c, request.Type, run)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondJobStarted(c, job)
+}
+
+// getJobs lists the jobs of the user, newest first
+func getJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, jobQueue.list(jobOwner(c)))
+}
+
+// getJob reports the status and progress of a job, with its result once it succeeded
+func getJob(c *gin.Context) {
+	job, found := jobQueue.get(c.Param("id"), jobOwner(c))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Job not found: %s", c.Param("id"))})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// cancelJob cancels a pending or running job, finished jobs are left as they are
+func cancelJob(c *gin.Context) {
+	job, found := jobQueue.cancel(c.Param("id"), jobOwner(c))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Job not found: %s", c.Param("id"))})
+		return
+	}
+	log.Printf("Canceling %s job %s requested from %s", job.Type, job.ID, c.ClientIP())
+	c.JSON(http.StatusAccepted, job)
+}
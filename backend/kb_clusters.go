@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// clusterListPageSize bounds each page of the cluster-wide list, so large fleets are read in chunks
+	clusterListPageSize = 500
+	// clusterListCacheTTL lets cluster pickers of many users share one scan
+	clusterListCacheTTL = 10 * time.Second
+)
+
+// ClusterComponentSummary is a component or sharding of a KubeBlocks cluster
+type ClusterComponentSummary struct {
+	Name           string `json:"name"`
+	ComponentDef   string `json:"componentDef,omitempty"`
+	ServiceVersion string `json:"serviceVersion,omitempty"`
+	Replicas       int64  `json:"replicas"`
+	// Shards is set for shardings, whose components each have the replicas
+	Shards int64  `json:"shards,omitempty"`
+	Phase  string `json:"phase,omitempty"`
+}
+
+// KubeBlocksClusterSummary is a KubeBlocks cluster as listed by the cluster picker
+type KubeBlocksClusterSummary struct {
+	Name              string                    `json:"name"`
+	Namespace         string                    `json:"namespace"`
+	Phase             string                    `json:"phase,omitempty"`
+	ClusterDef        string                    `json:"clusterDef,omitempty"`
+	Topology          string                    `json:"topology,omitempty"`
+	TerminationPolicy string                    `json:"terminationPolicy,omitempty"`
+	CreationTime      string                    `json:"creationTime"`
+	Components        []ClusterComponentSummary `json:"components"`
+}
+
+var clusterListCache = struct {
+	mu      sync.Mutex
+	entries map[string]clusterListEntry
+}{entries: map[string]clusterListEntry{}}
+
+type clusterListEntry struct {
+	listed   time.Time
+	clusters []KubeBlocksClusterSummary
+}
+
+// summarizeKubeBlocksCluster reads the components and shardings of a Cluster with their phases
+func summarizeKubeBlocksCluster(cluster *unstructured.Unstructured) KubeBlocksClusterSummary {
+	summary := KubeBlocksClusterSummary{
+		Name:         cluster.GetName(),
+		Namespace:    cluster.GetNamespace(),
+		CreationTime: cluster.GetCreationTimestamp().Format(time.RFC3339),
+		Components:   []ClusterComponentSummary{},
+	}
+	summary.Phase, _, _ = unstructured.NestedString(cluster.Object, "status", "phase")
+	summary.ClusterDef, _, _ = unstructured.NestedString(cluster.Object, "spec", "clusterDef")
+	summary.Topology, _, _ = unstructured.NestedString(cluster.Object, "spec", "topology")
+	summary.TerminationPolicy, _, _ = unstructured.NestedString(cluster.Object, "spec", "terminationPolicy")
+
+	componentSpecs, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	for _, item := range componentSpecs {
+		spec, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		component := ClusterComponentSummary{}
+		component.Name, _, _ = unstructured.NestedString(spec, "name")
+		component.ComponentDef, _, _ = unstructured.NestedString(spec, "componentDef")
+		component.ServiceVersion, _, _ = unstructured.NestedString(spec, "serviceVersion")
+		component.Replicas, _, _ = unstructured.NestedInt64(spec, "replicas")
+		component.Phase, _, _ = unstructured.NestedString(cluster.Object, "status", "components", component.Name, "phase")
+		summary.Components = append(summary.Components, component)
+	}
+
+	shardings, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "shardings")
+	for _, item := range shardings {
+		spec, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		component := ClusterComponentSummary{}
+		component.Name, _, _ = unstructured.NestedString(spec, "name")
+		component.Shards, _, _ = unstructured.NestedInt64(spec, "shards")
+		component.ComponentDef, _, _ = unstructured.NestedString(spec, "template", "componentDef")
+		component.ServiceVersion, _, _ = unstructured.NestedString(spec, "template", "serviceVersion")
+		component.Replicas, _, _ = unstructured.NestedInt64(spec, "template", "replicas")
+		component.Phase, _, _ = unstructured.NestedString(cluster.Object, "status", "shardings", component.Name, "phase")
+		summary.Components = append(summary.Components, component)
+	}
+	return summary
+}
+
+// scanKubeBlocksClusters lists the Clusters of all served namespaces in pages, a single paginated
+// list when no namespace allowlist is configured
+func scanKubeBlocksClusters(client *K8sClient) ([]KubeBlocksClusterSummary, error) {
+	namespaces := appConfig.WatchNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	clusters := []KubeBlocksClusterSummary{}
+	for _, namespace := range namespaces {
+		options := metav1.ListOptions{Limit: clusterListPageSize}
+		for {
+			list, err := client.dynamicClient.Resource(clusterGVR).Namespace(namespace).List(context.TODO(), options)
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				clusters = append(clusters, summarizeKubeBlocksCluster(&list.Items[i]))
+			}
+			if list.GetContinue() == "" {
+				break
+			}
+			options.Continue = list.GetContinue()
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Namespace != clusters[j].Namespace {
+			return clusters[i].Namespace < clusters[j].Namespace
+		}
+		return clusters[i].Name < clusters[j].Name
+	})
+	return clusters, nil
+}
+
+// getKubeBlocksClusters lists every KubeBlocks cluster of the served namespaces with its phase and
+// components, so the cluster picker does not need a namespace first. Scans are shared for 10s.
+func getKubeBlocksClusters(c *gin.Context) {
+	log.Printf("Listing KubeBlocks clusters in all namespaces requested from %s", c.ClientIP())
+
+	client := clientFor(c)
+	clusterListCache.mu.Lock()
+	entry, cached := clusterListCache.entries[client.name]
+	clusterListCache.mu.Unlock()
+	if !cached || time.Since(entry.listed) > clusterListCacheTTL {
+		clusters, err := scanKubeBlocksClusters(client)
+		if err != nil {
+			log.Printf("Error listing KubeBlocks clusters: %v", err)
+			c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		entry = clusterListEntry{listed: time.Now(), clusters: clusters}
+		clusterListCache.mu.Lock()
+		clusterListCache.entries[client.name] = entry
+		clusterListCache.mu.Unlock()
+	}
+
+	log.Printf("Found %d KubeBlocks clusters", len(entry.clusters))
+	c.JSON(http.StatusOK, gin.H{"clusters": entry.clusters, "listedAt": entry.listed.Format(time.RFC3339)})
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubeBlocks well-known labels
+const (
+	instanceLabel      = "app.kubernetes.io/instance"
+	componentNameLabel = "apps.kubeblocks.io/component-name"
+	roleLabel          = "kubeblocks.io/role"
+)
+
+// KubeBlocks GVRs used by the dedicated KubeBlocks views
+var (
+	clusterGVR              = schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"}
+	componentGVR            = schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"}
+	componentParameterGVR   = schema.GroupVersionResource{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"}
+	parameterGVR            = schema.GroupVersionResource{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"}
+	parametersDefinitionGVR = schema.GroupVersionResource{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parametersdefinitions"}
+	paramConfigRendererGVR  = schema.GroupVersionResource{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "paramconfigrenderers"}
+	configMapGVR            = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	opsRequestGVR           = schema.GroupVersionResource{Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"}
+	instanceGVR             = schema.GroupVersionResource{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"}
+	podGVR                  = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	pvcGVR                  = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "persistentvolumeclaims"}
+)
+
+// ParameterDriftEntry describes one configuration parameter of a component and
+// how its desired, rendered and default values compare
+type ParameterDriftEntry struct {
+	Name          string  `json:"name"`
+	File          string  `json:"file,omitempty"`
+	ConfigSpec    string  `json:"configSpec,omitempty"`
+	Source        string  `json:"source"`
+	Value         *string `json:"value,omitempty"`
+	RenderedValue *string `json:"renderedValue,omitempty"`
+	DefaultValue  *string `json:"defaultValue,omitempty"`
+	Drifted       bool    `json:"drifted"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// ComponentParametersView joins all configuration-related resources of a component
+type ComponentParametersView struct {
+	Component             string                `json:"component"`
+	Cluster               string                `json:"cluster"`
+	Namespace             string                `json:"namespace"`
+	ComponentParameter    *ResourceNode         `json:"componentParameter,omitempty"`
+	Parameters            []ResourceNode        `json:"parameters"`
+	ParametersDefinitions []ResourceNode        `json:"parametersDefinitions"`
+	ConfigMaps            []ResourceNode        `json:"configMaps"`
+	Entries               []ParameterDriftEntry `json:"entries"`
+	DriftCount            int                   `json:"driftCount"`
+}
+
+func getComponentParameters(c *gin.Context) {
+	componentName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		log.Printf("Namespace is required for fetching component parameters")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching component parameters"})
+		return
+	}
+
+	log.Printf("Fetching parameters of component %s in namespace '%s' requested from %s", componentName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	component, err := client.dynamicClient.Resource(componentGVR).Namespace(namespace).Get(context.TODO(), componentName, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Component not found: %s in namespace %s: %v", componentName, namespace, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Component not found: %s in namespace %s", componentName, namespace)})
+		return
+	}
+
+	view, err := buildComponentParametersView(client, component)
+	if err != nil {
+		log.Printf("Error building parameters view for component %s: %v", componentName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if view.ComponentParameter != nil {
+		redactResourceNodesFor(c, view.ComponentParameter)
+	}
+	for _, nodes := range [][]ResourceNode{view.Parameters, view.ParametersDefinitions, view.ConfigMaps} {
+		for i := range nodes {
+			redactResourceNodesFor(c, &nodes[i])
+		}
+	}
+
+	log.Printf("Component %s has %d parameters, %d drifted", componentName, len(view.Entries), view.DriftCount)
+	c.JSON(http.StatusOK, view)
+}
+
+// buildComponentParametersView collects the ComponentParameter, Parameters, ParametersDefinitions
+// and rendered ConfigMaps of a component and flags values that drift from their defaults
+func buildComponentParametersView(client *K8sClient, component *unstructured.Unstructured) (*ComponentParametersView, error) {
+	namespace := component.GetNamespace()
+	clusterName := component.GetLabels()[instanceLabel]
+	shortName := component.GetLabels()[componentNameLabel]
+	if shortName == "" {
+		shortName = strings.TrimPrefix(component.GetName(), clusterName+"-")
+	}
+
+	view := &ComponentParametersView{
+		Component:             component.GetName(),
+		Cluster:               clusterName,
+		Namespace:             namespace,
+		Parameters:            []ResourceNode{},
+		ParametersDefinitions: []ResourceNode{},
+		ConfigMaps:            []ResourceNode{},
+		Entries:               []ParameterDriftEntry{},
+	}
+
+	// Rendered ConfigMaps of the component
+	selector := labels.SelectorFromSet(labels.Set{instanceLabel: clusterName, componentNameLabel: shortName})
+	configMaps, err := client.dynamicClient.Resource(configMapGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configmaps: %v", err)
+	}
+	view.ConfigMaps = convertToResourceNodes(configMaps.Items)
+	if view.ConfigMaps == nil {
+		view.ConfigMaps = []ResourceNode{}
+	}
+
+	// Defaults from the ParametersDefinitions of the component's engine, keyed by config file name
+	defaultsByFile := map[string]map[string]string{}
+	definitions, err := componentParametersDefinitions(client, component)
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list paramconfigrenderers: %v", err)
+	} else {
+		for _, def := range definitions {
+			fileName, _, _ := unstructured.NestedString(def.Object, "spec", "fileName")
+			defaults := extractSchemaDefaults(def.Object)
+			if len(defaults) == 0 {
+				continue
+			}
+			if defaultsByFile[fileName] == nil {
+				defaultsByFile[fileName] = map[string]string{}
+			}
+			for k, v := range defaults {
+				defaultsByFile[fileName][normalizeParameterName(k)] = v
+			}
+			view.ParametersDefinitions = append(view.ParametersDefinitions, convertToResourceNode(def))
+		}
+	}
+
+	// Desired values from the ComponentParameter (same name as the Component)
+	componentParameter, err := client.dynamicClient.Resource(componentParameterGVR).Namespace(namespace).Get(context.TODO(), component.GetName(), metav1.GetOptions{})
+	if err == nil {
+		node := convertToResourceNode(*componentParameter)
+		view.ComponentParameter = &node
+
+		items, _, _ := unstructured.NestedSlice(componentParameter.Object, "spec", "configItemDetails")
+		for _, item := range items {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			configSpec, _, _ := unstructured.NestedString(itemMap, "name")
+			fileParams, _, _ := unstructured.NestedMap(itemMap, "configFileParams")
+			for file, params := range fileParams {
+				paramsMap, ok := params.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				values, _, _ := unstructured.NestedMap(paramsMap, "parameters")
+				for name, value := range values {
+					view.Entries = append(view.Entries, ParameterDriftEntry{
+						Name:       name,
+						File:       file,
+						ConfigSpec: configSpec,
+						Source:     "componentparameter/" + componentParameter.GetName(),
+						Value:      stringPtrFromInterface(value),
+					})
+				}
+			}
+		}
+	} else {
+		log.Printf("    ⚠️  No ComponentParameter found for component %s: %v", component.GetName(), err)
+	}
+
+	// Parameters CRs of the cluster that target this component
+	parameters, err := client.dynamicClient.Resource(parameterGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list parameters: %v", err)
+	} else {
+		for _, parameter := range parameters.Items {
+			paramCluster, _, _ := unstructured.NestedString(parameter.Object, "spec", "clusterName")
+			if paramCluster != clusterName {
+				continue
+			}
+			componentParameters, _, _ := unstructured.NestedSlice(parameter.Object, "spec", "componentParameters")
+			matched := false
+			for _, cp := range componentParameters {
+				cpMap, ok := cp.(map[string]interface{})
+				if !ok || cpMap["componentName"] != shortName {
+					continue
+				}
+				matched = true
+				values, _, _ := unstructured.NestedMap(cpMap, "parameters")
+				for name, value := range values {
+					view.Entries = append(view.Entries, ParameterDriftEntry{
+						Name:   name,
+						Source: "parameter/" + parameter.GetName(),
+						Value:  stringPtrFromInterface(value),
+					})
+				}
+			}
+			if matched {
+				view.Parameters = append(view.Parameters, convertToResourceNode(parameter))
+			}
+		}
+	}
+
+	// Compare desired values against rendered ConfigMaps and defaults
+	for i := range view.Entries {
+		entry := &view.Entries[i]
+		entry.RenderedValue = findRenderedValue(configMaps.Items, entry.File, entry.Name)
+		entry.DefaultValue = findDefaultValue(defaultsByFile, entry.File, entry.Name)
+
+		switch {
+		case entry.Value != nil && entry.RenderedValue != nil && !parameterValuesEqual(*entry.Value, *entry.RenderedValue):
+			entry.Drifted = true
+			entry.Reason = "desired value is not rendered yet"
+		case entry.DefaultValue != nil && effectiveValue(entry) != nil && !parameterValuesEqual(*effectiveValue(entry), *entry.DefaultValue):
+			entry.Drifted = true
+			entry.Reason = "value differs from default"
+		}
+		if entry.Drifted {
+			view.DriftCount++
+		}
+	}
+
+	sort.SliceStable(view.Entries, func(i, j int) bool {
+		if view.Entries[i].Name != view.Entries[j].Name {
+			return view.Entries[i].Name < view.Entries[j].Name
+		}
+		return view.Entries[i].Source < view.Entries[j].Source
+	})
+
+	return view, nil
+}
+
+// componentParametersDefinitions returns the ParametersDefinitions listed by the ParamConfigRenderers
+// of the component's ComponentDefinition and service version. Definitions of other engines, which
+// may use the same config file names, are left out.
+func componentParametersDefinitions(client *K8sClient, component *unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	compDef, _, _ := unstructured.NestedString(component.Object, "spec", "compDef")
+	serviceVersion, _, _ := unstructured.NestedString(component.Object, "spec", "serviceVersion")
+	if compDef == "" {
+		return nil, nil
+	}
+	renderers, err := client.dynamicClient.Resource(paramConfigRendererGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, renderer := range renderers.Items {
+		rendererCompDef, _, _ := unstructured.NestedString(renderer.Object, "spec", "componentDef")
+		rendererVersion, _, _ := unstructured.NestedString(renderer.Object, "spec", "serviceVersion")
+		if !componentDefMatches(compDef, rendererCompDef) || (rendererVersion != "" && !strings.HasPrefix(serviceVersion, rendererVersion)) {
+			continue
+		}
+		defs, _, _ := unstructured.NestedStringSlice(renderer.Object, "spec", "parametersDefs")
+		for _, name := range defs {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var definitions []unstructured.Unstructured
+	for _, name := range names {
+		definition, err := client.dynamicClient.Resource(parametersDefinitionGVR).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("    ⚠️  Unable to get parametersdefinition %s of %s: %v", name, compDef, err)
+			continue
+		}
+		definitions = append(definitions, *definition)
+	}
+	return definitions, nil
+}
+
+// componentDefMatches matches a ComponentDefinition name against the name prefix or regular
+// expression KubeBlocks resources refer to it by, e.g. apecloud-mysql matches apecloud-mysql-8.0
+func componentDefMatches(name, pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	if strings.HasPrefix(name, pattern) {
+		return true
+	}
+	matched, err := regexp.MatchString(pattern, name)
+	return err == nil && matched
+}
+
+// extractSchemaDefaults walks the JSON schema of a ParametersDefinition and collects property defaults
+func extractSchemaDefaults(obj map[string]interface{}) map[string]string {
+	defaults := map[string]string{}
+	schemaObj, found, _ := unstructured.NestedMap(obj, "spec", "parametersSchema", "schemaInJSON")
+	if !found {
+		return defaults
+	}
+
+	var walk func(properties map[string]interface{})
+	walk = func(properties map[string]interface{}) {
+		for name, prop := range properties {
+			propMap, ok := prop.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if nested, ok := propMap["properties"].(map[string]interface{}); ok {
+				walk(nested)
+				continue
+			}
+			if value, ok := propMap["default"]; ok {
+				if s := stringPtrFromInterface(value); s != nil {
+					defaults[name] = *s
+				}
+			}
+		}
+	}
+	if properties, ok := schemaObj["properties"].(map[string]interface{}); ok {
+		walk(properties)
+	}
+	return defaults
+}
+
+// findRenderedValue looks up a parameter in the rendered config files of the component
+func findRenderedValue(configMaps []unstructured.Unstructured, file, name string) *string {
+	for _, cm := range configMaps {
+		data, _, _ := unstructured.NestedStringMap(cm.Object, "data")
+		for key, content := range data {
+			if file != "" && key != file {
+				continue
+			}
+			if value := parseConfigValue(content, name); value != nil {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// findDefaultValue looks up the default of a parameter in the definition of its config file.
+// Parameters without a file have no default, another file may define a different parameter.
+func findDefaultValue(defaultsByFile map[string]map[string]string, file, name string) *string {
+	if value, ok := defaultsByFile[file][normalizeParameterName(name)]; ok {
+		return &value
+	}
+	return nil
+}
+
+// parseConfigValue extracts the value of a key from ini/properties/yaml-like config content
+func parseConfigValue(content, name string) *string {
+	wanted := normalizeParameterName(name)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		idx := strings.IndexAny(line, "=:")
+		if idx <= 0 {
+			continue
+		}
+		if normalizeParameterName(strings.TrimSpace(line[:idx])) != wanted {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(line[idx+1:]), `"'`)
+		return &value
+	}
+	return nil
+}
+
+func effectiveValue(entry *ParameterDriftEntry) *string {
+	if entry.Value != nil {
+		return entry.Value
+	}
+	return entry.RenderedValue
+}
+
+func normalizeParameterName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
+
+func parameterValuesEqual(a, b string) bool {
+	return strings.EqualFold(strings.Trim(a, `"'`), strings.Trim(b, `"'`))
+}
+
+func stringPtrFromInterface(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+	s := fmt.Sprintf("%v", value)
+	return &s
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	kubeconfigSessionHeader = "X-Kubeconfig-Session"
+	// kubeconfigMaxSize bounds uploaded kubeconfigs, real ones are a few KB
+	kubeconfigMaxSize = 256 << 10
+	// kubeconfigMaxSessions bounds the clients kept for uploaded kubeconfigs
+	kubeconfigMaxSessions = 100
+)
+
+// KubeconfigSession describes the cluster of an uploaded kubeconfig, returned on upload and by GET
+type KubeconfigSession struct {
+	// Session is the token selecting the kubeconfig, only returned on upload
+	Session   string `json:"session,omitempty"`
+	Cluster   string `json:"cluster"`
+	Context   string `json:"context"`
+	Server    string `json:"server"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+type kubeconfigSession struct {
+	client   *K8sClient
+	info     KubeconfigSession
+	created  time.Time
+	lastUsed time.Time
+}
+
+// kubeconfigSessionStore keeps the clients of uploaded kubeconfigs in memory only, keyed by the
+// SHA-256 of their session token. They are dropped once idle for the session TTL or on restart.
+type kubeconfigSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*kubeconfigSession
+}
+
+var kubeconfigSessions = &kubeconfigSessionStore{sessions: map[string]*kubeconfigSession{}}
+
+func kubeconfigSessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// kubeconfigSessionTTL is how long a session stays valid after it was last used
+func kubeconfigSessionTTL() time.Duration {
+	return time.Duration(appConfig.KubeconfigUpload.SessionTTLSeconds) * time.Second
+}
+
+// pruneLocked drops the expired sessions. The caller holds the lock.
+func (s *kubeconfigSessionStore) pruneLocked(now time.Time) {
+	for key, session := range s.sessions {
+		if now.Sub(session.lastUsed) > kubeconfigSessionTTL() {
+			log.Printf("Kubeconfig session of %s expired", session.info.Server)
+			delete(s.sessions, key)
+		}
+	}
+	metrics.SetGauge("kbviz_kubeconfig_sessions", "Sessions of uploaded kubeconfigs", nil, float64(len(s.sessions)))
+}
+
+// add stores the client and returns the token of its session, evicting the least recently used
+// session when the store is full
+func (s *kubeconfigSessionStore) add(session *kubeconfigSession) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.pruneLocked(now)
+	if len(s.sessions) >= kubeconfigMaxSessions {
+		oldest := ""
+		for key, existing := range s.sessions {
+			if oldest == "" || existing.lastUsed.Before(s.sessions[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		log.Printf("⚠️  Evicting kubeconfig session of %s, %d sessions are open", s.sessions[oldest].info.Server, len(s.sessions))
+		delete(s.sessions, oldest)
+	}
+	session.created, session.lastUsed = now, now
+	s.sessions[kubeconfigSessionKey(token)] = session
+	metrics.SetGauge("kbviz_kubeconfig_sessions", "Sessions of uploaded kubeconfigs", nil, float64(len(s.sessions)))
+	return token, nil
+}
+
+// lookup returns the session of the token and extends it, nil when unknown or expired
+func (s *kubeconfigSessionStore) lookup(token string) *kubeconfigSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.pruneLocked(now)
+	session := s.sessions[kubeconfigSessionKey(token)]
+	if session != nil {
+		session.lastUsed = now
+	}
+	return session
+}
+
+func (s *kubeconfigSessionStore) remove(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := kubeconfigSessionKey(token)
+	_, found := s.sessions[key]
+	delete(s.sessions, key)
+	metrics.SetGauge("kbviz_kubeconfig_sessions", "Sessions of uploaded kubeconfigs", nil, float64(len(s.sessions)))
+	return found
+}
+
+// describe returns the public description of the session, with its expiry
+func (session *kubeconfigSession) describe() KubeconfigSession {
+	info := session.info
+	info.CreatedAt = session.created.UTC().Format(time.RFC3339)
+	info.ExpiresAt = session.lastUsed.Add(kubeconfigSessionTTL()).UTC().Format(time.RFC3339)
+	return info
+}
+
+// kubeconfigSessionToken reads the session of a request from the X-Kubeconfig-Session header, or
+// the kubeconfigSession query parameter for EventSource and WebSocket clients that cannot set headers
+func kubeconfigSessionToken(c *gin.Context) string {
+	if token := c.GetHeader(kubeconfigSessionHeader); token != "" {
+		return token
+	}
+	return c.Query("kubeconfigSession")
+}
+
+// checkUploadedKubeconfig rejects what would make the server run commands or read its own files
+// on behalf of the uploader: exec and auth provider plugins and paths to certificates or tokens
+func checkUploadedKubeconfig(config *clientcmdapi.Config, contextName string) error {
+	context := config.Contexts[contextName]
+	if context == nil {
+		return fmt.Errorf("context %q not found in the kubeconfig", contextName)
+	}
+	cluster := config.Clusters[context.Cluster]
+	if cluster == nil {
+		return fmt.Errorf("cluster %q of context %s not found in the kubeconfig", context.Cluster, contextName)
+	}
+	if cluster.CertificateAuthority != "" {
+		return fmt.Errorf("certificate-authority files are not supported, embed certificate-authority-data")
+	}
+	authInfo := config.AuthInfos[context.AuthInfo]
+	if authInfo == nil {
+		return nil
+	}
+	switch {
+	case authInfo.Exec != nil:
+		return fmt.Errorf("exec credential plugins are not supported, use a token or client certificate data")
+	case authInfo.AuthProvider != nil:
+		return fmt.Errorf("auth provider plugins are not supported, use a token or client certificate data")
+	case authInfo.ClientCertificate != "" || authInfo.ClientKey != "" || authInfo.TokenFile != "":
+		return fmt.Errorf("certificate, key and token files are not supported, embed their data")
+	}
+	return nil
+}
+
+// readUploadedKubeconfig reads the kubeconfig from the kubeconfig file of a multipart form, or
+// from the request body
+func readUploadedKubeconfig(c *gin.Context) ([]byte, error) {
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("kubeconfig")
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig file is required in the form: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, kubeconfigMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > kubeconfigMaxSize {
+		return nil, fmt.Errorf("kubeconfig is larger than %d KB", kubeconfigMaxSize>>10)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("kubeconfig is required")
+	}
+	return data, nil
+}
+
+// uploadKubeconfig creates a session for an uploaded kubeconfig, so a desktop user or a hosted
+// demo can visualize their own cluster. The kubeconfig is only kept as the client built from it,
+// in memory. ?context= selects a context other than the current one.
+func uploadKubeconfig(c *gin.Context) {
+	log.Printf("Kubeconfig upload requested from %s", c.ClientIP())
+
+	data, err := readUploadedKubeconfig(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rawConfig, err := clientcmd.Load(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid kubeconfig: %v", err)})
+		return
+	}
+	contextName := defaultString(c.Query("context"), rawConfig.CurrentContext)
+	if err := checkUploadedKubeconfig(rawConfig, contextName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported kubeconfig: %v", err)})
+		return
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*rawConfig, &clientcmd.ConfigOverrides{CurrentContext: contextName}).ClientConfig()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid kubeconfig: %v", err)})
+		return
+	}
+
+	// Sessions are named randomly, not after the kubeconfig, so sessions of the same kubeconfig never
+	// share caches or WebSocket streams
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	name := "session-" + hex.EncodeToString(id)
+	client, err := newK8sClient(name, restConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := client.discoveryClient.ServerVersion(); err != nil {
+		log.Printf("⚠️  Cluster %s of an uploaded kubeconfig is unreachable: %v", restConfig.Host, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Unable to reach %s with the kubeconfig: %v", restConfig.Host, err)})
+		return
+	}
+
+	session := &kubeconfigSession{
+		client: client,
+		info:   KubeconfigSession{Cluster: name, Context: contextName, Server: restConfig.Host},
+	}
+	token, err := kubeconfigSessions.add(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("✓ Kubeconfig session %s created for %s (context %s)", name, restConfig.Host, contextName)
+
+	info := session.describe()
+	info.Session = token
+	c.JSON(http.StatusCreated, info)
+}
+
+// getKubeconfigSession describes the session of the request
+func getKubeconfigSession(c *gin.Context) {
+	session := kubeconfigSessions.lookup(kubeconfigSessionToken(c))
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No kubeconfig session, upload a kubeconfig first"})
+		return
+	}
+	c.JSON(http.StatusOK, session.describe())
+}
+
+// deleteKubeconfigSession forgets the session of the request and its client
+func deleteKubeconfigSession(c *gin.Context) {
+	if !kubeconfigSessions.remove(kubeconfigSessionToken(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No kubeconfig session, upload a kubeconfig first"})
+		return
+	}
+	log.Printf("Kubeconfig session deleted from %s", c.ClientIP())
+	c.Status(http.StatusNoContent)
+}
+
+// kubeconfigSessionClient returns the client of the request's kubeconfig session. found is false
+// when the request selects no session; a selected but unknown session is an error.
+func kubeconfigSessionClient(c *gin.Context) (client *K8sClient, found bool, err error) {
+	token := kubeconfigSessionToken(c)
+	// A stale session must not keep its owner from uploading the kubeconfig again
+	if token == "" || (c.Request.Method == http.MethodPost && strings.HasSuffix(c.FullPath(), "/kubeconfigs")) {
+		return nil, false, nil
+	}
+	if !appConfig.KubeconfigUpload.Enabled {
+		return nil, true, fmt.Errorf("Kubeconfig uploads are disabled, set KB_VIZ_KUBECONFIG_UPLOAD=true to enable them")
+	}
+	session := kubeconfigSessions.lookup(token)
+	if session == nil {
+		return nil, true, fmt.Errorf("Kubeconfig session expired or unknown, upload the kubeconfig again")
+	}
+	return session.client, true, nil
+}
+
+// inKubeconfigSession reports whether the request is served by the cluster of an uploaded kubeconfig
+func inKubeconfigSession(c *gin.Context) bool {
+	_, found, _ := kubeconfigSessionClient(c)
+	return found
+}
+
+// kubeconfigUploadEnabledMiddleware rejects the kubeconfig session endpoints unless uploads are enabled
+func kubeconfigUploadEnabledMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !appConfig.KubeconfigUpload.Enabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Kubeconfig uploads are disabled, set KB_VIZ_KUBECONFIG_UPLOAD=true to enable them"})
+			return
+		}
+		c.Next()
+	}
+}
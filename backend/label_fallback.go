@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// addUnselectedResources adds the resources of the namespace that do not match the label
+// selector to the pool. It is the slow path for trees whose descendants lack the instance label.
+// The pool may already be shared through the pool cache, so the resources go into a copy that
+// replaces it in the cache rather than into the shared pool.
+func (rtb *ResourceTreeBuilder) addUnselectedResources() {
+	log.Printf("🐢 Root has no children matching %s, listing resource types without the label selector", rtb.listOptions.LabelSelector)
+
+	unselected := NewResourceTreeBuilder(rtb.client, rtb.namespace, metav1.ListOptions{FieldSelector: rtb.listOptions.FieldSelector})
+	unselected.includeRBAC = rtb.includeRBAC
+	resourceTypes, probed := unselected.detectResourceTypes(rtb.candidateResourceTypes())
+
+	pool := NewResourcePoolWithCapacity(rtb.pool.Size())
+	for _, resource := range rtb.pool.GetAllResources() {
+		pool.AddResource(resource)
+	}
+
+	added := 0
+	for _, gvr := range resourceTypes {
+		resourceList, found := probed[gvr]
+		if !found {
+			var err error
+			resourceList, err = rtb.client.dynamicClient.Resource(gvr).Namespace(rtb.namespace).List(context.TODO(), unselected.listOptions)
+			if err != nil {
+				log.Printf("    ⚠️  Skipping resource type %s due to error: %v", gvr.Resource, err)
+				continue
+			}
+		}
+		for i := range resourceList.Items {
+			resource := &resourceList.Items[i]
+			if pool.GetResource(resource.GetUID()) == nil {
+				pool.AddResource(resource)
+				added++
+			}
+		}
+	}
+	for _, missing := range pool.LinkAnnotatedParents() {
+		rtb.addWarning("Parent %s of %s/%s not found in the tree", missing.parent, missing.resource.GetKind(), missing.resource.GetName())
+	}
+	rtb.pool = pool
+	resourcePools.put(poolCacheKey(rtb.client, rtb.namespace, rtb.listOptions), pool)
+	log.Printf("🐢 Added %d resources not matching the label selector", added)
+}
+
+// warnUnlabeledDescendants reports the kinds of the tree found only without the label selector
+func (rtb *ResourceTreeBuilder) warnUnlabeledDescendants(root *ResourceTreeNode) {
+	selector, err := labels.Parse(rtb.listOptions.LabelSelector)
+	if err != nil {
+		return
+	}
+
+	kinds := map[string]bool{}
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		for _, child := range node.Children {
+			if !child.Virtual && !selector.Matches(labels.Set(child.Resource.GetLabels())) {
+				kinds[child.Resource.GetKind()] = true
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+	if len(kinds) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	rtb.addWarning("Descendants of %s/%s do not carry the label %s (%s), so the namespace was listed without the label selector, which is slower on large namespaces. Label them to restore the fast path",
+		root.Resource.GetKind(), root.Resource.GetName(), rtb.listOptions.LabelSelector, strings.Join(names, ", "))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Layout groups in the order siblings are suggested to be drawn: the workload hierarchy first,
+// then what it uses, and data protection resources last
+var layoutGroupOrder = []string{"components", "networking", "configuration", "storage", "operations", "batch", "dataprotection", "other"}
+
+var layoutGroupByKind = map[string]string{
+	"Cluster":               "components",
+	"Component":             "components",
+	"InstanceSet":           "components",
+	"Instance":              "components",
+	"StatefulSet":           "components",
+	"Deployment":            "components",
+	"ReplicaSet":            "components",
+	"DaemonSet":             "components",
+	"Pod":                   "components",
+	"Service":               "networking",
+	"EndpointSlice":         "networking",
+	"Ingress":               "networking",
+	"NetworkPolicy":         "networking",
+	"ConfigMap":             "configuration",
+	"Secret":                "configuration",
+	"ComponentParameter":    "configuration",
+	"Parameter":             "configuration",
+	"ServiceAccount":        "configuration",
+	"Role":                  "configuration",
+	"RoleBinding":           "configuration",
+	"PersistentVolumeClaim": "storage",
+	"OpsRequest":            "operations",
+	"Job":                   "batch",
+	"CronJob":               "batch",
+	"BackupPolicy":          "dataprotection",
+	"BackupSchedule":        "dataprotection",
+	"Backup":                "dataprotection",
+	"Restore":               "dataprotection",
+}
+
+// LayoutHint lets the frontend place a node without walking the tree again
+type LayoutHint = api.LayoutHint
+
+func layoutGroup(node *ResourceTreeNode) string {
+	if group, found := layoutGroupByKind[node.Resource.GetKind()]; found {
+		return group
+	}
+	return "other"
+}
+
+func layoutGroupRank(group string) int {
+	for rank, candidate := range layoutGroupOrder {
+		if candidate == group {
+			return rank
+		}
+	}
+	return len(layoutGroupOrder)
+}
+
+// AnnotateLayout sets the depth, subtree size, group and sibling order of every node, so large
+// trees render the same way on every client
+func (rtb *ResourceTreeBuilder) AnnotateLayout(root *ResourceTreeNode) {
+	var annotate func(node *ResourceTreeNode, depth int) int
+	annotate = func(node *ResourceTreeNode, depth int) int {
+		size := 1
+		for _, child := range node.Children {
+			size += annotate(child, depth+1)
+		}
+		node.Layout = &LayoutHint{Depth: depth, SubtreeSize: size, Group: layoutGroup(node)}
+
+		siblings := append([]*ResourceTreeNode{}, node.Children...)
+		sort.SliceStable(siblings, func(i, j int) bool {
+			a, b := siblings[i], siblings[j]
+			if rankA, rankB := layoutGroupRank(a.Layout.Group), layoutGroupRank(b.Layout.Group); rankA != rankB {
+				return rankA < rankB
+			}
+			if a.Resource.GetKind() != b.Resource.GetKind() {
+				return a.Resource.GetKind() < b.Resource.GetKind()
+			}
+			return a.Resource.GetName() < b.Resource.GetName()
+		})
+		for order, child := range siblings {
+			child.Layout.Order = order
+		}
+		return size
+	}
+	annotate(root, 0)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionConfig configures lease-based leader election between replicas
+type LeaderElectionConfig struct {
+	Enabled        bool   `json:"enabled"`
+	LeaseName      string `json:"leaseName"`
+	LeaseNamespace string `json:"leaseNamespace"`
+	// AdvertiseAddress is the URL followers proxy leader-only reads to while this instance leads,
+	// e.g. http://10.0.0.5:8080. It defaults to http://$POD_IP with the port of server.addr when
+	// serving plain HTTP.
+	AdvertiseAddress string `json:"advertiseAddress"`
+}
+
+// leaderProxiedHeader marks requests a follower proxied to the leader, so they are never proxied twice
+const leaderProxiedHeader = "X-KB-Viz-Proxied-By"
+
+// LeaderState tracks the leader election status of this instance.
+// Leader-only work (informer caches, recorders) is registered with RunWhenLeader
+// and started/stopped as leadership changes; followers keep serving read-only
+// requests directly against the API server.
+type LeaderState struct {
+	mu       sync.RWMutex
+	enabled  bool
+	identity string
+	leader   string
+	isLeader bool
+	tasks    []func(ctx context.Context)
+	leadCtx  context.Context // Set while leading, cancelled on loss of leadership
+}
+
+var leaderState = &LeaderState{isLeader: true}
+
+// IsLeader reports whether this instance currently holds the lease (always true when election is disabled)
+func (ls *LeaderState) IsLeader() bool {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return ls.isLeader
+}
+
+// RunWhenLeader registers a task that runs only while this instance is the leader.
+// The context passed to the task is cancelled when leadership is lost.
+func (ls *LeaderState) RunWhenLeader(task func(ctx context.Context)) {
+	ls.mu.Lock()
+	ls.tasks = append(ls.tasks, task)
+	enabled := ls.enabled
+	leadCtx := ls.leadCtx
+	ls.mu.Unlock()
+
+	// Without leader election this instance owns everything from the start
+	if !enabled {
+		go task(context.Background())
+	} else if leadCtx != nil && leadCtx.Err() == nil {
+		go task(leadCtx)
+	}
+}
+
+func (ls *LeaderState) setLeader(leader string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.leader = leader
+	ls.isLeader = leader == ls.identity
+	value := 0.0
+	if ls.isLeader {
+		value = 1
+	}
+	metrics.SetGauge("kbviz_leader", "Whether this instance currently holds the leader lease", nil, value)
+}
+
+func (ls *LeaderState) startTasks(ctx context.Context) {
+	ls.mu.Lock()
+	ls.leadCtx = ctx
+	tasks := append([]func(ctx context.Context){}, ls.tasks...)
+	ls.mu.Unlock()
+	for _, task := range tasks {
+		go task(ctx)
+	}
+}
+
+// leaderAddress returns the address the current leader advertises in its lease identity,
+// empty when there is no leader or it advertises none
+func (ls *LeaderState) leaderAddress() string {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	if _, address, found := strings.Cut(ls.leader, "@"); found {
+		return address
+	}
+	return ""
+}
+
+// advertiseAddress is the address this instance publishes in its lease identity
+func advertiseAddress(config LeaderElectionConfig, server ServerConfig) string {
+	if config.AdvertiseAddress != "" {
+		return strings.TrimSuffix(config.AdvertiseAddress, "/")
+	}
+	podIP := os.Getenv("POD_IP")
+	if podIP == "" || server.TLSCertFile != "" {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(server.Addr)
+	if err != nil || port == "" {
+		return ""
+	}
+	return "http://" + net.JoinHostPort(podIP, port)
+}
+
+// proxyToLeader answers a read of state only the leader keeps (history, stats samples) by
+// forwarding it to the address the leader advertises in the lease. Without one, or when the
+// request was already proxied, it answers 503 so clients can retry once leadership settles.
+func proxyToLeader(c *gin.Context, what string) {
+	address := leaderState.leaderAddress()
+	if address == "" || c.GetHeader(leaderProxiedHeader) != "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("%s kept by the leader replica, which advertises no reachable address, see /api/leader", what)})
+		return
+	}
+	target, err := url.Parse(address)
+	if err != nil || target.Host == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("Invalid leader address %q", address)})
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("⚠️  Failed to proxy %s to leader %s: %v", r.URL.Path, address, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to reach the leader replica: %v", err)})
+	}
+	c.Request.Header.Set(leaderProxiedHeader, leaderState.identity)
+	log.Printf("Proxying %s to leader %s", c.Request.URL.Path, address)
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// startLeaderElection joins the election and keeps rejoining after leadership is lost. The lease
+// identity is the hostname, followed by @ and the advertised address when there is one.
+func startLeaderElection(config LeaderElectionConfig) {
+	identity, err := os.Hostname()
+	if err != nil || identity == "" {
+		identity = fmt.Sprintf("kb-viz-%d", time.Now().UnixNano())
+	}
+	if address := advertiseAddress(config, appConfig.Server); address != "" {
+		identity += "@" + address
+	}
+
+	leaderState.mu.Lock()
+	leaderState.enabled = true
+	leaderState.identity = identity
+	leaderState.isLeader = false
+	leaderState.mu.Unlock()
+	metrics.SetGauge("kbviz_leader", "Whether this instance currently holds the leader lease", nil, 0)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      config.LeaseName,
+			Namespace: config.LeaseNamespace,
+		},
+		Client: k8sClient.clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	log.Printf("Joining leader election for lease %s/%s as %s", config.LeaseNamespace, config.LeaseName, identity)
+	go func() {
+		for {
+			leaderelection.RunOrDie(context.Background(), leaderelection.LeaderElectionConfig{
+				Lock:            lock,
+				LeaseDuration:   15 * time.Second,
+				RenewDeadline:   10 * time.Second,
+				RetryPeriod:     2 * time.Second,
+				ReleaseOnCancel: true,
+				Name:            config.LeaseName,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: func(ctx context.Context) {
+						log.Printf("👑 Became leader, starting leader-only tasks")
+						leaderState.setLeader(identity)
+						leaderState.startTasks(ctx)
+					},
+					OnStoppedLeading: func() {
+						log.Printf("⚠️  Lost leadership, leader-only tasks stopped")
+						leaderState.setLeader("")
+					},
+					OnNewLeader: func(leader string) {
+						log.Printf("Current leader: %s", leader)
+						leaderState.setLeader(leader)
+					},
+				},
+			})
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+func getLeaderStatus(c *gin.Context) {
+	leaderState.mu.RLock()
+	defer leaderState.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  leaderState.enabled,
+		"identity": leaderState.identity,
+		"leader":   leaderState.leader,
+		"isLeader": leaderState.isLeader,
+	})
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	defaultLogTailLines = 50
+	// maxLogStreams bounds the concurrent log streams of one request
+	maxLogStreams = 50
+)
+
+// LogLine is one line of a container log, prefixed with its pod and container
+type LogLine struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Line      string `json:"line"`
+	Text      string `json:"text"`
+}
+
+// logSource is a container whose log is streamed
+type logSource struct {
+	pod       string
+	container string
+}
+
+// clusterLogSources returns the containers of the pods in the tree, optionally only the named container
+func clusterLogSources(root *ResourceTreeNode, container string) []logSource {
+	var sources []logSource
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		if node.Resource.GetKind() == "Pod" {
+			containers, _, _ := unstructured.NestedSlice(node.Resource.Object, "spec", "containers")
+			for _, c := range containers {
+				containerMap, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := containerMap["name"].(string)
+				if container == "" || name == container {
+					sources = append(sources, logSource{pod: node.Resource.GetName(), container: name})
+				}
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return sources
+}
+
+// parseTailLines reads the tailLines parameter of log streams subscribed over WebSocket, whose
+// parameters do not go through the validation of query parameters
+func parseTailLines(value string) (int64, error) {
+	if value == "" {
+		return defaultLogTailLines, nil
+	}
+	tailLines, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || tailLines < 0 || tailLines > maxLogTailLines {
+		return 0, fmt.Errorf("Invalid tailLines: %s", value)
+	}
+	return tailLines, nil
+}
+
+// streamClusterLogs multiplexes the logs of every container of the cluster's pods into one SSE
+// stream of log events. Pods created after the stream started are not followed.
+func streamClusterLogs(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+	container := c.Query("container")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for streaming cluster logs"})
+		return
+	}
+	params, err := queryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tailLines := int64(defaultLogTailLines)
+	if params.TailLines != nil {
+		tailLines = *params.TailLines
+	}
+
+	log.Printf("Streaming logs of cluster %s in namespace '%s' (container '%s') requested from %s", clusterName, namespace, container, c.ClientIP())
+
+	stream, status, err := openLogStream(clientFor(c), clusterName, namespace, container, tailLines)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	prepareSSE(c)
+	stream.run(c.Request.Context(), func(name string, data interface{}) { sendSSE(c, name, data) }, true)
+}
+
+// logStream follows the logs of the containers of a cluster, for the SSE stream of the cluster
+// and the logs topic of /api/ws
+type logStream struct {
+	client      *K8sClient
+	clusterName string
+	namespace   string
+	tailLines   int64
+	sources     []logSource
+	warnings    []string
+}
+
+// openLogStream resolves the containers whose logs are streamed from the tree of the cluster
+func openLogStream(client *K8sClient, clusterName, namespace, container string, tailLines int64) (*logStream, int, error) {
+	rootTreeNode, _, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		return nil, status, err
+	}
+	stream := &logStream{client: client, clusterName: clusterName, namespace: namespace, tailLines: tailLines}
+	stream.sources = clusterLogSources(rootTreeNode, container)
+	if len(stream.sources) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("No containers to stream logs from in cluster %s", clusterName)
+	}
+	if len(stream.sources) > maxLogStreams {
+		stream.warnings = append(stream.warnings, fmt.Sprintf("Only the first %d of %d containers are streamed, select one with container=", maxLogStreams, len(stream.sources)))
+		stream.sources = stream.sources[:maxLogStreams]
+	}
+	return stream, http.StatusOK, nil
+}
+
+// run sends a streams event, then the lines of all containers as log events until ctx is done
+// or every log stream ended. heartbeat adds heartbeat events for SSE.
+func (stream *logStream) run(ctx context.Context, emit func(name string, data interface{}), heartbeat bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Streams feed one channel so that only this goroutine emits
+	lines := make(chan LogLine, 256)
+	ended := make(chan logSource)
+	var wg sync.WaitGroup
+	for _, source := range stream.sources {
+		wg.Add(1)
+		go func(source logSource) {
+			defer wg.Done()
+			options := &corev1.PodLogOptions{Container: source.container, Follow: true, TailLines: &stream.tailLines}
+			logs, err := stream.client.clientset.CoreV1().Pods(stream.namespace).GetLogs(source.pod, options).Stream(ctx)
+			if err != nil {
+				log.Printf("⚠️  Unable to stream logs of %s/%s: %v", source.pod, source.container, err)
+			} else {
+				defer logs.Close()
+				scanner := bufio.NewScanner(logs)
+				scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+				for scanner.Scan() {
+					line := LogLine{
+						Pod:       source.pod,
+						Container: source.container,
+						Line:      scanner.Text(),
+						Text:      fmt.Sprintf("[%s/%s] %s", source.pod, source.container, scanner.Text()),
+					}
+					select {
+					case lines <- line:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			select {
+			case ended <- source:
+			case <-ctx.Done():
+			}
+		}(source)
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	emit("streams", gin.H{"containers": len(stream.sources), "warnings": stream.warnings})
+
+	var heartbeats <-chan time.Time
+	if heartbeat {
+		ticker := time.NewTicker(streamHeartbeatInterval())
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+
+	remaining := len(stream.sources)
+	for remaining > 0 {
+		select {
+		case <-ctx.Done():
+			log.Printf("Log stream of cluster %s closed by client", stream.clusterName)
+			return
+		case <-heartbeats:
+			emit("heartbeat", gin.H{"time": time.Now().Format(time.RFC3339)})
+		case line := <-lines:
+			emit("log", line)
+		case source := <-ended:
+			remaining--
+			emit("stream-end", gin.H{"pod": source.pod, "container": source.container})
+		}
+	}
+	emit("end", gin.H{"reason": "all log streams ended"})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+const (
+	treeVersionHeader       = api.TreeVersionHeader
+	longPollDefaultTimeout  = 30 * time.Second
+	longPollMaxTimeout      = 120 * time.Second
+	longPollRefreshInterval = 2 * time.Second
+)
+
+// treeVersion fingerprints the tree from the UIDs and resourceVersions of its nodes,
+// so any created, updated or deleted resource changes the version. Retained deleted
+// nodes count separately, so their removal after the grace period is a change as well.
+func treeVersion(root *ResourceTreeNode) string {
+	var entries []string
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		entry := fmt.Sprintf("%s@%s", node.Resource.GetUID(), node.Resource.GetResourceVersion())
+		if node.Deleted {
+			entry += "~deleted"
+		}
+		entries = append(entries, entry)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	sort.Strings(entries)
+
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry))
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// buildTreeForRequest builds the tree of a tree request, applies its pipeline and sets its version header.
+// With ?waitFor=<version> it long-polls for proxies that strip WebSockets and SSE: it only
+// returns once the tree differs from that version, or with a nil tree and 304 after timeoutSeconds.
+func buildTreeForRequest(c *gin.Context, resourceType, rootResourceName, namespace string) (*ResourceTreeNode, *ResourceTreeBuilder, int, error) {
+	options, err := parseTreeOptions(c)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	build := func() (*ResourceTreeNode, *ResourceTreeBuilder, int, error) {
+		rootTreeNode, treeBuilder, status, err := buildTreeForRootWithin(clientFor(c), resourceType, rootResourceName, namespace, options)
+		if err == nil {
+			// Deleted nodes are only kept for live sessions, which long-poll with waitFor
+			treeBuilder.RetainDeletedNodes(rootTreeNode, resourceType, rootResourceName, c.Query("waitFor") != "")
+			options.Pipeline.Apply(rootTreeNode)
+			setPartialHeaders(c, treeBuilder)
+		}
+		return rootTreeNode, treeBuilder, status, err
+	}
+
+	waitFor := c.Query("waitFor")
+	if waitFor == "" {
+		rootTreeNode, treeBuilder, status, err := build()
+		if err == nil {
+			c.Header(treeVersionHeader, treeVersion(rootTreeNode))
+		}
+		return rootTreeNode, treeBuilder, status, err
+	}
+
+	timeout := longPollDefaultTimeout
+	params, err := queryParams(c)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	if params.TimeoutSeconds != nil {
+		timeout = time.Duration(*params.TimeoutSeconds) * time.Second
+		if timeout > longPollMaxTimeout {
+			timeout = longPollMaxTimeout
+		}
+	}
+
+	log.Printf("Long-polling tree of %s/%s for changes from version %s (timeout %s)", resourceType, rootResourceName, waitFor, timeout)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(longPollRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		rootTreeNode, treeBuilder, status, err := build()
+		if err != nil {
+			return nil, nil, status, err
+		}
+		if version := treeVersion(rootTreeNode); version != waitFor {
+			log.Printf("Tree of %s/%s changed to version %s", resourceType, rootResourceName, version)
+			c.Header(treeVersionHeader, version)
+			return rootTreeNode, treeBuilder, http.StatusOK, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, nil, http.StatusNotModified, nil
+		case <-deadline.C:
+			log.Printf("Tree of %s/%s unchanged after %s", resourceType, rootResourceName, timeout)
+			c.Header(treeVersionHeader, waitFor)
+			return nil, nil, http.StatusNotModified, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,15 +2,18 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -20,25 +23,21 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
 )
 
 type K8sClient struct {
+	name            string // Cluster name used by the cluster query parameter
 	clientset       kubernetes.Interface
 	dynamicClient   dynamic.Interface
 	discoveryClient discovery.DiscoveryInterface
+	apiDiscovery    *discoveryCache
+	config          *rest.Config // Shared by request-scoped copies of the client
+	httpClient      *http.Client
 }
 
-type ResourceNode struct {
-	Name         string            `json:"name"`
-	Kind         string            `json:"kind"`
-	APIVersion   string            `json:"apiVersion"`
-	Namespace    string            `json:"namespace,omitempty"`
-	UID          string            `json:"uid"`
-	Labels       map[string]string `json:"labels,omitempty"`
-	Annotations  map[string]string `json:"annotations,omitempty"`
-	CreationTime string            `json:"creationTime"`
-	Status       string            `json:"status,omitempty"`
-}
+type ResourceNode = api.ResourceNode
 
 type ResourceRelationship struct {
 	Parent   ResourceNode   `json:"parent"`
@@ -48,48 +47,142 @@ type ResourceRelationship struct {
 var k8sClient *K8sClient
 
 func main() {
+	// Subcommands that don't start the server
+	if len(os.Args) > 1 && os.Args[1] == "install" {
+		os.Exit(runInstall(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "capture" {
+		os.Exit(runCapture(os.Args[2:]))
+	}
+
+	demo := flag.Bool("demo", false, "Serve demo KubeBlocks clusters from an in-memory fake API server instead of a real cluster")
+	replay := flag.String("replay", "", "Serve the resources of comma-separated fixture files written by `kb-viz capture` instead of a real cluster")
+	flag.Parse()
+
 	log.Println("Starting K8s Resource Visualizer backend...")
 
-	// Initialize Kubernetes client
-	log.Println("Initializing Kubernetes client...")
+	// Load configuration
 	var err error
-	k8sClient, err = initK8sClient()
+	appConfig, err = loadConfig()
 	if err != nil {
-		log.Fatalf("Failed to initialize Kubernetes client: %v", err)
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	log.Println("✓ Configuration loaded")
+
+	// Resource types of the tree builder, reloaded when the mounted file changes
+	if appConfig.TreeTypesFile != "" {
+		if err := loadTreeTypes(appConfig.TreeTypesFile); err != nil {
+			log.Fatalf("Failed to load tree resource types: %v", err)
+		}
+		go watchTreeTypes(context.Background(), appConfig.TreeTypesFile)
+	}
+
+	// Bucket of asynchronous exports
+	exportStorage, err = newArtifactStore(appConfig.ExportStorage)
+	if err != nil {
+		log.Fatalf("Failed to configure export storage: %v", err)
+	}
+
+	// Initialize Kubernetes client
+	switch {
+	case *demo:
+		log.Println("Starting in demo mode, no cluster is contacted...")
+		k8sClient, err = newDemoClient()
+		if err != nil {
+			log.Fatalf("Failed to create the demo clusters: %v", err)
+		}
+		// Configured clusters are real, the demo only serves the fake one
+		appConfig.Clusters = nil
+	case *replay != "":
+		log.Println("Starting in replay mode, no cluster is contacted...")
+		k8sClient, err = newReplayClient(strings.Split(*replay, ","))
+		if err != nil {
+			log.Fatalf("Failed to replay fixtures: %v", err)
+		}
+		appConfig.Clusters = nil
+	default:
+		log.Println("Initializing Kubernetes client...")
+		k8sClient, err = initK8sClient()
+		if err != nil {
+			log.Fatalf("Failed to initialize Kubernetes client: %v", err)
+		}
+		log.Println("✓ Kubernetes client initialized successfully")
+	}
+
+	// Additional clusters selected with the cluster query parameter
+	initClusterClients(k8sClient, appConfig.Clusters)
+
+	// Served KubeBlocks API versions are reported by /api/version
+	go probeKubeBlocksAPIVersions(k8sClient)
+
+	// Keep resource aliases in sync with the CRDs of the default cluster
+	go watchCRDAliases(context.Background(), k8sClient)
+
+	// Release expired resource pools even when no tree is built
+	go resourcePools.expireLoop(context.Background())
+
+	// Prime caches before reporting ready
+	warmupNamespaces := appConfig.WarmupNamespaces
+	if len(warmupNamespaces) == 0 {
+		warmupNamespaces = appConfig.WatchNamespaces
+	}
+	go warmCaches(k8sClient, warmupNamespaces)
+
+	// Join leader election when running multiple replicas
+	if appConfig.LeaderElection.Enabled {
+		startLeaderElection(appConfig.LeaderElection)
 	}
-	log.Println("✓ Kubernetes client initialized successfully")
+
+	// Per-cluster tree gauges are collected by the leader only
+	if appConfig.ClusterMetricsIntervalSeconds > 0 {
+		leaderState.RunWhenLeader(collectClusterMetrics)
+	}
+
+	// Change history of the configured clusters is recorded by the leader as well
+	startConfiguredRecorders(k8sClient, appConfig.RecordHistory)
 
 	// Initialize Gin router
 	log.Println("Setting up HTTP router and middleware...")
-	router := gin.Default()
+	router := gin.New()
+	router.Use(requestIDMiddleware(), gin.LoggerWithFormatter(requestLogFormatter), gin.Recovery(), connectionDeadlineMiddleware(appConfig.Server))
 
 	// Configure CORS
 	log.Println("Configuring CORS middleware...")
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	if len(appConfig.AllowedOrigins) > 0 {
+		// Explicit origins may send the CSRF cookie along
+		config.AllowOrigins = appConfig.AllowedOrigins
+		config.AllowCredentials = true
+	} else {
+		config.AllowAllOrigins = true
+	}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", requestIDHeader, csrfHeader, shareTokenHeader}
+	config.ExposeHeaders = []string{treeVersionHeader, treePartialHeader, treeContinueHeader, requestIDHeader}
 	router.Use(cors.New(config))
 	log.Println("✓ CORS middleware configured")
 
+	// Metrics endpoint
+	router.GET("/metrics", metricsHandler)
+	// Readiness waits for the cache warm-up
+	router.GET("/readyz", readinessCheck)
+
 	// API routes
 	log.Println("Registering API routes...")
-	api := router.Group("/api")
-	{
-		api.GET("/health", healthCheck)
-		api.GET("/resources/:type", getResourcesByType)
-		api.GET("/resources/:type/:root/tree", getResourceTree)
-		api.GET("/namespaces", getNamespaces)
-	}
+	// The unversioned /api prefix is kept as an alias of /api/v1 for existing consumers
+	registerV1Routes(router.Group("/api"))
+	registerV1Routes(router.Group("/api/v1"))
+	registerV2Routes(router.Group("/api/v2"))
 	log.Println("✓ API routes registered:")
-	log.Println("  - GET /api/health")
-	log.Println("  - GET /api/resources/:type")
-	log.Println("  - GET /api/resources/:type/:root/tree")
-	log.Println("  - GET /api/namespaces")
+	for _, route := range router.Routes() {
+		log.Printf("  - %s %s", route.Method, route.Path)
+	}
 
-	log.Println("🚀 Server starting on :8080")
 	log.Println("Ready to accept requests...")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	log.Fatal(serve(router, appConfig.Server))
 }
 
 func initK8sClient() (*K8sClient, error) {
@@ -122,9 +215,23 @@ func initK8sClient() (*K8sClient, error) {
 		log.Println("✓ Using in-cluster Kubernetes configuration")
 	}
 
+	return newK8sClient(defaultClusterName, config)
+}
+
+// newK8sClient creates the clients of one cluster from its REST config
+func newK8sClient(name string, config *rest.Config) (*K8sClient, error) {
+	// Protect the API server from the fan-out of pool building
+	applyRequestBudget(config, appConfig)
+
+	// All clients share one HTTP client, so request-scoped copies reuse its connections
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+
 	// Create clientset
 	log.Println("Creating Kubernetes clientset...")
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clientset: %v", err)
 	}
@@ -132,7 +239,7 @@ func initK8sClient() (*K8sClient, error) {
 
 	// Create dynamic client
 	log.Println("Creating dynamic client...")
-	dynamicClient, err := dynamic.NewForConfig(config)
+	dynamicClient, err := dynamic.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
 	}
@@ -140,31 +247,39 @@ func initK8sClient() (*K8sClient, error) {
 
 	// Create discovery client
 	log.Println("Creating discovery client...")
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create discovery client: %v", err)
 	}
 	log.Println("✓ Discovery client created successfully")
 
 	return &K8sClient{
+		name:            name,
 		clientset:       clientset,
 		dynamicClient:   dynamicClient,
 		discoveryClient: discoveryClient,
+		apiDiscovery:    &discoveryCache{client: discoveryClient},
+		config:          config,
+		httpClient:      httpClient,
 	}, nil
 }
 
 func healthCheck(c *gin.Context) {
 	log.Printf("Health check requested from %s", c.ClientIP())
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"message": "K8s Resource Visualizer API is running",
-	})
+	report := cachedHealthReport(clientFor(c))
+	c.JSON(healthStatusCode(c, report), report)
 }
 
 func getNamespaces(c *gin.Context) {
 	log.Printf("Fetching namespaces requested from %s", c.ClientIP())
-	namespaces, err := k8sClient.clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := clientFor(c).clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
+		// Per-team deployments may not be allowed to list namespaces, the allowlist is authoritative then
+		if len(appConfig.WatchNamespaces) > 0 {
+			log.Printf("Unable to list namespaces (%v), returning configured allowlist", err)
+			c.JSON(http.StatusOK, appConfig.WatchNamespaces)
+			return
+		}
 		log.Printf("Error fetching namespaces: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -172,6 +287,9 @@ func getNamespaces(c *gin.Context) {
 
 	var namespaceList []string
 	for _, ns := range namespaces.Items {
+		if !appConfig.namespaceAllowed(ns.Name) {
+			continue
+		}
 		namespaceList = append(namespaceList, ns.Name)
 	}
 	log.Printf("Found %d namespaces: %v", len(namespaceList), namespaceList)
@@ -201,17 +319,39 @@ func getResourcesByType(c *gin.Context) {
 	}
 	log.Printf("Resolved GVR: %+v", gvr)
 
+	// columns or jsonpath reduce every object to the selected fields
+	columns, err := parseFieldSelection(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var resources []ResourceNode
 
 	// Get resources from specific namespace
 	log.Printf("Fetching resources from namespace: %s", namespace)
-	resourceList, err := k8sClient.dynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	resourceList, err := clientFor(c).dynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		log.Printf("Error fetching resources from namespace %s: %v", namespace, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	log.Printf("Found %d resources in namespace %s", len(resourceList.Items), namespace)
+	if !revealAllowed(c) {
+		for i := range resourceList.Items {
+			redactObject(resourceList.Items[i].Object)
+		}
+	}
+	if columns != nil {
+		selected, err := selectFields(columns, resourceList.Items)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Returning %d columns of %d resources of type %s", len(selected.Columns), len(selected.Items), resourceType)
+		c.JSON(http.StatusOK, selected)
+		return
+	}
 	resources = convertToResourceNodes(resourceList.Items)
 
 	log.Printf("Returning %d resources of type %s", len(resources), resourceType)
@@ -223,33 +363,127 @@ func getResourceTree(c *gin.Context) {
 	rootResourceName := c.Param("root")
 	namespace := c.Query("namespace")
 
+	format := c.Query("format")
+	if format != "" && format != treeFormatArgoCD && format != treeFormatNDJSON {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported tree format: %s", format)})
+		return
+	}
+
 	log.Printf("Building resource tree with %s/%s as root node in namespace '%s' requested from %s", resourceType, rootResourceName, namespace, c.ClientIP())
 
+	// A continuation returns the subtrees of the nodes a partial tree left unexpanded
+	if token := c.Query("continue"); token != "" {
+		subtrees, treeBuilder, status, err := buildTreeContinuation(c, token)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		for _, subtree := range subtrees {
+			treeBuilder.DecorateTree(subtree)
+		}
+		setPartialHeaders(c, treeBuilder)
+		if format == treeFormatArgoCD {
+			c.JSON(http.StatusOK, NewArgoResourceTree(subtrees...))
+			return
+		}
+		if format == treeFormatNDJSON {
+			streamTree(c, treeBuilder, subtrees...)
+			return
+		}
+		if subtrees == nil {
+			subtrees = []*ResourceTreeNode{}
+		}
+		c.JSON(http.StatusOK, subtrees)
+		return
+	}
+
+	rootTreeNode, treeBuilder, status, err := buildTreeForRequest(c, resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if rootTreeNode == nil {
+		// Long-poll timed out without changes
+		c.Status(status)
+		return
+	}
+
+	// Decorate nodes with placement, problems, relationships and endpoint readiness
+	treeBuilder.DecorateTree(rootTreeNode)
+
+	if format == treeFormatArgoCD {
+		argoTree := NewArgoResourceTree(rootTreeNode)
+		log.Printf("Successfully built Argo CD resource tree with root %s/%s containing %d nodes", rootTreeNode.Resource.GetKind(), rootTreeNode.Resource.GetName(), len(argoTree.Nodes))
+		c.JSON(http.StatusOK, argoTree)
+		return
+	}
+	if format == treeFormatNDJSON {
+		streamTree(c, treeBuilder, rootTreeNode)
+		return
+	}
+
+	// Return tree structure as an array with the root node
+	treeArray := []*ResourceTreeNode{rootTreeNode}
+	totalNodes := treeBuilder.CountNodes(rootTreeNode)
+	log.Printf("Successfully built resource tree with root %s/%s containing %d total nodes", rootTreeNode.Resource.GetKind(), rootTreeNode.Resource.GetName(), totalNodes)
+
+	c.JSON(http.StatusOK, treeArray)
+}
+
+// fetchResource resolves the resource type and gets a single resource, cluster-scoped when the namespace is empty.
+// On failure it returns the HTTP status code that best describes the error.
+func fetchResource(client *K8sClient, resourceType, name, namespace string) (*unstructured.Unstructured, schema.GroupVersionResource, int, error) {
+	gvr, err := getGVRForResourceType(resourceType)
+	if err != nil {
+		log.Printf("Unknown resource type '%s': %v", resourceType, err)
+		return nil, gvr, http.StatusBadRequest, fmt.Errorf("Unknown resource type: %s", resourceType)
+	}
+
+	var resource *unstructured.Unstructured
+	if namespace != "" {
+		resource, err = client.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	} else {
+		resource, err = client.dynamicClient.Resource(gvr).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	if err != nil {
+		log.Printf("Resource not found: %s/%s in namespace %s: %v", resourceType, name, namespace, err)
+		if errors.IsNotFound(err) {
+			return nil, gvr, http.StatusNotFound, fmt.Errorf("Resource not found: %s/%s in namespace %s", resourceType, name, namespace)
+		}
+		return nil, gvr, http.StatusInternalServerError, err
+	}
+	return resource, gvr, http.StatusOK, nil
+}
+
+// buildTreeForRoot fetches the root resource and builds its ownership tree.
+// On failure it returns the HTTP status code that best describes the error.
+func buildTreeForRoot(client *K8sClient, resourceType, rootResourceName, namespace string) (*ResourceTreeNode, *ResourceTreeBuilder, int, error) {
+	return buildTreeForRootWithin(client, resourceType, rootResourceName, namespace, TreeOptions{})
+}
+
+// buildTreeForRootWithin builds the tree like buildTreeForRoot, leaving nodes unexpanded once the
+// time budget is spent or the node cap is reached
+func buildTreeForRootWithin(client *K8sClient, resourceType, rootResourceName, namespace string, options TreeOptions) (*ResourceTreeNode, *ResourceTreeBuilder, int, error) {
 	// Get the root resource that will serve as the tree's root node
 	log.Printf("Resolving GVR for root resource type: %s", resourceType)
 
 	gvr, err := getGVRForResourceType(resourceType)
 	if err != nil {
 		log.Printf("Unknown resource type '%s': %v", resourceType, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown resource type: %s", resourceType)})
-		return
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Unknown resource type: %s", resourceType)
 	}
 
 	// For tree structure building, we require a namespace to be specified
 	if namespace == "" {
 		log.Printf("Namespace is required for building resource tree")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for building resource tree"})
-		return
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Namespace parameter is required for building resource tree")
 	}
 
-	var rootResource *unstructured.Unstructured
 	log.Printf("Fetching root resource: %s/%s in namespace %s", resourceType, rootResourceName, namespace)
-	rootResource, err = k8sClient.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), rootResourceName, metav1.GetOptions{})
-
+	rootResource, err := client.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), rootResourceName, metav1.GetOptions{})
 	if err != nil {
 		log.Printf("Root resource not found: %s/%s in namespace %s: %v", resourceType, rootResourceName, namespace, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Root resource not found: %s/%s in namespace %s", resourceType, rootResourceName, namespace)})
-		return
+		return nil, nil, http.StatusNotFound, fmt.Errorf("Root resource not found: %s/%s in namespace %s", resourceType, rootResourceName, namespace)
 	}
 	log.Printf("Found root resource: %s (UID: %s)", rootResource.GetName(), rootResource.GetUID())
 
@@ -260,92 +494,107 @@ func getResourceTree(c *gin.Context) {
 		LabelSelector: fmt.Sprintf("app.kubernetes.io/instance=%s", rootResourceName),
 	}
 	// Create tree builder
-	treeBuilder := NewResourceTreeBuilder(k8sClient, namespace, listOptions)
+	treeBuilder := NewResourceTreeBuilder(client, namespace, listOptions)
+	treeBuilder.SetOptions(options)
 
 	// Build the tree using new format
 	rootTreeNode, err := treeBuilder.GetResourceTree(rootResource)
 	if err != nil {
 		log.Printf("Error building resource tree: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, nil, http.StatusInternalServerError, err
 	}
 
-	// Return tree structure as an array with the root node
-	treeArray := []*ResourceTreeNode{rootTreeNode}
-	totalNodes := treeBuilder.CountNodes(rootTreeNode)
-	log.Printf("Successfully built resource tree with root %s/%s containing %d total nodes", rootResource.GetKind(), rootResource.GetName(), totalNodes)
+	return rootTreeNode, treeBuilder, http.StatusOK, nil
+}
 
-	c.JSON(http.StatusOK, treeArray)
+// resourceMappings holds the builtin resource type aliases (including KubeBlocks custom resources)
+var resourceMappings = map[string]schema.GroupVersionResource{
+	// Standard Kubernetes resources
+	"pod":                    {Group: "", Version: "v1", Resource: "pods"},
+	"pods":                   {Group: "", Version: "v1", Resource: "pods"},
+	"service":                {Group: "", Version: "v1", Resource: "services"},
+	"services":               {Group: "", Version: "v1", Resource: "services"},
+	"deployment":             {Group: "apps", Version: "v1", Resource: "deployments"},
+	"deployments":            {Group: "apps", Version: "v1", Resource: "deployments"},
+	"replicaset":             {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"replicasets":            {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"statefulset":            {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"statefulsets":           {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"daemonset":              {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"daemonsets":             {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"configmap":              {Group: "", Version: "v1", Resource: "configmaps"},
+	"configmaps":             {Group: "", Version: "v1", Resource: "configmaps"},
+	"secret":                 {Group: "", Version: "v1", Resource: "secrets"},
+	"secrets":                {Group: "", Version: "v1", Resource: "secrets"},
+	"ingress":                {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"ingresses":              {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"job":                    {Group: "batch", Version: "v1", Resource: "jobs"},
+	"jobs":                   {Group: "batch", Version: "v1", Resource: "jobs"},
+	"cronjob":                {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"cronjobs":               {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"persistentvolumeclaim":  {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
+	"persistentvolumeclaims": {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
+	"pvc":                    {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
+	"persistentvolume":       {Group: "", Version: "v1", Resource: "persistentvolumes"},
+	"persistentvolumes":      {Group: "", Version: "v1", Resource: "persistentvolumes"},
+	"pv":                     {Group: "", Version: "v1", Resource: "persistentvolumes"},
+	"storageclass":           {Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	"storageclasses":         {Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	"sc":                     {Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	"endpointslice":          {Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+	"endpointslices":         {Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+
+	// KubeBlocks custom resources
+	"cluster":               {Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
+	"clusters":              {Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
+	"component":             {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
+	"components":            {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
+	"cmp":                   {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
+	"backuppolicy":          {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
+	"backuppolicies":        {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
+	"bp":                    {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
+	"backup":                {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
+	"backups":               {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
+	"backupschedule":        {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
+	"backupschedules":       {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
+	"bs":                    {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
+	"restore":               {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
+	"restores":              {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
+	"opsrequest":            {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+	"opsrequests":           {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+	"ops":                   {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+	"componentparameter":    {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
+	"componentparameters":   {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
+	"parameter":             {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
+	"parameters":            {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
+	"parametersdefinition":  {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parametersdefinitions"},
+	"parametersdefinitions": {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parametersdefinitions"},
+	"instance":              {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
+	"instances":             {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
+	"inst":                  {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
+	"instanceset":           {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
+	"instancesets":          {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
+	"its":                   {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
 }
 
 func getGVRForResourceType(resourceType string) (schema.GroupVersionResource, error) {
-	// Common resource mappings (including KubeBlocks custom resources)
-	resourceMappings := map[string]schema.GroupVersionResource{
-		// Standard Kubernetes resources
-		"pod":                    {Group: "", Version: "v1", Resource: "pods"},
-		"pods":                   {Group: "", Version: "v1", Resource: "pods"},
-		"service":                {Group: "", Version: "v1", Resource: "services"},
-		"services":               {Group: "", Version: "v1", Resource: "services"},
-		"deployment":             {Group: "apps", Version: "v1", Resource: "deployments"},
-		"deployments":            {Group: "apps", Version: "v1", Resource: "deployments"},
-		"replicaset":             {Group: "apps", Version: "v1", Resource: "replicasets"},
-		"replicasets":            {Group: "apps", Version: "v1", Resource: "replicasets"},
-		"statefulset":            {Group: "apps", Version: "v1", Resource: "statefulsets"},
-		"statefulsets":           {Group: "apps", Version: "v1", Resource: "statefulsets"},
-		"daemonset":              {Group: "apps", Version: "v1", Resource: "daemonsets"},
-		"daemonsets":             {Group: "apps", Version: "v1", Resource: "daemonsets"},
-		"configmap":              {Group: "", Version: "v1", Resource: "configmaps"},
-		"configmaps":             {Group: "", Version: "v1", Resource: "configmaps"},
-		"secret":                 {Group: "", Version: "v1", Resource: "secrets"},
-		"secrets":                {Group: "", Version: "v1", Resource: "secrets"},
-		"ingress":                {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
-		"ingresses":              {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
-		"job":                    {Group: "batch", Version: "v1", Resource: "jobs"},
-		"jobs":                   {Group: "batch", Version: "v1", Resource: "jobs"},
-		"cronjob":                {Group: "batch", Version: "v1", Resource: "cronjobs"},
-		"cronjobs":               {Group: "batch", Version: "v1", Resource: "cronjobs"},
-		"persistentvolumeclaim":  {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
-		"persistentvolumeclaims": {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
-		"pvc":                    {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
-
-		// KubeBlocks custom resources
-		"cluster":             {Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
-		"clusters":            {Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
-		"component":           {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
-		"components":          {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
-		"cmp":                 {Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
-		"backuppolicy":        {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
-		"backuppolicies":      {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
-		"bp":                  {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
-		"backup":              {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
-		"backups":             {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
-		"backupschedule":      {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
-		"backupschedules":     {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
-		"bs":                  {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
-		"restore":             {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
-		"restores":            {Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
-		"opsrequest":          {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
-		"opsrequests":         {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
-		"ops":                 {Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
-		"componentparameter":  {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
-		"componentparameters": {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
-		"parameter":           {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
-		"parameters":          {Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
-		"instance":            {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
-		"instances":           {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
-		"inst":                {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
-		"instanceset":         {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
-		"instancesets":        {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
-		"its":                 {Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
-	}
-
 	// Normalize resource type (lowercase)
 	normalizedType := strings.ToLower(resourceType)
 
+	// Names read from the installed CRDs follow changes of their definitions
+	if gvr, exists := crdAliases.resolve(normalizedType); exists {
+		return gvr, nil
+	}
+
 	if gvr, exists := resourceMappings[normalizedType]; exists {
 		return gvr, nil
 	}
 
+	// Fall back to configured custom types and API discovery
+	if gvr, exists := resolveResourceType(normalizedType); exists {
+		return gvr, nil
+	}
+
 	return schema.GroupVersionResource{}, fmt.Errorf("unknown resource type: %s", resourceType)
 }
 
@@ -357,7 +606,8 @@ func convertToResourceNodes(resources []unstructured.Unstructured) []ResourceNod
 	return nodes
 }
 
-func convertToResourceNode(resource unstructured.Unstructured) ResourceNode {
+// resourceStatus is the status shown for a resource: its phase, or the value of a status extractor
+func resourceStatus(resource *unstructured.Unstructured) string {
 	status := "Unknown"
 	if statusObj, found, err := unstructured.NestedFieldNoCopy(resource.Object, "status"); found && err == nil {
 		if statusMap, ok := statusObj.(map[string]interface{}); ok {
@@ -366,16 +616,27 @@ func convertToResourceNode(resource unstructured.Unstructured) ResourceNode {
 			}
 		}
 	}
+	if extracted, ok := extractStatus(resource); ok {
+		status = extracted
+	}
+	return status
+}
 
+// convertToResourceNode converts a resource as is, responses convert through resourceNodeFor
+func convertToResourceNode(resource unstructured.Unstructured) ResourceNode {
 	return ResourceNode{
-		Name:         resource.GetName(),
-		Kind:         resource.GetKind(),
-		APIVersion:   resource.GetAPIVersion(),
-		Namespace:    resource.GetNamespace(),
-		UID:          string(resource.GetUID()),
-		Labels:       resource.GetLabels(),
-		Annotations:  resource.GetAnnotations(),
-		CreationTime: resource.GetCreationTimestamp().Time.Format("2006-01-02 15:04:05"),
-		Status:       status,
+		Name:              resource.GetName(),
+		Kind:              resource.GetKind(),
+		APIVersion:        resource.GetAPIVersion(),
+		Namespace:         resource.GetNamespace(),
+		UID:               string(resource.GetUID()),
+		Labels:            resource.GetLabels(),
+		Annotations:       resource.GetAnnotations(),
+		CreationTime:      resource.GetCreationTimestamp().Time.Format("2006-01-02 15:04:05"),
+		Status:            resourceStatus(&resource),
+		Age:               resourceAge(&resource, time.Now()),
+		LastUpdated:       formatLastUpdated(&resource),
+		DeletionTimestamp: deletionTimestamp(&resource),
+		Finalizers:        resource.GetFinalizers(),
 	}
 }
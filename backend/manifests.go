@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestRef identifies one resource to export
+type ManifestRef struct {
+	// GVR is either a resource type alias (e.g. "pod", "its") or "group/version/resource"
+	GVR       string `json:"gvr"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// ManifestExportRequest is the body of POST /api/manifests/export
+type ManifestExportRequest struct {
+	Items  []ManifestRef `json:"items"`
+	Format string        `json:"format"` // yaml (default) or zip
+}
+
+// parseGVR resolves an alias or an explicit group/version/resource string
+func parseGVR(value string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(value, "/")
+	switch len(parts) {
+	case 3:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	case 2:
+		// Core group, e.g. v1/pods
+		return schema.GroupVersionResource{Group: "", Version: parts[0], Resource: parts[1]}, nil
+	}
+	return getGVRForResourceType(value)
+}
+
+// fetchManifest gets a resource and strips the fields that are noise in exported manifests
+func fetchManifest(client *K8sClient, ref ManifestRef) (*unstructured.Unstructured, error) {
+	gvr, err := parseGVR(ref.GVR)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource *unstructured.Unstructured
+	if ref.Namespace != "" {
+		resource, err = client.dynamicClient.Resource(gvr).Namespace(ref.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	} else {
+		resource, err = client.dynamicClient.Resource(gvr).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resource.SetManagedFields(nil)
+	return resource, nil
+}
+
+// maskedBanner is the YAML comment heading a manifest whose sensitive values were masked, so a
+// masked manifest is not mistaken for the real one when it is applied again
+func maskedBanner(masked []string) []byte {
+	if len(masked) == 0 {
+		return nil
+	}
+	return []byte(fmt.Sprintf("# Masked by kb-viz, export with reveal=true to include: %s\n", strings.Join(masked, ", ")))
+}
+
+// writeManifests fetches the resources and writes them as a multi-document YAML or a zip archive,
+// returning the numbers of exported and failed resources
+func writeManifests(w io.Writer, client *K8sClient, items []ManifestRef, format string, reveal bool) (int, int, error) {
+	type exported struct {
+		ref  ManifestRef
+		data []byte
+	}
+	var documents []exported
+	var failures []string
+	for _, ref := range items {
+		resource, err := fetchManifest(client, ref)
+		if err != nil {
+			log.Printf("    ⚠️  Unable to export %s %s/%s: %v", ref.GVR, ref.Namespace, ref.Name, err)
+			failures = append(failures, fmt.Sprintf("%s %s/%s: %v", ref.GVR, ref.Namespace, ref.Name, err))
+			continue
+		}
+		var masked []string
+		if !reveal {
+			masked = redactObject(resource.Object)
+		}
+		data, err := yaml.Marshal(resource.Object)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s/%s: %v", ref.GVR, ref.Namespace, ref.Name, err))
+			continue
+		}
+		documents = append(documents, exported{ref: ref, data: append(maskedBanner(masked), data...)})
+	}
+
+	if format == "zip" {
+		archive := zip.NewWriter(w)
+		for _, doc := range documents {
+			name := fmt.Sprintf("%s/%s-%s.yaml", defaultString(doc.ref.Namespace, "_cluster"), strings.ReplaceAll(doc.ref.GVR, "/", "_"), doc.ref.Name)
+			writer, err := archive.Create(name)
+			if err == nil {
+				_, err = writer.Write(doc.data)
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+		if len(failures) > 0 {
+			if writer, err := archive.Create("errors.txt"); err == nil {
+				_, _ = writer.Write([]byte(strings.Join(failures, "\n") + "\n"))
+			}
+		}
+		return len(documents), len(failures), archive.Close()
+	}
+
+	var buf bytes.Buffer
+	for _, failure := range failures {
+		fmt.Fprintf(&buf, "# Failed to export %s\n", failure)
+	}
+	for i, doc := range documents {
+		if i > 0 || len(failures) > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(doc.data)
+	}
+	_, err := w.Write(buf.Bytes())
+	return len(documents), len(failures), err
+}
+
+// exportManifests downloads the manifests of the requested resources, or with async=true uploads
+// them to the export storage in the background
+func exportManifests(c *gin.Context) {
+	var request ManifestExportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid export request: %v", err)})
+		return
+	}
+	if len(request.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required for export"})
+		return
+	}
+	format := strings.ToLower(request.Format)
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format: %s", request.Format)})
+		return
+	}
+	async, err := asyncRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The webhook only saw POST /manifests/export, every exported resource is reviewed on its own
+	for _, ref := range request.Items {
+		if ref.Namespace != "" && !appConfig.namespaceAllowed(ref.Namespace) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", ref.Namespace)})
+			return
+		}
+		gvr, err := parseGVR(ref.GVR)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown resource type: %s", ref.GVR)})
+			return
+		}
+		if err := resourceAccessAllowed(c, "get", gvr, ref.Name, ref.Namespace); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s %s/%s: %v", ref.GVR, ref.Namespace, ref.Name, err)})
+			return
+		}
+	}
+
+	log.Printf("Exporting %d manifests as %s requested from %s", len(request.Items), format, c.ClientIP())
+
+	client := clientFor(c)
+	reveal := revealAllowed(c)
+	filename := fmt.Sprintf("manifests-%s.%s", time.Now().Format("20060102-150405"), format)
+	contentType := "application/yaml"
+	if format == "zip" {
+		contentType = "application/zip"
+	}
+
+	if async {
+		startExport(c, JobTypeManifestExport, filename, contentType, func(w io.Writer) error {
+			exported, failed, err := writeManifests(w, client, request.Items, format, reveal)
+			log.Printf("Exported %d manifests (%d failed) as %s", exported, failed, format)
+			return err
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	exported, failed, err := writeManifests(&buf, client, request.Items, format, reveal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+	log.Printf("Exported %d manifests (%d failed) as %s", exported, failed, format)
+}
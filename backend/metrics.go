@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricFamily holds all series of one metric
+type metricFamily struct {
+	name       string
+	help       string
+	metricType string // counter or gauge
+	series     map[string]float64
+}
+
+// MetricsRegistry is a minimal registry rendering the Prometheus text exposition format
+type MetricsRegistry struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+var metrics = NewMetricsRegistry()
+
+// NewMetricsRegistry creates a new MetricsRegistry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		families: make(map[string]*metricFamily),
+	}
+}
+
+// AddCounter increases a counter series by delta
+func (mr *MetricsRegistry) AddCounter(name, help string, labels map[string]string, delta float64) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.family(name, help, "counter").series[formatLabels(labels)] += delta
+}
+
+// SetGauge sets a gauge series to value
+func (mr *MetricsRegistry) SetGauge(name, help string, labels map[string]string, value float64) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.family(name, help, "gauge").series[formatLabels(labels)] = value
+}
+
+// DeleteSeries removes a series, e.g. when the object it describes disappears
+func (mr *MetricsRegistry) DeleteSeries(name string, labels map[string]string) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if family := mr.families[name]; family != nil {
+		delete(family.series, formatLabels(labels))
+	}
+}
+
+func (mr *MetricsRegistry) family(name, help, metricType string) *metricFamily {
+	family := mr.families[name]
+	if family == nil {
+		family = &metricFamily{name: name, help: help, metricType: metricType, series: make(map[string]float64)}
+		mr.families[name] = family
+	}
+	return family
+}
+
+// Render returns all metrics in the Prometheus text exposition format
+func (mr *MetricsRegistry) Render() string {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	names := make([]string, 0, len(mr.families))
+	for name := range mr.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		family := mr.families[name]
+		fmt.Fprintf(&sb, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", family.name, family.metricType)
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "%s%s %v\n", family.name, key, family.series[key])
+		}
+	}
+	return sb.String()
+}
+
+// formatLabels renders a label set as {k="v",...} with sorted keys
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[key])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, key, value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func metricsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(metrics.Render()))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// namespaceAllowlistMiddleware rejects requests targeting namespaces outside the configured allowlist
+func namespaceAllowlistMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, namespace := range []string{c.Query("namespace"), c.Param("ns")} {
+			if namespace == "" || appConfig.namespaceAllowed(namespace) {
+				continue
+			}
+			log.Printf("Rejecting request for namespace '%s' outside the allowlist from %s", namespace, c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", namespace)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// writeEnabledMiddleware rejects mutating requests unless writes are enabled in the configuration
+func writeEnabledMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !appConfig.WriteEnabled {
+			log.Printf("Rejecting %s %s from %s: write actions are disabled", c.Request.Method, c.FullPath(), c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Write actions are disabled, set KB_VIZ_WRITE_ENABLED=true to enable them"})
+			return
+		}
+		c.Next()
+	}
+}
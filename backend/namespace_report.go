@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const reportFormatMarkdown = "markdown"
+
+// StorageTotals sums the PersistentVolumeClaims of a namespace
+type StorageTotals struct {
+	Claims int `json:"claims"`
+	// Requested and Capacity are the sums of the requested and bound sizes, e.g. 60Gi
+	Requested string `json:"requested"`
+	Capacity  string `json:"capacity"`
+	// ByStorageClass is the requested size per storage class
+	ByStorageClass map[string]string `json:"byStorageClass"`
+}
+
+// BackupTotals sums the Backups of a namespace
+type BackupTotals struct {
+	Backups int            `json:"backups"`
+	ByPhase map[string]int `json:"byPhase"`
+	// TotalSize is the sum of the sizes reported by completed backups
+	TotalSize     string `json:"totalSize"`
+	LastCompleted string `json:"lastCompleted,omitempty"`
+}
+
+// NamespaceReport is the KubeBlocks footprint of a namespace, for handover documentation
+type NamespaceReport struct {
+	Namespace    string `json:"namespace"`
+	CreationTime string `json:"creationTime,omitempty"`
+	GeneratedAt  string `json:"generatedAt"`
+	// Clusters are the KubeBlocks clusters of the namespace with their components
+	Clusters          []KubeBlocksClusterSummary `json:"clusters"`
+	Components        int                        `json:"components"`
+	ComponentsByPhase map[string]int             `json:"componentsByPhase"`
+	Storage           StorageTotals              `json:"storage"`
+	Backups           BackupTotals               `json:"backups"`
+	OpsInProgress     []OverviewItem             `json:"opsInProgress"`
+	Warnings          []string                   `json:"warnings,omitempty"`
+}
+
+// addQuantity adds the quantity at the fields of the object to the sum, ignoring missing or invalid values
+func addQuantity(sum *resource.Quantity, object map[string]interface{}, fields ...string) *resource.Quantity {
+	value, found, _ := unstructured.NestedString(object, fields...)
+	if !found {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil
+	}
+	sum.Add(quantity)
+	return &quantity
+}
+
+// formatStorageSize formats a size in binary units with one decimal, e.g. 2.4Gi, since sums of
+// fractional quantities like 1.2Gi are otherwise printed in millibytes
+func formatStorageSize(quantity resource.Quantity) string {
+	size := float64(quantity.Value())
+	unit := ""
+	for _, next := range []string{"Ki", "Mi", "Gi", "Ti", "Pi"} {
+		if size < 1024 {
+			break
+		}
+		size, unit = size/1024, next
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", size), ".0") + unit
+}
+
+// buildNamespaceReport lists the KubeBlocks resources of the namespace and sums them up. Missing
+// CRDs or permissions leave a section empty with a warning, like the overview does.
+func buildNamespaceReport(client *K8sClient, namespace string) *NamespaceReport {
+	report := &NamespaceReport{
+		Namespace:         namespace,
+		GeneratedAt:       time.Now().UTC().Format(time.RFC3339),
+		Clusters:          []KubeBlocksClusterSummary{},
+		ComponentsByPhase: map[string]int{},
+		Storage:           StorageTotals{ByStorageClass: map[string]string{}},
+		Backups:           BackupTotals{ByPhase: map[string]int{}},
+		OpsInProgress:     []OverviewItem{},
+	}
+
+	ns, err := client.clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to get namespace: %v", err))
+	} else if !ns.CreationTimestamp.IsZero() {
+		report.CreationTime = ns.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	clusters, err := listInAllowedNamespaces(client, clusterGVR, namespace)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to list clusters: %v", err))
+	}
+	for i := range clusters {
+		report.Clusters = append(report.Clusters, summarizeKubeBlocksCluster(&clusters[i]))
+	}
+	sort.Slice(report.Clusters, func(i, j int) bool { return report.Clusters[i].Name < report.Clusters[j].Name })
+
+	components, err := listInAllowedNamespaces(client, componentGVR, namespace)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to list components: %v", err))
+	}
+	for i := range components {
+		phase, _, _ := unstructured.NestedString(components[i].Object, "status", "phase")
+		report.ComponentsByPhase[defaultString(phase, "Unknown")]++
+	}
+	report.Components = len(components)
+
+	pvcs, err := listInAllowedNamespaces(client, pvcGVR, namespace)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to list PersistentVolumeClaims: %v", err))
+	}
+	requested, capacity := resource.Quantity{}, resource.Quantity{}
+	byClass := map[string]*resource.Quantity{}
+	for i := range pvcs {
+		storageClass, _, _ := unstructured.NestedString(pvcs[i].Object, "spec", "storageClassName")
+		storageClass = defaultString(storageClass, "default")
+		if byClass[storageClass] == nil {
+			byClass[storageClass] = &resource.Quantity{}
+		}
+		if quantity := addQuantity(&requested, pvcs[i].Object, "spec", "resources", "requests", "storage"); quantity != nil {
+			byClass[storageClass].Add(*quantity)
+		}
+		addQuantity(&capacity, pvcs[i].Object, "status", "capacity", "storage")
+	}
+	report.Storage.Claims = len(pvcs)
+	report.Storage.Requested = formatStorageSize(requested)
+	report.Storage.Capacity = formatStorageSize(capacity)
+	for storageClass, quantity := range byClass {
+		report.Storage.ByStorageClass[storageClass] = formatStorageSize(*quantity)
+	}
+
+	backups, err := listInAllowedNamespaces(client, backupGVR, namespace)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to list Backups: %v", err))
+	}
+	totalSize := resource.Quantity{}
+	for i := range backups {
+		phase, _, _ := unstructured.NestedString(backups[i].Object, "status", "phase")
+		report.Backups.ByPhase[defaultString(phase, "Unknown")]++
+		if phase != "Completed" {
+			continue
+		}
+		addQuantity(&totalSize, backups[i].Object, "status", "totalSize")
+		if completed, _, _ := unstructured.NestedString(backups[i].Object, "status", "completionTimestamp"); completed > report.Backups.LastCompleted {
+			report.Backups.LastCompleted = completed
+		}
+	}
+	report.Backups.Backups = len(backups)
+	report.Backups.TotalSize = formatStorageSize(totalSize)
+
+	opsRequests, err := listInAllowedNamespaces(client, opsRequestGVR, namespace)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Unable to list OpsRequests: %v", err))
+	}
+	for i := range opsRequests {
+		phase, _, _ := unstructured.NestedString(opsRequests[i].Object, "status", "phase")
+		if runningOpsPhases[phase] {
+			startTime, _, _ := unstructured.NestedString(opsRequests[i].Object, "status", "startTimestamp")
+			report.OpsInProgress = append(report.OpsInProgress, newOverviewItem(&opsRequests[i], startTime))
+		}
+	}
+	sort.Slice(report.OpsInProgress, func(i, j int) bool { return report.OpsInProgress[i].Name < report.OpsInProgress[j].Name })
+
+	return report
+}
+
+// sortedCounts formats counts by key as "Running 2, Failed 1", largest first
+func sortedCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s %d", key, counts[key]))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// renderNamespaceReport writes the report as a Markdown document
+func renderNamespaceReport(report *NamespaceReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# KubeBlocks report of namespace %s\n\n", report.Namespace)
+	if report.CreationTime != "" {
+		fmt.Fprintf(&b, "- Namespace created: %s\n", report.CreationTime)
+	}
+	fmt.Fprintf(&b, "- Report generated: %s\n", report.GeneratedAt)
+	fmt.Fprintf(&b, "- Clusters: %d\n", len(report.Clusters))
+	fmt.Fprintf(&b, "- Components: %d (%s)\n\n", report.Components, sortedCounts(report.ComponentsByPhase))
+
+	b.WriteString("## Clusters\n\n")
+	if len(report.Clusters) == 0 {
+		b.WriteString("No clusters.\n\n")
+	} else {
+		b.WriteString("| Cluster | Phase | Definition | Termination policy | Created | Components |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, cluster := range report.Clusters {
+			components := make([]string, 0, len(cluster.Components))
+			for _, component := range cluster.Components {
+				entry := fmt.Sprintf("%s (%s, %d replicas", component.Name, defaultString(component.ComponentDef, "-"), component.Replicas)
+				if component.Shards > 0 {
+					entry += fmt.Sprintf(" × %d shards", component.Shards)
+				}
+				components = append(components, entry+")")
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", cluster.Name, defaultString(cluster.Phase, "Unknown"),
+				defaultString(cluster.ClusterDef, "-"), defaultString(cluster.TerminationPolicy, "-"), cluster.CreationTime, strings.Join(components, "<br>"))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Storage\n\n")
+	fmt.Fprintf(&b, "- Volume claims: %d\n", report.Storage.Claims)
+	fmt.Fprintf(&b, "- Requested: %s\n", report.Storage.Requested)
+	fmt.Fprintf(&b, "- Capacity: %s\n", report.Storage.Capacity)
+	classes := make([]string, 0, len(report.Storage.ByStorageClass))
+	for storageClass := range report.Storage.ByStorageClass {
+		classes = append(classes, storageClass)
+	}
+	sort.Strings(classes)
+	for _, storageClass := range classes {
+		fmt.Fprintf(&b, "- Storage class %s: %s\n", storageClass, report.Storage.ByStorageClass[storageClass])
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Backups\n\n")
+	fmt.Fprintf(&b, "- Backups: %d (%s)\n", report.Backups.Backups, sortedCounts(report.Backups.ByPhase))
+	fmt.Fprintf(&b, "- Total size of completed backups: %s\n", report.Backups.TotalSize)
+	fmt.Fprintf(&b, "- Last completed: %s\n\n", defaultString(report.Backups.LastCompleted, "never"))
+
+	b.WriteString("## Operations in progress\n\n")
+	if len(report.OpsInProgress) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, ops := range report.OpsInProgress {
+		fmt.Fprintf(&b, "- %s on cluster %s: %s since %s\n", ops.Name, defaultString(ops.Cluster, "-"), ops.Status, defaultString(ops.Time, "-"))
+	}
+
+	if len(report.Warnings) > 0 {
+		b.WriteString("\n## Warnings\n\n")
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(&b, "- %s\n", warning)
+		}
+	}
+	return b.String()
+}
+
+// getNamespaceReport summarizes the KubeBlocks footprint of a namespace for handover documentation:
+// its clusters and components, storage and backup totals and the OpsRequests in progress. It answers
+// JSON, or a Markdown document with ?format=markdown or Accept: text/markdown.
+func getNamespaceReport(c *gin.Context) {
+	namespace := c.Param("ns")
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/markdown") {
+		format = reportFormatMarkdown
+	}
+	if format != "" && format != "json" && format != reportFormatMarkdown {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported report format: %s", format)})
+		return
+	}
+
+	log.Printf("Building report of namespace '%s' requested from %s", namespace, c.ClientIP())
+
+	report := buildNamespaceReport(clientFor(c), namespace)
+	log.Printf("Namespace report: %d clusters, %d components, %d volume claims (%s), %d backups, %d OpsRequests in progress",
+		len(report.Clusters), report.Components, report.Storage.Claims, report.Storage.Requested, report.Backups.Backups, len(report.OpsInProgress))
+
+	if format == reportFormatMarkdown {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderNamespaceReport(report)))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Object storage providers of asynchronous exports
+const (
+	StorageProviderS3  = "s3"
+	StorageProviderGCS = "gcs"
+)
+
+// gcsEndpoint is the S3-compatible XML API of Google Cloud Storage, used with HMAC keys
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// ExportStorageConfig configures the bucket asynchronous exports are written to
+type ExportStorageConfig struct {
+	// Provider is s3 or gcs; empty disables asynchronous exports
+	Provider string `json:"provider"`
+	Bucket   string `json:"bucket"`
+	// Prefix is prepended to the object keys, e.g. "kb-viz/exports/"
+	Prefix string `json:"prefix"`
+	// Region of the bucket, defaults to us-east-1 for s3 and auto for gcs
+	Region string `json:"region"`
+	// Endpoint overrides the service endpoint, e.g. for MinIO
+	Endpoint string `json:"endpoint"`
+	// PathStyle addresses the bucket in the path instead of the host name (always used for gcs and custom endpoints)
+	PathStyle bool `json:"pathStyle"`
+	// AccessKeyID and SecretAccessKey are the S3 credentials or GCS HMAC key, usually set from the environment
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	// URLExpirySeconds is how long signed download URLs are valid
+	URLExpirySeconds int `json:"urlExpirySeconds"`
+}
+
+// artifactStore stores export artifacts and signs URLs to download them
+type artifactStore interface {
+	// Put uploads the body of the given size and SHA-256 under the key
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64, sha256Hex string) error
+	// SignedURL returns a URL downloading the object until it expires
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// newArtifactStore creates the store of the configured provider, nil when none is configured
+func newArtifactStore(config ExportStorageConfig) (artifactStore, error) {
+	if config.Provider == "" {
+		return nil, nil
+	}
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("export storage needs a bucket and credentials")
+	}
+	store := &s3Store{config: config, httpClient: &http.Client{}}
+	switch config.Provider {
+	case StorageProviderS3:
+		store.config.Region = defaultString(config.Region, "us-east-1")
+		if config.Endpoint == "" {
+			store.config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", store.config.Region)
+		} else {
+			store.config.PathStyle = true
+		}
+	case StorageProviderGCS:
+		store.config.Region = defaultString(config.Region, "auto")
+		store.config.Endpoint = defaultString(config.Endpoint, gcsEndpoint)
+		store.config.PathStyle = true
+	default:
+		return nil, fmt.Errorf("unsupported export storage provider %q, use s3 or gcs", config.Provider)
+	}
+	if _, err := url.Parse(store.config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid export storage endpoint %q: %v", store.config.Endpoint, err)
+	}
+	return store, nil
+}
+
+// s3Store talks to S3 and S3-compatible APIs, including the XML API of GCS, with Signature Version 4
+type s3Store struct {
+	config     ExportStorageConfig
+	httpClient *http.Client
+}
+
+// objectURL returns the URL of the object, virtual-hosted or path-style
+func (s *s3Store) objectURL(key string) *url.URL {
+	endpoint, _ := url.Parse(s.config.Endpoint)
+	objectPath := "/" + s.config.Prefix + key
+	if s.config.PathStyle {
+		objectPath = "/" + s.config.Bucket + objectPath
+	} else {
+		endpoint.Host = s.config.Bucket + "." + endpoint.Host
+	}
+	return &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: objectPath, RawPath: uriEncode(objectPath, false)}
+}
+
+func (s *s3Store) Put(ctx context.Context, key, contentType string, body io.Reader, size int64, sha256Hex string) error {
+	target := s.objectURL(key)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", contentType)
+
+	now := time.Now().UTC()
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", sha256Hex)
+	headers := map[string]string{
+		"content-type":         contentType,
+		"host":                 target.Host,
+		"x-amz-content-sha256": sha256Hex,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	signedHeaders, signature := s.sign(http.MethodPut, target, url.Values{}, headers, sha256Hex, now)
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, s.scope(now), signedHeaders, signature))
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return fmt.Errorf("upload of %s failed with %s: %s", key, response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func (s *s3Store) SignedURL(key string, expiry time.Duration) (string, error) {
+	target := s.objectURL(key)
+	now := time.Now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	_, signature := s.sign(http.MethodGet, target, query, map[string]string{"host": target.Host}, "UNSIGNED-PAYLOAD", now)
+	query.Set("X-Amz-Signature", signature)
+	target.RawQuery = canonicalQuery(query)
+	return target.String(), nil
+}
+
+// scope is the credential scope of Signature Version 4
+func (s *s3Store) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.config.Region)
+}
+
+// sign computes the Signature Version 4 of a request, returning the signed header names and the signature
+func (s *s3Store) sign(method string, target *url.URL, query url.Values, headers map[string]string, payloadHash string, now time.Time) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{method, uriEncode(target.Path, false), canonicalQuery(query), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), s.scope(now), hex.EncodeToString(hashedRequest[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	for _, part := range []string{s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as Signature Version 4 expects
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes unless encodeSlash is set
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
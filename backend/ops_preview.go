@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Effects an OpsRequest has on the nodes of a cluster tree
+const (
+	OpsEffectRestart     = "restart"
+	OpsEffectResize      = "resize"
+	OpsEffectScale       = "scale"
+	OpsEffectStop        = "stop"
+	OpsEffectStart       = "start"
+	OpsEffectSwitchover  = "switchover"
+	OpsEffectReconfigure = "reconfigure"
+	OpsEffectExpose      = "expose"
+)
+
+const vctNameLabel = "apps.kubeblocks.io/vct-name"
+
+// opsSpecFields maps each OpsRequest type to the spec field listing its components
+var opsSpecFields = map[string][]string{
+	"Restart":           {"spec", "restart"},
+	"VerticalScaling":   {"spec", "verticalScaling"},
+	"HorizontalScaling": {"spec", "horizontalScaling"},
+	"VolumeExpansion":   {"spec", "volumeExpansion"},
+	"Upgrade":           {"spec", "upgrade", "components"},
+	"Reconfiguring":     {"spec", "reconfigures"},
+	"Switchover":        {"spec", "switchover"},
+	"Stop":              {"spec", "stop"},
+	"Start":             {"spec", "start"},
+	"Expose":            {"spec", "expose"},
+}
+
+// OpsImpact is a tree node affected by an OpsRequest
+type OpsImpact struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	Component string `json:"component,omitempty"`
+	Effect    string `json:"effect"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// OpsPreview describes what an OpsRequest would change in its cluster
+type OpsPreview struct {
+	Type       string      `json:"type"`
+	Cluster    string      `json:"cluster"`
+	Namespace  string      `json:"namespace"`
+	DryRun     bool        `json:"dryRun"` // whether the server-side dry-run accepted the OpsRequest
+	Components []string    `json:"components"`
+	Impacts    []OpsImpact `json:"impacts"`
+	Warnings   []string    `json:"warnings,omitempty"`
+}
+
+// opsComponentItems returns the per-component items of the OpsRequest, keyed by component name
+func opsComponentItems(ops *unstructured.Unstructured, opsType string) map[string]map[string]interface{} {
+	items := map[string]map[string]interface{}{}
+	field, ok := opsSpecFields[opsType]
+	if !ok {
+		return items
+	}
+	list, _, _ := unstructured.NestedSlice(ops.Object, field...)
+	for _, item := range list {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(itemMap, "componentName")
+		if name == "" {
+			name, _, _ = unstructured.NestedString(itemMap, "componentSpecName")
+		}
+		if name != "" {
+			items[name] = itemMap
+		}
+	}
+	return items
+}
+
+// analyzeOpsImpact matches the components of the OpsRequest against the current cluster tree
+func analyzeOpsImpact(ops *unstructured.Unstructured, opsType string, root *ResourceTreeNode) ([]string, []OpsImpact) {
+	items := opsComponentItems(ops, opsType)
+
+	// Stop, Start and Restart without components apply to the whole cluster
+	wholeCluster := len(items) == 0
+	var components []string
+	for name := range items {
+		components = append(components, name)
+	}
+	sort.Strings(components)
+
+	var impacts []OpsImpact
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		resource := node.Resource
+		component := resource.GetLabels()[componentNameLabel]
+		item, selected := items[component]
+		if component != "" && (selected || wholeCluster) {
+			if effect, detail := opsNodeEffect(opsType, resource, item); effect != "" {
+				impacts = append(impacts, OpsImpact{
+					Kind:      resource.GetKind(),
+					Name:      resource.GetName(),
+					UID:       string(resource.GetUID()),
+					Component: component,
+					Effect:    effect,
+					Detail:    detail,
+				})
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return components, impacts
+}
+
+// opsNodeEffect returns the effect of the OpsRequest on one resource of a selected component
+func opsNodeEffect(opsType string, resource *unstructured.Unstructured, item map[string]interface{}) (string, string) {
+	kind := resource.GetKind()
+	switch opsType {
+	case "Restart", "Upgrade":
+		if kind == "Pod" {
+			return OpsEffectRestart, ""
+		}
+	case "VerticalScaling":
+		if kind == "Pod" {
+			requests, _, _ := unstructured.NestedStringMap(item, "requests")
+			limits, _, _ := unstructured.NestedStringMap(item, "limits")
+			return OpsEffectRestart, fmt.Sprintf("recreated with requests %v and limits %v", requests, limits)
+		}
+	case "Reconfiguring":
+		if kind == "Pod" {
+			return OpsEffectReconfigure, "may restart if a changed parameter is not dynamic"
+		}
+	case "Switchover":
+		if kind == "Pod" {
+			return OpsEffectSwitchover, "role may change"
+		}
+	case "Stop":
+		if kind == "Pod" {
+			return OpsEffectStop, "deleted"
+		}
+	case "Start":
+		if kind == "InstanceSet" {
+			return OpsEffectStart, "pods recreated"
+		}
+	case "HorizontalScaling":
+		if kind == "InstanceSet" {
+			current, _, _ := unstructured.NestedInt64(resource.Object, "spec", "replicas")
+			if replicas, found, _ := unstructured.NestedInt64(item, "replicas"); found {
+				return OpsEffectScale, fmt.Sprintf("replicas %d -> %d", current, replicas)
+			}
+			scaleOut, _, _ := unstructured.NestedInt64(item, "scaleOut", "replicaChanges")
+			scaleIn, _, _ := unstructured.NestedInt64(item, "scaleIn", "replicaChanges")
+			return OpsEffectScale, fmt.Sprintf("replicas %d -> %d", current, current+scaleOut-scaleIn)
+		}
+	case "VolumeExpansion":
+		if kind == "PersistentVolumeClaim" {
+			templates, _, _ := unstructured.NestedSlice(item, "volumeClaimTemplates")
+			for _, template := range templates {
+				templateMap, ok := template.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _, _ := unstructured.NestedString(templateMap, "name")
+				if resource.GetLabels()[vctNameLabel] != name && !strings.HasPrefix(resource.GetName(), name+"-") {
+					continue
+				}
+				storage, _, _ := unstructured.NestedString(templateMap, "storage")
+				current, _, _ := unstructured.NestedString(resource.Object, "spec", "resources", "requests", "storage")
+				return OpsEffectResize, fmt.Sprintf("storage %s -> %s", current, storage)
+			}
+		}
+	case "Expose":
+		if kind == "Service" {
+			return OpsEffectExpose, "service may be added or removed"
+		}
+	}
+	return "", ""
+}
+
+func previewOpsRequest(c *gin.Context) {
+	// Decode through the unstructured JSON scheme so integers stay int64
+	body, err := c.GetRawData()
+	ops := &unstructured.Unstructured{}
+	if err == nil {
+		body, err = yaml.YAMLToJSON(body)
+	}
+	if err == nil {
+		err = ops.UnmarshalJSON(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid OpsRequest manifest: %v", err)})
+		return
+	}
+	if ops.GetKind() != "OpsRequest" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Expected an OpsRequest, got %q", ops.GetKind())})
+		return
+	}
+
+	namespace := defaultString(ops.GetNamespace(), c.Query("namespace"))
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace is required for previewing an OpsRequest"})
+		return
+	}
+	if !appConfig.namespaceAllowed(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", namespace)})
+		return
+	}
+	ops.SetNamespace(namespace)
+	if ops.GetName() == "" && ops.GetGenerateName() == "" {
+		ops.SetGenerateName("preview-")
+	}
+
+	opsType, _, _ := unstructured.NestedString(ops.Object, "spec", "type")
+	clusterName, _, _ := unstructured.NestedString(ops.Object, "spec", "clusterName")
+	if clusterName == "" {
+		clusterName, _, _ = unstructured.NestedString(ops.Object, "spec", "clusterRef")
+	}
+	if clusterName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "spec.clusterName is required"})
+		return
+	}
+
+	log.Printf("Previewing %s OpsRequest on cluster %s in namespace '%s' requested from %s", opsType, clusterName, namespace, c.ClientIP())
+
+	preview := OpsPreview{
+		Type:      opsType,
+		Cluster:   clusterName,
+		Namespace: namespace,
+	}
+	if _, ok := opsSpecFields[opsType]; !ok {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("Impact analysis is not available for OpsRequest type %q", opsType))
+	}
+
+	// Let the API server and the KubeBlocks webhooks validate the OpsRequest without persisting it
+	client := clientFor(c)
+	_, err = client.dynamicClient.Resource(opsRequestGVR).Namespace(namespace).Create(context.TODO(), ops, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	switch {
+	case err == nil:
+		preview.DryRun = true
+	case errors.IsInvalid(err) || errors.IsBadRequest(err):
+		log.Printf("OpsRequest rejected by server-side dry-run: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	default:
+		log.Printf("⚠️  Server-side dry-run of OpsRequest failed: %v", err)
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("Server-side dry-run unavailable: %v", err))
+	}
+
+	rootTreeNode, _, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview.Components, preview.Impacts = analyzeOpsImpact(ops, opsType, rootTreeNode)
+	if preview.Components == nil {
+		preview.Components = []string{}
+	}
+	if preview.Impacts == nil {
+		preview.Impacts = []OpsImpact{}
+	}
+
+	log.Printf("OpsRequest preview on cluster %s: %d affected nodes", clusterName, len(preview.Impacts))
+	c.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const overviewBackupWindow = 24 * time.Hour
+
+var backupGVR = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"}
+
+// runningOpsPhases are the OpsRequest phases of requests still in progress
+var runningOpsPhases = map[string]bool{
+	"Pending":    true,
+	"Creating":   true,
+	"Running":    true,
+	"Cancelling": true,
+}
+
+// OverviewItem is a resource listed on the landing page
+type OverviewItem struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Cluster   string `json:"cluster,omitempty"`
+	Status    string `json:"status"`
+	Time      string `json:"time,omitempty"`
+}
+
+// Overview is the fleet summary of the landing page
+type Overview struct {
+	Namespace           string         `json:"namespace,omitempty"`
+	Clusters            int            `json:"clusters"`
+	ClustersByPhase     map[string]int `json:"clustersByPhase"`
+	RunningOpsRequests  []OverviewItem `json:"runningOpsRequests"`
+	FailedBackups       []OverviewItem `json:"failedBackups"`
+	UnhealthyComponents []OverviewItem `json:"unhealthyComponents"`
+	Warnings            []string       `json:"warnings,omitempty"`
+}
+
+// listInAllowedNamespaces lists a resource type in the namespace, or in every allowed namespace when it is empty
+func listInAllowedNamespaces(client *K8sClient, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	return listInAllowedNamespacesWith(client, gvr, namespace, metav1.ListOptions{})
+}
+
+// listInAllowedNamespacesWith is listInAllowedNamespaces with list options, e.g. a label selector
+func listInAllowedNamespacesWith(client *K8sClient, gvr schema.GroupVersionResource, namespace string, options metav1.ListOptions) ([]unstructured.Unstructured, error) {
+	namespaces := []string{namespace}
+	if namespace == "" {
+		namespaces = appConfig.WatchNamespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{metav1.NamespaceAll}
+		}
+	}
+
+	var items []unstructured.Unstructured
+	for _, ns := range namespaces {
+		list, err := client.dynamicClient.Resource(gvr).Namespace(ns).List(context.TODO(), options)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, list.Items...)
+	}
+	return items, nil
+}
+
+func newOverviewItem(resource *unstructured.Unstructured, timestamp string) OverviewItem {
+	return OverviewItem{
+		Kind:      resource.GetKind(),
+		Name:      resource.GetName(),
+		Namespace: resource.GetNamespace(),
+		Cluster:   resource.GetLabels()[instanceLabel],
+		Status:    resourceStatus(resource),
+		Time:      timestamp,
+	}
+}
+
+func getOverview(c *gin.Context) {
+	namespace := c.Query("namespace")
+	log.Printf("Fetching overview of namespace '%s' requested from %s", namespace, c.ClientIP())
+
+	client := clientFor(c)
+	overview := Overview{
+		Namespace:           namespace,
+		ClustersByPhase:     map[string]int{},
+		RunningOpsRequests:  []OverviewItem{},
+		FailedBackups:       []OverviewItem{},
+		UnhealthyComponents: []OverviewItem{},
+	}
+
+	// Missing CRDs or permissions leave a section empty with a warning. The webhook only saw
+	// /overview, so every listed type is reviewed as a list in the namespace.
+	listAuthorized := func(gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+		if err := resourceAccessAllowed(c, "list", gvr, "", namespace); err != nil {
+			return nil, err
+		}
+		return listInAllowedNamespaces(client, gvr, namespace)
+	}
+	clusters, err := listAuthorized(clusterGVR)
+	if err != nil {
+		overview.Warnings = append(overview.Warnings, fmt.Sprintf("Unable to list clusters: %v", err))
+	}
+	for i := range clusters {
+		phase, _, _ := unstructured.NestedString(clusters[i].Object, "status", "phase")
+		overview.ClustersByPhase[defaultString(phase, "Unknown")]++
+	}
+	overview.Clusters = len(clusters)
+
+	opsRequests, err := listAuthorized(opsRequestGVR)
+	if err != nil {
+		overview.Warnings = append(overview.Warnings, fmt.Sprintf("Unable to list OpsRequests: %v", err))
+	}
+	for i := range opsRequests {
+		phase, _, _ := unstructured.NestedString(opsRequests[i].Object, "status", "phase")
+		if runningOpsPhases[phase] {
+			startTime, _, _ := unstructured.NestedString(opsRequests[i].Object, "status", "startTimestamp")
+			overview.RunningOpsRequests = append(overview.RunningOpsRequests, newOverviewItem(&opsRequests[i], startTime))
+		}
+	}
+
+	backups, err := listAuthorized(backupGVR)
+	if err != nil {
+		overview.Warnings = append(overview.Warnings, fmt.Sprintf("Unable to list Backups: %v", err))
+	}
+	cutoff := time.Now().Add(-overviewBackupWindow)
+	for i := range backups {
+		phase, _, _ := unstructured.NestedString(backups[i].Object, "status", "phase")
+		if phase != "Failed" {
+			continue
+		}
+		failedAt := backups[i].GetCreationTimestamp().Time
+		if value, found, _ := unstructured.NestedString(backups[i].Object, "status", "completionTimestamp"); found {
+			if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+				failedAt = parsed
+			}
+		}
+		if failedAt.After(cutoff) {
+			overview.FailedBackups = append(overview.FailedBackups, newOverviewItem(&backups[i], failedAt.Format(time.RFC3339)))
+		}
+	}
+	sort.Slice(overview.FailedBackups, func(i, j int) bool {
+		return overview.FailedBackups[i].Time > overview.FailedBackups[j].Time
+	})
+
+	components, err := listAuthorized(componentGVR)
+	if err != nil {
+		overview.Warnings = append(overview.Warnings, fmt.Sprintf("Unable to list components: %v", err))
+	}
+	for i := range components {
+		if computeHealth(&components[i]) == HealthDegraded {
+			overview.UnhealthyComponents = append(overview.UnhealthyComponents, newOverviewItem(&components[i], ""))
+		}
+	}
+
+	log.Printf("Overview: %d clusters, %d running OpsRequests, %d failed backups, %d unhealthy components",
+		overview.Clusters, len(overview.RunningOpsRequests), len(overview.FailedBackups), len(overview.UnhealthyComponents))
+	c.JSON(http.StatusOK, overview)
+}
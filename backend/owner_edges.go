@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Edge type linking a resource to an owner other than the one it is placed under
+const EdgeTypeSecondaryOwner = api.EdgeTypeSecondaryOwner
+
+// PrimaryOwner returns the owner a resource is placed under in the tree: its controller
+// when that is in the pool, otherwise the first owner in the pool. Resources without owner
+// references are placed under their annotated parent.
+func (rp *ResourcePool) PrimaryOwner(resource *unstructured.Unstructured) types.UID {
+	if len(resource.GetOwnerReferences()) == 0 {
+		return rp.parents[resource.GetUID()]
+	}
+	var first types.UID
+	for _, ref := range resource.GetOwnerReferences() {
+		if rp.resources[ref.UID] == nil {
+			continue
+		}
+		if ref.Controller != nil && *ref.Controller {
+			return ref.UID
+		}
+		if first == "" {
+			first = ref.UID
+		}
+	}
+	return first
+}
+
+// SplitChildrenByPrimaryOwner returns the children placed under the owner, and those that
+// are placed under another, primary owner
+func (rp *ResourcePool) SplitChildrenByPrimaryOwner(ownerUID types.UID) ([]*unstructured.Unstructured, []*unstructured.Unstructured) {
+	var primary, secondary []*unstructured.Unstructured
+	for _, child := range rp.byOwner[ownerUID] {
+		if rp.PrimaryOwner(child) == ownerUID {
+			primary = append(primary, child)
+		} else {
+			secondary = append(secondary, child)
+		}
+	}
+	return primary, secondary
+}
+
+// missingParent is a parent annotation that does not resolve to a resource of the pool
+type missingParent struct {
+	resource *unstructured.Unstructured
+	parent   string
+}
+
+// LinkAnnotatedParents places resources without owner references under the resource named by
+// their parent annotation (<kind>/<name> in the same namespace). It returns the annotations that
+// do not resolve to a resource of the pool, to be reported as warnings.
+func (rp *ResourcePool) LinkAnnotatedParents() []missingParent {
+	byName := map[string]*unstructured.Unstructured{}
+	for _, resource := range rp.resources {
+		byName[strings.ToLower(resource.GetNamespace()+"/"+resource.GetKind()+"/"+resource.GetName())] = resource
+	}
+
+	var missing []missingParent
+	for uid, resource := range rp.resources {
+		value := resource.GetAnnotations()[vizParentAnnotation]
+		if value == "" || len(resource.GetOwnerReferences()) > 0 || rp.parents[uid] != "" {
+			continue
+		}
+		parent := byName[strings.ToLower(resource.GetNamespace()+"/"+value)]
+		if parent == nil || parent.GetUID() == uid {
+			missing = append(missing, missingParent{resource: resource, parent: value})
+			continue
+		}
+		rp.parents[uid] = parent.GetUID()
+		rp.byOwner[parent.GetUID()] = append(rp.byOwner[parent.GetUID()], resource)
+	}
+	return missing
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// permissionCheckConcurrency bounds the SelfSubjectAccessReviews in flight
+const permissionCheckConcurrency = 8
+
+// PermissionRow is the result of the access reviews of one resource in one namespace
+type PermissionRow struct {
+	Group       string `json:"group"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// Namespace is empty for cluster-scoped resources and for namespaced ones checked in all namespaces
+	Namespace string `json:"namespace,omitempty"`
+	// InTree marks the types the tree builder lists, missing permissions hide them from trees
+	InTree bool            `json:"inTree"`
+	Verbs  map[string]bool `json:"verbs"`
+	// Reasons explains denied verbs when the API server gives a reason
+	Reasons map[string]string `json:"reasons,omitempty"`
+}
+
+// PermissionReport is the response of /api/permissions/check
+type PermissionReport struct {
+	Cluster string          `json:"cluster"`
+	Allowed bool            `json:"allowed"`
+	Checked int             `json:"checked"`
+	Denied  int             `json:"denied"`
+	Rows    []PermissionRow `json:"rows"`
+}
+
+// permissionCheck is a single access review of a row
+type permissionCheck struct {
+	row  *PermissionRow
+	verb string
+}
+
+// denied reports whether a verb of the row is denied
+func (row *PermissionRow) denied() bool {
+	for _, allowed := range row.Verbs {
+		if !allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionRows expands the rules of the server's role into rows checked in each namespace, one
+// row per resource with the verbs of all rules granting it
+func permissionRows(rules []rbacv1.PolicyRule, namespaces []string, treeTypes map[string]bool) []*PermissionRow {
+	var rows []*PermissionRow
+	byKey := map[string]*PermissionRow{}
+	for _, namespace := range namespaces {
+		for _, rule := range rules {
+			for _, group := range rule.APIGroups {
+				for _, name := range rule.Resources {
+					resource, subresource, _ := strings.Cut(name, "/")
+					key := namespace + "|" + group + "|" + name
+					row := byKey[key]
+					if row == nil {
+						row = &PermissionRow{Group: group, Resource: resource, Subresource: subresource, Namespace: namespace,
+							InTree: subresource == "" && treeTypes[group+"/"+resource], Verbs: map[string]bool{}}
+						byKey[key] = row
+						rows = append(rows, row)
+					}
+					for _, verb := range rule.Verbs {
+						row.Verbs[verb] = false
+					}
+				}
+			}
+		}
+	}
+	return rows
+}
+
+// checkPermissions runs a SelfSubjectAccessReview for every verb of the rows
+func checkPermissions(client *K8sClient, rows []*PermissionRow) {
+	var checks []permissionCheck
+	for _, row := range rows {
+		for verb := range row.Verbs {
+			checks = append(checks, permissionCheck{row: row, verb: verb})
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, permissionCheckConcurrency)
+	for _, check := range checks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(check permissionCheck) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			review := &authorizationv1.SelfSubjectAccessReview{Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   check.row.Namespace,
+					Verb:        check.verb,
+					Group:       check.row.Group,
+					Resource:    check.row.Resource,
+					Subresource: check.row.Subresource,
+				},
+			}}
+			allowed, reason := false, ""
+			result, err := client.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), review, metav1.CreateOptions{})
+			if err != nil {
+				reason = fmt.Sprintf("access review failed: %v", err)
+			} else {
+				allowed = result.Status.Allowed && !result.Status.Denied
+				reason = defaultString(result.Status.Reason, result.Status.EvaluationError)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			check.row.Verbs[check.verb] = allowed
+			if !allowed && reason != "" {
+				if check.row.Reasons == nil {
+					check.row.Reasons = map[string]string{}
+				}
+				check.row.Reasons[check.verb] = reason
+			}
+		}(check)
+	}
+	wg.Wait()
+}
+
+// getPermissionsCheck reviews the access of the server's credentials to everything it reads, and
+// writes when writes are enabled, the same permissions `kb-viz install` grants. Namespaced
+// permissions are checked in namespace=, in each namespace of the allowlist, or in all namespaces.
+func getPermissionsCheck(c *gin.Context) {
+	client := clientFor(c)
+	log.Printf("Checking permissions of cluster %s requested from %s", client.name, c.ClientIP())
+
+	namespaces := []string{""}
+	if namespace := c.Query("namespace"); namespace != "" {
+		namespaces = []string{namespace}
+	} else if len(appConfig.WatchNamespaces) > 0 {
+		namespaces = appConfig.WatchNamespaces
+	}
+
+	treeTypes := map[string]bool{}
+	for _, gvr := range currentTreeResourceTypes() {
+		treeTypes[gvr.Group+"/"+gvr.Resource] = true
+	}
+	namespaced, clusterScoped := installReadResources(appConfig)
+	rows := append(permissionRows(namespacedPolicyRules(appConfig, namespaced), namespaces, treeTypes),
+		permissionRows(policyRulesFor(clusterScoped, installReadVerbs), []string{""}, treeTypes)...)
+	checkPermissions(client, rows)
+
+	// Denied rows first, so restrictive RBAC is diagnosed at a glance
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].denied() && !rows[j].denied() })
+	report := PermissionReport{Cluster: client.name, Allowed: true, Rows: make([]PermissionRow, 0, len(rows))}
+	for _, row := range rows {
+		for _, allowed := range row.Verbs {
+			report.Checked++
+			if !allowed {
+				report.Denied++
+				report.Allowed = false
+			}
+		}
+		report.Rows = append(report.Rows, *row)
+	}
+
+	log.Printf("Permission check of cluster %s: %d of %d access reviews denied", client.name, report.Denied, report.Checked)
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/internal/retry"
+)
+
+const (
+	defaultMaxAttempts = 3
+	// watchTimeoutSeconds is how long one long-poll of WatchTree waits for a change
+	watchTimeoutSeconds = 30
+)
+
+// Client calls the visualizer API. Requests are retried on transport errors, 429 and gateway
+// errors with jittered exponential backoff.
+type Client struct {
+	// BaseURL is the address of the server, e.g. http://localhost:8080
+	BaseURL string
+	// Cluster selects one of the configured clusters, empty for the default one
+	Cluster string
+	// Header is sent with every request, e.g. the user header or the X-Share-Token of a share link
+	Header http.Header
+	// HTTPClient sends the requests. Its Timeout must exceed the long-polls of WatchTree.
+	HTTPClient *http.Client
+	// MaxAttempts bounds the attempts of a request, retries included
+	MaxAttempts int
+}
+
+// NewClient returns a client of the server at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		Header:      http.Header{},
+		HTTPClient:  &http.Client{},
+		MaxAttempts: defaultMaxAttempts,
+	}
+}
+
+// Error is a failed API call, Message is the error returned by the server
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether the error is a 404 of the API
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ListResources lists the resources of a type in a namespace
+func (c *Client) ListResources(ctx context.Context, resourceType, namespace string) ([]ResourceNode, error) {
+	query := url.Values{"namespace": {namespace}}
+	var resources []ResourceNode
+	if _, err := c.get(ctx, "/api/v1/resources/"+url.PathEscape(resourceType), query, &resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// ListResourceColumns lists the resources of a type in a namespace reduced to the columns, given
+// like kubectl custom-columns, e.g. "REPLICAS:.spec.replicas,READY:.status.readyReplicas"
+func (c *Client) ListResourceColumns(ctx context.Context, resourceType, namespace, columns string) (*ResourceColumns, error) {
+	query := url.Values{"namespace": {namespace}, "columns": {columns}}
+	result := &ResourceColumns{}
+	if _, err := c.get(ctx, "/api/v1/resources/"+url.PathEscape(resourceType), query, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTree returns the v2 tree of a root resource and its version. query holds further options of
+// the tree endpoint, e.g. kinds, rbac or timeBudgetMs, and may be nil.
+func (c *Client) GetTree(ctx context.Context, resourceType, name, namespace string, query url.Values) (*TreeV2, string, error) {
+	tree, version, _, err := c.getTree(ctx, resourceType, name, namespace, query, "")
+	return tree, version, err
+}
+
+// WatchTree calls handle with the tree of a root resource, then again every time it changes, until
+// ctx is done or handle returns an error. It long-polls the tree endpoint with waitFor, so it also
+// works through proxies that strip WebSockets and SSE.
+func (c *Client) WatchTree(ctx context.Context, resourceType, name, namespace string, query url.Values, handle func(tree *TreeV2, version string) error) error {
+	version := ""
+	for {
+		tree, newVersion, changed, err := c.getTree(ctx, resourceType, name, namespace, query, version)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if !changed {
+			continue
+		}
+		version = newVersion
+		if err := handle(tree, version); err != nil {
+			return err
+		}
+	}
+}
+
+// getTree gets the tree, long-polling for a change from waitFor when set. changed is false when the
+// long-poll timed out with the tree still at waitFor.
+func (c *Client) getTree(ctx context.Context, resourceType, name, namespace string, query url.Values, waitFor string) (*TreeV2, string, bool, error) {
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = value
+	}
+	values.Set("namespace", namespace)
+	if waitFor != "" {
+		values.Set("waitFor", waitFor)
+		values.Set("timeoutSeconds", strconv.Itoa(watchTimeoutSeconds))
+	}
+
+	path := fmt.Sprintf("/api/v2/resources/%s/%s/tree", url.PathEscape(resourceType), url.PathEscape(name))
+	tree := &TreeV2{}
+	resp, err := c.get(ctx, path, values, tree)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, waitFor, false, nil
+	}
+	return tree, resp.Header.Get(TreeVersionHeader), true, nil
+}
+
+// get sends a GET request, retrying transient failures, and decodes a 200 response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) (*http.Response, error) {
+	if c.Cluster != "" {
+		query.Set("cluster", c.Cluster)
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	maxAttempts := c.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range c.Header {
+			req.Header[key] = values
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := httpClient.Do(req)
+		retryable := attempt < maxAttempts && ctx.Err() == nil &&
+			((err != nil && retry.TransientError(err)) || (err == nil && retry.TransientStatus(resp.StatusCode)))
+		if !retryable {
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			return resp, decodeResponse(resp, out)
+		}
+
+		retryAfter := ""
+		if resp != nil {
+			retryAfter = resp.Header.Get("Retry-After")
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(retry.Backoff(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// decodeResponse decodes a 200 response into out and turns error statuses into an *Error
+func decodeResponse(resp *http.Response, out interface{}) error {
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) != nil || payload.Error == "" {
+		payload.Error = strings.TrimSpace(string(body))
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: payload.Error}
+}
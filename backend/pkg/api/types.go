@@ -0,0 +1,207 @@
+// Package api holds the response types of the visualizer API and a Go client for it, so tools
+// can consume the server without duplicating its types
+package api
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Health statuses of tree nodes
+const (
+	HealthUnknown     = "Unknown"
+	HealthHealthy     = "Healthy"
+	HealthProgressing = "Progressing"
+	HealthDegraded    = "Degraded"
+)
+
+// Edge types of the v2 tree schema
+const (
+	EdgeTypeOwner = "owner"
+	// EdgeTypeSecondaryOwner points from a co-owner to a resource placed under its primary owner
+	EdgeTypeSecondaryOwner = "secondaryOwner"
+	// EdgeTypeReference points to a resource referenced by name, possibly in another namespace
+	EdgeTypeReference = "reference"
+	// EdgeTypeSelector points from a Service to the pods it selects
+	EdgeTypeSelector = "selector"
+)
+
+// TreeVersionHeader carries the version of a tree, sent back with waitFor to long-poll for changes
+const TreeVersionHeader = "X-Tree-Version"
+
+// ResourceNode is the summary of a resource returned by the resource lists and the v2 tree
+type ResourceNode struct {
+	Name         string            `json:"name"`
+	Kind         string            `json:"kind"`
+	APIVersion   string            `json:"apiVersion"`
+	Namespace    string            `json:"namespace,omitempty"`
+	UID          string            `json:"uid"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	CreationTime string            `json:"creationTime"`
+	Status       string            `json:"status,omitempty"`
+	Age          string            `json:"age,omitempty"`
+	LastUpdated  string            `json:"lastUpdated,omitempty"`
+	// DeletionTimestamp is set while the resource is terminating, Finalizers may be holding it
+	DeletionTimestamp string   `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string `json:"finalizers,omitempty"`
+}
+
+// ResourceColumns is a resource list reduced to the fields selected with columns or jsonpath
+type ResourceColumns struct {
+	Columns []string      `json:"columns"`
+	Items   []ResourceRow `json:"items"`
+}
+
+// ResourceRow holds the values of the selected columns of one resource, in the order of the
+// columns. A column selecting nothing is null, one selecting several values is a list.
+type ResourceRow struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace,omitempty"`
+	UID       string        `json:"uid"`
+	Values    []interface{} `json:"values"`
+}
+
+// TreeV2 is the v2 tree response: a flat list of summary nodes connected by edges
+type TreeV2 struct {
+	SchemaVersion string   `json:"schemaVersion"`
+	Root          string   `json:"root"`
+	Health        string   `json:"health"`
+	Nodes         []NodeV2 `json:"nodes"`
+	Edges         []EdgeV2 `json:"edges"`
+	Warnings      []string `json:"warnings"`
+	// Partial trees were cut short by timeBudgetMs, Continue resumes them
+	Partial  bool   `json:"partial,omitempty"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// Node returns the node with the UID, nil when the tree has none
+func (tree *TreeV2) Node(uid string) *NodeV2 {
+	for i := range tree.Nodes {
+		if tree.Nodes[i].UID == uid {
+			return &tree.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Children returns the UIDs of the nodes the edges of a type lead to from the node
+func (tree *TreeV2) Children(uid, edgeType string) []string {
+	var children []string
+	for _, edge := range tree.Edges {
+		if edge.From == uid && edge.Type == edgeType {
+			children = append(children, edge.To)
+		}
+	}
+	return children
+}
+
+// NodeV2 is a summary of one resource in the v2 tree
+type NodeV2 struct {
+	ResourceNode
+	Health         string                     `json:"health"`
+	DisplayName    string                     `json:"displayName,omitempty"`
+	Virtual        bool                       `json:"virtual,omitempty"`
+	Placement      *PodPlacement              `json:"placement,omitempty"`
+	Problems       []Problem                  `json:"problems,omitempty"`
+	Endpoints      *EndpointSummary           `json:"endpoints,omitempty"`
+	Storage        *StorageChain              `json:"storage,omitempty"`
+	Truncated      bool                       `json:"truncated,omitempty"`
+	Deleted        bool                       `json:"deleted,omitempty"`
+	GitOpsSource   *GitOpsSource              `json:"gitopsSource,omitempty"`
+	Layout         *LayoutHint                `json:"layout,omitempty"`
+	DeletedAt      string                     `json:"deletedAt,omitempty"`
+	JobHistory     *JobHistorySummary         `json:"jobHistory,omitempty"`
+	ServiceAccount *ServiceAccountPermissions `json:"serviceAccount,omitempty"`
+	HiddenSystem   int                        `json:"hiddenSystemChildren,omitempty"`
+}
+
+// EdgeV2 connects two nodes of the v2 tree by UID
+type EdgeV2 struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Type           string `json:"type"`
+	CrossNamespace bool   `json:"crossNamespace,omitempty"`
+}
+
+// PodPlacement describes where a pod is scheduled
+type PodPlacement struct {
+	NodeName  string `json:"nodeName,omitempty"`
+	Zone      string `json:"zone,omitempty"`
+	Region    string `json:"region,omitempty"`
+	NodeReady *bool  `json:"nodeReady,omitempty"`
+}
+
+// Problem is an issue detected on a tree node
+type Problem struct {
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+}
+
+// EndpointSummary counts the ready and not-ready backends of a Service
+type EndpointSummary struct {
+	Slices          int      `json:"slices"`
+	Ready           int      `json:"ready"`
+	NotReady        int      `json:"notReady"`
+	NotReadyTargets []string `json:"notReadyTargets,omitempty"`
+}
+
+// StorageChain describes where the data of a PVC lives: PVC -> PV -> StorageClass
+type StorageChain struct {
+	VolumeName        string `json:"volumeName,omitempty"`
+	Capacity          string `json:"capacity,omitempty"`
+	ReclaimPolicy     string `json:"reclaimPolicy,omitempty"`
+	CSIDriver         string `json:"csiDriver,omitempty"`
+	VolumeHandle      string `json:"volumeHandle,omitempty"`
+	StorageClass      string `json:"storageClass,omitempty"`
+	Provisioner       string `json:"provisioner,omitempty"`
+	VolumeBindingMode string `json:"volumeBindingMode,omitempty"`
+	AllowExpansion    bool   `json:"allowVolumeExpansion"`
+}
+
+// GitOpsSource is the GitOps application that applied a resource
+type GitOpsSource struct {
+	Tool      string `json:"tool"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Inherited is set on resources created by a controller from a resource the application applied
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// LayoutHint lets the frontend place a node without walking the tree again
+type LayoutHint struct {
+	Depth int `json:"depth"`
+	// SubtreeSize counts the node and all its descendants
+	SubtreeSize int    `json:"subtreeSize"`
+	Group       string `json:"group"`
+	// Order is the suggested position among the siblings: by group, then kind, then name
+	Order int `json:"order"`
+}
+
+// JobHistorySummary condenses the Jobs of a CronJob that are not shown as children
+type JobHistorySummary struct {
+	Total      int          `json:"total"`
+	Active     int          `json:"active"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	LastRun    *metav1.Time `json:"lastRun,omitempty"`
+	LastState  string       `json:"lastState,omitempty"`
+	LastFailed *metav1.Time `json:"lastFailed,omitempty"`
+}
+
+// ServiceAccountPermissions is the ServiceAccount of a pod and the rules granted to it in its namespace
+type ServiceAccountPermissions struct {
+	Name     string           `json:"name"`
+	Missing  bool             `json:"missing,omitempty"`
+	Bindings []BindingSummary `json:"bindings"`
+}
+
+// BindingSummary is a RoleBinding granting a role to a ServiceAccount
+type BindingSummary struct {
+	Name     string              `json:"name"`
+	RoleKind string              `json:"roleKind"`
+	RoleName string              `json:"roleName"`
+	Rules    []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
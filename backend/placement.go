@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Well-known node topology labels
+const (
+	zoneLabel         = "topology.kubernetes.io/zone"
+	regionLabel       = "topology.kubernetes.io/region"
+	legacyZoneLabel   = "failure-domain.beta.kubernetes.io/zone"
+	legacyRegionLabel = "failure-domain.beta.kubernetes.io/region"
+)
+
+// PodPlacement describes where a pod is scheduled
+type PodPlacement = api.PodPlacement
+
+// PlacementPod is a pod entry in the placement matrix
+type PlacementPod struct {
+	Name      string `json:"name"`
+	Component string `json:"component,omitempty"`
+	Status    string `json:"status"`
+	PodPlacement
+}
+
+// ComponentSpread summarizes how the replicas of one component are spread
+type ComponentSpread struct {
+	Component  string   `json:"component"`
+	Replicas   int      `json:"replicas"`
+	Nodes      int      `json:"nodes"`
+	Zones      int      `json:"zones"`
+	Colocated  bool     `json:"colocated"`
+	SharedNode []string `json:"sharedNodes,omitempty"`
+}
+
+// PlacementMatrix is the pods-by-node/zone view of a cluster
+type PlacementMatrix struct {
+	Cluster    string                      `json:"cluster"`
+	Namespace  string                      `json:"namespace"`
+	Pods       []PlacementPod              `json:"pods"`
+	ByNode     map[string][]string         `json:"byNode"`
+	ByZone     map[string][]string         `json:"byZone"`
+	Components map[string]*ComponentSpread `json:"components"`
+}
+
+// nodeInfo caches the placement-relevant attributes of a node
+type nodeInfo struct {
+	zone   string
+	region string
+	ready  bool
+}
+
+// listNodeInfo loads zone/region labels and readiness of all nodes
+func listNodeInfo(client *K8sClient) (map[string]nodeInfo, error) {
+	nodes, err := client.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]nodeInfo, len(nodes.Items))
+	for _, node := range nodes.Items {
+		info := nodeInfo{
+			zone:   node.Labels[zoneLabel],
+			region: node.Labels[regionLabel],
+		}
+		if info.zone == "" {
+			info.zone = node.Labels[legacyZoneLabel]
+		}
+		if info.region == "" {
+			info.region = node.Labels[legacyRegionLabel]
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				info.ready = condition.Status == corev1.ConditionTrue
+			}
+		}
+		infos[node.Name] = info
+	}
+	return infos, nil
+}
+
+// nodeInfoCacheTTL lets the placement decoration of tree refreshes (long-polls, WebSocket pushes)
+// share one node list rather than listing every node on each of them
+const nodeInfoCacheTTL = 15 * time.Second
+
+var nodeInfoCache = struct {
+	mu      sync.Mutex
+	entries map[string]nodeInfoEntry
+}{entries: map[string]nodeInfoEntry{}}
+
+type nodeInfoEntry struct {
+	listed time.Time
+	nodes  map[string]nodeInfo
+}
+
+// cachedNodeInfo returns the node info of the client, listing the nodes again once the cached
+// list is older than the TTL. Failed lists are not cached.
+func cachedNodeInfo(client *K8sClient) (map[string]nodeInfo, error) {
+	nodeInfoCache.mu.Lock()
+	entry, cached := nodeInfoCache.entries[client.name]
+	nodeInfoCache.mu.Unlock()
+	if cached && time.Since(entry.listed) < nodeInfoCacheTTL {
+		return entry.nodes, nil
+	}
+
+	nodes, err := listNodeInfo(client)
+	if err != nil {
+		return nil, err
+	}
+	nodeInfoCache.mu.Lock()
+	defer nodeInfoCache.mu.Unlock()
+	// Drop the lists of clients no longer used, e.g. expired kubeconfig sessions
+	for name, other := range nodeInfoCache.entries {
+		if time.Since(other.listed) >= nodeInfoCacheTTL {
+			delete(nodeInfoCache.entries, name)
+		}
+	}
+	nodeInfoCache.entries[client.name] = nodeInfoEntry{listed: time.Now(), nodes: nodes}
+	return nodes, nil
+}
+
+// podPlacement computes the placement of a pod from the node info cache
+func podPlacement(pod *unstructured.Unstructured, nodes map[string]nodeInfo) *PodPlacement {
+	nodeName, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName")
+	placement := &PodPlacement{NodeName: nodeName}
+	if info, ok := nodes[nodeName]; ok {
+		ready := info.ready
+		placement.Zone = info.zone
+		placement.Region = info.region
+		placement.NodeReady = &ready
+	}
+	return placement
+}
+
+// DecoratePlacement sets the placement of every pod in the tree. Nodes are only read when the tree
+// has pods, from a list shared for nodeInfoCacheTTL, so zones and readiness may lag behind by that much.
+func (rtb *ResourceTreeBuilder) DecoratePlacement(root *ResourceTreeNode) {
+	var pods []*ResourceTreeNode
+	var collect func(node *ResourceTreeNode)
+	collect = func(node *ResourceTreeNode) {
+		if node.Resource.GetKind() == "Pod" {
+			pods = append(pods, node)
+		}
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(root)
+	if len(pods) == 0 {
+		return
+	}
+
+	nodes, err := cachedNodeInfo(rtb.client)
+	if err != nil {
+		// Node access is optional, pods still get their nodeName
+		log.Printf("⚠️  Unable to list nodes for placement decoration: %v", err)
+		nodes = map[string]nodeInfo{}
+	}
+	for _, pod := range pods {
+		pod.Placement = podPlacement(pod.Resource, nodes)
+	}
+}
+
+func getClusterPlacement(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+
+	log.Printf("Building placement matrix for cluster %s in namespace '%s' requested from %s", clusterName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	rootTreeNode, treeBuilder, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes, err := listNodeInfo(client)
+	if err != nil {
+		log.Printf("Error listing nodes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list nodes: %v", err)})
+		return
+	}
+
+	matrix := &PlacementMatrix{
+		Cluster:    clusterName,
+		Namespace:  namespace,
+		Pods:       []PlacementPod{},
+		ByNode:     map[string][]string{},
+		ByZone:     map[string][]string{},
+		Components: map[string]*ComponentSpread{},
+	}
+
+	componentNodes := map[string]map[string]int{}
+	componentZones := map[string]map[string]bool{}
+	for _, pod := range treeBuilder.GetResourcesByKind(rootTreeNode, "Pod") {
+		placement := podPlacement(pod, nodes)
+		component := pod.GetLabels()[componentNameLabel]
+		matrix.Pods = append(matrix.Pods, PlacementPod{
+			Name:         pod.GetName(),
+			Component:    component,
+			Status:       resourceStatus(pod),
+			PodPlacement: *placement,
+		})
+
+		if placement.NodeName != "" {
+			matrix.ByNode[placement.NodeName] = append(matrix.ByNode[placement.NodeName], pod.GetName())
+		}
+		if placement.Zone != "" {
+			matrix.ByZone[placement.Zone] = append(matrix.ByZone[placement.Zone], pod.GetName())
+		}
+
+		spread := matrix.Components[component]
+		if spread == nil {
+			spread = &ComponentSpread{Component: component}
+			matrix.Components[component] = spread
+			componentNodes[component] = map[string]int{}
+			componentZones[component] = map[string]bool{}
+		}
+		spread.Replicas++
+		if placement.NodeName != "" {
+			componentNodes[component][placement.NodeName]++
+		}
+		if placement.Zone != "" {
+			componentZones[component][placement.Zone] = true
+		}
+	}
+
+	for component, spread := range matrix.Components {
+		spread.Nodes = len(componentNodes[component])
+		spread.Zones = len(componentZones[component])
+		for nodeName, count := range componentNodes[component] {
+			if count > 1 {
+				spread.Colocated = true
+				spread.SharedNode = append(spread.SharedNode, nodeName)
+			}
+		}
+		sort.Strings(spread.SharedNode)
+	}
+
+	sort.Slice(matrix.Pods, func(i, j int) bool { return matrix.Pods[i].Name < matrix.Pods[j].Name })
+
+	log.Printf("Placement matrix for cluster %s: %d pods across %d nodes and %d zones", clusterName, len(matrix.Pods), len(matrix.ByNode), len(matrix.ByZone))
+	c.JSON(http.StatusOK, matrix)
+}
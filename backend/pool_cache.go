@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PoolCacheConfig bounds the pools kept for lazy child expansion, continuations and UID lookups.
+// The least recently used pools are evicted first when a bound is exceeded.
+type PoolCacheConfig struct {
+	// TTLSeconds is how long a built pool is served before it expires
+	TTLSeconds int `json:"ttlSeconds"`
+	// MaxMB bounds the estimated memory of the cached pools (0 disables the bound)
+	MaxMB int `json:"maxMB"`
+	// MaxNamespaces bounds the namespaces with a cached pool, across clusters (0 disables the bound)
+	MaxNamespaces int `json:"maxNamespaces"`
+}
+
+func defaultPoolCacheConfig() PoolCacheConfig {
+	return PoolCacheConfig{
+		TTLSeconds:    30,
+		MaxMB:         256,
+		MaxNamespaces: 50,
+	}
+}
+
+// Reasons a cached pool is evicted, the reason label of kbviz_pool_cache_evictions_total
+const (
+	poolEvictedExpired    = "expired"
+	poolEvictedNamespaces = "namespaces"
+	poolEvictedMemory     = "memory"
+)
+
+type cachedPool struct {
+	pool     *ResourcePool
+	built    time.Time
+	lastUsed time.Time
+	bytes    int64 // Estimated memory of the pool
+}
+
+// poolCache keeps the pools of recent tree builds, keyed by cluster, namespace and selector
+type poolCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedPool
+	bytes   int64
+}
+
+var resourcePools = &poolCache{entries: map[string]*cachedPool{}}
+
+func poolCacheKey(client *K8sClient, namespace string, listOptions metav1.ListOptions) string {
+	return fmt.Sprintf("%s|%s|%s", client.name, namespace, listOptions.LabelSelector)
+}
+
+// poolCacheTTL bounds how long a built pool serves lazy child expansion
+func poolCacheTTL() time.Duration {
+	if appConfig == nil || appConfig.PoolCache.TTLSeconds <= 0 {
+		return time.Duration(defaultPoolCacheConfig().TTLSeconds) * time.Second
+	}
+	return time.Duration(appConfig.PoolCache.TTLSeconds) * time.Second
+}
+
+// put stores a freshly built pool, drops expired ones and evicts the least recently used pools
+// beyond the namespace and memory bounds. A pool larger than the memory bound is not cached.
+func (pc *poolCache) put(key string, pool *ResourcePool) {
+	size := estimatePoolBytes(pool)
+	var bounds PoolCacheConfig
+	if appConfig != nil {
+		bounds = appConfig.PoolCache
+	}
+	maxBytes := int64(bounds.MaxMB) << 20
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	defer pc.recordSize()
+	pc.expire()
+	pc.remove(key, "")
+	if maxBytes > 0 && size > maxBytes {
+		log.Printf("⚠️  Not caching pool %s of %d MB, above the pool cache bound of %d MB", key, size>>20, bounds.MaxMB)
+		return
+	}
+	now := time.Now()
+	pc.entries[key] = &cachedPool{pool: pool, built: now, lastUsed: now, bytes: size}
+	pc.bytes += size
+
+	for {
+		reason := ""
+		switch {
+		case bounds.MaxNamespaces > 0 && pc.namespaces() > bounds.MaxNamespaces:
+			reason = poolEvictedNamespaces
+		case maxBytes > 0 && pc.bytes > maxBytes:
+			reason = poolEvictedMemory
+		default:
+			return
+		}
+		victim := pc.leastRecentlyUsed(key)
+		if victim == "" {
+			return
+		}
+		log.Printf("Evicting cached pool %s (%s bound)", victim, reason)
+		pc.remove(victim, reason)
+	}
+}
+
+// expireLoop drops expired pools every TTL, so their memory is released without further builds
+func (pc *poolCache) expireLoop(ctx context.Context) {
+	ticker := time.NewTicker(poolCacheTTL())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pc.mu.Lock()
+			pc.expire()
+			pc.recordSize()
+			pc.mu.Unlock()
+		}
+	}
+}
+
+// lookup returns the freshest unexpired pool whose key has the prefix and that contains the UID,
+// marking it as used
+func (pc *poolCache) lookup(prefix string, uid types.UID) (*cachedPool, *unstructured.Unstructured) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	ttl := poolCacheTTL()
+	var found *cachedPool
+	var resource *unstructured.Unstructured
+	for key, entry := range pc.entries {
+		if !strings.HasPrefix(key, prefix) || time.Since(entry.built) > ttl {
+			continue
+		}
+		if candidate := entry.pool.GetResource(uid); candidate != nil && (found == nil || entry.built.After(found.built)) {
+			found = entry
+			resource = candidate
+		}
+	}
+	if found != nil {
+		found.lastUsed = time.Now()
+	}
+	return found, resource
+}
+
+// get returns the unexpired pool of the key and when it was built, marking it as used
+func (pc *poolCache) get(key string) (*ResourcePool, time.Time) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	entry := pc.entries[key]
+	if entry == nil || time.Since(entry.built) > poolCacheTTL() {
+		return nil, time.Time{}
+	}
+	entry.lastUsed = time.Now()
+	return entry.pool, entry.built
+}
+
+// findByUID returns the freshest unexpired pool of the cluster and namespace that contains the UID
+func (pc *poolCache) findByUID(client *K8sClient, namespace string, uid types.UID) *ResourcePool {
+	found, _ := pc.lookup(fmt.Sprintf("%s|%s|", client.name, namespace), uid)
+	if found == nil {
+		return nil
+	}
+	return found.pool
+}
+
+// expire drops the expired pools, the caller holds the lock
+func (pc *poolCache) expire() {
+	ttl := poolCacheTTL()
+	for key, entry := range pc.entries {
+		if time.Since(entry.built) > ttl {
+			pc.remove(key, poolEvictedExpired)
+		}
+	}
+}
+
+// remove drops a pool, counting it as evicted unless reason is empty. The caller holds the lock.
+func (pc *poolCache) remove(key, reason string) {
+	entry := pc.entries[key]
+	if entry == nil {
+		return
+	}
+	delete(pc.entries, key)
+	pc.bytes -= entry.bytes
+	if reason != "" {
+		metrics.AddCounter("kbviz_pool_cache_evictions_total", "Number of cached resource pools evicted, by reason", map[string]string{"reason": reason}, 1)
+	}
+}
+
+// namespaces counts the cluster and namespace pairs with a cached pool, the caller holds the lock
+func (pc *poolCache) namespaces() int {
+	namespaces := map[string]bool{}
+	for key := range pc.entries {
+		namespaces[key[:strings.LastIndex(key, "|")]] = true
+	}
+	return len(namespaces)
+}
+
+// leastRecentlyUsed returns the key of the least recently used pool other than keep, the caller holds the lock
+func (pc *poolCache) leastRecentlyUsed(keep string) string {
+	victim := ""
+	for key, entry := range pc.entries {
+		if key != keep && (victim == "" || entry.lastUsed.Before(pc.entries[victim].lastUsed)) {
+			victim = key
+		}
+	}
+	return victim
+}
+
+// recordSize exports the size of the cache, the caller holds the lock
+func (pc *poolCache) recordSize() {
+	metrics.SetGauge("kbviz_pool_cache_entries", "Number of cached resource pools", nil, float64(len(pc.entries)))
+	metrics.SetGauge("kbviz_pool_cache_bytes", "Estimated memory of the cached resource pools", nil, float64(pc.bytes))
+}
+
+// estimatePoolBytes estimates the memory held by the resources of a pool and its indexes
+func estimatePoolBytes(pool *ResourcePool) int64 {
+	var size int64
+	for _, resource := range pool.resources {
+		size += estimateValueBytes(resource.Object)
+		// Entries of the UID, owner, kind and label indexes
+		size += 64 * int64(2+len(resource.GetOwnerReferences())+len(resource.GetLabels()))
+	}
+	return size
+}
+
+// estimateValueBytes approximates the memory of a decoded JSON value
+func estimateValueBytes(value interface{}) int64 {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		size := int64(48)
+		for key, item := range value {
+			size += 16 + int64(len(key)) + estimateValueBytes(item)
+		}
+		return size
+	case []interface{}:
+		size := int64(24)
+		for _, item := range value {
+			size += estimateValueBytes(item)
+		}
+		return size
+	case string:
+		return 16 + int64(len(value))
+	}
+	return 16
+}
+
+// ChildNode is a direct child returned for lazy tree expansion
+type ChildNode struct {
+	ResourceNode
+	Health     string `json:"health"`
+	ChildCount int    `json:"childCount"`
+}
+
+func getNodeChildren(c *gin.Context) {
+	uid := types.UID(c.Param("uid"))
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for expanding a node"})
+		return
+	}
+
+	log.Printf("Expanding children of node %s in namespace '%s' requested from %s", uid, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	pool := resourcePools.findByUID(client, namespace, uid)
+	if pool == nil {
+		// Nothing cached, build the pool of the whole namespace
+		log.Printf("No cached pool contains node %s, building pool of namespace %s", uid, namespace)
+		treeBuilder := NewResourceTreeBuilder(client, namespace, metav1.ListOptions{})
+		if err := treeBuilder.buildResourcePool(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		pool = treeBuilder.pool
+	}
+	node := pool.GetResource(uid)
+	if node == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Node not found: %s in namespace %s", uid, namespace)})
+		return
+	}
+	// Expanding a node is reviewed like a tree rooted at it, the webhook only saw the UID
+	if err := objectAccessAllowed(c, node.GetAPIVersion(), node.GetKind(), node.GetName(), node.GetNamespace()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	children := []ChildNode{}
+	primary, _ := pool.SplitChildrenByPrimaryOwner(uid)
+	for _, child := range primary {
+		grandchildren, _ := pool.SplitChildrenByPrimaryOwner(child.GetUID())
+		children = append(children, ChildNode{
+			ResourceNode: resourceNodeFor(c, child),
+			Health:       computeHealth(child),
+			ChildCount:   len(grandchildren),
+		})
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Kind != children[j].Kind {
+			return children[i].Kind < children[j].Kind
+		}
+		return children[i].Name < children[j].Name
+	})
+
+	log.Printf("Node %s has %d direct children", uid, len(children))
+	c.JSON(http.StatusOK, children)
+}
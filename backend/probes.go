@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Startup time below which a liveness probe without startup probe may kill a starting container
+const minLivenessStartupSeconds = 30
+
+// ProbeSummary describes a configured probe
+type ProbeSummary struct {
+	Type                string `json:"type"`
+	Handler             string `json:"handler"`
+	InitialDelaySeconds int32  `json:"initialDelaySeconds"`
+	PeriodSeconds       int32  `json:"periodSeconds"`
+	TimeoutSeconds      int32  `json:"timeoutSeconds"`
+	SuccessThreshold    int32  `json:"successThreshold"`
+	FailureThreshold    int32  `json:"failureThreshold"`
+	// FailureWindowSeconds is how long the probe fails before the kubelet acts on it
+	FailureWindowSeconds int32 `json:"failureWindowSeconds"`
+}
+
+// ContainerProbes are the probes of a container with their current results
+type ContainerProbes struct {
+	Name             string         `json:"name"`
+	Probes           []ProbeSummary `json:"probes"`
+	Ready            bool           `json:"ready"`
+	Started          *bool          `json:"started,omitempty"`
+	RestartCount     int32          `json:"restartCount"`
+	State            string         `json:"state"`
+	LastTermination  string         `json:"lastTermination,omitempty"`
+	RecentFailures   []ClusterEvent `json:"recentFailures"`
+	Misconfiguration []string       `json:"misconfiguration"`
+}
+
+// PodProbes summarizes the probes of every container of a pod
+type PodProbes struct {
+	Pod        string            `json:"pod"`
+	Namespace  string            `json:"namespace"`
+	Role       string            `json:"role,omitempty"`
+	Phase      string            `json:"phase"`
+	Ready      bool              `json:"ready"`
+	Containers []ContainerProbes `json:"containers"`
+}
+
+// probeHandler describes what a probe checks, e.g. "httpGet :8080/health"
+func probeHandler(probe *corev1.Probe) string {
+	switch {
+	case probe.HTTPGet != nil:
+		return fmt.Sprintf("httpGet %s:%s%s", strings.ToLower(string(probe.HTTPGet.Scheme)), probe.HTTPGet.Port.String(), probe.HTTPGet.Path)
+	case probe.TCPSocket != nil:
+		return fmt.Sprintf("tcpSocket :%s", probe.TCPSocket.Port.String())
+	case probe.GRPC != nil:
+		return fmt.Sprintf("grpc :%d", probe.GRPC.Port)
+	case probe.Exec != nil:
+		return "exec " + strings.Join(probe.Exec.Command, " ")
+	}
+	return "none"
+}
+
+func summarizeProbe(probeType string, probe *corev1.Probe) ProbeSummary {
+	return ProbeSummary{
+		Type:                 probeType,
+		Handler:              probeHandler(probe),
+		InitialDelaySeconds:  probe.InitialDelaySeconds,
+		PeriodSeconds:        probe.PeriodSeconds,
+		TimeoutSeconds:       probe.TimeoutSeconds,
+		SuccessThreshold:     probe.SuccessThreshold,
+		FailureThreshold:     probe.FailureThreshold,
+		FailureWindowSeconds: probe.PeriodSeconds * probe.FailureThreshold,
+	}
+}
+
+// probeMisconfiguration reports probe settings known to make containers flap
+func probeMisconfiguration(container *corev1.Container) []string {
+	findings := []string{}
+	probes := map[string]*corev1.Probe{"liveness": container.LivenessProbe, "readiness": container.ReadinessProbe, "startup": container.StartupProbe}
+	for _, probeType := range []string{"liveness", "readiness", "startup"} {
+		probe := probes[probeType]
+		if probe != nil && probe.PeriodSeconds > 0 && probe.TimeoutSeconds >= probe.PeriodSeconds {
+			findings = append(findings, fmt.Sprintf("The %s probe timeout (%ds) is not shorter than its period (%ds), slow checks overlap", probeType, probe.TimeoutSeconds, probe.PeriodSeconds))
+		}
+	}
+
+	liveness := container.LivenessProbe
+	if container.ReadinessProbe == nil {
+		findings = append(findings, "No readiness probe, the container receives traffic as soon as it starts")
+	}
+	if liveness == nil {
+		return findings
+	}
+	if container.ReadinessProbe != nil && reflect.DeepEqual(liveness.ProbeHandler, container.ReadinessProbe.ProbeHandler) {
+		findings = append(findings, "The liveness probe runs the same check as the readiness probe, so an unavailable dependency restarts the container instead of only taking it out of service")
+	}
+	if liveness.FailureThreshold == 1 {
+		findings = append(findings, "The liveness probe restarts the container after a single failure")
+	}
+	if container.StartupProbe == nil {
+		if startup := liveness.InitialDelaySeconds + liveness.PeriodSeconds*liveness.FailureThreshold; startup < minLivenessStartupSeconds {
+			findings = append(findings, fmt.Sprintf("Without a startup probe the liveness probe kills the container if it is not live within %ds, e.g. during crash recovery of a database; add a startup probe", startup))
+		}
+	}
+	return findings
+}
+
+// containerState describes the current state of a container
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running"
+	case state.Waiting != nil:
+		return "Waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		return fmt.Sprintf("Terminated: %s (exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+	}
+	return "Unknown"
+}
+
+// getPodProbes summarizes the liveness, readiness and startup probes of a pod with their current
+// results, recent probe failures and settings that make containers flap
+func getPodProbes(c *gin.Context) {
+	podName := c.Param("name")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required for fetching pod probes"})
+		return
+	}
+
+	log.Printf("Summarizing probes of pod %s in namespace '%s' requested from %s", podName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	pod, err := client.clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Probe failures are reported as Unhealthy events, liveness restarts as Killing events
+	failures := map[string][]ClusterEvent{}
+	events, err := client.clientset.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.kind=Pod,involvedObject.name=%s", podName),
+	})
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list events of pod %s: %v", podName, err)
+	} else {
+		for i := range events.Items {
+			event := &events.Items[i]
+			if event.Reason != "Unhealthy" && !(event.Reason == "Killing" && strings.Contains(event.Message, "probe")) {
+				continue
+			}
+			if event.InvolvedObject.UID != pod.UID {
+				continue
+			}
+			container := strings.TrimSuffix(strings.TrimPrefix(event.InvolvedObject.FieldPath, "spec.containers{"), "}")
+			failures[container] = append(failures[container], newClusterEvent(event))
+		}
+	}
+
+	statuses := map[string]corev1.ContainerStatus{}
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses[status.Name] = status
+	}
+
+	result := PodProbes{
+		Pod:        pod.Name,
+		Namespace:  pod.Namespace,
+		Role:       pod.Labels[roleLabel],
+		Phase:      string(pod.Status.Phase),
+		Containers: []ContainerProbes{},
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			result.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		probes := ContainerProbes{
+			Name:             container.Name,
+			Probes:           []ProbeSummary{},
+			State:            "Unknown",
+			RecentFailures:   failures[container.Name],
+			Misconfiguration: probeMisconfiguration(container),
+		}
+		if container.StartupProbe != nil {
+			probes.Probes = append(probes.Probes, summarizeProbe("startup", container.StartupProbe))
+		}
+		if container.LivenessProbe != nil {
+			probes.Probes = append(probes.Probes, summarizeProbe("liveness", container.LivenessProbe))
+		}
+		if container.ReadinessProbe != nil {
+			probes.Probes = append(probes.Probes, summarizeProbe("readiness", container.ReadinessProbe))
+		}
+		if status, found := statuses[container.Name]; found {
+			probes.Ready = status.Ready
+			probes.Started = status.Started
+			probes.RestartCount = status.RestartCount
+			probes.State = containerState(status.State)
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				probes.LastTermination = fmt.Sprintf("%s (exit code %d) at %s", terminated.Reason, terminated.ExitCode, terminated.FinishedAt.UTC().Format(time.RFC3339))
+			}
+		}
+		if probes.RecentFailures == nil {
+			probes.RecentFailures = []ClusterEvent{}
+		}
+		sort.Slice(probes.RecentFailures, func(i, j int) bool {
+			return probes.RecentFailures[i].LastTimestamp > probes.RecentFailures[j].LastTimestamp
+		})
+		result.Containers = append(result.Containers, probes)
+	}
+
+	log.Printf("Pod %s has %d containers, ready: %t", podName, len(result.Containers), result.Ready)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Problem types detected on tree nodes
+const (
+	ProblemCrashLoopBackOff = "CrashLoopBackOff"
+	ProblemImagePull        = "ImagePullBackOff"
+	ProblemUnboundPVC       = "PendingUnboundPVC"
+	ProblemUnschedulable    = "Unschedulable"
+	ProblemOOMKilled        = "OOMKilled"
+	ProblemProbeFailing     = "ProbeFailing"
+	ProblemHighRestarts     = "HighRestartCount"
+	// ProblemBackupRepoNotReady is reported on BackupPolicies and Backups whose BackupRepo is missing or not ready
+	ProblemBackupRepoNotReady = "BackupRepoNotReady"
+)
+
+// Problem severities
+const (
+	SeverityWarning  = "Warning"
+	SeverityCritical = "Critical"
+)
+
+const (
+	highRestartThreshold = 5
+	probeGracePeriod     = time.Minute
+)
+
+// Problem is an issue detected on a tree node
+type Problem = api.Problem
+
+// ProblemNode is a node with problems in the problems summary
+type ProblemNode struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	UID       string    `json:"uid"`
+	Problems  []Problem `json:"problems"`
+}
+
+// ProblemsSummary summarizes all problems detected in a tree
+type ProblemsSummary struct {
+	Root   string         `json:"root"`
+	Total  int            `json:"total"`
+	ByType map[string]int `json:"byType"`
+	Nodes  []ProblemNode  `json:"nodes"`
+}
+
+// DetectProblems runs the problem detectors over the tree and tags the affected nodes
+func (rtb *ResourceTreeBuilder) DetectProblems(root *ResourceTreeNode) {
+	// Index the PVCs of the pool by name for the unbound-PVC detector
+	pvcs := map[string]*unstructured.Unstructured{}
+	if rtb.pool != nil {
+		for _, resource := range rtb.pool.GetAllResources() {
+			if resource.GetKind() == "PersistentVolumeClaim" {
+				pvcs[resource.GetName()] = resource
+			}
+		}
+	}
+
+	var detect func(node *ResourceTreeNode)
+	detect = func(node *ResourceTreeNode) {
+		switch node.Resource.GetKind() {
+		case "Pod":
+			node.Problems = detectPodProblems(node.Resource, pvcs, rtb.language)
+		case "BackupPolicy", "Backup":
+			node.Problems = rtb.detectBackupRepoProblems(node.Resource)
+		}
+		for _, child := range node.Children {
+			detect(child)
+		}
+	}
+	detect(root)
+}
+
+// detectPodProblems inspects the status of a pod for common failure patterns, with messages in the language
+func detectPodProblems(pod *unstructured.Unstructured, pvcs map[string]*unstructured.Unstructured, language string) []Problem {
+	var problems []Problem
+
+	statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+	initStatuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "initContainerStatuses")
+	for _, status := range append(initStatuses, statuses...) {
+		statusMap, ok := status.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		container, _, _ := unstructured.NestedString(statusMap, "name")
+
+		waitingReason, _, _ := unstructured.NestedString(statusMap, "state", "waiting", "reason")
+		waitingMessage, _, _ := unstructured.NestedString(statusMap, "state", "waiting", "message")
+		switch waitingReason {
+		case "CrashLoopBackOff":
+			problems = append(problems, Problem{Type: ProblemCrashLoopBackOff, Severity: SeverityCritical, Container: container,
+				Message: localize(language, "Container %s is crash looping", container)})
+		case "ImagePullBackOff", "ErrImagePull", "InvalidImageName":
+			problems = append(problems, Problem{Type: ProblemImagePull, Severity: SeverityCritical, Container: container,
+				Message: localize(language, "Container %s cannot pull its image: %s", container, waitingMessage)})
+		}
+
+		for _, state := range []string{"state", "lastState"} {
+			if reason, _, _ := unstructured.NestedString(statusMap, state, "terminated", "reason"); reason == "OOMKilled" {
+				problems = append(problems, Problem{Type: ProblemOOMKilled, Severity: SeverityCritical, Container: container,
+					Message: localize(language, "Container %s was killed for exceeding its memory limit", container)})
+				break
+			}
+		}
+
+		if restarts, _, _ := unstructured.NestedInt64(statusMap, "restartCount"); restarts >= highRestartThreshold && waitingReason != "CrashLoopBackOff" {
+			problems = append(problems, Problem{Type: ProblemHighRestarts, Severity: SeverityWarning, Container: container,
+				Message: localize(language, "Container %s restarted %d times", container, restarts)})
+		}
+
+		// Running but not ready past the grace period means the readiness probe keeps failing
+		ready, _, _ := unstructured.NestedBool(statusMap, "ready")
+		startedAt, found, _ := unstructured.NestedString(statusMap, "state", "running", "startedAt")
+		if found && !ready && containerHasProbe(pod, container) {
+			if started, err := time.Parse(time.RFC3339, startedAt); err == nil && time.Since(started) > probeGracePeriod {
+				problems = append(problems, Problem{Type: ProblemProbeFailing, Severity: SeverityWarning, Container: container,
+					Message: localize(language, "Container %s has been running for %s without becoming ready", container, time.Since(started).Round(time.Second))})
+			}
+		}
+	}
+
+	phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+	if phase == "Pending" {
+		problems = append(problems, detectPendingProblems(pod, pvcs, language)...)
+	}
+
+	return problems
+}
+
+// detectPendingProblems explains why a pending pod is not scheduled
+func detectPendingProblems(pod *unstructured.Unstructured, pvcs map[string]*unstructured.Unstructured, language string) []Problem {
+	var problems []Problem
+
+	volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+	for _, volume := range volumes {
+		volumeMap, ok := volume.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		claimName, found, _ := unstructured.NestedString(volumeMap, "persistentVolumeClaim", "claimName")
+		if !found {
+			continue
+		}
+		pvc := pvcs[claimName]
+		if pvc == nil {
+			continue
+		}
+		if pvcPhase, _, _ := unstructured.NestedString(pvc.Object, "status", "phase"); pvcPhase != "Bound" {
+			problems = append(problems, Problem{Type: ProblemUnboundPVC, Severity: SeverityCritical,
+				Message: localize(language, "Pod is pending because PVC %s is %s", claimName, strings.ToLower(defaultString(pvcPhase, "unbound")))})
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(pod.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok || conditionMap["type"] != "PodScheduled" || conditionMap["status"] != "False" {
+			continue
+		}
+		message, _, _ := unstructured.NestedString(conditionMap, "message")
+		problemType := ProblemUnschedulable
+		if strings.Contains(message, "unbound") && strings.Contains(message, "PersistentVolumeClaim") {
+			problemType = ProblemUnboundPVC
+		}
+		problems = append(problems, Problem{Type: problemType, Severity: SeverityCritical,
+			Message: localize(language, "Pod cannot be scheduled: %s", message)})
+	}
+	return problems
+}
+
+// containerHasProbe reports whether the container defines a readiness or startup probe
+func containerHasProbe(pod *unstructured.Unstructured, name string) bool {
+	containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+	for _, container := range containers {
+		containerMap, ok := container.(map[string]interface{})
+		if !ok || containerMap["name"] != name {
+			continue
+		}
+		_, hasReadiness := containerMap["readinessProbe"]
+		_, hasStartup := containerMap["startupProbe"]
+		return hasReadiness || hasStartup
+	}
+	return false
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// summarizeProblems collects the problems tagged in the tree
+func summarizeProblems(root *ResourceTreeNode) *ProblemsSummary {
+	summary := &ProblemsSummary{
+		Root:   fmt.Sprintf("%s/%s", root.Resource.GetKind(), root.Resource.GetName()),
+		ByType: map[string]int{},
+		Nodes:  []ProblemNode{},
+	}
+
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		if len(node.Problems) > 0 {
+			summary.Nodes = append(summary.Nodes, ProblemNode{
+				Kind:      node.Resource.GetKind(),
+				Name:      node.Resource.GetName(),
+				Namespace: node.Resource.GetNamespace(),
+				UID:       string(node.Resource.GetUID()),
+				Problems:  node.Problems,
+			})
+			for _, problem := range node.Problems {
+				summary.ByType[problem.Type]++
+				summary.Total++
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	sort.Slice(summary.Nodes, func(i, j int) bool {
+		if summary.Nodes[i].Kind != summary.Nodes[j].Kind {
+			return summary.Nodes[i].Kind < summary.Nodes[j].Kind
+		}
+		return summary.Nodes[i].Name < summary.Nodes[j].Name
+	})
+	return summary
+}
+
+func getResourceProblems(c *gin.Context) {
+	resourceType := c.Param("type")
+	rootResourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Detecting problems in tree of %s/%s in namespace '%s' requested from %s", resourceType, rootResourceName, namespace, c.ClientIP())
+
+	rootTreeNode, treeBuilder, status, err := buildTreeForRoot(clientFor(c), resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	treeBuilder.language = requestLanguage(c)
+	treeBuilder.DetectProblems(rootTreeNode)
+	summary := summarizeProblems(rootTreeNode)
+
+	log.Printf("Detected %d problems on %d nodes in tree of %s/%s", summary.Total, len(summary.Nodes), resourceType, rootResourceName)
+	c.JSON(http.StatusOK, summary)
+}
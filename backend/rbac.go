@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+var (
+	serviceAccountGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "serviceaccounts"}
+	roleGVR           = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}
+	roleBindingGVR    = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}
+	clusterRoleGVR    = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+)
+
+// RBAC resources added to the pool when RBAC is shown, so those owned by tree resources become nodes
+var rbacResourceTypes = []schema.GroupVersionResource{serviceAccountGVR, roleGVR, roleBindingGVR}
+
+// ServiceAccountPermissions is the ServiceAccount of a pod and the rules granted to it in its namespace
+type ServiceAccountPermissions = api.ServiceAccountPermissions
+
+// BindingSummary is a RoleBinding granting a role to a ServiceAccount
+type BindingSummary = api.BindingSummary
+
+// bindsServiceAccount reports whether one of the binding subjects is the ServiceAccount
+func bindsServiceAccount(binding *rbacv1.RoleBinding, name string) bool {
+	for _, subject := range binding.Subjects {
+		if subject.Kind != rbacv1.ServiceAccountKind || subject.Name != name {
+			continue
+		}
+		if subject.Namespace == "" || subject.Namespace == binding.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachServiceAccounts summarizes the permissions of every pod's ServiceAccount on the pod node.
+// ServiceAccounts and their RoleBindings and Roles that are not in the tree through ownerReferences
+// are added under the root, so the tree shows every identity the pods of the root run with.
+func (rtb *ResourceTreeBuilder) AttachServiceAccounts(root *ResourceTreeNode) {
+	inTree := map[types.UID]bool{}
+	var pods []*ResourceTreeNode
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		inTree[node.Resource.GetUID()] = true
+		if node.Resource.GetKind() == "Pod" {
+			pods = append(pods, node)
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	if len(pods) == 0 {
+		return
+	}
+
+	namespace := root.Resource.GetNamespace()
+	var bindings []unstructured.Unstructured
+	if list, err := rtb.client.dynamicClient.Resource(roleBindingGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{}); err != nil {
+		rtb.addWarning("Unable to list RoleBindings in namespace %s: %v", namespace, err)
+	} else {
+		bindings = list.Items
+	}
+
+	// Roles are shared by bindings and ServiceAccounts by pods, fetch each once
+	roles := map[string]*unstructured.Unstructured{}
+	getRole := func(kind, name string) *unstructured.Unstructured {
+		key := kind + "/" + name
+		if role, cached := roles[key]; cached {
+			return role
+		}
+		var role *unstructured.Unstructured
+		var err error
+		if kind == "ClusterRole" {
+			role, err = rtb.client.dynamicClient.Resource(clusterRoleGVR).Get(context.TODO(), name, metav1.GetOptions{})
+		} else {
+			role, err = rtb.client.dynamicClient.Resource(roleGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		}
+		if err != nil {
+			log.Printf("⚠️  Unable to get %s %s: %v", kind, name, err)
+			role = nil
+		}
+		roles[key] = role
+		return role
+	}
+
+	accounts := map[string]*ServiceAccountPermissions{}
+	for _, pod := range pods {
+		name, _, _ := unstructured.NestedString(pod.Resource.Object, "spec", "serviceAccountName")
+		if name == "" {
+			name = "default"
+		}
+		if permissions, cached := accounts[name]; cached {
+			pod.ServiceAccount = permissions
+			continue
+		}
+
+		permissions := &ServiceAccountPermissions{Name: name, Bindings: []BindingSummary{}}
+		accounts[name] = permissions
+		pod.ServiceAccount = permissions
+
+		account, err := rtb.client.dynamicClient.Resource(serviceAccountGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("⚠️  Unable to get ServiceAccount %s of pod %s: %v", name, pod.Resource.GetName(), err)
+			permissions.Missing = true
+			continue
+		}
+		var accountNode *ResourceTreeNode
+		if !inTree[account.GetUID()] {
+			accountNode = &ResourceTreeNode{Resource: account, Children: []*ResourceTreeNode{}}
+			root.Children = append(root.Children, accountNode)
+			inTree[account.GetUID()] = true
+		}
+
+		for i := range bindings {
+			var binding rbacv1.RoleBinding
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(bindings[i].Object, &binding); err != nil || !bindsServiceAccount(&binding, name) {
+				continue
+			}
+			summary := BindingSummary{Name: binding.Name, RoleKind: binding.RoleRef.Kind, RoleName: binding.RoleRef.Name}
+			role := getRole(binding.RoleRef.Kind, binding.RoleRef.Name)
+			if role != nil {
+				var typed rbacv1.Role
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(role.Object, &typed); err == nil {
+					summary.Rules = typed.Rules
+				}
+			}
+			permissions.Bindings = append(permissions.Bindings, summary)
+
+			// Bindings hang below the ServiceAccount they grant to, roles below their binding
+			if accountNode == nil || inTree[bindings[i].GetUID()] {
+				continue
+			}
+			bindingNode := &ResourceTreeNode{Resource: &bindings[i], Children: []*ResourceTreeNode{}}
+			accountNode.Children = append(accountNode.Children, bindingNode)
+			inTree[bindings[i].GetUID()] = true
+			if role != nil && !inTree[role.GetUID()] {
+				bindingNode.Children = append(bindingNode.Children, &ResourceTreeNode{Resource: role, Children: []*ResourceTreeNode{}})
+				inTree[role.GetUID()] = true
+			}
+		}
+	}
+}
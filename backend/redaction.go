@@ -0,0 +1,264 @@
+package main
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Placeholder replacing the value of a redacted field
+const redactedValue = "***"
+
+// Context key caching the reveal decision of a request
+const revealKey = "kbviz.reveal"
+
+// RedactionConfig lists the fields masked in responses unless the caller may reveal them.
+// Secret data is removed as well.
+type RedactionConfig struct {
+	// EnvDenylist masks env vars whose name contains one of the entries, case-insensitive. Entries
+	// with a * are glob patterns matching the whole name instead, e.g. *_KEY.
+	EnvDenylist []string `json:"envDenylist"`
+	// AnnotationDenylist masks annotations whose key matches one of the entries, like EnvDenylist
+	AnnotationDenylist []string `json:"annotationDenylist"`
+	// AllowReveal honours ?reveal=true when no authorization webhook is configured. With a webhook
+	// the caller needs the "reveal" verb on the resource instead.
+	AllowReveal bool `json:"allowReveal"`
+}
+
+func defaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		EnvDenylist:        []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "CREDENTIAL", "PRIVATE_KEY", "ACCESS_KEY", "API_KEY", "*_KEY"},
+		AnnotationDenylist: []string{"connection-credential", "password", "secret", "token"},
+	}
+}
+
+// matchesDenylist reports whether the name contains one of the denylist entries, or matches one of
+// its glob patterns
+func matchesDenylist(name string, denylist []string) bool {
+	name = strings.ToLower(name)
+	for _, entry := range denylist {
+		entry = strings.ToLower(entry)
+		if strings.Contains(entry, "*") {
+			if matched, _ := path.Match(entry, name); matched {
+				return true
+			}
+			continue
+		}
+		if entry != "" && strings.Contains(name, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// revealAllowed reports whether the request asked for ?reveal=true and may see sensitive fields.
+// Requests authorized by a share token never reveal.
+func revealAllowed(c *gin.Context) bool {
+	if c.Query("reveal") != "true" || c.GetBool(sharedRequestKey) {
+		return false
+	}
+	if cached, found := c.Get(revealKey); found {
+		return cached.(bool)
+	}
+
+	allowed := appConfig.Redaction.AllowReveal
+	if webhook := appConfig.Authorization; webhook.URL != "" {
+		review := subjectAccessReviewFor(c, webhook)
+		if review.Spec.ResourceAttributes != nil {
+			review.Spec.ResourceAttributes.Verb = "reveal"
+		} else {
+			review.Spec.NonResourceAttributes.Verb = "reveal"
+		}
+		var reason string
+		var err error
+		allowed, reason, err = reviewAccess(webhook, review)
+		if err != nil {
+			log.Printf("⚠️  Authorization webhook failed for reveal of %s: %v", c.Request.URL.Path, err)
+			allowed = false
+		} else if !allowed {
+			log.Printf("Authorization webhook denied reveal of %s for user '%s': %s", c.Request.URL.Path, review.Spec.User, reason)
+		}
+	}
+	if allowed {
+		log.Printf("🔓 Revealing sensitive fields of %s to %s", c.Request.URL.Path, c.ClientIP())
+	}
+	c.Set(revealKey, allowed)
+	return allowed
+}
+
+// redactAnnotations masks the values of the annotations on the denylist
+func redactAnnotations(annotations map[string]string) map[string]string {
+	var redacted map[string]string
+	for key := range annotations {
+		if !matchesDenylist(key, appConfig.Redaction.AnnotationDenylist) {
+			continue
+		}
+		if redacted == nil {
+			redacted = make(map[string]string, len(annotations))
+			for k, v := range annotations {
+				redacted[k] = v
+			}
+		}
+		redacted[key] = redactedValue
+	}
+	if redacted == nil {
+		return annotations
+	}
+	return redacted
+}
+
+// redactEnv masks the values of env vars on the denylist and returns their names. Any list named env
+// whose items have a name and a value is treated as env vars, so containers of pods, workload
+// templates and KubeBlocks component specs are all covered. References through valueFrom are kept,
+// they hold no secret.
+func redactEnv(value interface{}) []string {
+	var masked []string
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			if items, ok := field.([]interface{}); ok && key == "env" {
+				for _, item := range items {
+					envVar, ok := item.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					name, _ := envVar["name"].(string)
+					if _, hasValue := envVar["value"]; hasValue && matchesDenylist(name, appConfig.Redaction.EnvDenylist) {
+						envVar["value"] = redactedValue
+						masked = append(masked, name)
+					}
+				}
+				continue
+			}
+			masked = append(masked, redactEnv(field)...)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			masked = append(masked, redactEnv(item)...)
+		}
+	}
+	return masked
+}
+
+// redactObject masks the sensitive fields of a resource in place: the payload of Secrets along with
+// the last-applied annotation holding a copy of it, env vars and annotations on the denylists. It
+// returns the masked fields, e.g. "env DB_PASSWORD".
+func redactObject(object map[string]interface{}) []string {
+	var masked []string
+	resource := unstructured.Unstructured{Object: object}
+	if resource.GetKind() == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			if _, found := object[field]; found {
+				unstructured.RemoveNestedField(object, field)
+				masked = append(masked, field)
+			}
+		}
+		if annotations := resource.GetAnnotations(); annotations != nil {
+			if _, found := annotations[lastAppliedAnnotation]; found {
+				annotations[lastAppliedAnnotation] = redactedValue
+				resource.SetAnnotations(annotations)
+			}
+		}
+	}
+	if spec, ok := object["spec"]; ok {
+		for _, name := range redactEnv(spec) {
+			masked = append(masked, "env "+name)
+		}
+	}
+	if annotations := resource.GetAnnotations(); len(annotations) > 0 {
+		redacted := redactAnnotations(annotations)
+		var keys []string
+		for key, value := range redacted {
+			if value == redactedValue && annotations[key] != redactedValue {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			masked = append(masked, "annotation "+key)
+		}
+		resource.SetAnnotations(redacted)
+	}
+	return masked
+}
+
+// redactResource returns a copy of the resource with its sensitive fields masked. Pooled resources
+// are shared between requests, so they are never modified.
+func redactResource(resource *unstructured.Unstructured) *unstructured.Unstructured {
+	copied := resource.DeepCopy()
+	redactObject(copied.Object)
+	return copied
+}
+
+// redactResourceFor redacts the resource unless the request may reveal it
+func redactResourceFor(c *gin.Context, resource *unstructured.Unstructured) *unstructured.Unstructured {
+	if revealAllowed(c) {
+		return resource
+	}
+	return redactResource(resource)
+}
+
+// redactResourceNode masks the annotations of a node on the denylist, and the last applied
+// configuration of a Secret, which holds its data
+func redactResourceNode(node *ResourceNode) {
+	annotations := redactAnnotations(node.Annotations)
+	if _, found := annotations[lastAppliedAnnotation]; found && node.Kind == "Secret" {
+		masked := make(map[string]string, len(annotations))
+		for key, value := range annotations {
+			masked[key] = value
+		}
+		masked[lastAppliedAnnotation] = redactedValue
+		annotations = masked
+	}
+	node.Annotations = annotations
+}
+
+// resourceNodeFor converts a resource for a response, redacted unless the request may reveal it.
+// Handlers answering with ResourceNodes convert through it, or pass nodes built without the
+// request to redactResourceNodesFor; trees are redacted by RedactTree.
+func resourceNodeFor(c *gin.Context, resource *unstructured.Unstructured) ResourceNode {
+	node := convertToResourceNode(*resource)
+	if !revealAllowed(c) {
+		redactResourceNode(&node)
+	}
+	return node
+}
+
+// resourceNodesFor converts a list of resources for a response like resourceNodeFor
+func resourceNodesFor(c *gin.Context, resources []unstructured.Unstructured) []ResourceNode {
+	nodes := make([]ResourceNode, 0, len(resources))
+	for i := range resources {
+		nodes = append(nodes, resourceNodeFor(c, &resources[i]))
+	}
+	return nodes
+}
+
+// redactResourceNodesFor redacts nodes built without the request, e.g. by lookups shared with
+// other endpoints, unless the request may reveal them
+func redactResourceNodesFor(c *gin.Context, nodes ...*ResourceNode) {
+	if revealAllowed(c) {
+		return
+	}
+	for _, node := range nodes {
+		redactResourceNode(node)
+	}
+}
+
+// RedactTree replaces the resources of the tree with redacted copies
+func RedactTree(root *ResourceTreeNode) {
+	var redact func(node *ResourceTreeNode)
+	redact = func(node *ResourceTreeNode) {
+		node.Resource = redactResource(node.Resource)
+		for i := range node.References {
+			node.References[i].Target.Annotations = redactAnnotations(node.References[i].Target.Annotations)
+		}
+		for _, child := range node.Children {
+			redact(child)
+		}
+	}
+	redact(root)
+}
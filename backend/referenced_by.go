@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// referenceRule is a field of a spec referencing a resource of a kind by name: a map under key
+// holding the name in nameField, a list of such maps, or, without nameField, a string named key
+type referenceRule struct {
+	kind      string
+	key       string
+	nameField string
+}
+
+// referenceRules are the references followed backwards by the referencedBy endpoint, from env vars,
+// volumes and pod specs of workloads to the names KubeBlocks resources refer to each other by
+var referenceRules = []referenceRule{
+	{kind: "ConfigMap", key: "configMapRef", nameField: "name"},
+	{kind: "ConfigMap", key: "configMapKeyRef", nameField: "name"},
+	{kind: "ConfigMap", key: "configMap", nameField: "name"},
+	{kind: "Secret", key: "secretRef", nameField: "name"},
+	{kind: "Secret", key: "secretKeyRef", nameField: "name"},
+	{kind: "Secret", key: "secret", nameField: "secretName"},
+	{kind: "Secret", key: "imagePullSecrets", nameField: "name"},
+	{kind: "PersistentVolumeClaim", key: "persistentVolumeClaim", nameField: "claimName"},
+	{kind: "ServiceAccount", key: "serviceAccountName"},
+	{kind: "Service", key: "serviceName"},
+	{kind: "BackupPolicy", key: "backupPolicyName"},
+	{kind: "Backup", key: "backupName"},
+	{kind: "Backup", key: "backup", nameField: "name"},
+	{kind: "Cluster", key: "clusterName"},
+	{kind: "Cluster", key: "clusterRef"},
+}
+
+// ResourceReferrer is a resource whose spec references the target
+type ResourceReferrer struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+	// Paths are the fields holding the reference, e.g. spec.template.spec.volumes[0].configMap
+	Paths []string `json:"paths"`
+}
+
+// ReferencedByResult is the response of GET /api/resources/:type/:name/referencedBy
+type ReferencedByResult struct {
+	Kind         string             `json:"kind"`
+	Name         string             `json:"name"`
+	Namespace    string             `json:"namespace"`
+	ReferencedBy []ResourceReferrer `json:"referencedBy"`
+	// Scanned is the number of resources of the namespace searched for references
+	Scanned int `json:"scanned"`
+	// PoolBuiltAt is when the scanned resources were listed, older than the request when cached
+	PoolBuiltAt string `json:"poolBuiltAt"`
+}
+
+// findReferences returns the paths of the fields of value referencing name with the rules
+func findReferences(value interface{}, path, name, namespace string, rules []referenceRule) []string {
+	var found []string
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := typed[key]
+			fieldPath := path + "." + key
+			for _, rule := range rules {
+				if rule.key == key {
+					found = append(found, matchReference(field, fieldPath, name, namespace, rule)...)
+				}
+			}
+			found = append(found, findReferences(field, fieldPath, name, namespace, rules)...)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			found = append(found, findReferences(item, fmt.Sprintf("%s[%d]", path, i), name, namespace, rules)...)
+		}
+	}
+	return found
+}
+
+// matchReference checks a field named after a rule. References naming another namespace do not count.
+func matchReference(field interface{}, path, name, namespace string, rule referenceRule) []string {
+	matches := func(ref map[string]interface{}) bool {
+		refNamespace, _ := ref["namespace"].(string)
+		return ref[rule.nameField] == name && (refNamespace == "" || refNamespace == namespace)
+	}
+	switch typed := field.(type) {
+	case string:
+		if rule.nameField == "" && typed == name {
+			return []string{path}
+		}
+	case map[string]interface{}:
+		if rule.nameField != "" && matches(typed) {
+			return []string{path}
+		}
+	case []interface{}:
+		var found []string
+		for i, item := range typed {
+			if ref, ok := item.(map[string]interface{}); ok && rule.nameField != "" && matches(ref) {
+				found = append(found, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		return found
+	}
+	return nil
+}
+
+// referrersOf scans the specs of the pooled resources for references to the target
+func referrersOf(target *unstructured.Unstructured, resources []*unstructured.Unstructured) []ResourceReferrer {
+	var rules []referenceRule
+	for _, rule := range referenceRules {
+		if rule.kind == target.GetKind() {
+			rules = append(rules, rule)
+		}
+	}
+	referrers := []ResourceReferrer{}
+	if len(rules) == 0 {
+		return referrers
+	}
+	sortResources(resources)
+	for _, resource := range resources {
+		if resource.GetUID() == target.GetUID() {
+			continue
+		}
+		spec, found := resource.Object["spec"]
+		if !found {
+			continue
+		}
+		if paths := findReferences(spec, "spec", target.GetName(), target.GetNamespace(), rules); len(paths) > 0 {
+			referrers = append(referrers, ResourceReferrer{
+				Kind:      resource.GetKind(),
+				Name:      resource.GetName(),
+				Namespace: resource.GetNamespace(),
+				UID:       string(resource.GetUID()),
+				Paths:     paths,
+			})
+		}
+	}
+	return referrers
+}
+
+// getReferencedBy answers "what breaks if I delete this?": the resources of the namespace whose spec
+// references the resource, e.g. the pods mounting a ConfigMap or the Backups of a BackupPolicy. The
+// namespace's resources come from the pool cache when a tree of it was built recently, or with
+// ?refresh=true are listed again. References from other namespaces are not found.
+func getReferencedBy(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace parameter is required"})
+		return
+	}
+
+	log.Printf("Finding references to %s/%s in namespace '%s' requested from %s", resourceType, resourceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	target, _, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	listOptions := metav1.ListOptions{}
+	pool, builtAt := resourcePools.get(poolCacheKey(client, namespace, listOptions))
+	if pool == nil || c.Query("refresh") == "true" {
+		treeBuilder := NewResourceTreeBuilder(client, namespace, listOptions)
+		if err := treeBuilder.buildResourcePool(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		pool, builtAt = treeBuilder.pool, time.Now()
+	}
+
+	result := ReferencedByResult{
+		Kind:         target.GetKind(),
+		Name:         target.GetName(),
+		Namespace:    namespace,
+		ReferencedBy: referrersOf(target, pool.GetAllResources()),
+		Scanned:      pool.Size(),
+		PoolBuiltAt:  builtAt.UTC().Format(time.RFC3339),
+	}
+	log.Printf("%s/%s is referenced by %d of %d resources", target.GetKind(), resourceName, len(result.ReferencedBy), result.Scanned)
+	c.JSON(http.StatusOK, result)
+}
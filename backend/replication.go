@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// vizReplicationPeerAnnotation names the DR peer of a Cluster as [<configured cluster>/]<namespace>/<name>,
+	// on the Cluster itself or on an OpsRequest targeting it
+	vizReplicationPeerAnnotation = "viz.kubeblocks.io/replication-peer"
+	// vizReplicationRoleAnnotation is the role of the annotated Cluster in the pair, primary or standby
+	vizReplicationRoleAnnotation = "viz.kubeblocks.io/replication-role"
+)
+
+// Roles of a Cluster in a replication pair
+const (
+	ReplicationPrimary = "primary"
+	ReplicationStandby = "standby"
+)
+
+// ReplicationEndpoint is one Cluster of a replication pair
+type ReplicationEndpoint struct {
+	// KubeCluster is the configured cluster (kube-context) the Cluster lives in, see /api/clusters-config
+	KubeCluster string `json:"kubeCluster"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Role        string `json:"role,omitempty"`
+	Phase       string `json:"phase,omitempty"`
+	// Found is false when the peer does not exist or its cluster could not be read
+	Found bool `json:"found"`
+	// TreeURL is the v2 tree of the Cluster, to render both sides of the pair together
+	TreeURL string `json:"treeURL"`
+}
+
+// ReplicationPair links a primary Cluster to its standby, possibly in another kube-context
+type ReplicationPair struct {
+	Primary ReplicationEndpoint `json:"primary"`
+	Standby ReplicationEndpoint `json:"standby"`
+	// Mutual is set when both Clusters name each other as peer
+	Mutual bool `json:"mutual"`
+	// Sources are the resources declaring the pair, e.g. annotation:default/ns/name or opsrequest:default/ns/name
+	Sources  []string `json:"sources"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ReplicationPairsResult is the response of GET /api/kubeblocks/replication-pairs
+type ReplicationPairsResult struct {
+	Pairs    []ReplicationPair `json:"pairs"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// replicationDeclaration is a Cluster naming its peer, by annotation or through an OpsRequest
+type replicationDeclaration struct {
+	peer   string // Reference of the peer
+	role   string
+	source string
+}
+
+// replicationScan holds the Clusters and declarations read from the configured clusters
+type replicationScan struct {
+	mu           sync.Mutex
+	phases       map[string]string                 // Phase by Cluster reference
+	declarations map[string]replicationDeclaration // By declaring Cluster reference
+	warnings     []string
+}
+
+// replicationRef is the reference of a Cluster, <configured cluster>/<namespace>/<name>
+func replicationRef(kubeCluster, namespace, name string) string {
+	return kubeCluster + "/" + namespace + "/" + name
+}
+
+// parseReplicationPeer resolves a peer annotation, <namespace>/<name> refers to the Cluster's own kube-context
+func parseReplicationPeer(value, kubeCluster string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	if len(parts) == 2 {
+		parts = append([]string{kubeCluster}, parts...)
+	}
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("invalid replication peer %q, expected [cluster/]namespace/name", value)
+	}
+	return replicationRef(parts[0], parts[1], parts[2]), nil
+}
+
+// newReplicationEndpoint describes the Cluster of a reference as found by the scan
+func (scan *replicationScan) newReplicationEndpoint(ref, role string) ReplicationEndpoint {
+	parts := strings.SplitN(ref, "/", 3)
+	phase, found := scan.phases[ref]
+	return ReplicationEndpoint{
+		KubeCluster: parts[0],
+		Namespace:   parts[1],
+		Name:        parts[2],
+		Role:        role,
+		Phase:       phase,
+		Found:       found,
+		TreeURL: fmt.Sprintf("/api/v2/resources/cluster/%s/tree?%s", url.PathEscape(parts[2]),
+			url.Values{"namespace": {parts[1]}, "cluster": {parts[0]}}.Encode()),
+	}
+}
+
+// scanReplication reads the Clusters and the OpsRequests declaring peers of one configured cluster.
+// Annotations on a Cluster take precedence over OpsRequests, and newer OpsRequests over older ones.
+func (scan *replicationScan) scanReplication(kubeCluster string, client *K8sClient) {
+	clusters, err := listInAllowedNamespaces(client, clusterGVR, "")
+	if err != nil {
+		scan.warn("Unable to list clusters of %s: %v", kubeCluster, err)
+		return
+	}
+	opsRequests, err := listInAllowedNamespaces(client, opsRequestGVR, "")
+	if err != nil {
+		scan.warn("Unable to list OpsRequests of %s: %v", kubeCluster, err)
+	}
+
+	phases := map[string]string{}
+	declarations := map[string]replicationDeclaration{}
+	declare := func(resource *unstructured.Unstructured, clusterName, source string) {
+		value := resource.GetAnnotations()[vizReplicationPeerAnnotation]
+		if value == "" {
+			return
+		}
+		peer, err := parseReplicationPeer(value, kubeCluster)
+		if err != nil {
+			scan.warn("%s/%s %s in %s: %v", resource.GetKind(), resource.GetName(), resource.GetNamespace(), kubeCluster, err)
+			return
+		}
+		role := strings.ToLower(resource.GetAnnotations()[vizReplicationRoleAnnotation])
+		if role != "" && role != ReplicationPrimary && role != ReplicationStandby {
+			scan.warn("%s/%s %s in %s: invalid replication role %q, expected %s or %s", resource.GetKind(), resource.GetName(), resource.GetNamespace(), kubeCluster, role, ReplicationPrimary, ReplicationStandby)
+			role = ""
+		}
+		ref := replicationRef(kubeCluster, resource.GetNamespace(), clusterName)
+		declarations[ref] = replicationDeclaration{peer: peer, role: role,
+			source: source + ":" + replicationRef(kubeCluster, resource.GetNamespace(), resource.GetName())}
+	}
+
+	sort.SliceStable(opsRequests, func(i, j int) bool {
+		return opsRequests[i].GetCreationTimestamp().Time.Before(opsRequests[j].GetCreationTimestamp().Time)
+	})
+	for i := range opsRequests {
+		clusterName, _, _ := unstructured.NestedString(opsRequests[i].Object, "spec", "clusterName")
+		if clusterName == "" {
+			// KubeBlocks 0.x
+			clusterName, _, _ = unstructured.NestedString(opsRequests[i].Object, "spec", "clusterRef")
+		}
+		phase, _, _ := unstructured.NestedString(opsRequests[i].Object, "status", "phase")
+		if clusterName == "" || phase == "Failed" || phase == "Cancelled" {
+			continue
+		}
+		declare(&opsRequests[i], clusterName, "opsrequest")
+	}
+	for i := range clusters {
+		phase, _, _ := unstructured.NestedString(clusters[i].Object, "status", "phase")
+		phases[replicationRef(kubeCluster, clusters[i].GetNamespace(), clusters[i].GetName())] = phase
+		declare(&clusters[i], clusters[i].GetName(), "annotation")
+	}
+
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+	for ref, phase := range phases {
+		scan.phases[ref] = phase
+	}
+	for ref, declaration := range declarations {
+		scan.declarations[ref] = declaration
+	}
+}
+
+func (scan *replicationScan) warn(format string, args ...interface{}) {
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+	scan.warnings = append(scan.warnings, fmt.Sprintf(format, args...))
+}
+
+// pairs correlates the declarations of both sides into pairs
+func (scan *replicationScan) pairs() []ReplicationPair {
+	refs := make([]string, 0, len(scan.declarations))
+	for ref := range scan.declarations {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	pairs := []ReplicationPair{}
+	paired := map[string]bool{}
+	for _, ref := range refs {
+		declaration := scan.declarations[ref]
+		if paired[ref] {
+			continue
+		}
+		paired[ref] = true
+		pair := ReplicationPair{Sources: []string{declaration.source}}
+
+		peerDeclaration, peerDeclares := scan.declarations[declaration.peer]
+		peerRole := ""
+		switch {
+		case peerDeclares && peerDeclaration.peer == ref:
+			pair.Mutual = true
+			paired[declaration.peer] = true
+			pair.Sources = append(pair.Sources, peerDeclaration.source)
+			peerRole = peerDeclaration.role
+		case peerDeclares:
+			pair.Warnings = append(pair.Warnings, fmt.Sprintf("%s names %s as its peer, not %s", declaration.peer, peerDeclaration.peer, ref))
+		}
+
+		role := declaration.role
+		switch {
+		case role == "" && peerRole == "":
+			pair.Warnings = append(pair.Warnings, fmt.Sprintf("No replication role set, %s is assumed to be the primary", ref))
+			role = ReplicationPrimary
+		case role == "":
+			role = oppositeReplicationRole(peerRole)
+		case peerRole == role:
+			pair.Warnings = append(pair.Warnings, fmt.Sprintf("Both %s and %s are %s", ref, declaration.peer, role))
+		}
+
+		primary, standby := ref, declaration.peer
+		if role == ReplicationStandby {
+			primary, standby = standby, primary
+		}
+		pair.Primary = scan.newReplicationEndpoint(primary, ReplicationPrimary)
+		pair.Standby = scan.newReplicationEndpoint(standby, ReplicationStandby)
+		if _, configured := clusterClients.clients[strings.SplitN(declaration.peer, "/", 2)[0]]; !configured {
+			pair.Warnings = append(pair.Warnings, fmt.Sprintf("Cluster %s of peer %s is not configured", strings.SplitN(declaration.peer, "/", 2)[0], declaration.peer))
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+func oppositeReplicationRole(role string) string {
+	if role == ReplicationPrimary {
+		return ReplicationStandby
+	}
+	return ReplicationPrimary
+}
+
+// getReplicationPairs correlates KubeBlocks Clusters configured as primary/DR pairs across all
+// configured clusters, so the UI can render both trees side by side with the replication link
+func getReplicationPairs(c *gin.Context) {
+	log.Printf("Listing replication pairs across %d clusters requested from %s", len(clusterClients.infos), c.ClientIP())
+
+	scan := &replicationScan{phases: map[string]string{}, declarations: map[string]replicationDeclaration{}}
+	var wg sync.WaitGroup
+	for _, info := range clusterClients.infos {
+		client := clusterClients.clients[info.Name]
+		if client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(kubeCluster string, client *K8sClient) {
+			defer wg.Done()
+			scan.scanReplication(kubeCluster, client.withRequestID(requestIDFor(c)))
+		}(info.Name, client)
+	}
+	wg.Wait()
+
+	result := ReplicationPairsResult{Pairs: scan.pairs(), Warnings: scan.warnings}
+	sort.Strings(result.Warnings)
+	log.Printf("Found %d replication pairs across %d clusters", len(result.Pairs), len(clusterClients.infos))
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "requestID"
+)
+
+// validRequestID limits incoming IDs to what is safe to copy into headers and logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDMiddleware accepts the caller's X-Request-ID or generates one, and returns it in the response
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestIDFor returns the ID of the request, empty outside of requestIDMiddleware
+func requestIDFor(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// requestLogFormatter is the gin access log format with the request ID appended
+func requestLogFormatter(param gin.LogFormatterParams) string {
+	id, _ := param.Keys[requestIDContextKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | request-id=%s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		id,
+		param.ErrorMessage,
+	)
+}
+
+// requestIDRoundTripper tags API server requests with the ID of the visualizer request. The API
+// server records the user agent in its audit events, which lets operators correlate both logs.
+type requestIDRoundTripper struct {
+	next http.RoundTripper
+	id   string
+}
+
+func (rt *requestIDRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(requestIDHeader, rt.id)
+	req.Header.Set("User-Agent", fmt.Sprintf("%s request-id/%s", req.Header.Get("User-Agent"), rt.id))
+	return rt.next.RoundTrip(req)
+}
+
+// withRequestID returns a copy of the client whose API calls carry the request ID. The copy shares
+// the transport, rate limiter and discovery cache of the client, falling back to the client itself on error.
+func (k *K8sClient) withRequestID(id string) *K8sClient {
+	if id == "" || k.httpClient == nil {
+		return k
+	}
+	httpClient := &http.Client{
+		Transport: &requestIDRoundTripper{next: k.httpClient.Transport, id: id},
+		Timeout:   k.httpClient.Timeout,
+	}
+	clientset, err := kubernetes.NewForConfigAndClient(k.config, httpClient)
+	if err != nil {
+		log.Printf("⚠️  Unable to tag API calls with request ID %s: %v", id, err)
+		return k
+	}
+	dynamicClient, err := dynamic.NewForConfigAndClient(k.config, httpClient)
+	if err != nil {
+		log.Printf("⚠️  Unable to tag API calls with request ID %s: %v", id, err)
+		return k
+	}
+
+	tagged := *k
+	tagged.clientset = clientset
+	tagged.dynamicClient = dynamicClient
+	return &tagged
+}
+
+// baseClient returns the registered client of the cluster, for work that outlives the request
+func baseClient(client *K8sClient) *K8sClient {
+	if registered, ok := clusterClients.clients[client.name]; ok {
+		return registered
+	}
+	return client
+}
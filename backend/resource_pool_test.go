@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Pools of the benchmarks hold 1000 synthetic clusters of 4 pods, 14000 resources
+const (
+	benchPoolClusters = 1000
+	benchPoolPods     = 4
+)
+
+// benchPoolResources generates the resources of the bench clusters with their owner references
+func benchPoolResources(b *testing.B) []*unstructured.Unstructured {
+	b.Helper()
+	objects := generateBenchObjects("bench", benchPoolClusters, benchPoolPods, true)
+	resources := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, object := range objects {
+		if object.owner != nil {
+			object.object.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: object.owner.GetAPIVersion(),
+				Kind:       object.owner.GetKind(),
+				Name:       object.owner.GetName(),
+				UID:        object.owner.GetUID(),
+			}})
+		}
+		resources = append(resources, object.object)
+	}
+	return resources
+}
+
+func benchPool(b *testing.B) (*ResourcePool, []*unstructured.Unstructured) {
+	b.Helper()
+	resources := benchPoolResources(b)
+	pool := NewResourcePoolWithCapacity(len(resources))
+	for _, resource := range resources {
+		pool.AddResource(resource)
+	}
+	return pool, resources
+}
+
+func BenchmarkAddResource(b *testing.B) {
+	resources := benchPoolResources(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool := NewResourcePoolWithCapacity(len(resources))
+		for _, resource := range resources {
+			pool.AddResource(resource)
+		}
+	}
+	b.ReportMetric(float64(len(resources)), "resources/op")
+}
+
+// BenchmarkGetChildrenByOwner looks up the children of every resource of the pool, the lookup is a
+// map access whatever the pool size
+func BenchmarkGetChildrenByOwner(b *testing.B) {
+	pool, resources := benchPool(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resource := resources[i%len(resources)]
+		pool.GetChildrenByOwner(resource.GetUID())
+	}
+}
+
+// BenchmarkSelectResources selects the pods of one cluster, as a Service selector does, through the
+// label index rather than by scanning all pods
+func BenchmarkSelectResources(b *testing.B) {
+	pool, _ := benchPool(b)
+	selectors := make([]labels.Selector, benchPoolClusters)
+	for i := range selectors {
+		selectors[i] = labels.SelectorFromSet(labels.Set{instanceLabel: benchClusterName(i)})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if matches := pool.SelectResources("Pod", selectors[i%len(selectors)]); len(matches) != benchPoolPods {
+			b.Fatalf("selected %d pods, expected %d", len(matches), benchPoolPods)
+		}
+	}
+}
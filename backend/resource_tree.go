@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -14,23 +16,89 @@ import (
 
 // ResourceTreeNode represents a node in the resource tree
 type ResourceTreeNode struct {
-	Resource *unstructured.Unstructured `json:"resource"`
-	Children []*ResourceTreeNode        `json:"children"`
+	Resource  *unstructured.Unstructured `json:"resource"`
+	Children  []*ResourceTreeNode        `json:"children"`
+	Placement *PodPlacement              `json:"placement,omitempty"` // Only set for pods
+	// DisplayName overrides the resource name in the UI (viz.kubeblocks.io/display-name)
+	DisplayName string `json:"displayName,omitempty"`
+	// Virtual marks synthetic nodes that do not exist in the cluster, e.g. annotation groups
+	Virtual bool `json:"virtual,omitempty"`
+	// Health is the worst health of the subtree, only set by the health post-processor (tree_pipeline.go)
+	Health string `json:"health,omitempty"`
+	// Problems detected on the resource, e.g. crash-looping containers
+	Problems []Problem `json:"problems,omitempty"`
+	// SelectorTargets holds the UIDs of pods selected by a Service
+	SelectorTargets []string `json:"selectorTargets,omitempty"`
+	// Endpoints summarizes the EndpointSlices of a Service
+	Endpoints *EndpointSummary `json:"endpoints,omitempty"`
+	// Storage describes the PV and StorageClass backing a PVC
+	Storage *StorageChain `json:"storage,omitempty"`
+	// Age and LastUpdated let the UI sort children by recency
+	Age         string `json:"age,omitempty"`
+	LastUpdated string `json:"lastUpdated,omitempty"`
+	// ServiceAccount summarizes the permissions a pod runs with, only set when RBAC is shown
+	ServiceAccount *ServiceAccountPermissions `json:"serviceAccount,omitempty"`
+	// DeletionTimestamp and Finalizers show resources stuck terminating
+	DeletionTimestamp string   `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string `json:"finalizers,omitempty"`
+	// Layout holds the depth, subtree size and suggested sibling order of the node
+	Layout *LayoutHint `json:"layout,omitempty"`
+	// GitOpsSource is the Argo CD or Flux application that applied the resource or its ancestor
+	GitOpsSource *GitOpsSource `json:"gitopsSource,omitempty"`
+	// Deleted marks nodes that disappeared during a live session, kept until DeletedAt plus the grace period
+	Deleted   bool   `json:"deleted,omitempty"`
+	DeletedAt string `json:"deletedAt,omitempty"`
+	// Truncated marks nodes whose children were not expanded within the time budget or node cap
+	Truncated bool `json:"truncated,omitempty"`
+	// References are resources outside the tree referenced by name and namespace
+	References []ResourceReference `json:"references,omitempty"`
+	// SecondaryChildren holds the UIDs of resources this resource co-owns that are placed under their primary owner
+	SecondaryChildren []string `json:"secondaryChildren,omitempty"`
+	// JobHistory summarizes the Jobs of a CronJob that are collapsed out of its children
+	JobHistory *JobHistorySummary `json:"jobHistory,omitempty"`
+	// HiddenSystemChildren counts the children hidden as system resources, see showSystem
+	HiddenSystemChildren int `json:"hiddenSystemChildren,omitempty"`
 }
 
 // ResourcePool manages a pool of resources for efficient tree building
 type ResourcePool struct {
 	resources map[types.UID]*unstructured.Unstructured
 	byOwner   map[types.UID][]*unstructured.Unstructured
+	parents   map[types.UID]types.UID // Parents set by annotation for resources without owners
+	byKind    map[string][]*unstructured.Unstructured
+	byLabel   map[string][]*unstructured.Unstructured // Keyed by key=value, for selector resolution
 }
 
+// poolDetailLogLimit is the pool size up to which every resource of a built pool is logged
+const poolDetailLogLimit = 200
+
 // ResourceTreeBuilder builds resource trees based on ownerReference relationships
 type ResourceTreeBuilder struct {
 	client      *K8sClient
 	namespace   string
 	visited     map[types.UID]bool // To prevent cycles
+	mu          sync.Mutex         // Guards nodeCount, warnings and truncated while children are resolved in parallel
 	listOptions metav1.ListOptions
 	pool        *ResourcePool // Resource pool for efficient lookups
+	warnings    []string      // Non-fatal issues reported to API consumers
+	deadline    time.Time     // Time budget of the tree walk, zero when unlimited
+	truncated   []string      // UIDs of nodes left unexpanded when a limit was reached
+	maxNodes    int           // Cap on the number of tree nodes, zero when unlimited
+	nodeCount   int
+	includeRBAC bool   // Show ServiceAccounts, Roles and RoleBindings
+	reveal      bool   // Keep sensitive fields when decorating, they are redacted by default
+	language    string // Language of problem messages and warnings, see i18n.go
+	showSystem  bool   // Show ControllerRevisions, Succeeded pods and old completed Jobs
+	// includeRevisions shows the ControllerRevisions of StatefulSets and InstanceSets
+	includeRevisions bool
+	// deferGrouping leaves the visualization annotations to the pipeline of the request
+	deferGrouping bool
+	// backupRepos caches the BackupRepos of BackupPolicies and Backups, by name
+	backupRepos map[string]backupRepoLookup
+	// workers resolve large child sets in parallel, per kind, see tree_parallel.go
+	workers int
+	// root is the UID of the resource the tree is built from, continuation tokens are bound to it
+	root types.UID
 }
 
 // NewResourceTreeBuilder creates a new ResourceTreeBuilder
@@ -41,30 +109,64 @@ func NewResourceTreeBuilder(client *K8sClient, namespace string, listOptions met
 		visited:     make(map[types.UID]bool),
 		listOptions: listOptions,
 		pool:        nil, // Will be built when needed
+		maxNodes:    appConfig.MaxTreeNodes,
+		includeRBAC: appConfig.ShowRBAC,
+		language:    languageEnglish,
+		showSystem:  appConfig.ShowSystem,
+		workers:     appConfig.TreeBuildWorkers,
 	}
 }
 
+// addWarning records a non-fatal issue encountered while building the tree, in the language of the request
+func (rtb *ResourceTreeBuilder) addWarning(format string, args ...interface{}) {
+	log.Printf("⚠️  %s", fmt.Sprintf(format, args...))
+	warning := localize(rtb.language, format, args...)
+	rtb.mu.Lock()
+	rtb.warnings = append(rtb.warnings, warning)
+	rtb.mu.Unlock()
+}
+
+// Warnings returns the non-fatal issues encountered while building the tree
+func (rtb *ResourceTreeBuilder) Warnings() []string {
+	return rtb.warnings
+}
+
 // NewResourcePool creates a new ResourcePool
 func NewResourcePool() *ResourcePool {
+	return NewResourcePoolWithCapacity(0)
+}
+
+// NewResourcePoolWithCapacity creates a ResourcePool sized for the expected number of resources,
+// so large pools are not rehashed while they are filled
+func NewResourcePoolWithCapacity(capacity int) *ResourcePool {
 	return &ResourcePool{
-		resources: make(map[types.UID]*unstructured.Unstructured),
-		byOwner:   make(map[types.UID][]*unstructured.Unstructured),
+		resources: make(map[types.UID]*unstructured.Unstructured, capacity),
+		byOwner:   make(map[types.UID][]*unstructured.Unstructured, capacity/2),
+		parents:   make(map[types.UID]types.UID),
+		byKind:    make(map[string][]*unstructured.Unstructured),
+		byLabel:   make(map[string][]*unstructured.Unstructured, capacity),
 	}
 }
 
-// AddResource adds a resource to the pool and indexes it by owner references
+// AddResource adds a resource to the pool and indexes it by owner references, kind and labels.
+// A resource already in the pool is not indexed twice.
 func (rp *ResourcePool) AddResource(resource *unstructured.Unstructured) {
 	uid := resource.GetUID()
+	if rp.resources[uid] != nil {
+		return
+	}
 	rp.resources[uid] = resource
 
 	// Index by owner references
-	ownerReferences := resource.GetOwnerReferences()
-	for _, ownerRef := range ownerReferences {
-		if rp.byOwner[ownerRef.UID] == nil {
-			rp.byOwner[ownerRef.UID] = make([]*unstructured.Unstructured, 0)
-		}
+	for _, ownerRef := range resource.GetOwnerReferences() {
 		rp.byOwner[ownerRef.UID] = append(rp.byOwner[ownerRef.UID], resource)
 	}
+
+	kind := resource.GetKind()
+	rp.byKind[kind] = append(rp.byKind[kind], resource)
+	for key, value := range resource.GetLabels() {
+		rp.byLabel[key+"="+value] = append(rp.byLabel[key+"="+value], resource)
+	}
 }
 
 // GetChildrenByOwner returns all resources that have the specified owner UID
@@ -82,12 +184,12 @@ func (rp *ResourcePool) Size() int {
 	return len(rp.resources)
 }
 
-// GetRootResources returns all resources that have no owner references
+// GetRootResources returns all resources that have no owner references nor annotated parent
 func (rp *ResourcePool) GetRootResources() []*unstructured.Unstructured {
 	var roots []*unstructured.Unstructured
 	for _, resource := range rp.resources {
 		ownerReferences := resource.GetOwnerReferences()
-		if len(ownerReferences) == 0 {
+		if len(ownerReferences) == 0 && rp.parents[resource.GetUID()] == "" {
 			roots = append(roots, resource)
 		}
 	}
@@ -202,52 +304,83 @@ func (rp *ResourcePool) PrintResourcePoolSummary() {
 	fmt.Printf("\n🌱 Roots: %d\n", rootCount)
 }
 
+// candidateResourceTypes returns the resource types the pool is built from
+func (rtb *ResourceTreeBuilder) candidateResourceTypes() []schema.GroupVersionResource {
+	candidates := rtb.getSupportedResourceTypes()
+	if rtb.includeRBAC {
+		candidates = append(candidates, rbacResourceTypes...)
+	}
+	if rtb.showSystem || rtb.includeRevisions {
+		candidates = append(candidates, systemResourceTypes...)
+	}
+	return candidates
+}
+
 // buildResourcePool builds a pool of all resources matching the ListOptions
 func (rtb *ResourceTreeBuilder) buildResourcePool() error {
 	log.Printf("🏗️  Building resource pool...")
 
-	rtb.pool = NewResourcePool()
-	resourceTypes := rtb.getSupportedResourceTypes()
+	// Only list the types that have matching resources
+	resourceTypes, probed := rtb.detectResourceTypes(rtb.candidateResourceTypes())
 
-	totalResources := 0
+	// List every type first, so the pool is sized once for all of them
+	lists := make([]*unstructured.UnstructuredList, 0, len(resourceTypes))
+	listedTypes := make([]schema.GroupVersionResource, 0, len(resourceTypes))
+	capacity := 0
 	for _, gvr := range resourceTypes {
 		log.Printf("  📦 Loading resource type: %s", gvr.Resource)
 
-		var resourceList *unstructured.UnstructuredList
+		// The detection probe may already have returned all resources of the type
+		resourceList, found := probed[gvr]
 		var err error
 
 		// Search in the specified namespace or cluster-wide
-		if rtb.namespace != "" {
-			resourceList, err = rtb.client.dynamicClient.Resource(gvr).Namespace(rtb.namespace).List(context.TODO(), rtb.listOptions)
-		} else {
-			resourceList, err = rtb.client.dynamicClient.Resource(gvr).List(context.TODO(), rtb.listOptions)
+		if !found {
+			if rtb.namespace != "" {
+				resourceList, err = rtb.client.dynamicClient.Resource(gvr).Namespace(rtb.namespace).List(context.TODO(), rtb.listOptions)
+			} else {
+				resourceList, err = rtb.client.dynamicClient.Resource(gvr).List(context.TODO(), rtb.listOptions)
+			}
 		}
 
 		if err != nil {
 			log.Printf("    ⚠️  Skipping resource type %s due to error: %v", gvr.Resource, err)
 			continue
 		}
+		lists = append(lists, resourceList)
+		listedTypes = append(listedTypes, gvr)
+		capacity += len(resourceList.Items)
+	}
 
+	rtb.pool = NewResourcePoolWithCapacity(capacity)
+	totalResources := 0
+	for i, resourceList := range lists {
 		// Add all resources to the pool
-		resourceCount := 0
-		for i := range resourceList.Items {
-			resource := &resourceList.Items[i]
-			rtb.pool.AddResource(resource)
-			resourceCount++
+		for j := range resourceList.Items {
+			rtb.pool.AddResource(&resourceList.Items[j])
 		}
 
-		if resourceCount > 0 {
-			log.Printf("    ✅ Added %d resources of type %s", resourceCount, gvr.Resource)
-			totalResources += resourceCount
+		if len(resourceList.Items) > 0 {
+			log.Printf("    ✅ Added %d resources of type %s", len(resourceList.Items), listedTypes[i].Resource)
+			totalResources += len(resourceList.Items)
 		}
 	}
 
 	log.Printf("🎯 Resource pool built successfully with %d total resources", totalResources)
 
+	for _, missing := range rtb.pool.LinkAnnotatedParents() {
+		rtb.addWarning("Parent %s of %s/%s not found in the tree", missing.parent, missing.resource.GetKind(), missing.resource.GetName())
+	}
+
+	// Keep the pool around for lazy child expansion
+	resourcePools.put(poolCacheKey(rtb.client, rtb.namespace, rtb.listOptions), rtb.pool)
+
 	// Print resource pool summary for debugging
 	log.Printf("📊 Resource Pool Summary:")
 	rtb.pool.PrintResourcePoolSummary()
-	rtb.pool.PrintResourcePool()
+	if rtb.pool.Size() <= poolDetailLogLimit {
+		rtb.pool.PrintResourcePool()
+	}
 
 	return nil
 }
@@ -258,6 +391,7 @@ func (rtb *ResourceTreeBuilder) GetResourceTree(rootResource *unstructured.Unstr
 	if rootResource == nil {
 		return nil, fmt.Errorf("root resource cannot be nil")
 	}
+	rtb.root = rootResource.GetUID()
 
 	// Build resource pool if not already built
 	if rtb.pool == nil {
@@ -266,14 +400,33 @@ func (rtb *ResourceTreeBuilder) GetResourceTree(rootResource *unstructured.Unstr
 		}
 	}
 
-	return rtb.buildTreeFromPool(rootResource)
+	// Without children under the selector, the descendants may lack the instance label
+	fallback := rtb.listOptions.LabelSelector != "" && len(rtb.pool.GetChildrenByOwner(rootResource.GetUID())) == 0
+	if fallback {
+		rtb.addUnselectedResources()
+	}
+
+	tree, err := rtb.buildTreeFromPool(rootResource)
+	if err == nil && fallback {
+		rtb.warnUnlabeledDescendants(tree)
+	}
+	return tree, err
 }
 
-// buildTreeFromPool builds a tree using the pre-built resource pool
+// buildTreeFromPool builds a tree using the pre-built resource pool. The root always counts
+// towards the node cap, so a continuation past the cap still shows its truncated node.
 func (rtb *ResourceTreeBuilder) buildTreeFromPool(rootResource *unstructured.Unstructured) (*ResourceTreeNode, error) {
+	rtb.countNode()
+	return rtb.buildSubtree(rootResource, rtb.visited, true)
+}
+
+// buildSubtree builds the tree of a resource, visited holds its ancestors to detect cycles.
+// Large child sets are resolved in parallel when parallel is set, only once per branch.
+// The caller has counted the resource towards the node cap.
+func (rtb *ResourceTreeBuilder) buildSubtree(rootResource *unstructured.Unstructured, visited map[types.UID]bool, parallel bool) (*ResourceTreeNode, error) {
 	rootUID := rootResource.GetUID()
-	if rtb.visited[rootUID] {
-		log.Printf("⚠️  Cycle detected for resource %s/%s (UID: %s)", rootResource.GetKind(), rootResource.GetName(), rootUID)
+	if visited[rootUID] {
+		rtb.addWarning("Cycle detected for resource %s/%s (UID: %s)", rootResource.GetKind(), rootResource.GetName(), rootUID)
 		return &ResourceTreeNode{
 			Resource: rootResource,
 			Children: []*ResourceTreeNode{},
@@ -281,9 +434,9 @@ func (rtb *ResourceTreeBuilder) buildTreeFromPool(rootResource *unstructured.Uns
 	}
 
 	// Mark this resource as visited to prevent cycles
-	rtb.visited[rootUID] = true
+	visited[rootUID] = true
 	defer func() {
-		rtb.visited[rootUID] = false // Reset for other branches
+		visited[rootUID] = false // Reset for other branches
 	}()
 
 	log.Printf("🌳 Building tree node for %s/%s (UID: %s)",
@@ -294,31 +447,56 @@ func (rtb *ResourceTreeBuilder) buildTreeFromPool(rootResource *unstructured.Uns
 		Children: []*ResourceTreeNode{},
 	}
 
-	// Find all child resources that have this resource as owner from the pool
-	children := rtb.pool.GetChildrenByOwner(rootUID)
+	// Stop expanding once the time budget is spent or the node cap is reached, a continuation resumes from here
+	if rtb.budgetExceeded() || rtb.nodeLimitReached() {
+		if children, _ := rtb.pool.SplitChildrenByPrimaryOwner(rootUID); len(children) > 0 {
+			rtb.truncate(node)
+		}
+		return node, nil
+	}
+
+	// Find all child resources that have this resource as owner from the pool. Resources with
+	// several owners are placed under their primary owner only, the others get a secondary edge.
+	// Children are sorted by kind and name, so trees are stable between builds.
+	children, secondary := rtb.pool.SplitChildrenByPrimaryOwner(rootUID)
+	sortResources(children)
+	sortResources(secondary)
+	for _, child := range secondary {
+		node.SecondaryChildren = append(node.SecondaryChildren, string(child.GetUID()))
+	}
 	log.Printf("📊 Found %d direct children for %s/%s from resource pool",
 		len(children), rootResource.GetKind(), rootResource.GetName())
 
 	// Recursively build subtrees for each child
+	var shown []*unstructured.Unstructured
 	for _, child := range children {
-		// Remove the child from pool since it's now being used
-		log.Printf("🔍 Removing child %s/%s (UID: %s) from resource pool (remaining: %d)",
-			child.GetKind(), child.GetName(), child.GetUID(), rtb.pool.Size()-1)
-		// rtb.pool.RemoveResource(child.GetUID())
-
-		childNode, err := rtb.buildTreeFromPool(child)
-		if err != nil {
-			log.Printf("⚠️  Error building subtree for %s/%s: %v",
-				child.GetKind(), child.GetName(), err)
-			// Create a leaf node for this child
-			leafNode := &ResourceTreeNode{
-				Resource: child,
-				Children: []*ResourceTreeNode{},
-			}
-			node.Children = append(node.Children, leafNode)
+		if rtb.hiddenAsSystem(rootResource, child) {
+			node.HiddenSystemChildren++
 			continue
 		}
-		node.Children = append(node.Children, childNode)
+		shown = append(shown, child)
+	}
+	if parallel && rtb.workers > 1 && len(shown) >= parallelChildThreshold {
+		node.Children = rtb.buildChildrenInParallel(node, shown, visited)
+	} else {
+		for _, child := range shown {
+			if rtb.reserveNodes(1) == 0 {
+				rtb.truncate(node)
+				break
+			}
+			node.Children = append(node.Children, rtb.buildChild(child, visited, parallel))
+		}
+	}
+
+	// Only keep the running and latest Jobs of a CronJob
+	if rootResource.GetKind() == "CronJob" {
+		collapseJobHistory(node)
+	}
+
+	// Hide, rename and group children according to their visualization annotations, unless the
+	// group post-processor of the request pipeline does it after the build
+	if !rtb.deferGrouping {
+		applyVisualizationHints(node)
 	}
 
 	log.Printf("✅ Successfully built tree node for %s/%s with %d children",
@@ -327,6 +505,28 @@ func (rtb *ResourceTreeBuilder) buildTreeFromPool(rootResource *unstructured.Uns
 	return node, nil
 }
 
+// DecorateTree enriches a built tree with placement, problems, selector relationships,
+// endpoint readiness, storage chains, allowlisted cross-namespace references, BackupRepos and freshness
+func (rtb *ResourceTreeBuilder) DecorateTree(root *ResourceTreeNode) {
+	rtb.DecoratePlacement(root)
+	rtb.DetectProblems(root)
+	rtb.ResolveSelectorEdges(root)
+	rtb.SummarizeEndpoints(root)
+	rtb.AttachStorageChains(root)
+	rtb.ResolveCrossNamespaceRefs(root)
+	rtb.LinkBackupRepos(root)
+	rtb.AnnotateFreshness(root)
+	rtb.AnnotateDeletions(root)
+	rtb.AnnotateGitOpsSources(root)
+	if rtb.includeRBAC {
+		rtb.AttachServiceAccounts(root)
+	}
+	rtb.AnnotateLayout(root)
+	if !rtb.reveal {
+		RedactTree(root)
+	}
+}
+
 // GetAllResourceTrees builds trees for all root resources (resources without owners)
 func (rtb *ResourceTreeBuilder) GetAllResourceTrees() ([]*ResourceTreeNode, error) {
 	// Build resource pool if not already built
@@ -337,6 +537,7 @@ func (rtb *ResourceTreeBuilder) GetAllResourceTrees() ([]*ResourceTreeNode, erro
 	}
 
 	roots := rtb.pool.GetRootResources()
+	sortResources(roots)
 	log.Printf("🌲 Found %d root resources to build trees from", len(roots))
 
 	var trees []*ResourceTreeNode
@@ -377,51 +578,10 @@ func (rtb *ResourceTreeBuilder) hasOwnerReference(resource *unstructured.Unstruc
 	return false
 }
 
-// getSupportedResourceTypes returns all resource types that should be searched for children
+// getSupportedResourceTypes returns all resource types that should be searched for children,
+// the builtin ones adjusted by the tree types file
 func (rtb *ResourceTreeBuilder) getSupportedResourceTypes() []schema.GroupVersionResource {
-	return []schema.GroupVersionResource{
-		// Core resources
-		{Group: "", Version: "v1", Resource: "pods"},
-		{Group: "", Version: "v1", Resource: "services"},
-		{Group: "", Version: "v1", Resource: "configmaps"},
-		// {Group: "", Version: "v1", Resource: "secrets"},
-		{Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
-		// {Group: "", Version: "v1", Resource: "serviceaccounts"},
-		// {Group: "", Version: "v1", Resource: "endpoints"},
-
-		// Apps resources
-		{Group: "apps", Version: "v1", Resource: "deployments"},
-		{Group: "apps", Version: "v1", Resource: "replicasets"},
-		{Group: "apps", Version: "v1", Resource: "statefulsets"},
-		{Group: "apps", Version: "v1", Resource: "daemonsets"},
-
-		// Batch resources
-		{Group: "batch", Version: "v1", Resource: "jobs"},
-		{Group: "batch", Version: "v1", Resource: "cronjobs"},
-
-		// // Networking resources
-		// {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
-		// {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
-
-		// // RBAC resources
-		// {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
-		// {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
-		// {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
-		// {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
-
-		// KubeBlocks resources
-		{Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
-		{Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
-		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
-		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
-		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
-		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
-		{Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
-		{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
-		{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
-		{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
-		{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
-	}
+	return currentTreeResourceTypes()
 }
 
 // PrintTree prints the tree structure for debugging (optional utility function)
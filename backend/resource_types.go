@@ -0,0 +1,257 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Sources of resolvable resource types
+const (
+	ResourceTypeSourceBuiltin   = "builtin"
+	ResourceTypeSourceCustom    = "custom"
+	ResourceTypeSourceDiscovery = "discovery"
+)
+
+const discoveryCacheTTL = 5 * time.Minute
+
+// CustomResourceType is a resource type added through configuration
+type CustomResourceType struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Resource   string   `json:"resource"`
+	Kind       string   `json:"kind,omitempty"`
+	ShortNames []string `json:"shortNames,omitempty"`
+	Namespaced *bool    `json:"namespaced,omitempty"`
+}
+
+// ResourceTypeInfo describes a resource type the server can resolve
+type ResourceTypeInfo struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Resource   string   `json:"resource"`
+	Kind       string   `json:"kind,omitempty"`
+	ShortNames []string `json:"shortNames"`
+	Namespaced bool     `json:"namespaced"`
+	Source     string   `json:"source"`
+}
+
+// GVR returns the GroupVersionResource of the type
+func (rti *ResourceTypeInfo) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: rti.Group, Version: rti.Version, Resource: rti.Resource}
+}
+
+// discoveryCache caches the preferred API resources served by a cluster
+type discoveryCache struct {
+	client  discovery.DiscoveryInterface
+	mu      sync.Mutex
+	fetched time.Time
+	types   []ResourceTypeInfo
+	aliases map[string]schema.GroupVersionResource
+}
+
+// get returns the cached discovery results, refreshing them when stale
+func (dc *discoveryCache) get() ([]ResourceTypeInfo, map[string]schema.GroupVersionResource) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.types != nil && time.Since(dc.fetched) < discoveryCacheTTL {
+		return dc.types, dc.aliases
+	}
+
+	resourceLists, err := dc.client.ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			log.Printf("⚠️  API discovery failed: %v", err)
+			return dc.types, dc.aliases
+		}
+		// Partial results are still usable
+		log.Printf("⚠️  API discovery partially failed: %v", err)
+	}
+
+	var types []ResourceTypeInfo
+	aliases := map[string]schema.GroupVersionResource{}
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			// Skip subresources such as pods/log
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			info := ResourceTypeInfo{
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Resource:   resource.Name,
+				Kind:       resource.Kind,
+				ShortNames: append([]string{}, resource.ShortNames...),
+				Namespaced: resource.Namespaced,
+				Source:     ResourceTypeSourceDiscovery,
+			}
+			types = append(types, info)
+
+			gvr := info.GVR()
+			for _, alias := range append([]string{resource.Name, resource.SingularName, strings.ToLower(resource.Kind)}, resource.ShortNames...) {
+				if alias == "" {
+					continue
+				}
+				if _, exists := aliases[alias]; !exists {
+					aliases[alias] = gvr
+				}
+			}
+		}
+	}
+
+	dc.types = types
+	dc.aliases = aliases
+	dc.fetched = time.Now()
+	log.Printf("✓ API discovery refreshed: %d resource types", len(types))
+	return dc.types, dc.aliases
+}
+
+// resolveResourceType resolves a normalized alias through custom types and API discovery
+func resolveResourceType(alias string) (schema.GroupVersionResource, bool) {
+	for _, custom := range appConfig.CustomResourceTypes {
+		gvr := schema.GroupVersionResource{Group: custom.Group, Version: custom.Version, Resource: custom.Resource}
+		if alias == custom.Resource || alias == strings.ToLower(custom.Kind) {
+			return gvr, true
+		}
+		for _, shortName := range custom.ShortNames {
+			if alias == strings.ToLower(shortName) {
+				return gvr, true
+			}
+		}
+	}
+
+	if k8sClient == nil {
+		return schema.GroupVersionResource{}, false
+	}
+	_, aliases := k8sClient.apiDiscovery.get()
+	gvr, exists := aliases[alias]
+	return gvr, exists
+}
+
+// clusterScopedResourceType reports whether the cluster serves the type without namespaces, as
+// far as discovery and the configured custom types tell
+func clusterScopedResourceType(client *K8sClient, gvr schema.GroupVersionResource) bool {
+	for _, info := range listResourceTypes(client) {
+		if info.GVR() == gvr {
+			return !info.Namespaced
+		}
+	}
+	return false
+}
+
+// listResourceTypes returns every resource type the server can resolve in the cluster, builtin types first
+func listResourceTypes(client *K8sClient) []ResourceTypeInfo {
+	discovered, _ := client.apiDiscovery.get()
+	discoveredByGVR := make(map[schema.GroupVersionResource]ResourceTypeInfo, len(discovered))
+	for _, info := range discovered {
+		discoveredByGVR[info.GVR()] = info
+	}
+
+	seen := map[schema.GroupVersionResource]bool{}
+	var types []ResourceTypeInfo
+
+	// Builtin types, with their aliases as shortnames
+	builtin := map[schema.GroupVersionResource]*ResourceTypeInfo{}
+	var builtinOrder []schema.GroupVersionResource
+	for alias, gvr := range resourceMappings {
+		info := builtin[gvr]
+		if info == nil {
+			info = &ResourceTypeInfo{
+				Group:      gvr.Group,
+				Version:    gvr.Version,
+				Resource:   gvr.Resource,
+				ShortNames: []string{},
+				Namespaced: true,
+				Source:     ResourceTypeSourceBuiltin,
+			}
+			if found, ok := discoveredByGVR[gvr]; ok {
+				info.Kind = found.Kind
+				info.Namespaced = found.Namespaced
+			}
+			builtin[gvr] = info
+			builtinOrder = append(builtinOrder, gvr)
+		}
+		if alias != gvr.Resource {
+			info.ShortNames = append(info.ShortNames, alias)
+		}
+	}
+	for _, gvr := range builtinOrder {
+		sort.Strings(builtin[gvr].ShortNames)
+		types = append(types, *builtin[gvr])
+		seen[gvr] = true
+	}
+
+	// Custom types from configuration
+	for _, custom := range appConfig.CustomResourceTypes {
+		gvr := schema.GroupVersionResource{Group: custom.Group, Version: custom.Version, Resource: custom.Resource}
+		if seen[gvr] {
+			continue
+		}
+		info := ResourceTypeInfo{
+			Group:      custom.Group,
+			Version:    custom.Version,
+			Resource:   custom.Resource,
+			Kind:       custom.Kind,
+			ShortNames: append([]string{}, custom.ShortNames...),
+			Namespaced: true,
+			Source:     ResourceTypeSourceCustom,
+		}
+		if found, ok := discoveredByGVR[gvr]; ok && info.Kind == "" {
+			info.Kind = found.Kind
+		}
+		if custom.Namespaced != nil {
+			info.Namespaced = *custom.Namespaced
+		}
+		types = append(types, info)
+		seen[gvr] = true
+	}
+
+	// Everything else served by the cluster
+	for _, info := range discovered {
+		if seen[info.GVR()] {
+			continue
+		}
+		types = append(types, info)
+		seen[info.GVR()] = true
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].Source != types[j].Source {
+			return resourceTypeSourceOrder(types[i].Source) < resourceTypeSourceOrder(types[j].Source)
+		}
+		if types[i].Group != types[j].Group {
+			return types[i].Group < types[j].Group
+		}
+		return types[i].Resource < types[j].Resource
+	})
+	return types
+}
+
+func resourceTypeSourceOrder(source string) int {
+	switch source {
+	case ResourceTypeSourceBuiltin:
+		return 0
+	case ResourceTypeSourceCustom:
+		return 1
+	}
+	return 2
+}
+
+func getResourceTypes(c *gin.Context) {
+	log.Printf("Listing supported resource types requested from %s", c.ClientIP())
+	types := listResourceTypes(clientFor(c))
+	log.Printf("Returning %d resource types", len(types))
+	c.JSON(http.StatusOK, types)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/internal/retry"
+)
+
+// retryingRoundTripper retries idempotent API server requests failing with transient errors
+// (timeouts, connection resets, 429 and gateway errors) with jittered exponential backoff, and
+// bounds each attempt of a non-streaming request with a deadline
+type retryingRoundTripper struct {
+	next        http.RoundTripper
+	maxAttempts int
+	callTimeout time.Duration
+}
+
+// streamingRequest reports whether the request opens a long-lived stream (watches, followed logs)
+func streamingRequest(req *http.Request) bool {
+	query := req.URL.Query()
+	return query.Get("watch") == "true" || query.Get("watch") == "1" || query.Get("follow") == "true"
+}
+
+// cancelOnClose releases the deadline of an attempt once its body is consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (coc *cancelOnClose) Close() error {
+	err := coc.ReadCloser.Close()
+	coc.cancel()
+	return err
+}
+
+func (rrt *retryingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	streaming := streamingRequest(req)
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		cancel := context.CancelFunc(func() {})
+		if rrt.callTimeout > 0 && !streaming {
+			var ctx context.Context
+			ctx, cancel = context.WithTimeout(req.Context(), rrt.callTimeout)
+			attemptReq = req.WithContext(ctx)
+		}
+
+		resp, err := rrt.next.RoundTrip(attemptReq)
+		retryable := idempotent && attempt < rrt.maxAttempts && req.Context().Err() == nil &&
+			((err != nil && retry.TransientError(err)) || (err == nil && retry.TransientStatus(resp.StatusCode)))
+		if !retryable {
+			if err != nil || resp.Body == nil {
+				cancel()
+				return resp, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		reason, cause, retryAfter := "error", "", ""
+		if err != nil {
+			cause = err.Error()
+		} else {
+			reason = strconv.Itoa(resp.StatusCode)
+			cause = resp.Status
+			retryAfter = resp.Header.Get("Retry-After")
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		cancel()
+
+		delay := retry.Backoff(attempt, retryAfter)
+		log.Printf("⚠️  Retrying %s %s in %v (attempt %d/%d, %s)", req.Method, req.URL.Path, delay, attempt+1, rrt.maxAttempts, cause)
+		metrics.AddCounter("kbviz_apiserver_retries_total", "Number of API server requests retried after a transient failure", map[string]string{"reason": reason}, 1)
+		sleepContext(req.Context(), delay)
+	}
+}
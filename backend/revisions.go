@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Kinds whose ControllerRevisions are shown with ?revisions=true
+var revisionedKinds = map[string]bool{"StatefulSet": true, "InstanceSet": true}
+
+// RevisionSummary is a ControllerRevision of a StatefulSet or InstanceSet
+type RevisionSummary struct {
+	Name      string `json:"name"`
+	Revision  int64  `json:"revision"`
+	Hash      string `json:"hash,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	// Current and Update mark the revisions the workload reports in its status
+	Current bool `json:"current,omitempty"`
+	Update  bool `json:"update,omitempty"`
+	// Pods counts the pods running the revision
+	Pods int `json:"pods"`
+}
+
+// RevisionDiff compares the pod templates of two revisions of a workload
+type RevisionDiff struct {
+	Kind    string         `json:"kind"`
+	Name    string         `json:"name"`
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Entries []CompareEntry `json:"entries"`
+}
+
+// listOwnedRevisions lists the ControllerRevisions controlled by a workload, oldest first
+func listOwnedRevisions(client *K8sClient, owner *unstructured.Unstructured) ([]appsv1.ControllerRevision, error) {
+	list, err := client.clientset.AppsV1().ControllerRevisions(owner.GetNamespace()).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var revisions []appsv1.ControllerRevision
+	for _, revision := range list.Items {
+		if controller := metav1.GetControllerOf(&revision); controller != nil && controller.UID == owner.GetUID() {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	return revisions, nil
+}
+
+// revisionTemplate extracts the pod template a revision stores. StatefulSets and InstanceSets store
+// it as a patch of spec.template that replaces the whole template.
+func revisionTemplate(revision *appsv1.ControllerRevision) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(revision.Data.Raw, &data); err != nil {
+		return nil, fmt.Errorf("Revision %s holds invalid data: %v", revision.Name, err)
+	}
+	template, found, _ := unstructured.NestedMap(data, "spec", "template")
+	if !found {
+		return nil, fmt.Errorf("Revision %s holds no pod template", revision.Name)
+	}
+	delete(template, "$patch")
+	return template, nil
+}
+
+// findRevision selects a revision by name or revision number
+func findRevision(revisions []appsv1.ControllerRevision, ref string) *appsv1.ControllerRevision {
+	number, numberErr := strconv.ParseInt(ref, 10, 64)
+	for i := range revisions {
+		if revisions[i].Name == ref || (numberErr == nil && revisions[i].Revision == number) {
+			return &revisions[i]
+		}
+	}
+	return nil
+}
+
+// getResourceRevisions lists the ControllerRevisions of a StatefulSet or InstanceSet with the pods running each
+func getResourceRevisions(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Listing revisions of %s/%s in namespace '%s' requested from %s", resourceType, resourceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	workload, _, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	revisions, err := listOwnedRevisions(client, workload)
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Pods carry the hash of their revision in the controller-revision-hash label
+	podsByHash := map[string]int{}
+	pods, err := client.clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("    ⚠️  Unable to list pods of %s/%s: %v", resourceType, resourceName, err)
+	} else {
+		for _, pod := range pods.Items {
+			if controller := metav1.GetControllerOf(&pod); controller != nil && controller.UID == workload.GetUID() {
+				podsByHash[pod.Labels[appsv1.ControllerRevisionHashLabelKey]]++
+			}
+		}
+	}
+
+	currentRevision, _, _ := unstructured.NestedString(workload.Object, "status", "currentRevision")
+	updateRevision, _, _ := unstructured.NestedString(workload.Object, "status", "updateRevision")
+	result := make([]RevisionSummary, 0, len(revisions))
+	for _, revision := range revisions {
+		result = append(result, RevisionSummary{
+			Name:      revision.Name,
+			Revision:  revision.Revision,
+			Hash:      revision.Labels[appsv1.ControllerRevisionHashLabelKey],
+			CreatedAt: revision.CreationTimestamp.UTC().Format(time.RFC3339),
+			Current:   revision.Name == currentRevision,
+			Update:    revision.Name == updateRevision,
+			Pods:      podsByHash[revision.Name] + podsByHash[revision.Labels[appsv1.ControllerRevisionHashLabelKey]],
+		})
+	}
+
+	log.Printf("Found %d revisions of %s/%s", len(result), resourceType, resourceName)
+	c.JSON(http.StatusOK, gin.H{"kind": workload.GetKind(), "name": workload.GetName(), "revisions": result})
+}
+
+// getRevisionDiff compares the pod templates of two revisions of a StatefulSet or InstanceSet. from and
+// to accept a revision name or number, and default to the two latest revisions.
+func getRevisionDiff(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Diffing revisions %s..%s of %s/%s in namespace '%s' requested from %s", c.Query("from"), c.Query("to"), resourceType, resourceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	workload, _, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	revisions, err := listOwnedRevisions(client, workload)
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s/%s has no revisions", resourceType, resourceName)})
+		return
+	}
+
+	to := &revisions[len(revisions)-1]
+	if ref := c.Query("to"); ref != "" {
+		if to = findRevision(revisions, ref); to == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %s of %s/%s not found", ref, resourceType, resourceName)})
+			return
+		}
+	}
+	from := to
+	if ref := c.Query("from"); ref != "" {
+		if from = findRevision(revisions, ref); from == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %s of %s/%s not found", ref, resourceType, resourceName)})
+			return
+		}
+	} else {
+		// The revision preceding to
+		for i := range revisions {
+			if revisions[i].Revision < to.Revision {
+				from = &revisions[i]
+			}
+		}
+	}
+
+	fromTemplate, err := revisionTemplate(from)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	toTemplate, err := revisionTemplate(to)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	// Redacted env values and annotations are masked on both sides, so they never show up as differences
+	if !revealAllowed(c) {
+		redactObject(fromTemplate)
+		redactObject(toTemplate)
+	}
+
+	diff := RevisionDiff{
+		Kind:    workload.GetKind(),
+		Name:    workload.GetName(),
+		From:    from.Name,
+		To:      to.Name,
+		Entries: compareValues("", fromTemplate, toTemplate),
+	}
+	if diff.Entries == nil {
+		diff.Entries = []CompareEntry{}
+	}
+
+	log.Printf("Found %d differences between revisions %s and %s", len(diff.Entries), from.Name, to.Name)
+	c.JSON(http.StatusOK, diff)
+}
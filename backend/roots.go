@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// How a root was reached from the requested resource
+const (
+	// RootViaOwners follows owner references and parent annotations only
+	RootViaOwners = "owners"
+	// RootViaInstanceLabel ends with the app.kubernetes.io/instance label naming a Cluster
+	RootViaInstanceLabel = "instanceLabel"
+)
+
+// maxRootDepth bounds the walk up the owners of a resource
+const maxRootDepth = 32
+
+// ResourceRoot is a tree root above a resource, with the path leading to it
+type ResourceRoot struct {
+	Resource ResourceNode `json:"resource"`
+	Via      string       `json:"via"`
+	// Path lists the resources from the requested one up to the root
+	Path []ResourceNode `json:"path"`
+	// Tree is the API path of the tree of the root
+	Tree string `json:"tree"`
+}
+
+// RootsLookup is the response of /api/resources/:type/:root/roots
+type RootsLookup struct {
+	Resource ResourceNode `json:"resource"`
+	// Clusters are the KubeBlocks Clusters the resource belongs to
+	Clusters []ResourceRoot `json:"clusters"`
+	// Roots are the topmost owners that are not Clusters
+	Roots []ResourceRoot `json:"roots"`
+	// UnresolvedOwners are owner references that could not be read, the walk stops at them
+	UnresolvedOwners []metav1.OwnerReference `json:"unresolvedOwners,omitempty"`
+}
+
+// rootStep is a resource reached by the walk with the path leading to it
+type rootStep struct {
+	resource *unstructured.Unstructured
+	path     []*unstructured.Unstructured
+}
+
+// gvrForKind resolves the kind of an owner reference or parent annotation to its resource type,
+// preferring the version of apiVersion when discovery serves it
+func gvrForKind(client *K8sClient, apiVersion, kind string) (schema.GroupVersionResource, bool) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, false
+	}
+	discovered, _ := client.apiDiscovery.get()
+	var found *ResourceTypeInfo
+	for i := range discovered {
+		if discovered[i].Kind != kind || (apiVersion != "" && discovered[i].Group != gv.Group) {
+			continue
+		}
+		if found == nil || discovered[i].Version == gv.Version {
+			found = &discovered[i]
+		}
+	}
+	if found != nil {
+		return found.GVR(), true
+	}
+	gvr, err := getGVRForResourceType(kind)
+	if err != nil || (apiVersion != "" && gvr.Group != gv.Group) {
+		return schema.GroupVersionResource{}, false
+	}
+	return gvr, true
+}
+
+// getRelated reads a resource related to another, in its namespace unless that is cluster-scoped
+func getRelated(client *K8sClient, gvr schema.GroupVersionResource, name, namespace string) (*unstructured.Unstructured, error) {
+	if namespace != "" {
+		resource, err := client.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err == nil || !errors.IsNotFound(err) {
+			return resource, err
+		}
+	}
+	// Namespaced resources may be owned by cluster-scoped ones
+	return client.dynamicClient.Resource(gvr).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// treePath returns the API path of the tree rooted at a resource
+func treePath(resource *unstructured.Unstructured) string {
+	path := fmt.Sprintf("/api/resources/%s/%s/tree", strings.ToLower(resource.GetKind()), resource.GetName())
+	if resource.GetNamespace() != "" {
+		path += "?namespace=" + resource.GetNamespace()
+	}
+	return path
+}
+
+// findRoots walks the owner references and parent annotations of a resource upward, breadth first
+// so every root is reported with its shortest path. A resource carrying the instance label of an
+// existing Cluster links to that Cluster as well, covering resources KubeBlocks labels but does not own.
+func findRoots(client *K8sClient, start *unstructured.Unstructured) *RootsLookup {
+	lookup := &RootsLookup{Resource: convertToResourceNode(*start), Clusters: []ResourceRoot{}, Roots: []ResourceRoot{}}
+	addRoot := func(step rootStep, via string) {
+		root := ResourceRoot{Resource: convertToResourceNode(*step.resource), Via: via, Tree: treePath(step.resource)}
+		for _, resource := range step.path {
+			root.Path = append(root.Path, convertToResourceNode(*resource))
+		}
+		if step.resource.GetKind() == "Cluster" && step.resource.GroupVersionKind().Group == clusterGVR.Group {
+			lookup.Clusters = append(lookup.Clusters, root)
+		} else {
+			lookup.Roots = append(lookup.Roots, root)
+		}
+	}
+
+	visited := map[types.UID]bool{start.GetUID(): true}
+	labeled := map[string]*rootStep{}
+	var instances []string
+	queue := []rootStep{{resource: start, path: []*unstructured.Unstructured{start}}}
+	for len(queue) > 0 {
+		step := queue[0]
+		queue = queue[1:]
+		resource := step.resource
+		namespace := resource.GetNamespace()
+
+		next := func(parent *unstructured.Unstructured) {
+			if visited[parent.GetUID()] || len(step.path) >= maxRootDepth {
+				return
+			}
+			visited[parent.GetUID()] = true
+			path := append(append([]*unstructured.Unstructured{}, step.path...), parent)
+			queue = append(queue, rootStep{resource: parent, path: path})
+		}
+
+		owned := false
+		for _, ref := range resource.GetOwnerReferences() {
+			owned = true
+			gvr, found := gvrForKind(client, ref.APIVersion, ref.Kind)
+			if !found {
+				lookup.UnresolvedOwners = append(lookup.UnresolvedOwners, ref)
+				continue
+			}
+			owner, err := getRelated(client, gvr, ref.Name, namespace)
+			if err != nil {
+				log.Printf("    ⚠️  Unable to read owner %s/%s of %s/%s: %v", ref.Kind, ref.Name, resource.GetKind(), resource.GetName(), err)
+				lookup.UnresolvedOwners = append(lookup.UnresolvedOwners, ref)
+				continue
+			}
+			next(owner)
+		}
+
+		// Resources without owner references are placed under their annotated parent
+		if value := resource.GetAnnotations()[vizParentAnnotation]; !owned && value != "" {
+			kind, name, _ := strings.Cut(value, "/")
+			if gvr, err := getGVRForResourceType(kind); err == nil {
+				if parent, err := getRelated(client, gvr, name, namespace); err == nil {
+					owned = true
+					next(parent)
+				} else {
+					log.Printf("    ⚠️  Unable to read parent %s of %s/%s: %v", value, resource.GetKind(), resource.GetName(), err)
+				}
+			}
+		}
+
+		if !owned {
+			addRoot(step, RootViaOwners)
+		}
+
+		// Labeled clusters are checked once the owners are walked, so clusters reachable through
+		// owners keep their owner path
+		if instance := resource.GetLabels()[instanceLabel]; instance != "" && namespace != "" && labeled[instance] == nil {
+			labeled[instance] = &rootStep{resource: resource, path: step.path}
+			instances = append(instances, instance)
+		}
+	}
+
+	for _, instance := range instances {
+		step := labeled[instance]
+		cluster, err := client.dynamicClient.Resource(clusterGVR).Namespace(step.resource.GetNamespace()).Get(context.TODO(), instance, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				log.Printf("    ⚠️  Unable to read cluster %s labeled on %s/%s: %v", instance, step.resource.GetKind(), step.resource.GetName(), err)
+			}
+			continue
+		}
+		if visited[cluster.GetUID()] {
+			continue
+		}
+		visited[cluster.GetUID()] = true
+		addRoot(rootStep{resource: cluster, path: append(append([]*unstructured.Unstructured{}, step.path...), cluster)}, RootViaInstanceLabel)
+	}
+
+	sortRoots := func(roots []ResourceRoot) {
+		sort.SliceStable(roots, func(i, j int) bool { return len(roots[i].Path) < len(roots[j].Path) })
+	}
+	sortRoots(lookup.Clusters)
+	sortRoots(lookup.Roots)
+	return lookup
+}
+
+// getResourceRoots reports the trees a resource belongs to: the KubeBlocks Clusters above it through
+// owner references, parent annotations and the instance label, and the topmost owners otherwise
+func getResourceRoots(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	log.Printf("Resolving roots of %s/%s in namespace '%s' requested from %s", resourceType, resourceName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	resource, _, status, err := fetchResource(client, resourceType, resourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	lookup := findRoots(client, resource)
+	redactResourceNodesFor(c, &lookup.Resource)
+	for _, roots := range [][]ResourceRoot{lookup.Clusters, lookup.Roots} {
+		for i := range roots {
+			redactResourceNodesFor(c, &roots[i].Resource)
+			for j := range roots[i].Path {
+				redactResourceNodesFor(c, &roots[i].Path[j])
+			}
+		}
+	}
+
+	log.Printf("%s/%s belongs to %d clusters and %d other roots", resourceType, resourceName, len(lookup.Clusters), len(lookup.Roots))
+	c.JSON(http.StatusOK, lookup)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// registerV1Routes registers the v1 API, whose tree endpoint returns raw resources
+func registerV1Routes(api *gin.RouterGroup) {
+	api.Use(languageMiddleware(), originCheckMiddleware(), csrfMiddleware(), requestValidationMiddleware(), namespaceAllowlistMiddleware(), clusterSelectionMiddleware(), shareTokenMiddleware(), authorizationWebhookMiddleware())
+
+	api.GET("/health", healthCheck)
+	api.GET("/leader", getLeaderStatus)
+	api.GET("/version", getVersion)
+	api.GET("/permissions/check", getPermissionsCheck)
+	api.GET("/csrf-token", getCSRFToken)
+	api.POST("/share", createShareToken)
+	api.GET("/bookmarks", getBookmarks)
+	api.POST("/bookmarks", createBookmark)
+	api.DELETE("/bookmarks/:id", deleteBookmark)
+	api.GET("/resources/:type", getResourcesByType)
+	api.PATCH("/resources/:type/:root", writeEnabledMiddleware(), patchResource)
+	api.GET("/resources/:type/:root/tree", getResourceTree)
+	api.GET("/resources/:type/:root/tree/export", exportResourceTree)
+	api.POST("/resources/:type/:root/tree/labels", writeEnabledMiddleware(), editTreeLabels)
+	api.GET("/resources/:type/:root/problems", getResourceProblems)
+	api.GET("/resources/:type/:root/images", getResourceImages)
+	api.GET("/resources/:type/:root/diff", getResourceDiff)
+	api.POST("/resources/:type/:root/diff", getResourceDiff)
+	api.GET("/resources/:type/:root/deletion-impact", getDeletionImpact)
+	api.GET("/resources/:type/:root/referencedBy", getReferencedBy)
+	api.GET("/resources/:type/:root/revisions", getResourceRevisions)
+	api.GET("/resources/:type/:root/revisions/diff", getRevisionDiff)
+	api.GET("/resources/:type/:root/roots", getResourceRoots)
+	api.POST("/resources/:type/:root/restart", writeEnabledMiddleware(), restartResource)
+	api.GET("/resources/:type/:root/scale", scaleResource)
+	api.PUT("/resources/:type/:root/scale", writeEnabledMiddleware(), scaleResource)
+	api.GET("/nodes/:uid/children", getNodeChildren)
+	api.GET("/uid/:uid", getResourceByUID)
+	api.GET("/namespaces", getNamespaces)
+	api.GET("/namespaces/:ns/stuck-deletions", getStuckDeletions)
+	api.GET("/namespaces/:ns/report", getNamespaceReport)
+	api.GET("/overview", getOverview)
+	api.GET("/gitops/apps", getGitOpsApps)
+	api.GET("/clusters-config", getClustersConfig)
+	api.POST("/kubeconfigs", kubeconfigUploadEnabledMiddleware(), uploadKubeconfig)
+	api.GET("/kubeconfigs/current", kubeconfigUploadEnabledMiddleware(), getKubeconfigSession)
+	api.DELETE("/kubeconfigs/current", kubeconfigUploadEnabledMiddleware(), deleteKubeconfigSession)
+	api.GET("/resourcetypes", getResourceTypes)
+	api.GET("/resourcetypes/tree", getTreeResourceTypes)
+	api.GET("/kubeblocks/components/:name/parameters", getComponentParameters)
+	api.GET("/kubeblocks/clusters", getKubeBlocksClusters)
+	api.GET("/kubeblocks/clusters/compare", compareKubeBlocksClusters)
+	api.GET("/kubeblocks/backuprepos", getBackupRepos)
+	api.GET("/kubeblocks/replication-pairs", getReplicationPairs)
+	api.GET("/kubeblocks/instances/:name", getInstanceDetail)
+	api.GET("/pods/:name/probes", getPodProbes)
+	api.GET("/pods/:name/datapath", getPodDataPath)
+	api.POST("/pods/:name/debug", writeEnabledMiddleware(), createDebugContainer)
+	api.GET("/pods/:name/exec", execInContainer)
+	api.POST("/kubeblocks/ops/preview", previewOpsRequest)
+	api.GET("/cronjobs/:name/jobs", getCronJobHistory)
+	api.GET("/clusters/:name/placement", getClusterPlacement)
+	api.GET("/clusters/:name/scheduling-report", getSchedulingReport)
+	api.POST("/clusters/:name/simulate-disruption", simulateDisruption)
+	api.GET("/clusters/:name/events/stream", streamClusterEvents)
+	api.GET("/clusters/:name/history", getClusterHistory)
+	api.GET("/clusters/:name/stats/history", getClusterStatsHistory)
+	api.GET("/clusters/:name/logs/stream", streamClusterLogs)
+	api.POST("/clusters/:name/wait", waitForCluster)
+	api.GET("/ws", serveWebSocket)
+	api.POST("/manifests/export", exportManifests)
+	api.POST("/jobs", createJob)
+	api.GET("/jobs", getJobs)
+	api.GET("/jobs/:id", getJob)
+	api.DELETE("/jobs/:id", cancelJob)
+}
+
+// registerV2Routes registers the v2 API, which uses typed summary nodes and edges
+func registerV2Routes(api *gin.RouterGroup) {
+	api.Use(languageMiddleware(), originCheckMiddleware(), csrfMiddleware(), requestValidationMiddleware(), namespaceAllowlistMiddleware(), clusterSelectionMiddleware(), shareTokenMiddleware(), authorizationWebhookMiddleware())
+
+	api.GET("/resources/:type/:root/tree", getResourceTreeV2)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Resources served with a scale subresource that can be scaled from the topology view
+var scalableResources = map[string]bool{
+	"deployments":  true,
+	"statefulsets": true,
+	"instancesets": true,
+}
+
+// ScaleInfo is the scale subresource of a workload
+type ScaleInfo struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	Replicas        int64  `json:"replicas"`
+	CurrentReplicas int64  `json:"currentReplicas"`
+	Selector        string `json:"selector,omitempty"`
+}
+
+// ScaleRequest is the body of PUT /api/resources/:type/:name/scale
+type ScaleRequest struct {
+	Replicas *int64 `json:"replicas"`
+}
+
+func newScaleInfo(kind, namespace string, scale *unstructured.Unstructured) ScaleInfo {
+	info := ScaleInfo{Kind: kind, Name: scale.GetName(), Namespace: namespace}
+	info.Replicas, _, _ = unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	info.CurrentReplicas, _, _ = unstructured.NestedInt64(scale.Object, "status", "replicas")
+	info.Selector, _, _ = unstructured.NestedString(scale.Object, "status", "selector")
+	return info
+}
+
+// apiErrorStatusCode maps an API error of a write to a response status
+func apiErrorStatusCode(err error) int {
+	switch {
+	case errors.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.IsConflict(err):
+		return http.StatusConflict
+	case errors.IsInvalid(err):
+		return http.StatusUnprocessableEntity
+	case errors.IsBadRequest(err):
+		return http.StatusBadRequest
+	case errors.IsForbidden(err):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// scaleResource reads (GET) or sets (PUT) the replicas of a workload through its scale subresource.
+// dryRun=true only validates the update.
+func scaleResource(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceName := c.Param("root")
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace is required for scaling a workload"})
+		return
+	}
+
+	gvr, err := getGVRForResourceType(resourceType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown resource type: %s", resourceType)})
+		return
+	}
+	if !scalableResources[gvr.Resource] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s cannot be scaled", gvr.Resource)})
+		return
+	}
+
+	client := clientFor(c)
+	resources := client.dynamicClient.Resource(gvr).Namespace(namespace)
+	resource, err := resources.Get(context.TODO(), resourceName, metav1.GetOptions{})
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	scale, err := resources.Get(context.TODO(), resourceName, metav1.GetOptions{}, "scale")
+	if err != nil {
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Request.Method == http.MethodGet {
+		c.JSON(http.StatusOK, newScaleInfo(resource.GetKind(), namespace, scale))
+		return
+	}
+
+	dryRun, err := dryRunRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var request ScaleRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Replicas == nil || *request.Replicas < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be {\"replicas\": <non-negative number>}"})
+		return
+	}
+
+	log.Printf("Scaling %s/%s in namespace '%s' to %d replicas requested from %s", resource.GetKind(), resourceName, namespace, *request.Replicas, c.ClientIP())
+
+	// The update carries the resourceVersion of the read, so concurrent changes answer 409
+	desired := scale.DeepCopy()
+	if err := unstructured.SetNestedField(desired.Object, *request.Replicas, "spec", "replicas"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := resources.Update(context.TODO(), desired, metav1.UpdateOptions{DryRun: dryRunOption(dryRun)}, "scale")
+	if err != nil {
+		log.Printf("Error scaling %s/%s in namespace %s: %v", resource.GetKind(), resourceName, namespace, err)
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun {
+		respondDryRun(c, scale, updated, newScaleInfo(resource.GetKind(), namespace, updated))
+		return
+	}
+	log.Printf("✓ Scaled %s/%s in namespace %s to %d replicas", resource.GetKind(), resourceName, namespace, *request.Replicas)
+	c.JSON(http.StatusOK, newScaleInfo(resource.GetKind(), namespace, updated))
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Scheduling constraints evaluated by the scheduling report
+const (
+	ConstraintRequiredAntiAffinity  = "requiredAntiAffinity"
+	ConstraintPreferredAntiAffinity = "preferredAntiAffinity"
+	ConstraintTopologySpread        = "topologySpread"
+	ConstraintColocation            = "colocation"
+)
+
+const hostnameLabel = "kubernetes.io/hostname"
+
+// SchedulingFinding is a constraint that the current placement of a component does not satisfy
+type SchedulingFinding struct {
+	Severity    string   `json:"severity"`
+	Constraint  string   `json:"constraint"`
+	TopologyKey string   `json:"topologyKey,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	Pods        []string `json:"pods,omitempty"`
+	Message     string   `json:"message"`
+}
+
+// ConstraintEvaluation is a declared constraint with the pods it selects in each topology domain
+type ConstraintEvaluation struct {
+	Constraint        string `json:"constraint"`
+	TopologyKey       string `json:"topologyKey"`
+	Selector          string `json:"selector"`
+	MaxSkew           int32  `json:"maxSkew,omitempty"`
+	WhenUnsatisfiable string `json:"whenUnsatisfiable,omitempty"`
+	Skew              int    `json:"skew,omitempty"`
+	// Domains lists the selected pods by topology value; spread constraints include empty eligible domains
+	Domains   map[string][]string `json:"domains"`
+	Satisfied bool                `json:"satisfied"`
+}
+
+// ComponentScheduling evaluates the scheduling constraints of the pods of one component
+type ComponentScheduling struct {
+	Component   string                 `json:"component"`
+	Replicas    int                    `json:"replicas"`
+	Unscheduled []string               `json:"unscheduled,omitempty"`
+	Constraints []ConstraintEvaluation `json:"constraints"`
+	Findings    []SchedulingFinding    `json:"findings"`
+	Satisfied   bool                   `json:"satisfied"`
+}
+
+// SchedulingReport is the response of /api/clusters/:name/scheduling-report
+type SchedulingReport struct {
+	Cluster    string                `json:"cluster"`
+	Namespace  string                `json:"namespace"`
+	Satisfied  bool                  `json:"satisfied"`
+	Nodes      int                   `json:"nodes"`
+	Zones      int                   `json:"zones"`
+	Components []ComponentScheduling `json:"components"`
+}
+
+// scheduledPod is a pod of the cluster with the labels of the node it runs on
+type scheduledPod struct {
+	pod        *corev1.Pod
+	component  string
+	nodeLabels map[string]string
+}
+
+// topologyValue returns the domain of the pod for a topology key, empty when its node lacks the label
+func (sp *scheduledPod) topologyValue(key string) string {
+	if value := sp.nodeLabels[key]; value != "" {
+		return value
+	}
+	if key == hostnameLabel {
+		return sp.pod.Spec.NodeName
+	}
+	return ""
+}
+
+// declaredConstraint is a constraint of the pod specs, with the pods declaring it
+type declaredConstraint struct {
+	constraint        string
+	topologyKey       string
+	selector          labels.Selector
+	maxSkew           int32
+	whenUnsatisfiable string
+	pods              []*scheduledPod
+}
+
+// collectConstraints gathers the distinct anti-affinity and topology spread constraints of the pods
+func collectConstraints(pods []*scheduledPod) []*declaredConstraint {
+	var constraints []*declaredConstraint
+	byKey := map[string]*declaredConstraint{}
+	add := func(pod *scheduledPod, constraint, topologyKey string, selector *metav1.LabelSelector, maxSkew int32, whenUnsatisfiable string) {
+		parsed, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			log.Printf("    ⚠️  Ignoring %s constraint of pod %s with invalid selector: %v", constraint, pod.pod.Name, err)
+			return
+		}
+		key := fmt.Sprintf("%s|%s|%s|%d|%s", constraint, topologyKey, parsed.String(), maxSkew, whenUnsatisfiable)
+		if existing := byKey[key]; existing != nil {
+			existing.pods = append(existing.pods, pod)
+			return
+		}
+		declared := &declaredConstraint{constraint: constraint, topologyKey: topologyKey, selector: parsed,
+			maxSkew: maxSkew, whenUnsatisfiable: whenUnsatisfiable, pods: []*scheduledPod{pod}}
+		byKey[key] = declared
+		constraints = append(constraints, declared)
+	}
+
+	for _, pod := range pods {
+		if affinity := pod.pod.Spec.Affinity; affinity != nil && affinity.PodAntiAffinity != nil {
+			for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				add(pod, ConstraintRequiredAntiAffinity, term.TopologyKey, term.LabelSelector, 0, "")
+			}
+			for _, weighted := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				add(pod, ConstraintPreferredAntiAffinity, weighted.PodAffinityTerm.TopologyKey, weighted.PodAffinityTerm.LabelSelector, 0, "")
+			}
+		}
+		for _, spread := range pod.pod.Spec.TopologySpreadConstraints {
+			add(pod, ConstraintTopologySpread, spread.TopologyKey, spread.LabelSelector, spread.MaxSkew, string(spread.WhenUnsatisfiable))
+		}
+	}
+	return constraints
+}
+
+// evaluateAntiAffinity checks that no pod declaring the term shares a domain with another selected pod
+func evaluateAntiAffinity(declared *declaredConstraint, clusterPods []*scheduledPod, language string) (ConstraintEvaluation, []SchedulingFinding) {
+	evaluation := ConstraintEvaluation{Constraint: declared.constraint, TopologyKey: declared.topologyKey,
+		Selector: declared.selector.String(), Domains: map[string][]string{}, Satisfied: true}
+	for _, pod := range clusterPods {
+		if domain := pod.topologyValue(declared.topologyKey); domain != "" && declared.selector.Matches(labels.Set(pod.pod.Labels)) {
+			evaluation.Domains[domain] = append(evaluation.Domains[domain], pod.pod.Name)
+		}
+	}
+
+	severity := SeverityCritical
+	format := "Pods %s share %s %s although a required anti-affinity keeps them apart"
+	if declared.constraint == ConstraintPreferredAntiAffinity {
+		severity = SeverityWarning
+		format = "Pods %s share %s %s although a preferred anti-affinity asks to keep them apart"
+	}
+
+	var findings []SchedulingFinding
+	reported := map[string]bool{}
+	for _, pod := range declared.pods {
+		domain := pod.topologyValue(declared.topologyKey)
+		if domain == "" || reported[domain] || len(evaluation.Domains[domain]) < 2 {
+			continue
+		}
+		reported[domain] = true
+		evaluation.Satisfied = false
+		findings = append(findings, SchedulingFinding{
+			Severity:    severity,
+			Constraint:  declared.constraint,
+			TopologyKey: declared.topologyKey,
+			Domain:      domain,
+			Pods:        evaluation.Domains[domain],
+			Message:     localize(language, format, strings.Join(evaluation.Domains[domain], ", "), declared.topologyKey, domain),
+		})
+	}
+	return evaluation, findings
+}
+
+// evaluateTopologySpread computes the skew of the selected pods over the eligible domains, the
+// domains of the nodes matching the node selector of the declaring pods
+func evaluateTopologySpread(declared *declaredConstraint, clusterPods []*scheduledPod, nodes []corev1.Node, language string) (ConstraintEvaluation, []SchedulingFinding) {
+	evaluation := ConstraintEvaluation{Constraint: declared.constraint, TopologyKey: declared.topologyKey, Selector: declared.selector.String(),
+		MaxSkew: declared.maxSkew, WhenUnsatisfiable: declared.whenUnsatisfiable, Domains: map[string][]string{}, Satisfied: true}
+	nodeSelector := labels.SelectorFromSet(declared.pods[0].pod.Spec.NodeSelector)
+	for _, node := range nodes {
+		if domain := node.Labels[declared.topologyKey]; domain != "" && nodeSelector.Matches(labels.Set(node.Labels)) {
+			evaluation.Domains[domain] = []string{}
+		}
+	}
+	for _, pod := range clusterPods {
+		domain := pod.topologyValue(declared.topologyKey)
+		if _, eligible := evaluation.Domains[domain]; eligible && declared.selector.Matches(labels.Set(pod.pod.Labels)) {
+			evaluation.Domains[domain] = append(evaluation.Domains[domain], pod.pod.Name)
+		}
+	}
+	if len(evaluation.Domains) == 0 {
+		return evaluation, nil
+	}
+
+	minDomain, maxDomain := "", ""
+	for domain, pods := range evaluation.Domains {
+		if minDomain == "" || len(pods) < len(evaluation.Domains[minDomain]) || (len(pods) == len(evaluation.Domains[minDomain]) && domain < minDomain) {
+			minDomain = domain
+		}
+		if maxDomain == "" || len(pods) > len(evaluation.Domains[maxDomain]) || (len(pods) == len(evaluation.Domains[maxDomain]) && domain < maxDomain) {
+			maxDomain = domain
+		}
+	}
+	evaluation.Skew = len(evaluation.Domains[maxDomain]) - len(evaluation.Domains[minDomain])
+	if evaluation.Skew <= int(declared.maxSkew) {
+		return evaluation, nil
+	}
+
+	evaluation.Satisfied = false
+	severity := SeverityCritical
+	if declared.whenUnsatisfiable == string(corev1.ScheduleAnyway) {
+		severity = SeverityWarning
+	}
+	return evaluation, []SchedulingFinding{{
+		Severity:    severity,
+		Constraint:  declared.constraint,
+		TopologyKey: declared.topologyKey,
+		Domain:      maxDomain,
+		Pods:        evaluation.Domains[maxDomain],
+		Message: localize(language, "Pods are spread over %s with a skew of %d (%s has %d, %s has %d), more than the allowed %d",
+			declared.topologyKey, evaluation.Skew, maxDomain, len(evaluation.Domains[maxDomain]), minDomain, len(evaluation.Domains[minDomain]), declared.maxSkew),
+	}}
+}
+
+// colocationFindings flags replicas sharing a node, or a single zone of a multi-zone cluster, that
+// no required constraint keeps apart
+func colocationFindings(pods []*scheduledPod, constraints []*declaredConstraint, zones int, language string) []SchedulingFinding {
+	constrained := map[string]bool{}
+	for _, declared := range constraints {
+		if declared.constraint == ConstraintRequiredAntiAffinity || (declared.constraint == ConstraintTopologySpread && declared.whenUnsatisfiable != string(corev1.ScheduleAnyway)) {
+			constrained[declared.topologyKey] = true
+		}
+	}
+
+	var findings []SchedulingFinding
+	byNode := map[string][]string{}
+	byZone := map[string][]string{}
+	for _, pod := range pods {
+		byNode[pod.pod.Spec.NodeName] = append(byNode[pod.pod.Spec.NodeName], pod.pod.Name)
+		if zone := pod.topologyValue(zoneLabel); zone != "" {
+			byZone[zone] = append(byZone[zone], pod.pod.Name)
+		}
+	}
+	if !constrained[hostnameLabel] {
+		for node, names := range byNode {
+			if len(names) > 1 {
+				findings = append(findings, SchedulingFinding{Severity: SeverityWarning, Constraint: ConstraintColocation, TopologyKey: hostnameLabel, Domain: node, Pods: names,
+					Message: localize(language, "Replicas %s run on the same node %s, nothing keeps them apart so losing the node takes them down together", strings.Join(names, ", "), node)})
+			}
+		}
+	}
+	if !constrained[zoneLabel] && zones > 1 && len(pods) > 1 && len(byZone) == 1 {
+		for zone, names := range byZone {
+			if len(names) == len(pods) {
+				findings = append(findings, SchedulingFinding{Severity: SeverityWarning, Constraint: ConstraintColocation, TopologyKey: zoneLabel, Domain: zone, Pods: names,
+					Message: localize(language, "All %d replicas run in zone %s although the nodes span %d zones", len(pods), zone, zones)})
+			}
+		}
+	}
+	return findings
+}
+
+// getSchedulingReport evaluates whether the pods of each component of a KubeBlocks cluster satisfy
+// their anti-affinity and topology spread constraints, and flags co-located replicas
+func getSchedulingReport(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+
+	log.Printf("Building scheduling report for cluster %s in namespace '%s' requested from %s", clusterName, namespace, c.ClientIP())
+
+	client := clientFor(c)
+	rootTreeNode, treeBuilder, status, err := buildTreeForRoot(client, "cluster", clusterName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodeList, err := client.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing nodes: %v", err)
+		c.JSON(apiErrorStatusCode(err), gin.H{"error": fmt.Sprintf("Failed to list nodes: %v", err)})
+		return
+	}
+	nodeLabels := map[string]map[string]string{}
+	zones := map[string]bool{}
+	for _, node := range nodeList.Items {
+		nodeLabels[node.Name] = node.Labels
+		if zone := node.Labels[zoneLabel]; zone != "" {
+			zones[zone] = true
+		}
+	}
+
+	language := requestLanguage(c)
+	report := &SchedulingReport{Cluster: clusterName, Namespace: namespace, Satisfied: true, Nodes: len(nodeList.Items), Zones: len(zones), Components: []ComponentScheduling{}}
+	components := map[string]*ComponentScheduling{}
+	var clusterPods []*scheduledPod
+	podsByComponent := map[string][]*scheduledPod{}
+	for _, resource := range treeBuilder.GetResourcesByKind(rootTreeNode, "Pod") {
+		var pod corev1.Pod
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(resource.Object, &pod); err != nil {
+			log.Printf("    ⚠️  Unable to convert pod %s: %v", resource.GetName(), err)
+			continue
+		}
+		component := pod.Labels[componentNameLabel]
+		if components[component] == nil {
+			components[component] = &ComponentScheduling{Component: component, Constraints: []ConstraintEvaluation{}, Findings: []SchedulingFinding{}, Satisfied: true}
+		}
+		components[component].Replicas++
+		if pod.Spec.NodeName == "" {
+			components[component].Unscheduled = append(components[component].Unscheduled, pod.Name)
+			continue
+		}
+		scheduled := &scheduledPod{pod: &pod, component: component, nodeLabels: nodeLabels[pod.Spec.NodeName]}
+		clusterPods = append(clusterPods, scheduled)
+		podsByComponent[component] = append(podsByComponent[component], scheduled)
+	}
+	sort.Slice(clusterPods, func(i, j int) bool { return clusterPods[i].pod.Name < clusterPods[j].pod.Name })
+
+	for name, component := range components {
+		pods := podsByComponent[name]
+		constraints := collectConstraints(pods)
+		for _, declared := range constraints {
+			var evaluation ConstraintEvaluation
+			var findings []SchedulingFinding
+			if declared.constraint == ConstraintTopologySpread {
+				evaluation, findings = evaluateTopologySpread(declared, clusterPods, nodeList.Items, language)
+			} else {
+				evaluation, findings = evaluateAntiAffinity(declared, clusterPods, language)
+			}
+			component.Constraints = append(component.Constraints, evaluation)
+			component.Findings = append(component.Findings, findings...)
+		}
+		component.Findings = append(component.Findings, colocationFindings(pods, constraints, len(zones), language)...)
+		for _, finding := range component.Findings {
+			if finding.Severity == SeverityCritical {
+				component.Satisfied = false
+				report.Satisfied = false
+			}
+		}
+		sort.Slice(component.Findings, func(i, j int) bool {
+			if component.Findings[i].Severity != component.Findings[j].Severity {
+				return component.Findings[i].Severity == SeverityCritical
+			}
+			return component.Findings[i].Domain < component.Findings[j].Domain
+		})
+		report.Components = append(report.Components, *component)
+	}
+	sort.Slice(report.Components, func(i, j int) bool { return report.Components[i].Component < report.Components[j].Component })
+
+	log.Printf("Scheduling report for cluster %s: %d components, satisfied: %t", clusterName, len(report.Components), report.Satisfied)
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+// Edge type for relationships resolved from label selectors
+const EdgeTypeSelector = api.EdgeTypeSelector
+
+// SelectResources returns the resources of a kind whose labels match the selector. The candidates
+// are the smallest of the resources of the kind and those carrying a label the selector requires.
+func (rp *ResourcePool) SelectResources(kind string, selector labels.Selector) []*unstructured.Unstructured {
+	candidates := rp.byKind[kind]
+	if requirements, selectable := selector.Requirements(); selectable {
+		for _, requirement := range requirements {
+			values := requirement.Values().List()
+			switch requirement.Operator() {
+			case selection.Equals, selection.DoubleEquals, selection.In:
+				if len(values) != 1 {
+					continue
+				}
+				if indexed := rp.byLabel[requirement.Key()+"="+values[0]]; len(indexed) < len(candidates) {
+					candidates = indexed
+				}
+			}
+		}
+	}
+
+	var matches []*unstructured.Unstructured
+	for _, resource := range candidates {
+		if resource.GetKind() != kind {
+			continue
+		}
+		if selector.Matches(labels.Set(resource.GetLabels())) {
+			matches = append(matches, resource)
+		}
+	}
+	return matches
+}
+
+// ResolveSelectorEdges links Services to the pods selected by their spec.selector
+func (rtb *ResourceTreeBuilder) ResolveSelectorEdges(root *ResourceTreeNode) {
+	if rtb.pool == nil {
+		return
+	}
+
+	var resolve func(node *ResourceTreeNode)
+	resolve = func(node *ResourceTreeNode) {
+		if node.Resource.GetKind() == "Service" {
+			selectorMap, found, _ := unstructured.NestedStringMap(node.Resource.Object, "spec", "selector")
+			// Services without selector (e.g. headless with manual endpoints) select nothing
+			if found && len(selectorMap) > 0 {
+				selector := labels.SelectorFromSet(selectorMap)
+				for _, pod := range rtb.pool.SelectResources("Pod", selector) {
+					node.SelectorTargets = append(node.SelectorTargets, string(pod.GetUID()))
+				}
+				log.Printf("🔗 Service %s selects %d pods", node.Resource.GetName(), len(node.SelectorTargets))
+			}
+		}
+		for _, child := range node.Children {
+			resolve(child)
+		}
+	}
+	resolve(root)
+}
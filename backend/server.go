@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ServerConfig tunes the HTTP server. Streaming requests (SSE, long-polls, NDJSON trees and
+// WebSocket upgrades) are exempt from the read and write timeouts, which would cut them off.
+type ServerConfig struct {
+	// Addr is the listen address
+	Addr string `json:"addr"`
+	// TLSCertFile and TLSKeyFile serve HTTPS, with HTTP/2 negotiated through ALPN
+	TLSCertFile string `json:"tlsCertFile"`
+	TLSKeyFile  string `json:"tlsKeyFile"`
+	// H2C serves HTTP/2 over cleartext as well, for proxies speaking HTTP/2 to the backend
+	H2C bool `json:"h2c"`
+	// ReadHeaderTimeoutSeconds bounds reading the request headers
+	ReadHeaderTimeoutSeconds int `json:"readHeaderTimeoutSeconds"`
+	// ReadTimeoutSeconds bounds reading the request of normal routes, body included
+	ReadTimeoutSeconds int `json:"readTimeoutSeconds"`
+	// WriteTimeoutSeconds bounds writing the response of normal routes, from the end of the request
+	WriteTimeoutSeconds int `json:"writeTimeoutSeconds"`
+	// IdleTimeoutSeconds closes keep-alive connections idle this long
+	IdleTimeoutSeconds int `json:"idleTimeoutSeconds"`
+	// StreamHeartbeatSeconds is the interval of heartbeats on SSE streams, below the idle timeout of proxies
+	StreamHeartbeatSeconds int `json:"streamHeartbeatSeconds"`
+	// MaxConcurrentStreams bounds the HTTP/2 streams of one connection
+	MaxConcurrentStreams uint32 `json:"maxConcurrentStreams"`
+}
+
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:                     ":8080",
+		ReadHeaderTimeoutSeconds: 10,
+		ReadTimeoutSeconds:       60,
+		WriteTimeoutSeconds:      120,
+		IdleTimeoutSeconds:       120,
+		StreamHeartbeatSeconds:   15,
+		MaxConcurrentStreams:     250,
+	}
+}
+
+// streamHeartbeatInterval is how often SSE streams send a heartbeat event
+func streamHeartbeatInterval() time.Duration {
+	if appConfig.Server.StreamHeartbeatSeconds <= 0 {
+		return 15 * time.Second
+	}
+	return time.Duration(appConfig.Server.StreamHeartbeatSeconds) * time.Second
+}
+
+// longLivedRequest reports whether the request holds its connection open for a long time
+func longLivedRequest(c *gin.Context) bool {
+	return strings.HasSuffix(c.FullPath(), "/stream") ||
+		strings.HasSuffix(c.FullPath(), "/wait") ||
+		c.Query("waitFor") != "" ||
+		c.Query("format") == treeFormatNDJSON ||
+		strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
+
+// connectionDeadlineMiddleware applies the write timeout of normal routes per request, and lifts the
+// read and write deadlines of streaming requests. The server itself sets no write timeout, it
+// could not tell the two apart.
+func connectionDeadlineMiddleware(config ServerConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		controller := http.NewResponseController(c.Writer)
+		if longLivedRequest(c) {
+			if err := controller.SetReadDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+				log.Printf("⚠️  Unable to lift the read deadline of %s: %v", c.Request.URL.Path, err)
+			}
+			if err := controller.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+				log.Printf("⚠️  Unable to lift the write deadline of %s: %v", c.Request.URL.Path, err)
+			}
+		} else if config.WriteTimeoutSeconds > 0 {
+			_ = controller.SetWriteDeadline(time.Now().Add(time.Duration(config.WriteTimeoutSeconds) * time.Second))
+		}
+		c.Next()
+	}
+}
+
+// serve runs the HTTP server: HTTPS with HTTP/2 when a certificate is configured, otherwise
+// HTTP/1.1 and, with h2c, cleartext HTTP/2
+func serve(handler http.Handler, config ServerConfig) error {
+	h2 := &http2.Server{
+		MaxConcurrentStreams: config.MaxConcurrentStreams,
+		IdleTimeout:          time.Duration(config.IdleTimeoutSeconds) * time.Second,
+	}
+	server := &http.Server{
+		Addr:              config.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(config.ReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(config.ReadTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(config.IdleTimeoutSeconds) * time.Second,
+	}
+
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+			return fmt.Errorf("both tlsCertFile and tlsKeyFile are required to serve HTTPS")
+		}
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if err := http2.ConfigureServer(server, h2); err != nil {
+			return err
+		}
+		log.Printf("🚀 Server starting on %s (HTTPS, HTTP/2)", config.Addr)
+		return server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+	}
+
+	if config.H2C {
+		server.Handler = h2c.NewHandler(handler, h2)
+		log.Printf("🚀 Server starting on %s (HTTP/1.1, h2c)", config.Addr)
+	} else {
+		log.Printf("🚀 Server starting on %s", config.Addr)
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	shareTokenHeader  = "X-Share-Token"
+	sharedRequestKey  = "sharedRequest"
+	shareDefaultTTL   = time.Hour
+	shareMaxTTL       = 24 * time.Hour
+	shareTokenVersion = "v1"
+)
+
+// Endpoints a share token grants read access to, for the tree of its root only
+var shareablePaths = map[string]bool{
+	"/resources/:type/:root/tree":        true,
+	"/resources/:type/:root/tree/export": true,
+	"/resources/:type/:root/problems":    true,
+	"/resources/:type/:root/images":      true,
+}
+
+// ShareClaims scope a share token to the tree of one root resource
+type ShareClaims struct {
+	Cluster   string `json:"cluster"`
+	Resource  string `json:"resource"` // Resolved GVR, e.g. apps.kubeblocks.io/v1/clusters
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Expires   int64  `json:"exp"`
+}
+
+// ShareRequest is the body of POST /api/share
+type ShareRequest struct {
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	TTLSeconds int    `json:"ttlSeconds"`
+}
+
+var shareSecret struct {
+	once  sync.Once
+	value []byte
+}
+
+// shareTokenSecret returns the configured signing secret, or a random one generated at startup.
+// Random secrets make tokens valid only on this replica until it restarts.
+func shareTokenSecret() []byte {
+	shareSecret.once.Do(func() {
+		if appConfig.ShareTokenSecret != "" {
+			shareSecret.value = []byte(appConfig.ShareTokenSecret)
+			return
+		}
+		shareSecret.value = make([]byte, 32)
+		if _, err := rand.Read(shareSecret.value); err != nil {
+			log.Fatalf("Unable to generate share token secret: %v", err)
+		}
+		log.Printf("⚠️  No shareTokenSecret configured, share tokens are only valid on this replica until it restarts")
+	})
+	return shareSecret.value
+}
+
+func signShareToken(claims ShareClaims) (string, error) {
+	return signToken(shareTokenVersion, claims)
+}
+
+func verifyShareToken(token string) (*ShareClaims, error) {
+	var claims ShareClaims
+	if err := verifyToken(token, shareTokenVersion, "share", &claims); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > claims.Expires {
+		return nil, fmt.Errorf("Share token expired")
+	}
+	return &claims, nil
+}
+
+// signToken encodes the claims as <version>.<payload>.<signature>, signed with the share token secret.
+// The version keeps tokens of different purposes from being accepted for each other.
+func signToken(version string, claims interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := version + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, shareTokenSecret())
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifyToken checks the version and signature of a token and decodes its claims
+func verifyToken(token, version, purpose string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != version {
+		return fmt.Errorf("Malformed %s token", purpose)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("Malformed %s token", purpose)
+	}
+	mac := hmac.New(sha256.New, shareTokenSecret())
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return fmt.Errorf("Invalid %s token", purpose)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("Malformed %s token", purpose)
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("Malformed %s token", purpose)
+	}
+	return nil
+}
+
+// covers reports why the request is outside the scope of the token, nil when it is within
+func (claims *ShareClaims) covers(c *gin.Context) error {
+	path := c.FullPath()
+	for _, prefix := range []string{"/api/v2", "/api/v1", "/api"} {
+		if strings.HasPrefix(path, prefix+"/") {
+			path = strings.TrimPrefix(path, prefix)
+			break
+		}
+	}
+	if c.Request.Method != http.MethodGet || !shareablePaths[path] {
+		return fmt.Errorf("Share tokens only grant read access to the shared tree")
+	}
+	gvr, err := getGVRForResourceType(c.Param("type"))
+	if err != nil || gvr.String() != claims.Resource || c.Param("root") != claims.Name ||
+		c.Query("namespace") != claims.Namespace || clientFor(c).name != claims.Cluster || inKubeconfigSession(c) {
+		return fmt.Errorf("Share token does not cover this resource")
+	}
+	return nil
+}
+
+// shareTokenMiddleware lets requests carrying a valid share token for their tree skip the
+// authorization webhook. Requests without a token are left to the webhook.
+func shareTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(shareTokenHeader)
+		if token == "" {
+			token = c.Query("share")
+		}
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		claims, err := verifyShareToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err := claims.covers(c); err != nil {
+			log.Printf("Rejecting %s %s with share token for %s/%s: %v", c.Request.Method, c.Request.URL.Path, claims.Namespace, claims.Name, err)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(sharedRequestKey, true)
+		c.Next()
+	}
+}
+
+// createShareToken mints a read-only token for the tree of one resource the user may read
+func createShareToken(c *gin.Context) {
+	var request ShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Type == "" || request.Name == "" || request.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be {\"type\": ..., \"name\": ..., \"namespace\": ..., \"ttlSeconds\": ...}"})
+		return
+	}
+	if !appConfig.namespaceAllowed(request.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Namespace %s is not served by this instance", request.Namespace)})
+		return
+	}
+	// Recipients have no access to the cluster of an uploaded kubeconfig
+	if inKubeconfigSession(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Share tokens cannot be created for the cluster of an uploaded kubeconfig"})
+		return
+	}
+	ttl := shareDefaultTTL
+	if request.TTLSeconds > 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+	if ttl > shareMaxTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ttlSeconds must not exceed %d", int(shareMaxTTL.Seconds()))})
+		return
+	}
+
+	_, gvr, status, err := fetchResource(clientFor(c), request.Type, request.Name, request.Namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	// The webhook only saw POST /share, the token must not grant more than the user may read
+	if err := subscriptionAllowed(c, request.Type, request.Name, request.Namespace); err != nil {
+		log.Printf("Refusing share token for %s/%s in namespace %s requested from %s: %v", request.Type, request.Name, request.Namespace, c.ClientIP(), err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	expires := time.Now().Add(ttl)
+	claims := ShareClaims{
+		Cluster:   clientFor(c).name,
+		Resource:  gvr.String(),
+		Name:      request.Name,
+		Namespace: request.Namespace,
+		Expires:   expires.Unix(),
+	}
+	token, err := signShareToken(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Created share token for %s/%s in namespace %s valid until %s, requested from %s", request.Type, request.Name, request.Namespace, expires.Format(time.RFC3339), c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"header":    shareTokenHeader,
+		"expiresAt": expires.UTC().Format(time.RFC3339),
+		"scope":     claims,
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// StatusExtractor maps fields of a custom resource to its status and health using JSONPath,
+// so new CRDs get meaningful statuses without code changes
+type StatusExtractor struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	// StatusPath selects the status string, e.g. "{.status.phase}"
+	StatusPath string `json:"statusPath,omitempty"`
+	// HealthPath selects one or more values evaluated against the value lists below,
+	// e.g. "{.status.components[*].phase}"
+	HealthPath     string   `json:"healthPath,omitempty"`
+	HealthyValues  []string `json:"healthyValues,omitempty"`
+	DegradedValues []string `json:"degradedValues,omitempty"`
+}
+
+// validateStatusExtractors checks that all configured JSONPath expressions parse
+func validateStatusExtractors(extractors []StatusExtractor) error {
+	for i, extractor := range extractors {
+		if extractor.Kind == "" {
+			return fmt.Errorf("statusExtractors[%d]: kind is required", i)
+		}
+		for _, expr := range []string{extractor.StatusPath, extractor.HealthPath} {
+			if expr == "" {
+				continue
+			}
+			if err := jsonpath.New(extractor.Kind).Parse(expr); err != nil {
+				return fmt.Errorf("statusExtractors[%d]: invalid JSONPath %q: %v", i, expr, err)
+			}
+		}
+	}
+	return nil
+}
+
+// findStatusExtractor returns the configured extractor for the resource's group and kind
+func findStatusExtractor(resource *unstructured.Unstructured) *StatusExtractor {
+	if appConfig == nil {
+		return nil
+	}
+	gvk := resource.GroupVersionKind()
+	for i := range appConfig.StatusExtractors {
+		extractor := &appConfig.StatusExtractors[i]
+		if extractor.Kind == gvk.Kind && extractor.Group == gvk.Group {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// evaluateJSONPath returns all values selected by the expression
+func evaluateJSONPath(resource *unstructured.Unstructured, expr string) ([]string, error) {
+	jp := jsonpath.New("extractor").AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, err
+	}
+	results, err := jp.FindResults(resource.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				values = append(values, fmt.Sprintf("%v", value.Interface()))
+			}
+		}
+	}
+	return values, nil
+}
+
+// extractStatus returns the configured status of the resource, if an extractor applies
+func extractStatus(resource *unstructured.Unstructured) (string, bool) {
+	extractor := findStatusExtractor(resource)
+	if extractor == nil || extractor.StatusPath == "" {
+		return "", false
+	}
+	values, err := evaluateJSONPath(resource, extractor.StatusPath)
+	if err != nil || len(values) == 0 {
+		return "", false
+	}
+	return strings.Join(values, ","), true
+}
+
+// extractHealth evaluates the configured health rule of the resource, if an extractor applies
+func extractHealth(resource *unstructured.Unstructured) (string, bool) {
+	extractor := findStatusExtractor(resource)
+	if extractor == nil || extractor.HealthPath == "" {
+		return "", false
+	}
+	values, err := evaluateJSONPath(resource, extractor.HealthPath)
+	if err != nil || len(values) == 0 {
+		return HealthUnknown, true
+	}
+
+	allHealthy := true
+	for _, value := range values {
+		if containsFold(extractor.DegradedValues, value) {
+			return HealthDegraded, true
+		}
+		if !containsFold(extractor.HealthyValues, value) {
+			allHealthy = false
+		}
+	}
+	if allHealthy {
+		return HealthHealthy, true
+	}
+	return HealthProgressing, true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/shanshanying/kb-cluster-resource-visualizer/backend/pkg/api"
+)
+
+var (
+	persistentVolumeGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "persistentvolumes"}
+	storageClassGVR     = schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"}
+)
+
+// StorageChain describes where the data of a PVC lives: PVC -> PV -> StorageClass
+type StorageChain = api.StorageChain
+
+// AttachStorageChains adds the bound PV as child of every PVC node and
+// summarizes the PV and StorageClass on the PVC node
+func (rtb *ResourceTreeBuilder) AttachStorageChains(root *ResourceTreeNode) {
+	storageClasses := map[string]*unstructured.Unstructured{}
+
+	var attach func(node *ResourceTreeNode)
+	attach = func(node *ResourceTreeNode) {
+		for _, child := range node.Children {
+			attach(child)
+		}
+		if node.Resource.GetKind() != "PersistentVolumeClaim" {
+			return
+		}
+
+		chain := &StorageChain{}
+		chain.VolumeName, _, _ = unstructured.NestedString(node.Resource.Object, "spec", "volumeName")
+		chain.StorageClass, _, _ = unstructured.NestedString(node.Resource.Object, "spec", "storageClassName")
+
+		if chain.VolumeName != "" {
+			pv, err := rtb.client.dynamicClient.Resource(persistentVolumeGVR).Get(context.TODO(), chain.VolumeName, metav1.GetOptions{})
+			if err != nil {
+				log.Printf("⚠️  Unable to get PV %s of PVC %s: %v", chain.VolumeName, node.Resource.GetName(), err)
+			} else {
+				chain.Capacity, _, _ = unstructured.NestedString(pv.Object, "spec", "capacity", "storage")
+				chain.ReclaimPolicy, _, _ = unstructured.NestedString(pv.Object, "spec", "persistentVolumeReclaimPolicy")
+				chain.CSIDriver, _, _ = unstructured.NestedString(pv.Object, "spec", "csi", "driver")
+				chain.VolumeHandle, _, _ = unstructured.NestedString(pv.Object, "spec", "csi", "volumeHandle")
+				if chain.StorageClass == "" {
+					chain.StorageClass, _, _ = unstructured.NestedString(pv.Object, "spec", "storageClassName")
+				}
+				node.Children = append(node.Children, &ResourceTreeNode{
+					Resource: pv,
+					Children: []*ResourceTreeNode{},
+				})
+			}
+		}
+
+		if chain.StorageClass != "" {
+			storageClass, cached := storageClasses[chain.StorageClass]
+			if !cached {
+				var err error
+				storageClass, err = rtb.client.dynamicClient.Resource(storageClassGVR).Get(context.TODO(), chain.StorageClass, metav1.GetOptions{})
+				if err != nil {
+					log.Printf("⚠️  Unable to get StorageClass %s: %v", chain.StorageClass, err)
+					storageClass = nil
+				}
+				storageClasses[chain.StorageClass] = storageClass
+			}
+			if storageClass != nil {
+				chain.Provisioner, _, _ = unstructured.NestedString(storageClass.Object, "provisioner")
+				chain.VolumeBindingMode, _, _ = unstructured.NestedString(storageClass.Object, "volumeBindingMode")
+				chain.AllowExpansion, _, _ = unstructured.NestedBool(storageClass.Object, "allowVolumeExpansion")
+				if chain.ReclaimPolicy == "" {
+					chain.ReclaimPolicy, _, _ = unstructured.NestedString(storageClass.Object, "reclaimPolicy")
+				}
+			}
+		}
+
+		node.Storage = chain
+	}
+	attach(root)
+}
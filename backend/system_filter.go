@@ -0,0 +1,38 @@
+package main
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var controllerRevisionGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "controllerrevisions"}
+
+// System resources added to the pool when they are shown; they are hidden from trees by default
+var systemResourceTypes = []schema.GroupVersionResource{controllerRevisionGVR}
+
+// hiddenAsSystem reports whether a child is bookkeeping of a controller rather than live topology:
+// ControllerRevisions, Succeeded pods and Jobs completed longer ago than the configured age. Jobs
+// of CronJobs are left to the job history, which condenses them. With ?revisions=true the
+// ControllerRevisions of StatefulSets and InstanceSets are shown.
+func (rtb *ResourceTreeBuilder) hiddenAsSystem(parent, child *unstructured.Unstructured) bool {
+	if rtb.showSystem {
+		return false
+	}
+	switch child.GetKind() {
+	case "ControllerRevision":
+		return !(rtb.includeRevisions && revisionedKinds[parent.GetKind()])
+	case "Pod":
+		phase, _, _ := unstructured.NestedString(child.Object, "status", "phase")
+		return phase == "Succeeded"
+	case "Job":
+		if parent.GetKind() == "CronJob" || appConfig.CompletedJobMaxAgeHours <= 0 || jobState(child) != JobStateSucceeded {
+			return false
+		}
+		value, _, _ := unstructured.NestedString(child.Object, "status", "completionTime")
+		completed, err := time.Parse(time.RFC3339, value)
+		return err == nil && time.Since(completed) > time.Duration(appConfig.CompletedJobMaxAgeHours)*time.Hour
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const minThrottleBackoff = 500 * time.Millisecond
+
+// observedRateLimiter wraps the client-side token bucket and records how long callers wait for it
+type observedRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+func (orl *observedRateLimiter) Accept() {
+	start := time.Now()
+	orl.RateLimiter.Accept()
+	recordRateLimiterWait(time.Since(start))
+}
+
+func (orl *observedRateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := orl.RateLimiter.Wait(ctx)
+	recordRateLimiterWait(time.Since(start))
+	return err
+}
+
+func recordRateLimiterWait(waited time.Duration) {
+	if waited <= 0 {
+		return
+	}
+	metrics.AddCounter("kbviz_client_ratelimit_wait_seconds_total",
+		"Total time spent waiting for the client-side rate limiter", nil, waited.Seconds())
+}
+
+// adaptiveBackoff tracks API server throttling (HTTP 429) and delays subsequent requests
+type adaptiveBackoff struct {
+	mu         sync.Mutex
+	maxBackoff time.Duration
+	current    time.Duration
+	until      time.Time
+}
+
+// wait blocks until the current backoff window has passed
+func (ab *adaptiveBackoff) wait(ctx context.Context) error {
+	ab.mu.Lock()
+	delay := time.Until(ab.until)
+	ab.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttled doubles the backoff (honoring Retry-After when it is longer)
+func (ab *adaptiveBackoff) throttled(retryAfter time.Duration) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	if ab.current == 0 {
+		ab.current = minThrottleBackoff
+	} else {
+		ab.current *= 2
+	}
+	if retryAfter > ab.current {
+		ab.current = retryAfter
+	}
+	if ab.current > ab.maxBackoff {
+		ab.current = ab.maxBackoff
+	}
+	ab.until = time.Now().Add(ab.current)
+
+	log.Printf("⚠️  API server throttled request, backing off for %v", ab.current)
+	metrics.AddCounter("kbviz_apiserver_throttled_total", "Number of requests answered with 429 by the API server", nil, 1)
+	metrics.SetGauge("kbviz_apiserver_backoff_seconds", "Current adaptive backoff applied after API server throttling", nil, ab.current.Seconds())
+}
+
+// succeeded resets the backoff after a non-throttled response
+func (ab *adaptiveBackoff) succeeded() {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	if ab.current == 0 {
+		return
+	}
+	ab.current = 0
+	metrics.SetGauge("kbviz_apiserver_backoff_seconds", "Current adaptive backoff applied after API server throttling", nil, 0)
+}
+
+// throttlingRoundTripper applies the adaptive backoff to every API server request
+type throttlingRoundTripper struct {
+	next    http.RoundTripper
+	backoff *adaptiveBackoff
+}
+
+func (trt *throttlingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := trt.backoff.wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := trt.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		trt.backoff.throttled(retryAfter)
+	} else {
+		trt.backoff.succeeded()
+	}
+	return resp, nil
+}
+
+// applyRequestBudget configures QPS/burst rate limiting and adaptive 429 backoff on a rest config
+func applyRequestBudget(config *rest.Config, appConfig *Config) {
+	config.QPS = appConfig.ClientQPS
+	config.Burst = appConfig.ClientBurst
+	config.RateLimiter = &observedRateLimiter{
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(appConfig.ClientQPS, appConfig.ClientBurst),
+	}
+
+	backoff := &adaptiveBackoff{maxBackoff: time.Duration(appConfig.MaxBackoffSeconds) * time.Second}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &throttlingRoundTripper{next: rt, backoff: backoff}
+	})
+	// Retries wrap the throttling, so every attempt waits for the backoff window
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &retryingRoundTripper{
+			next:        rt,
+			maxAttempts: appConfig.RetryMaxAttempts,
+			callTimeout: time.Duration(appConfig.CallTimeoutSeconds) * time.Second,
+		}
+	})
+
+	metrics.SetGauge("kbviz_client_qps", "Configured client-side QPS limit", nil, float64(appConfig.ClientQPS))
+	metrics.SetGauge("kbviz_client_burst", "Configured client-side burst limit", nil, float64(appConfig.ClientBurst))
+	metrics.SetGauge("kbviz_apiserver_backoff_seconds", "Current adaptive backoff applied after API server throttling", nil, 0)
+	log.Printf("✓ Client request budget configured: qps=%v burst=%d maxBackoff=%ds retries=%d callTimeout=%ds",
+		appConfig.ClientQPS, appConfig.ClientBurst, appConfig.MaxBackoffSeconds, appConfig.RetryMaxAttempts, appConfig.CallTimeoutSeconds)
+}
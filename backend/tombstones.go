@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// treeSessionIdleTimeout drops the tree sessions nobody requested for a while
+const treeSessionIdleTimeout = 10 * time.Minute
+
+// tombstone is a node that disappeared from a tree and is kept during the grace period
+type tombstone struct {
+	resource    *unstructured.Unstructured
+	displayName string
+	parent      types.UID
+	depth       int
+	deletedAt   time.Time
+}
+
+// treeSession remembers the nodes of the last build of a tree and its recently deleted nodes
+type treeSession struct {
+	seen       map[types.UID]tombstone
+	tombstones map[types.UID]tombstone
+	used       time.Time
+}
+
+var treeSessions = struct {
+	mu       sync.Mutex
+	sessions map[string]*treeSession
+}{sessions: map[string]*treeSession{}}
+
+func treeSessionKey(rtb *ResourceTreeBuilder, resourceType, rootResourceName string) string {
+	return fmt.Sprintf("%s|%s|%s/%s|rbac=%t|system=%t|revisions=%t", rtb.client.name, rtb.namespace, resourceType, rootResourceName, rtb.includeRBAC, rtb.showSystem, rtb.includeRevisions)
+}
+
+// RetainDeletedNodes compares the tree with the previous build of the same tree. Nodes that
+// disappeared since are kept under their former parent flagged as deleted until the grace period
+// ends, so live views show what an operation removed. Partial trees are not compared, their
+// unexpanded nodes are not gone.
+func (rtb *ResourceTreeBuilder) RetainDeletedNodes(root *ResourceTreeNode, resourceType, rootResourceName string, attach bool) {
+	grace := time.Duration(appConfig.DeletedNodeGraceSeconds) * time.Second
+	if grace <= 0 || root == nil || rtb.ContinuationToken() != "" {
+		return
+	}
+
+	current := map[types.UID]tombstone{}
+	nodes := map[types.UID]*ResourceTreeNode{}
+	var walk func(node *ResourceTreeNode, parent types.UID, depth int)
+	walk = func(node *ResourceTreeNode, parent types.UID, depth int) {
+		uid := node.Resource.GetUID()
+		nodes[uid] = node
+		if !node.Virtual {
+			current[uid] = tombstone{resource: node.Resource, displayName: node.DisplayName, parent: parent, depth: depth}
+		}
+		for _, child := range node.Children {
+			walk(child, uid, depth+1)
+		}
+	}
+	walk(root, "", 0)
+
+	now := time.Now()
+	key := treeSessionKey(rtb, resourceType, rootResourceName)
+	treeSessions.mu.Lock()
+	for existing, session := range treeSessions.sessions {
+		if now.Sub(session.used) > treeSessionIdleTimeout {
+			delete(treeSessions.sessions, existing)
+		}
+	}
+	session := treeSessions.sessions[key]
+	if session == nil {
+		session = &treeSession{tombstones: map[types.UID]tombstone{}}
+		treeSessions.sessions[key] = session
+	}
+	for uid, previous := range session.seen {
+		// Resources still in the pool were hidden, e.g. a pod that Succeeded, not deleted
+		if _, found := current[uid]; !found && rtb.pool.GetResource(uid) == nil {
+			previous.deletedAt = now
+			session.tombstones[uid] = previous
+			log.Printf("🪦 %s/%s disappeared from the tree of %s/%s", previous.resource.GetKind(), previous.resource.GetName(), resourceType, rootResourceName)
+		}
+	}
+	for uid, deleted := range session.tombstones {
+		if _, found := current[uid]; found || now.Sub(deleted.deletedAt) > grace {
+			delete(session.tombstones, uid)
+		}
+	}
+	session.seen = current
+	session.used = now
+	retained := make([]tombstone, 0, len(session.tombstones))
+	for _, deleted := range session.tombstones {
+		retained = append(retained, deleted)
+	}
+	treeSessions.mu.Unlock()
+
+	if !attach || len(retained) == 0 {
+		return
+	}
+	// Parents first, so deleted children hang below their deleted parent
+	sort.Slice(retained, func(i, j int) bool {
+		if retained[i].depth != retained[j].depth {
+			return retained[i].depth < retained[j].depth
+		}
+		return retained[i].resource.GetName() < retained[j].resource.GetName()
+	})
+	for _, deleted := range retained {
+		node := &ResourceTreeNode{
+			Resource:    deleted.resource,
+			Children:    []*ResourceTreeNode{},
+			DisplayName: deleted.displayName,
+			Deleted:     true,
+			DeletedAt:   deleted.deletedAt.UTC().Format(time.RFC3339),
+		}
+		parent := nodes[deleted.parent]
+		if parent == nil {
+			parent = root
+		}
+		parent.Children = append(parent.Children, node)
+		nodes[deleted.resource.GetUID()] = node
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	treePartialHeader  = "X-Tree-Partial"
+	treeContinueHeader = "X-Tree-Continue"
+)
+
+// treeContinuation is the decoded continuation token of a partial tree
+type treeContinuation struct {
+	Namespace string   `json:"namespace"`
+	Selector  string   `json:"selector,omitempty"`
+	Root      string   `json:"root"` // UID of the root of the partial tree, the UIDs descend from it
+	UIDs      []string `json:"uids"`
+}
+
+// continuationTokenVersion signs continuation tokens apart from share and exec tokens
+const continuationTokenVersion = "c1"
+
+// TreeOptions are the per-request settings of a tree build. Zero limits mean unlimited,
+// a nil IncludeRBAC keeps the configured default.
+type TreeOptions struct {
+	TimeBudget  time.Duration
+	MaxNodes    int
+	IncludeRBAC *bool
+	ShowSystem  *bool
+	// IncludeRevisions shows the ControllerRevisions of StatefulSets and InstanceSets
+	IncludeRevisions bool
+	// Reveal keeps sensitive fields in the decorated tree, see redaction.go
+	Reveal bool
+	// Language of problem messages and warnings, negotiated from Accept-Language
+	Language string
+	// Pipeline post-processes the trees of the request; with a pipeline, grouping by the
+	// visualization annotations only happens when it includes the group processor
+	Pipeline TreePipeline
+}
+
+// SetOptions applies the options of a request. A node cap can only lower the configured one.
+func (rtb *ResourceTreeBuilder) SetOptions(options TreeOptions) {
+	rtb.SetTimeBudget(options.TimeBudget)
+	if options.MaxNodes > 0 && (rtb.maxNodes == 0 || options.MaxNodes < rtb.maxNodes) {
+		rtb.maxNodes = options.MaxNodes
+	}
+	if options.IncludeRBAC != nil {
+		rtb.includeRBAC = *options.IncludeRBAC
+	}
+	if options.ShowSystem != nil {
+		rtb.showSystem = *options.ShowSystem
+	}
+	rtb.includeRevisions = options.IncludeRevisions
+	rtb.reveal = options.Reveal
+	rtb.deferGrouping = options.Pipeline != nil
+	if options.Language != "" {
+		rtb.language = options.Language
+	}
+}
+
+// countNode counts a node towards the node cap, even past it
+func (rtb *ResourceTreeBuilder) countNode() {
+	rtb.mu.Lock()
+	rtb.nodeCount++
+	rtb.mu.Unlock()
+}
+
+// reserveNodes counts up to n nodes towards the node cap and returns how many fit, the nodes
+// beyond are left out of the tree
+func (rtb *ResourceTreeBuilder) reserveNodes(n int) int {
+	rtb.mu.Lock()
+	defer rtb.mu.Unlock()
+	if rtb.maxNodes > 0 && rtb.nodeCount+n > rtb.maxNodes {
+		n = rtb.maxNodes - rtb.nodeCount
+		if n < 0 {
+			n = 0
+		}
+	}
+	rtb.nodeCount += n
+	return n
+}
+
+func (rtb *ResourceTreeBuilder) nodeLimitReached() bool {
+	rtb.mu.Lock()
+	defer rtb.mu.Unlock()
+	return rtb.maxNodes > 0 && rtb.nodeCount >= rtb.maxNodes
+}
+
+// truncate leaves the children of a node unexpanded and records it for the continuation token
+func (rtb *ResourceTreeBuilder) truncate(node *ResourceTreeNode) {
+	if node.Truncated {
+		return
+	}
+	node.Truncated = true
+	rtb.mu.Lock()
+	rtb.truncated = append(rtb.truncated, string(node.Resource.GetUID()))
+	first := len(rtb.truncated) == 1
+	rtb.mu.Unlock()
+	if !first {
+		return
+	}
+	if rtb.nodeLimitReached() {
+		rtb.addWarning("Tree truncated at %d nodes, expand the truncated nodes to see the rest", rtb.maxNodes)
+	} else {
+		rtb.addWarning("Tree truncated after the time budget was spent, expand the truncated nodes to see the rest")
+	}
+}
+
+// SetTimeBudget limits how long the tree may take from now, including building the pool
+func (rtb *ResourceTreeBuilder) SetTimeBudget(budget time.Duration) {
+	if budget > 0 {
+		rtb.deadline = time.Now().Add(budget)
+	}
+}
+
+func (rtb *ResourceTreeBuilder) budgetExceeded() bool {
+	return !rtb.deadline.IsZero() && time.Now().After(rtb.deadline)
+}
+
+// ContinuationToken returns the token resuming the nodes left unexpanded, or "" when the tree is complete
+func (rtb *ResourceTreeBuilder) ContinuationToken() string {
+	if len(rtb.truncated) == 0 {
+		return ""
+	}
+	token, err := signToken(continuationTokenVersion, treeContinuation{
+		Namespace: rtb.namespace,
+		Selector:  rtb.listOptions.LabelSelector,
+		Root:      string(rtb.root),
+		UIDs:      rtb.truncated,
+	})
+	if err != nil {
+		log.Printf("⚠️  Unable to sign continuation token: %v", err)
+		return ""
+	}
+	return token
+}
+
+// parseTreeOptions reads the timeBudgetMs, maxNodes, rbac, showSystem, revisions, reveal and pipeline query parameters
+func parseTreeOptions(c *gin.Context) (TreeOptions, error) {
+	var options TreeOptions
+	params, err := queryParams(c)
+	if err != nil {
+		return options, err
+	}
+	if params.TimeBudgetMs != nil {
+		options.TimeBudget = time.Duration(*params.TimeBudgetMs) * time.Millisecond
+	}
+	if params.MaxNodes != nil {
+		options.MaxNodes = *params.MaxNodes
+	}
+	options.IncludeRBAC = params.RBAC
+	options.ShowSystem = params.ShowSystem
+	options.IncludeRevisions = params.Revisions != nil && *params.Revisions
+	options.Reveal = revealAllowed(c)
+	options.Language = requestLanguage(c)
+	options.Pipeline, err = parseTreePipeline(c)
+	return options, err
+}
+
+// setPartialHeaders tells v1 clients, whose response is a bare array, that the tree is partial
+func setPartialHeaders(c *gin.Context, treeBuilder *ResourceTreeBuilder) {
+	if token := treeBuilder.ContinuationToken(); token != "" {
+		c.Header(treePartialHeader, "true")
+		c.Header(treeContinueHeader, token)
+	}
+}
+
+// buildTreeContinuation expands the nodes listed in a continuation token into subtrees, reusing
+// the pool of the partial build while it is cached. The subtrees honour the tree options as well.
+// Tokens are signed and name the root of the partial tree, which has to be the root of the
+// request, so authorization and share tokens checked on the root cover the continued nodes.
+func buildTreeContinuation(c *gin.Context, token string) ([]*ResourceTreeNode, *ResourceTreeBuilder, int, error) {
+	var continuation treeContinuation
+	if err := verifyToken(token, continuationTokenVersion, "continuation", &continuation); err != nil || len(continuation.UIDs) == 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Invalid continuation token")
+	}
+	// The namespace allowlist only checks the namespace parameter, so the token has to match it
+	if continuation.Namespace != c.Query("namespace") {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Continuation token does not belong to namespace %s", c.Query("namespace"))
+	}
+	options, err := parseTreeOptions(c)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+
+	client := clientFor(c)
+	root, _, status, err := fetchResource(client, c.Param("type"), c.Param("root"), continuation.Namespace)
+	if err != nil {
+		return nil, nil, status, err
+	}
+	if string(root.GetUID()) != continuation.Root {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("Continuation token does not belong to the tree of %s/%s", c.Param("type"), c.Param("root"))
+	}
+
+	treeBuilder := NewResourceTreeBuilder(client, continuation.Namespace, metav1.ListOptions{LabelSelector: continuation.Selector})
+	treeBuilder.SetOptions(options)
+	treeBuilder.root = root.GetUID()
+	treeBuilder.pool = resourcePools.findByUID(client, continuation.Namespace, types.UID(continuation.UIDs[0]))
+	if treeBuilder.pool == nil {
+		log.Printf("Pool of continuation in namespace %s expired, rebuilding it", continuation.Namespace)
+		if err := treeBuilder.buildResourcePool(); err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+	}
+
+	var subtrees []*ResourceTreeNode
+	for _, uid := range continuation.UIDs {
+		resource := treeBuilder.pool.GetResource(types.UID(uid))
+		if resource == nil {
+			treeBuilder.addWarning("Resource %s of the continuation no longer exists", uid)
+			continue
+		}
+		subtree, err := treeBuilder.buildTreeFromPool(resource)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		options.Pipeline.Apply(subtree)
+		subtrees = append(subtrees, subtree)
+	}
+	log.Printf("Continued %d truncated nodes in namespace %s, %d still truncated", len(subtrees), continuation.Namespace, len(treeBuilder.truncated))
+	return subtrees, treeBuilder, http.StatusOK, nil
+}
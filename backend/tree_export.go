@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// treeExportColumns are the columns of the flat resource inventory
+var treeExportColumns = []string{"kind", "name", "namespace", "status", "owner", "age", "labels"}
+
+// flattenTree turns the tree into inventory rows, parents before their children
+func flattenTree(node *ResourceTreeNode, owner string, now time.Time) [][]string {
+	resource := node.Resource
+	status := resourceStatus(resource)
+
+	age := resourceAge(resource, now)
+
+	labels := resource.GetLabels()
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, labels[key]))
+	}
+
+	rows := [][]string{{resource.GetKind(), resource.GetName(), resource.GetNamespace(), status, owner, age, strings.Join(pairs, ";")}}
+	self := fmt.Sprintf("%s/%s", resource.GetKind(), resource.GetName())
+	for _, child := range node.Children {
+		rows = append(rows, flattenTree(child, self, now)...)
+	}
+	return rows
+}
+
+// writeTreeInventory writes the tree as CSV inventory, returning the number of resources written
+func writeTreeInventory(w io.Writer, root *ResourceTreeNode) (int, error) {
+	rows := flattenTree(root, "", time.Now())
+	writer := csv.NewWriter(w)
+	writer.Write(treeExportColumns)
+	writer.WriteAll(rows)
+	return len(rows), writer.Error()
+}
+
+// exportResourceTree downloads the tree as CSV inventory, or with async=true uploads it to the
+// export storage in the background
+func exportResourceTree(c *gin.Context) {
+	resourceType := c.Param("type")
+	rootResourceName := c.Param("root")
+	namespace := c.Query("namespace")
+
+	format := defaultString(c.Query("format"), "csv")
+	if format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format: %s", format)})
+		return
+	}
+	async, err := asyncRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Exporting resource tree of %s/%s in namespace '%s' as %s requested from %s", resourceType, rootResourceName, namespace, format, c.ClientIP())
+	filename := fmt.Sprintf("%s-%s-inventory.csv", namespace, rootResourceName)
+
+	if async {
+		options, err := parseTreeOptions(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		client := clientFor(c)
+		startExport(c, JobTypeTreeExport, filename, "text/csv; charset=utf-8", func(w io.Writer) error {
+			rootTreeNode, _, _, err := buildTreeForRootWithin(client, resourceType, rootResourceName, namespace, options)
+			if err != nil {
+				return err
+			}
+			options.Pipeline.Apply(rootTreeNode)
+			count, err := writeTreeInventory(w, rootTreeNode)
+			log.Printf("Exported %d resources of %s/%s", count, resourceType, rootResourceName)
+			return err
+		})
+		return
+	}
+
+	rootTreeNode, _, status, err := buildTreeForRequest(c, resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if rootTreeNode == nil {
+		c.Status(status)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	count, err := writeTreeInventory(c.Writer, rootTreeNode)
+	if err != nil {
+		log.Printf("Error writing tree export: %v", err)
+		return
+	}
+	log.Printf("Exported %d resources of %s/%s", count, resourceType, rootResourceName)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TreeFilter prunes tree nodes by label and annotation selectors
+type TreeFilter struct {
+	includeLabels      []labels.Selector
+	excludeLabels      []labels.Selector
+	includeAnnotations []labels.Selector
+	excludeAnnotations []labels.Selector
+}
+
+// parseTreeFilter parses the filterLabel, excludeLabel, filterAnnotation and excludeAnnotation
+// query parameters. Each accepts label selector syntax (key=value, key!=value, key, key in (a,b))
+// and may be repeated; all filters must match. Returns nil when no filter is set.
+func parseTreeFilter(query map[string][]string) (*TreeFilter, error) {
+	filter := &TreeFilter{}
+	for param, target := range map[string]*[]labels.Selector{
+		"filterLabel":       &filter.includeLabels,
+		"excludeLabel":      &filter.excludeLabels,
+		"filterAnnotation":  &filter.includeAnnotations,
+		"excludeAnnotation": &filter.excludeAnnotations,
+	} {
+		for _, value := range query[param] {
+			selector, err := labels.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s %q: %v", param, value, err)
+			}
+			*target = append(*target, selector)
+		}
+	}
+	if len(filter.includeLabels)+len(filter.excludeLabels)+len(filter.includeAnnotations)+len(filter.excludeAnnotations) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+func (tf *TreeFilter) included(node *ResourceTreeNode) bool {
+	nodeLabels := labels.Set(node.Resource.GetLabels())
+	nodeAnnotations := labels.Set(node.Resource.GetAnnotations())
+	for _, selector := range tf.includeLabels {
+		if !selector.Matches(nodeLabels) {
+			return false
+		}
+	}
+	for _, selector := range tf.includeAnnotations {
+		if !selector.Matches(nodeAnnotations) {
+			return false
+		}
+	}
+	return true
+}
+
+func (tf *TreeFilter) excluded(node *ResourceTreeNode) bool {
+	nodeLabels := labels.Set(node.Resource.GetLabels())
+	nodeAnnotations := labels.Set(node.Resource.GetAnnotations())
+	for _, selector := range tf.excludeLabels {
+		if selector.Matches(nodeLabels) {
+			return true
+		}
+	}
+	for _, selector := range tf.excludeAnnotations {
+		if selector.Matches(nodeAnnotations) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply prunes the tree below the root. Excluded nodes are dropped with their subtree.
+// A node matching the include filters keeps its whole subtree; a node that does not match
+// is only kept as the path to matching descendants.
+func (tf *TreeFilter) Apply(root *ResourceTreeNode) {
+	if tf == nil {
+		return
+	}
+
+	pruned := 0
+	var filter func(node *ResourceTreeNode, matched bool) bool
+	filter = func(node *ResourceTreeNode, matched bool) bool {
+		if tf.excluded(node) {
+			return false
+		}
+		matched = matched || tf.included(node)
+
+		kept := node.Children[:0]
+		for _, child := range node.Children {
+			if filter(child, matched) {
+				kept = append(kept, child)
+			} else {
+				pruned++
+			}
+		}
+		node.Children = kept
+		return matched || len(kept) > 0
+	}
+
+	kept := root.Children[:0]
+	for _, child := range root.Children {
+		if filter(child, false) {
+			kept = append(kept, child)
+		} else {
+			pruned++
+		}
+	}
+	root.Children = kept
+
+	log.Printf("🔍 Tree filters pruned %d subtrees below %s/%s", pruned, root.Resource.GetKind(), root.Resource.GetName())
+}
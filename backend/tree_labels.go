@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+// Outcomes of a bulk label edit on one node
+const (
+	LabelEditUpdated   = "updated"
+	LabelEditUnchanged = "unchanged"
+	LabelEditFailed    = "failed"
+)
+
+// TreeLabelsRequest is the body of POST /api/resources/:type/:root/tree/labels
+type TreeLabelsRequest struct {
+	Labels            map[string]string `json:"labels,omitempty"`
+	RemoveLabels      []string          `json:"removeLabels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	RemoveAnnotations []string          `json:"removeAnnotations,omitempty"`
+	// Kinds restricts the edit to nodes of these kinds, all nodes when empty
+	Kinds []string `json:"kinds,omitempty"`
+	// Selector restricts the edit to nodes whose labels match this label selector
+	Selector string `json:"selector,omitempty"`
+}
+
+// TreeLabelsNode is the outcome of the edit on one node
+type TreeLabelsNode struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	UID       string `json:"uid"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TreeLabelsResult summarizes a bulk label edit of a tree
+type TreeLabelsResult struct {
+	Root      string           `json:"root"`
+	Matched   int              `json:"matched"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Nodes     []TreeLabelsNode `json:"nodes"`
+}
+
+// validate checks the keys and values of the edit and parses its selector
+func (request *TreeLabelsRequest) validate() (labels.Selector, error) {
+	if len(request.Labels)+len(request.RemoveLabels)+len(request.Annotations)+len(request.RemoveAnnotations) == 0 {
+		return nil, fmt.Errorf("Nothing to change, set labels, removeLabels, annotations or removeAnnotations")
+	}
+	for key, value := range request.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("Invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("Invalid value of label %s: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range request.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("Invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	for _, key := range request.RemoveLabels {
+		if _, found := request.Labels[key]; found {
+			return nil, fmt.Errorf("Label %s is both set and removed", key)
+		}
+	}
+	for _, key := range request.RemoveAnnotations {
+		if _, found := request.Annotations[key]; found {
+			return nil, fmt.Errorf("Annotation %s is both set and removed", key)
+		}
+	}
+	if request.Selector == "" {
+		return labels.Everything(), nil
+	}
+	selector, err := labels.Parse(request.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid selector %q: %v", request.Selector, err)
+	}
+	return selector, nil
+}
+
+// metadataChanges reports whether setting and removing keys changes the labels or annotations of a resource
+func metadataChanges(current, set map[string]string, remove []string) bool {
+	for key, value := range set {
+		if existing, found := current[key]; !found || existing != value {
+			return true
+		}
+	}
+	for _, key := range remove {
+		if _, found := current[key]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePatch is the merge patch setting and removing the labels and annotations of the edit
+func (request *TreeLabelsRequest) mergePatch() []byte {
+	metadata := map[string]interface{}{}
+	for field, edit := range map[string]struct {
+		set    map[string]string
+		remove []string
+	}{
+		"labels":      {request.Labels, request.RemoveLabels},
+		"annotations": {request.Annotations, request.RemoveAnnotations},
+	} {
+		if len(edit.set)+len(edit.remove) == 0 {
+			continue
+		}
+		values := map[string]interface{}{}
+		for key, value := range edit.set {
+			values[key] = value
+		}
+		for _, key := range edit.remove {
+			values[key] = nil
+		}
+		metadata[field] = values
+	}
+	patch, _ := json.Marshal(map[string]interface{}{"metadata": metadata})
+	return patch
+}
+
+// editTreeLabels applies or removes labels and annotations on every resource of a tree, or on those
+// matching kinds and selector, e.g. to tag all resources of a cluster for cost attribution.
+// dryRun=true validates the patches server-side without persisting them.
+func editTreeLabels(c *gin.Context) {
+	resourceType := c.Param("type")
+	rootResourceName := c.Param("root")
+	namespace := c.Query("namespace")
+	dryRun, err := dryRunRequested(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var request TreeLabelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid label edit: %v", err)})
+		return
+	}
+	selector, err := request.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Editing labels of the tree of %s/%s in namespace '%s' (dryRun=%t) requested from %s", resourceType, rootResourceName, namespace, dryRun, c.ClientIP())
+
+	client := clientFor(c)
+	root, _, status, err := buildTreeForRoot(client, resourceType, rootResourceName, namespace)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	kinds := map[string]bool{}
+	for _, kind := range request.Kinds {
+		kinds[strings.ToLower(kind)] = true
+	}
+	patch := request.mergePatch()
+	result := TreeLabelsResult{Root: string(root.Resource.GetUID()), Nodes: []TreeLabelsNode{}}
+	var changes []FieldChange
+	seen := map[types.UID]bool{}
+
+	var edit func(node *ResourceTreeNode)
+	edit = func(node *ResourceTreeNode) {
+		for _, child := range node.Children {
+			edit(child)
+		}
+		resource := node.Resource
+		if node.Virtual || node.Deleted || seen[resource.GetUID()] {
+			return
+		}
+		seen[resource.GetUID()] = true
+		if (len(kinds) > 0 && !kinds[strings.ToLower(resource.GetKind())]) || !selector.Matches(labels.Set(resource.GetLabels())) {
+			return
+		}
+
+		result.Matched++
+		outcome := TreeLabelsNode{Kind: resource.GetKind(), Name: resource.GetName(), Namespace: resource.GetNamespace(), UID: string(resource.GetUID())}
+		switch {
+		case !metadataChanges(resource.GetLabels(), request.Labels, request.RemoveLabels) &&
+			!metadataChanges(resource.GetAnnotations(), request.Annotations, request.RemoveAnnotations):
+			outcome.Result = LabelEditUnchanged
+			result.Unchanged++
+		default:
+			patched, err := patchNodeMetadata(client, node, patch, dryRun)
+			if err != nil {
+				log.Printf("    ⚠️  Unable to edit labels of %s/%s: %v", resource.GetKind(), resource.GetName(), err)
+				outcome.Result, outcome.Error = LabelEditFailed, err.Error()
+				result.Failed++
+				break
+			}
+			outcome.Result = LabelEditUpdated
+			result.Updated++
+			if dryRun {
+				for _, change := range dryRunChanges(c, resource, patched) {
+					change.Resource = resource.GetKind() + "/" + resource.GetName()
+					changes = append(changes, change)
+				}
+			}
+		}
+		result.Nodes = append(result.Nodes, outcome)
+	}
+	edit(root)
+
+	log.Printf("✓ Edited labels of the tree of %s/%s: %d matched, %d updated, %d unchanged, %d failed (dryRun=%t)", resourceType, rootResourceName, result.Matched, result.Updated, result.Unchanged, result.Failed, dryRun)
+	if dryRun {
+		respondDryRunChanges(c, root.Resource, changes, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// patchNodeMetadata applies the metadata merge patch to the resource of a node and returns the patched resource
+func patchNodeMetadata(client *K8sClient, node *ResourceTreeNode, patch []byte, dryRun bool) (*unstructured.Unstructured, error) {
+	resource := node.Resource
+	gvr, ok := gvrForKind(client, resource.GetAPIVersion(), resource.GetKind())
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type %s %s", resource.GetAPIVersion(), resource.GetKind())
+	}
+	var resources dynamic.ResourceInterface = client.dynamicClient.Resource(gvr)
+	if resource.GetNamespace() != "" {
+		resources = client.dynamicClient.Resource(gvr).Namespace(resource.GetNamespace())
+	}
+	return resources.Patch(context.TODO(), resource.GetName(), types.MergePatchType, patch, metav1.PatchOptions{DryRun: dryRunOption(dryRun)})
+}
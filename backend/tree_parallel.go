@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// parallelChildThreshold is the number of children from which they are resolved in parallel,
+// e.g. the pods of a large InstanceSet. Smaller sets are not worth the goroutines.
+const parallelChildThreshold = 32
+
+// sortResources sorts resources by kind, then name, then namespace, so trees do not depend on
+// the order resources were listed or indexed in
+func sortResources(resources []*unstructured.Unstructured) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.GetKind() != b.GetKind() {
+			return a.GetKind() < b.GetKind()
+		}
+		if a.GetName() != b.GetName() {
+			return a.GetName() < b.GetName()
+		}
+		return a.GetNamespace() < b.GetNamespace()
+	})
+}
+
+// buildChild builds the subtree of a child, falling back to a leaf when it fails
+func (rtb *ResourceTreeBuilder) buildChild(child *unstructured.Unstructured, visited map[types.UID]bool, parallel bool) *ResourceTreeNode {
+	childNode, err := rtb.buildSubtree(child, visited, parallel)
+	if err != nil {
+		log.Printf("⚠️  Error building subtree for %s/%s: %v",
+			child.GetKind(), child.GetName(), err)
+		return &ResourceTreeNode{
+			Resource: child,
+			Children: []*ResourceTreeNode{},
+		}
+	}
+	return childNode
+}
+
+// buildChildrenInParallel resolves the subtrees of sorted children with a pool of workers per
+// kind, so a kind with thousands of pods does not hold up the others. Each worker has its own
+// copy of the ancestors and builds its subtrees sequentially.
+//
+// The slots of the children under the node cap are reserved in child order before any worker
+// starts, so the same children are shown whatever the scheduling and the node is truncated after
+// the last one that fits. When the descendants of the children then run into the cap, which of
+// them got the remaining slots depends on scheduling, so the children are rebuilt one after the
+// other, as without workers, to keep the tree stable between builds.
+func (rtb *ResourceTreeBuilder) buildChildrenInParallel(node *ResourceTreeNode, children []*unstructured.Unstructured, visited map[types.UID]bool) []*ResourceTreeNode {
+	admitted := children[:rtb.reserveNodes(len(children))]
+	log.Printf("⚡ Resolving %d children of %s/%s with %d workers per kind",
+		len(admitted), node.Resource.GetKind(), node.Resource.GetName(), rtb.workers)
+
+	rtb.mu.Lock()
+	nodeCount, truncated, warnings := rtb.nodeCount, len(rtb.truncated), len(rtb.warnings)
+	rtb.mu.Unlock()
+
+	built := make([]*ResourceTreeNode, len(admitted))
+	var wg sync.WaitGroup
+	for start := 0; start < len(admitted); {
+		// Children are sorted by kind, so each kind is a contiguous range
+		end := start + 1
+		for end < len(admitted) && admitted[end].GetKind() == admitted[start].GetKind() {
+			end++
+		}
+
+		indexes := make(chan int, end-start)
+		for i := start; i < end; i++ {
+			indexes <- i
+		}
+		close(indexes)
+
+		workers := rtb.workers
+		if workers > end-start {
+			workers = end - start
+		}
+		for w := 0; w < workers; w++ {
+			ancestors := make(map[types.UID]bool, len(visited))
+			for uid, ancestor := range visited {
+				ancestors[uid] = ancestor
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					built[i] = rtb.buildChild(admitted[i], ancestors, false)
+				}
+			}()
+		}
+		start = end
+	}
+	wg.Wait()
+
+	rtb.mu.Lock()
+	raced := rtb.maxNodes > 0 && rtb.nodeCount >= rtb.maxNodes && len(rtb.truncated) > truncated
+	if raced {
+		rtb.nodeCount, rtb.truncated, rtb.warnings = nodeCount, rtb.truncated[:truncated], rtb.warnings[:warnings]
+	}
+	rtb.mu.Unlock()
+	if raced {
+		log.Printf("Node cap reached below the children of %s/%s, rebuilding them sequentially", node.Resource.GetKind(), node.Resource.GetName())
+		for i, child := range admitted {
+			built[i] = rtb.buildChild(child, visited, false)
+		}
+	}
+
+	if len(admitted) < len(children) {
+		rtb.truncate(node)
+	}
+	return built
+}
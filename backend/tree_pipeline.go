@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of tree post-processors
+const (
+	// TreeProcessorFilter processors prune nodes
+	TreeProcessorFilter = "filter"
+	// TreeProcessorDecorate processors annotate nodes without changing the shape of the tree
+	TreeProcessorDecorate = "decorate"
+	// TreeProcessorGroup processors hide, rename or regroup nodes
+	TreeProcessorGroup = "group"
+)
+
+// defaultTreePipeline is the pipeline of tree requests without ?pipeline= when none is configured
+var defaultTreePipeline = []string{"group", "filter"}
+
+// TreePostProcessor rewrites a built tree before it is decorated and returned
+type TreePostProcessor interface {
+	// Name is how the processor is listed in ?pipeline=
+	Name() string
+	// Kind is one of TreeProcessorFilter, TreeProcessorDecorate and TreeProcessorGroup
+	Kind() string
+	Process(root *ResourceTreeNode)
+}
+
+// treePostProcessors create the processors of a pipeline from the query of the request
+var treePostProcessors = map[string]func(query map[string][]string) (TreePostProcessor, error){
+	"filter": func(query map[string][]string) (TreePostProcessor, error) {
+		return parseTreeFilter(query)
+	},
+	"group": func(map[string][]string) (TreePostProcessor, error) {
+		return visualizationGrouping{}, nil
+	},
+	"health": func(map[string][]string) (TreePostProcessor, error) {
+		return healthRollup{}, nil
+	},
+}
+
+// Name implements TreePostProcessor, a nil filter leaves the tree unchanged
+func (tf *TreeFilter) Name() string { return "filter" }
+
+// Kind implements TreePostProcessor
+func (tf *TreeFilter) Kind() string { return TreeProcessorFilter }
+
+// Process implements TreePostProcessor
+func (tf *TreeFilter) Process(root *ResourceTreeNode) { tf.Apply(root) }
+
+// visualizationGrouping applies the viz.kubeblocks.io annotations below the root, see viz_hints.go
+type visualizationGrouping struct{}
+
+func (visualizationGrouping) Name() string { return "group" }
+
+func (visualizationGrouping) Kind() string { return TreeProcessorGroup }
+
+// Process rewrites the children of the deepest nodes first, as the builder did, so hidden
+// children that are themselves grouped are regrouped under their new parent
+func (visualizationGrouping) Process(root *ResourceTreeNode) {
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		for _, child := range node.Children {
+			walk(child)
+		}
+		applyVisualizationHints(node)
+	}
+	walk(root)
+}
+
+// healthRollup sets the health of every node to the worst health of its subtree
+type healthRollup struct{}
+
+func (healthRollup) Name() string { return "health" }
+
+func (healthRollup) Kind() string { return TreeProcessorDecorate }
+
+func (healthRollup) Process(root *ResourceTreeNode) {
+	rollupHealth(root, func(node *ResourceTreeNode, health string) {
+		node.Health = health
+	})
+}
+
+// TreePipeline is the chain of post-processors applied to the trees of a request, in order
+type TreePipeline []TreePostProcessor
+
+// Apply runs the processors on the tree
+func (tp TreePipeline) Apply(root *ResourceTreeNode) {
+	for _, processor := range tp {
+		processor.Process(root)
+	}
+}
+
+// Names lists the processors of the pipeline, e.g. for logs
+func (tp TreePipeline) Names() []string {
+	names := make([]string, 0, len(tp))
+	for _, processor := range tp {
+		names = append(names, processor.Name())
+	}
+	return names
+}
+
+// treePostProcessorNames lists the registered processors, sorted for error messages
+func treePostProcessorNames() []string {
+	names := make([]string, 0, len(treePostProcessors))
+	for name := range treePostProcessors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateTreePipeline checks that a configured pipeline only names registered processors, once each
+func validateTreePipeline(names []string) error {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if _, found := treePostProcessors[name]; !found {
+			return fmt.Errorf("unknown tree post-processor %q, available: %s", name, strings.Join(treePostProcessorNames(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("tree post-processor %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// newTreePipeline creates the processors named by the pipeline from the query of the request
+func newTreePipeline(names []string, query map[string][]string) (TreePipeline, error) {
+	pipeline := TreePipeline{}
+	for _, name := range names {
+		processor, err := treePostProcessors[name](query)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, processor)
+	}
+	return pipeline, nil
+}
+
+// parseTreePipeline reads ?pipeline=, a comma separated list of post-processors applied in order
+// after the tree is built, e.g. pipeline=filter,group,health. Without it the configured pipeline
+// is used. Selector filters are rejected when the pipeline leaves out the filter processor, rather
+// than silently returning an unfiltered tree.
+func parseTreePipeline(c *gin.Context) (TreePipeline, error) {
+	names := appConfig.TreePipeline
+	if value, given := c.GetQuery("pipeline"); given {
+		names = splitAndTrim(value)
+		if err := validateTreePipeline(names); err != nil {
+			return nil, &ParamError{Field: "pipeline", Value: value, Message: err.Error()}
+		}
+	}
+
+	query := c.Request.URL.Query()
+	pipeline, err := newTreePipeline(names, query)
+	if err != nil {
+		return nil, err
+	}
+	filtered := false
+	for _, name := range names {
+		filtered = filtered || name == "filter"
+	}
+	if !filtered {
+		for _, param := range []string{"filterLabel", "excludeLabel", "filterAnnotation", "excludeAnnotation"} {
+			if _, found := query[param]; found {
+				return nil, fmt.Errorf("%s requires the filter processor in the pipeline", param)
+			}
+		}
+	}
+
+	if c.Query("pipeline") != "" {
+		log.Printf("Applying tree pipeline %s", strings.Join(pipeline.Names(), " → "))
+	}
+	return pipeline, nil
+}
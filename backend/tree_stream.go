@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Tree format streaming one node per line instead of a nested array
+	treeFormatNDJSON = "ndjson"
+	// streamFlushInterval is the number of nodes written between flushes
+	streamFlushInterval = 100
+)
+
+// StreamedNode is a tree node of the NDJSON format. Children are replaced by their UIDs, and
+// Parent points to the node the line belongs under (empty for roots).
+type StreamedNode struct {
+	Parent string `json:"parent,omitempty"`
+	*ResourceTreeNode
+	Children []string `json:"children"`
+}
+
+// StreamTrailer is the last line of an NDJSON tree
+type StreamTrailer struct {
+	Done     bool     `json:"done"`
+	Nodes    int      `json:"nodes"`
+	Warnings []string `json:"warnings"`
+	Partial  bool     `json:"partial,omitempty"`
+	Continue string   `json:"continue,omitempty"`
+	// Error is set when the stream ended before all nodes were written
+	Error string `json:"error,omitempty"`
+}
+
+// streamTree writes the trees as NDJSON, parents before their children, encoding one node at a time
+// so the nested response is never held in memory. It stops as soon as the client goes away.
+func streamTree(c *gin.Context, treeBuilder *ResourceTreeBuilder, roots ...*ResourceTreeNode) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	ctx := c.Request.Context()
+
+	written := 0
+	var write func(node *ResourceTreeNode, parent string) error
+	write = func(node *ResourceTreeNode, parent string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := StreamedNode{Parent: parent, ResourceTreeNode: node, Children: make([]string, 0, len(node.Children))}
+		for _, child := range node.Children {
+			line.Children = append(line.Children, string(child.Resource.GetUID()))
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushInterval == 0 {
+			c.Writer.Flush()
+		}
+		for _, child := range node.Children {
+			if err := write(child, string(node.Resource.GetUID())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	trailer := StreamTrailer{Done: true, Warnings: treeBuilder.Warnings(), Continue: treeBuilder.ContinuationToken()}
+	for _, root := range roots {
+		if err := write(root, ""); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("⚠️  Client went away after %d streamed nodes: %v", written, err)
+				return
+			}
+			trailer.Done = false
+			trailer.Error = err.Error()
+			break
+		}
+	}
+	trailer.Nodes = written
+	trailer.Partial = trailer.Continue != ""
+	if trailer.Warnings == nil {
+		trailer.Warnings = []string{}
+	}
+	if err := encoder.Encode(trailer); err != nil {
+		log.Printf("⚠️  Unable to write the end of the streamed tree: %v", err)
+		return
+	}
+	c.Writer.Flush()
+	log.Printf("Streamed %d tree nodes", written)
+}
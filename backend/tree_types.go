@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// treeTypesReloadDelay lets the events of one update settle before the file is read, e.g. the
+	// create and rename of an editor saving the file
+	treeTypesReloadDelay = 200 * time.Millisecond
+	// treeTypesPollInterval is how often the file is checked when its directory cannot be watched
+	treeTypesPollInterval = 10 * time.Second
+)
+
+// TreeTypesFile selects the resource types the tree builder searches for children, on top of the
+// builtin ones. It is usually mounted from a ConfigMap and reloaded when it changes.
+type TreeTypesFile struct {
+	// Include adds types, e.g. ingresses or the CRDs of an operator
+	Include []TreeResourceType `json:"include"`
+	// Exclude removes builtin types
+	Exclude []TreeResourceType `json:"exclude"`
+}
+
+// TreeResourceType is a group/version/resource of the tree types file, the core group is empty
+type TreeResourceType struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+func (t TreeResourceType) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: t.Group, Version: t.Version, Resource: t.Resource}
+}
+
+// TreeTypesStatus reports the resource types in use and the state of the tree types file
+type TreeTypesStatus struct {
+	File     string             `json:"file,omitempty"`
+	LoadedAt string             `json:"loadedAt,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Types    []TreeResourceType `json:"types"`
+}
+
+var treeTypes = struct {
+	mu       sync.RWMutex
+	types    []schema.GroupVersionResource
+	data     []byte
+	loadedAt time.Time
+	err      error
+}{types: defaultTreeResourceTypes()}
+
+// defaultTreeResourceTypes returns the builtin resource types searched for children
+func defaultTreeResourceTypes() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		// Core resources
+		{Group: "", Version: "v1", Resource: "pods"},
+		{Group: "", Version: "v1", Resource: "services"},
+		{Group: "", Version: "v1", Resource: "configmaps"},
+		{Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
+
+		// Discovery resources (EndpointSlices inherit the labels of their Service)
+		{Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+
+		// Apps resources
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+		{Group: "apps", Version: "v1", Resource: "replicasets"},
+		{Group: "apps", Version: "v1", Resource: "statefulsets"},
+		{Group: "apps", Version: "v1", Resource: "daemonsets"},
+
+		// Batch resources
+		{Group: "batch", Version: "v1", Resource: "jobs"},
+		{Group: "batch", Version: "v1", Resource: "cronjobs"},
+
+		// KubeBlocks resources
+		{Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
+		{Group: "apps.kubeblocks.io", Version: "v1", Resource: "components"},
+		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backuppolicies"},
+		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"},
+		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"},
+		{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "restores"},
+		{Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+		{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "componentparameters"},
+		{Group: "parameters.kubeblocks.io", Version: "v1alpha1", Resource: "parameters"},
+		{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instances"},
+		{Group: "workloads.kubeblocks.io", Version: "v1", Resource: "instancesets"},
+	}
+}
+
+// parseTreeTypesFile validates a tree types file and applies it to the builtin types
+func parseTreeTypesFile(data []byte) ([]schema.GroupVersionResource, error) {
+	var file TreeTypesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, err
+	}
+	for _, entry := range append(append([]TreeResourceType{}, file.Include...), file.Exclude...) {
+		if entry.Version == "" || entry.Resource == "" {
+			return nil, fmt.Errorf("resource type %s/%s/%s needs a version and a resource", entry.Group, entry.Version, entry.Resource)
+		}
+	}
+
+	excluded := map[schema.GroupVersionResource]bool{}
+	for _, entry := range file.Exclude {
+		excluded[entry.GVR()] = true
+	}
+	seen := map[schema.GroupVersionResource]bool{}
+	var types []schema.GroupVersionResource
+	for _, gvr := range append(defaultTreeResourceTypes(), treeResourceTypeGVRs(file.Include)...) {
+		if excluded[gvr] || seen[gvr] {
+			continue
+		}
+		seen[gvr] = true
+		types = append(types, gvr)
+	}
+	return types, nil
+}
+
+func treeResourceTypeGVRs(entries []TreeResourceType) []schema.GroupVersionResource {
+	gvrs := make([]schema.GroupVersionResource, 0, len(entries))
+	for _, entry := range entries {
+		gvrs = append(gvrs, entry.GVR())
+	}
+	return gvrs
+}
+
+// readTreeTypesFile reads and parses the tree types file
+func readTreeTypesFile(path string) ([]schema.GroupVersionResource, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	types, err := parseTreeTypesFile(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tree types file %s: %v", path, err)
+	}
+	return types, data, nil
+}
+
+// loadTreeTypes replaces the tree resource types with those of the file if its content changed.
+// An invalid file keeps the previous types.
+func loadTreeTypes(path string) error {
+	types, data, err := readTreeTypesFile(path)
+
+	treeTypes.mu.Lock()
+	defer treeTypes.mu.Unlock()
+	treeTypes.err = err
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, treeTypes.data) {
+		return nil
+	}
+	treeTypes.types = types
+	treeTypes.data = data
+	treeTypes.loadedAt = time.Now()
+	metrics.AddCounter("kbviz_tree_types_reloads_total", "Number of reloads of the tree types file", map[string]string{"result": "success"}, 1)
+	log.Printf("✓ Loaded %d tree resource types from %s", len(types), path)
+
+	// Types detected empty under the previous selection would otherwise hide new ones until they expire
+	resourceTypeDetection.reset()
+	return nil
+}
+
+// watchTreeTypes reloads the tree types file whenever it changes. The directory is watched rather
+// than the file: the kubelet updates a mounted ConfigMap by pointing the ..data symlink at a new
+// directory, which changes the file without any event on the file itself.
+func watchTreeTypes(ctx context.Context, path string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(path))
+		if err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  Unable to watch the tree types file %s, checking it every %v instead: %v", path, treeTypesPollInterval, err)
+		pollTreeTypes(ctx, path)
+		return
+	}
+	defer watcher.Close()
+
+	reload := time.NewTimer(treeTypesReloadDelay)
+	reload.Stop()
+	lastError := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if name := filepath.Base(event.Name); name == filepath.Base(path) || name == "..data" {
+				reload.Reset(treeTypesReloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("⚠️  Error watching the tree types file %s: %v", path, err)
+		case <-reload.C:
+			lastError = reportTreeTypesError(loadTreeTypes(path), lastError)
+		}
+	}
+}
+
+// pollTreeTypes checks the tree types file for changes every treeTypesPollInterval
+func pollTreeTypes(ctx context.Context, path string) {
+	ticker := time.NewTicker(treeTypesPollInterval)
+	defer ticker.Stop()
+	lastError := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lastError = reportTreeTypesError(loadTreeTypes(path), lastError)
+		}
+	}
+}
+
+// reportTreeTypesError logs a failed reload once, not again while the file stays broken, and
+// returns the error to compare the next reload with
+func reportTreeTypesError(err error, lastError string) string {
+	if err == nil {
+		return ""
+	}
+	if err.Error() != lastError {
+		log.Printf("⚠️  Keeping the previous tree resource types: %v", err)
+		metrics.AddCounter("kbviz_tree_types_reloads_total", "Number of reloads of the tree types file", map[string]string{"result": "error"}, 1)
+	}
+	return err.Error()
+}
+
+// currentTreeResourceTypes returns the resource types the tree builder searches for children
+func currentTreeResourceTypes() []schema.GroupVersionResource {
+	treeTypes.mu.RLock()
+	defer treeTypes.mu.RUnlock()
+	return append([]schema.GroupVersionResource{}, treeTypes.types...)
+}
+
+// getTreeResourceTypes lists the resource types in use and the state of the tree types file
+func getTreeResourceTypes(c *gin.Context) {
+	treeTypes.mu.RLock()
+	defer treeTypes.mu.RUnlock()
+	status := TreeTypesStatus{File: appConfig.TreeTypesFile, Types: make([]TreeResourceType, 0, len(treeTypes.types))}
+	for _, gvr := range treeTypes.types {
+		status.Types = append(status.Types, TreeResourceType{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource})
+	}
+	if !treeTypes.loadedAt.IsZero() {
+		status.LoadedAt = treeTypes.loadedAt.Format(time.RFC3339)
+	}
+	if treeTypes.err != nil {
+		status.Error = treeTypes.err.Error()
+	}
+	c.JSON(http.StatusOK, status)
+}
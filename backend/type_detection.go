@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// typeDetectionTTL bounds how long a type found empty is skipped for a namespace and selector
+const typeDetectionTTL = 30 * time.Second
+
+// typeDetectionCache remembers which resource types have matching resources per namespace and selector
+type typeDetectionCache struct {
+	mu      sync.Mutex
+	entries map[string]typeDetectionEntry
+}
+
+type typeDetectionEntry struct {
+	detected time.Time
+	present  []schema.GroupVersionResource
+}
+
+var resourceTypeDetection = &typeDetectionCache{entries: map[string]typeDetectionEntry{}}
+
+func (tdc *typeDetectionCache) get(key string) ([]schema.GroupVersionResource, bool) {
+	tdc.mu.Lock()
+	defer tdc.mu.Unlock()
+	entry, ok := tdc.entries[key]
+	if !ok || time.Since(entry.detected) > typeDetectionTTL {
+		return nil, false
+	}
+	return entry.present, true
+}
+
+func (tdc *typeDetectionCache) set(key string, present []schema.GroupVersionResource) {
+	tdc.mu.Lock()
+	defer tdc.mu.Unlock()
+	tdc.entries[key] = typeDetectionEntry{detected: time.Now(), present: present}
+}
+
+// reset forgets every detection, e.g. after the candidate types changed
+func (tdc *typeDetectionCache) reset() {
+	tdc.mu.Lock()
+	defer tdc.mu.Unlock()
+	tdc.entries = map[string]typeDetectionEntry{}
+}
+
+// servedGroupResources returns the group/resources served by the cluster, or nil if discovery is unavailable
+func servedGroupResources(client *K8sClient) map[schema.GroupResource]bool {
+	discovered, _ := client.apiDiscovery.get()
+	if len(discovered) == 0 {
+		return nil
+	}
+	served := make(map[schema.GroupResource]bool, len(discovered))
+	for _, info := range discovered {
+		served[schema.GroupResource{Group: info.Group, Resource: info.Resource}] = true
+	}
+	return served
+}
+
+// detectResourceTypes narrows the candidate types to those with resources matching the builder's
+// selector, using discovery and a list with limit=1 per type. Probes that returned the complete
+// result are handed back so the pool does not list those types a second time.
+func (rtb *ResourceTreeBuilder) detectResourceTypes(candidates []schema.GroupVersionResource) ([]schema.GroupVersionResource, map[schema.GroupVersionResource]*unstructured.UnstructuredList) {
+	key := fmt.Sprintf("%s|%s|%s|%s", rtb.client.name, rtb.namespace, rtb.listOptions.LabelSelector, rtb.listOptions.FieldSelector)
+	if present, ok := resourceTypeDetection.get(key); ok {
+		log.Printf("  🔎 Using detected resource types for namespace '%s': %d of %d types have resources", rtb.namespace, len(present), len(candidates))
+		return present, nil
+	}
+
+	served := servedGroupResources(rtb.client)
+	probed := map[schema.GroupVersionResource]*unstructured.UnstructuredList{}
+	var present []schema.GroupVersionResource
+	for _, gvr := range candidates {
+		if served != nil && !served[gvr.GroupResource()] {
+			log.Printf("    ⏭️  Skipping resource type %s: not served by the cluster", gvr.Resource)
+			continue
+		}
+
+		probeOptions := rtb.listOptions
+		probeOptions.Limit = 1
+		var resourceList *unstructured.UnstructuredList
+		var err error
+		if rtb.namespace != "" {
+			resourceList, err = rtb.client.dynamicClient.Resource(gvr).Namespace(rtb.namespace).List(context.TODO(), probeOptions)
+		} else {
+			resourceList, err = rtb.client.dynamicClient.Resource(gvr).List(context.TODO(), probeOptions)
+		}
+		if err != nil {
+			// Let the pool report the error with a full list
+			present = append(present, gvr)
+			continue
+		}
+		if len(resourceList.Items) == 0 {
+			continue
+		}
+
+		present = append(present, gvr)
+		if resourceList.GetContinue() == "" {
+			probed[gvr] = resourceList
+		}
+	}
+
+	resourceTypeDetection.set(key, present)
+	log.Printf("  🔎 Detected resources of %d of %d types in namespace '%s'", len(present), len(candidates), rtb.namespace)
+	return present, probed
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// UIDLookup is a resource found by UID with its chain of owners, nearest first
+type UIDLookup struct {
+	Resource ResourceNode   `json:"resource"`
+	Health   string         `json:"health"`
+	Parents  []ResourceNode `json:"parents"`
+	// UnresolvedOwner is the owner reference the chain stops at when that owner is in no cached pool
+	UnresolvedOwner *metav1.OwnerReference `json:"unresolvedOwner,omitempty"`
+}
+
+// findResource returns the resource with the UID from the freshest unexpired pool of the cluster,
+// in any namespace
+func (pc *poolCache) findResource(client *K8sClient, uid types.UID) (*unstructured.Unstructured, *ResourcePool) {
+	found, resource := pc.lookup(client.name+"|", uid)
+	if found == nil {
+		return nil, nil
+	}
+	return resource, found.pool
+}
+
+// controllerReference returns the controller owner reference, or the first one
+func controllerReference(resource *unstructured.Unstructured) *metav1.OwnerReference {
+	refs := resource.GetOwnerReferences()
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
+
+// lookupUID resolves a UID and its owners through the cached pools
+func lookupUID(client *K8sClient, uid types.UID) *UIDLookup {
+	resource, pool := resourcePools.findResource(client, uid)
+	if resource == nil {
+		return nil
+	}
+	lookup := &UIDLookup{Resource: convertToResourceNode(*resource), Health: computeHealth(resource), Parents: []ResourceNode{}}
+
+	visited := map[types.UID]bool{uid: true}
+	for {
+		// The pool of the resource knows annotated parents too, other pools may hold owners it did not list
+		parentUID := pool.PrimaryOwner(resource)
+		if parentUID == "" {
+			ref := controllerReference(resource)
+			if ref == nil {
+				break
+			}
+			parentUID = ref.UID
+		}
+		if visited[parentUID] {
+			break
+		}
+		visited[parentUID] = true
+
+		parent := pool.GetResource(parentUID)
+		if parent == nil {
+			parent, pool = resourcePools.findResource(client, parentUID)
+		}
+		if parent == nil {
+			lookup.UnresolvedOwner = controllerReference(resource)
+			break
+		}
+		lookup.Parents = append(lookup.Parents, convertToResourceNode(*parent))
+		resource = parent
+	}
+	return lookup
+}
+
+// getResourceByUID resolves a UID, e.g. of an Event's involvedObject or an ownerReference, to its
+// resource and owner chain. Pools built in the last 30s are searched; with namespace= the pool of
+// that namespace is built when no cached pool has the UID.
+func getResourceByUID(c *gin.Context) {
+	uid := types.UID(c.Param("uid"))
+	namespace := c.Query("namespace")
+
+	log.Printf("Looking up UID %s requested from %s", uid, c.ClientIP())
+
+	client := clientFor(c)
+	lookup := lookupUID(client, uid)
+	if lookup == nil && namespace != "" {
+		log.Printf("No cached pool contains UID %s, building pool of namespace %s", uid, namespace)
+		treeBuilder := NewResourceTreeBuilder(client, namespace, metav1.ListOptions{})
+		if err := treeBuilder.buildResourcePool(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		lookup = lookupUID(client, uid)
+	}
+	// Cached pools may span namespaces the allowlist does not serve
+	if lookup == nil || (lookup.Resource.Namespace != "" && !appConfig.namespaceAllowed(lookup.Resource.Namespace)) {
+		message := fmt.Sprintf("UID not found in cached resources: %s", uid)
+		if namespace == "" {
+			message += ", pass namespace= to search a namespace"
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+		return
+	}
+
+	// The webhook only saw the UID, the resource and its owners are reviewed once resolved
+	if err := objectAccessAllowed(c, lookup.Resource.APIVersion, lookup.Resource.Kind, lookup.Resource.Name, lookup.Resource.Namespace); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	for i, parent := range lookup.Parents {
+		if err := objectAccessAllowed(c, parent.APIVersion, parent.Kind, parent.Name, parent.Namespace); err != nil {
+			lookup.Parents = lookup.Parents[:i]
+			break
+		}
+	}
+
+	redactResourceNodesFor(c, &lookup.Resource)
+	for i := range lookup.Parents {
+		redactResourceNodesFor(c, &lookup.Parents[i])
+	}
+
+	log.Printf("UID %s is %s/%s with %d parents", uid, lookup.Resource.Kind, lookup.Resource.Name, len(lookup.Parents))
+	c.JSON(http.StatusOK, lookup)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"k8s.io/apimachinery/pkg/api/validation/path"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	queryParamsKey = "queryParams"
+	// maxLogTailLines bounds the lines of history a log stream starts with
+	maxLogTailLines = 10000
+	rbacGroup       = "rbac.authorization.k8s.io"
+)
+
+// PathParams are the path parameters of the endpoints, validated before any handler runs
+type PathParams struct {
+	Type string `uri:"type" binding:"omitempty,resourcetype"`
+	// Root and Name are resource names, RBAC resources also allow the colons of e.g. system:controller
+	Root      string `uri:"root" binding:"omitempty,k8sname"`
+	Name      string `uri:"name" binding:"omitempty,k8sname"`
+	Namespace string `uri:"ns" binding:"omitempty,k8snamespace"`
+}
+
+// QueryParams are the query parameters shared by the endpoints. Optional values are pointers,
+// nil when the parameter is not given. Parameters of a single endpoint are checked by it.
+type QueryParams struct {
+	Namespace string `form:"namespace" binding:"omitempty,k8snamespace"`
+	Container string `form:"container" binding:"omitempty,k8slabel"`
+
+	TimeBudgetMs   *int   `form:"timeBudgetMs" binding:"omitempty,min=1,max=600000"`
+	MaxNodes       *int   `form:"maxNodes" binding:"omitempty,min=1"`
+	TimeoutSeconds *int   `form:"timeoutSeconds" binding:"omitempty,min=1"`
+	TailLines      *int64 `form:"tailLines" binding:"omitempty,min=0,max=10000"`
+	Minutes        *int   `form:"minutes" binding:"omitempty,min=0,max=525600"`
+	// Window is the period of history endpoints, e.g. 24h
+	Window *time.Duration `form:"window" binding:"omitempty,min=1s"`
+
+	PropagationPolicy string `form:"propagationPolicy" binding:"omitempty,oneof=Background Foreground Orphan"`
+
+	RBAC       *bool `form:"rbac"`
+	ShowSystem *bool `form:"showSystem"`
+	Revisions  *bool `form:"revisions"`
+	DryRun     *bool `form:"dryRun"`
+	Async      *bool `form:"async"`
+	// Reveal, Refresh, Stream and Strict are compared to "true" by their handlers
+	Reveal  string `form:"reveal" binding:"omitempty,oneof=true false"`
+	Refresh string `form:"refresh" binding:"omitempty,oneof=true false"`
+	Stream  string `form:"stream" binding:"omitempty,oneof=true false"`
+	Strict  string `form:"strict" binding:"omitempty,oneof=true false"`
+}
+
+// ParamError is an invalid parameter, answered as a 400 naming the field
+type ParamError struct {
+	Field   string
+	Value   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("Invalid %s %q: %s", e.Field, e.Value, e.Message)
+}
+
+var registerValidatorsOnce sync.Once
+
+// registerParamValidators adds the Kubernetes validators to the validator of gin's binding and
+// names fields after their parameters in errors
+func registerParamValidators() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		log.Printf("⚠️  Unable to register parameter validators, gin uses an unknown validator")
+		return
+	}
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"form", "uri", "json"} {
+			if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	engine.RegisterValidation("k8snamespace", func(fl validator.FieldLevel) bool {
+		return len(validation.IsDNS1123Label(fl.Field().String())) == 0
+	})
+	engine.RegisterValidation("k8slabel", func(fl validator.FieldLevel) bool {
+		return len(validation.IsDNS1123Label(fl.Field().String())) == 0
+	})
+	engine.RegisterValidation("k8sname", func(fl validator.FieldLevel) bool {
+		name := fl.Field().String()
+		if resourceType := fl.Parent().FieldByName("Type"); resourceType.IsValid() {
+			if gvr, err := getGVRForResourceType(resourceType.String()); err == nil && gvr.Group == rbacGroup {
+				return len(path.IsValidPathSegmentName(name)) == 0
+			}
+		}
+		return len(validation.IsDNS1123Subdomain(name)) == 0
+	})
+	engine.RegisterValidation("resourcetype", func(fl validator.FieldLevel) bool {
+		_, err := getGVRForResourceType(fl.Field().String())
+		return err == nil
+	})
+}
+
+// validationMessage explains a failed validator in the words of the API
+func validationMessage(tag, param string) string {
+	switch tag {
+	case "k8sname":
+		return "must be a lowercase RFC 1123 name, e.g. my-cluster"
+	case "k8snamespace", "k8slabel":
+		return "must be a lowercase RFC 1123 label of at most 63 characters"
+	case "resourcetype":
+		return "unknown resource type, see /api/resourcetypes"
+	case "min":
+		return "must be at least " + param
+	case "max":
+		return "must be at most " + param
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	}
+	return "is invalid"
+}
+
+// conversionError finds the parameter gin's binding was unable to convert to its field type
+func conversionError(target interface{}, tag string, lookup func(string) string) error {
+	targetType := reflect.TypeOf(target).Elem()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		name := field.Tag.Get(tag)
+		value := lookup(name)
+		if name == "" || value == "" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case fieldType == reflect.TypeOf(time.Duration(0)):
+			if _, err := time.ParseDuration(value); err != nil {
+				return &ParamError{Field: name, Value: value, Message: "must be a duration, e.g. 30m or 24h"}
+			}
+		case fieldType.Kind() == reflect.Bool:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return &ParamError{Field: name, Value: value, Message: "must be true or false"}
+			}
+		case fieldType.Kind() == reflect.Int || fieldType.Kind() == reflect.Int64:
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return &ParamError{Field: name, Value: value, Message: "must be an integer"}
+			}
+		}
+	}
+	return nil
+}
+
+// bindingError turns an error of gin's binding into a ParamError of the first invalid parameter
+func bindingError(err error, target interface{}, tag string, lookup func(string) string) error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) && len(validationErrors) > 0 {
+		fieldError := validationErrors[0]
+		return &ParamError{Field: fieldError.Field(), Value: lookup(fieldError.Field()), Message: validationMessage(fieldError.Tag(), fieldError.Param())}
+	}
+	if paramErr := conversionError(target, tag, lookup); paramErr != nil {
+		return paramErr
+	}
+	return err
+}
+
+// queryParams returns the validated query parameters of the request, bound once and shared by
+// the handlers. On error the returned parameters are empty, never nil.
+func queryParams(c *gin.Context) (*QueryParams, error) {
+	if cached, found := c.Get(queryParamsKey); found {
+		return cached.(*QueryParams), nil
+	}
+	registerValidatorsOnce.Do(registerParamValidators)
+
+	params := &QueryParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		return &QueryParams{}, bindingError(err, params, "form", c.Query)
+	}
+	c.Set(queryParamsKey, params)
+	return params, nil
+}
+
+// requestValidationMiddleware rejects requests whose path or query parameters are malformed with a
+// 400 naming the field, so handlers only see valid names, namespaces, resource types and limits
+func requestValidationMiddleware() gin.HandlerFunc {
+	registerValidatorsOnce.Do(registerParamValidators)
+	return func(c *gin.Context) {
+		var pathParams PathParams
+		err := c.ShouldBindUri(&pathParams)
+		if err != nil {
+			err = bindingError(err, &pathParams, "uri", c.Param)
+		} else {
+			_, err = queryParams(c)
+		}
+		if err != nil {
+			log.Printf("Rejecting %s %s from %s: %v", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+			response := gin.H{"error": err.Error()}
+			var paramErr *ParamError
+			if errors.As(err, &paramErr) {
+				response["field"] = paramErr.Field
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, response)
+			return
+		}
+		c.Next()
+	}
+}
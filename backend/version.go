@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, injected with -ldflags "-X main.version=... -X main.gitSHA=... -X main.buildDate=..."
+// by the build command in hack/build
+var (
+	version   = "dev"
+	gitSHA    = "unknown"
+	buildDate = "unknown"
+)
+
+// VersionInfo describes the server build and the KubeBlocks API versions of the cluster
+type VersionInfo struct {
+	Version               string              `json:"version"`
+	GitSHA                string              `json:"gitSHA"`
+	BuildDate             string              `json:"buildDate"`
+	GoVersion             string              `json:"goVersion"`
+	Platform              string              `json:"platform"`
+	KubeBlocksAPIVersions map[string][]string `json:"kubeBlocksAPIVersions"`
+}
+
+// KubeBlocks API versions served by the default cluster, probed at startup
+var kubeBlocksAPIVersions = struct {
+	mu       sync.RWMutex
+	versions map[string][]string
+}{versions: map[string][]string{}}
+
+// probeKubeBlocksAPIVersions records the served versions of the KubeBlocks API groups
+func probeKubeBlocksAPIVersions(client *K8sClient) {
+	check := checkKubeBlocks(client)
+	if check.Status == CheckStatusFailed {
+		log.Printf("⚠️  Unable to probe KubeBlocks API versions: %s", check.Message)
+		return
+	}
+
+	versions := map[string][]string{}
+	for group, served := range check.Details {
+		versions[group] = strings.Split(served, ",")
+	}
+	kubeBlocksAPIVersions.mu.Lock()
+	kubeBlocksAPIVersions.versions = versions
+	kubeBlocksAPIVersions.mu.Unlock()
+
+	groups := make([]string, 0, len(versions))
+	for group := range versions {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	log.Printf("✓ KubeBlocks API groups served: %v", groups)
+}
+
+func getVersion(c *gin.Context) {
+	kubeBlocksAPIVersions.mu.RLock()
+	versions := kubeBlocksAPIVersions.versions
+	kubeBlocksAPIVersions.mu.RUnlock()
+
+	c.JSON(http.StatusOK, VersionInfo{
+		Version:               version,
+		GitSHA:                gitSHA,
+		BuildDate:             buildDate,
+		GoVersion:             runtime.Version(),
+		Platform:              runtime.GOOS + "/" + runtime.GOARCH,
+		KubeBlocksAPIVersions: versions,
+	})
+}
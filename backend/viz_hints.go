@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Annotations giving operators declarative control over how resources are visualized
+const (
+	vizHiddenAnnotation      = "viz.kubeblocks.io/hidden"
+	vizGroupAnnotation       = "viz.kubeblocks.io/group"
+	vizDisplayNameAnnotation = "viz.kubeblocks.io/display-name"
+	vizParentAnnotation      = "viz.kubeblocks.io/parent"
+)
+
+// Synthetic group nodes created from the group annotation
+const (
+	vizGroupAPIVersion = "viz.kubeblocks.io/v1"
+	vizGroupKind       = "Group"
+)
+
+// applyVisualizationHints rewrites the direct children of a node according to their
+// visualization annotations: hidden children are replaced by their own children,
+// display names are applied and children sharing a group are wrapped in a group node
+func applyVisualizationHints(node *ResourceTreeNode) {
+	var children []*ResourceTreeNode
+	for _, child := range node.Children {
+		annotations := child.Resource.GetAnnotations()
+		if strings.EqualFold(annotations[vizHiddenAnnotation], "true") {
+			children = append(children, child.Children...)
+			continue
+		}
+		children = append(children, child)
+	}
+
+	groups := map[string]*ResourceTreeNode{}
+	var groupNames []string
+	result := make([]*ResourceTreeNode, 0, len(children))
+	for _, child := range children {
+		annotations := child.Resource.GetAnnotations()
+		if displayName := annotations[vizDisplayNameAnnotation]; displayName != "" {
+			child.DisplayName = displayName
+		}
+
+		group := annotations[vizGroupAnnotation]
+		if group == "" {
+			result = append(result, child)
+			continue
+		}
+		groupNode := groups[group]
+		if groupNode == nil {
+			groupNode = newGroupNode(node.Resource, group)
+			groups[group] = groupNode
+			groupNames = append(groupNames, group)
+		}
+		groupNode.Children = append(groupNode.Children, child)
+	}
+
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		result = append(result, groups[group])
+	}
+	node.Children = result
+}
+
+// newGroupNode creates a synthetic node grouping children of parent
+func newGroupNode(parent *unstructured.Unstructured, group string) *ResourceTreeNode {
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion(vizGroupAPIVersion)
+	resource.SetKind(vizGroupKind)
+	resource.SetName(group)
+	resource.SetNamespace(parent.GetNamespace())
+	resource.SetUID(types.UID(string(parent.GetUID()) + "/group/" + group))
+
+	return &ResourceTreeNode{
+		Resource:    resource,
+		Children:    []*ResourceTreeNode{},
+		DisplayName: group,
+		Virtual:     true,
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	waitDefaultTimeout = 10 * time.Minute
+	waitMaxTimeout     = time.Hour
+	// waitMaxBlocking bounds the resources listed as blocking the condition
+	waitMaxBlocking = 20
+)
+
+// waitConditions are the health rollups a wait can block on
+var waitConditions = []string{HealthHealthy, HealthProgressing, HealthDegraded, HealthUnknown}
+
+// WaitBlocker is a resource of the tree whose health is worse than the awaited condition
+type WaitBlocker struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Health string `json:"health"`
+}
+
+// WaitResult is the outcome of POST /api/clusters/:name/wait, and the data of its SSE events
+type WaitResult struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Condition string `json:"condition"`
+	// Met is set once the health rollup matched the condition for stableFor
+	Met     bool   `json:"met"`
+	Health  string `json:"health"`
+	Version string `json:"version,omitempty"`
+	// Since is when the rollup last started matching the condition
+	Since          string  `json:"since,omitempty"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	// Blocking are the resources keeping the rollup from the condition
+	Blocking []WaitBlocker `json:"blocking,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// parseWaitDuration reads a duration parameter as a Go duration (600s, 10m) or a number of seconds
+func parseWaitDuration(c *gin.Context, name string, fallback time.Duration) (time.Duration, error) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("Invalid %s: %s", name, value)
+	}
+	return duration, nil
+}
+
+// waitBlockers lists the resources of the tree whose own health is worse than the condition
+func waitBlockers(root *ResourceTreeNode, condition string) []WaitBlocker {
+	var blocking []WaitBlocker
+	var walk func(node *ResourceTreeNode)
+	walk = func(node *ResourceTreeNode) {
+		if len(blocking) >= waitMaxBlocking {
+			return
+		}
+		if health := computeHealth(node.Resource); healthSeverity[health] > healthSeverity[condition] && !node.Virtual {
+			blocking = append(blocking, WaitBlocker{Kind: node.Resource.GetKind(), Name: node.Resource.GetName(), Health: health})
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return blocking
+}
+
+// waitForCluster blocks until the health rollup of a cluster tree reaches ?condition= (Healthy by
+// default) and held it for ?stableFor=, or ?timeout= expires, so CI pipelines and the UI can wait for
+// a cluster to be back to healthy after an OpsRequest. The tree is rebuilt like long-polls do. It
+// answers 200 when the condition is met and 408 on timeout, or with ?stream=true sends a health
+// event on every change and a done event at the end.
+func waitForCluster(c *gin.Context) {
+	clusterName := c.Param("name")
+	namespace := c.Query("namespace")
+
+	condition := ""
+	for _, known := range waitConditions {
+		if strings.EqualFold(c.DefaultQuery("condition", HealthHealthy), known) {
+			condition = known
+		}
+	}
+	if condition == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid condition %q, expected one of %s", c.Query("condition"), strings.Join(waitConditions, ", "))})
+		return
+	}
+	timeout, err := parseWaitDuration(c, "timeout", waitDefaultTimeout)
+	if err == nil && (timeout == 0 || timeout > waitMaxTimeout) {
+		err = fmt.Errorf("Invalid timeout, it must be between 1s and %s", waitMaxTimeout)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stableFor, err := parseWaitDuration(c, "stableFor", 0)
+	if err == nil && stableFor >= timeout {
+		err = fmt.Errorf("Invalid stableFor, it must be shorter than the timeout")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stream := c.Query("stream") == "true"
+
+	log.Printf("Waiting up to %s for cluster %s in namespace '%s' to be %s, requested from %s", timeout, clusterName, namespace, condition, c.ClientIP())
+
+	client := clientFor(c)
+	options := TreeOptions{Language: requestLanguage(c)}
+	start := time.Now()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(longPollRefreshInterval)
+	defer ticker.Stop()
+	var heartbeats <-chan time.Time
+	if stream {
+		heartbeat := time.NewTicker(streamHeartbeatInterval())
+		defer heartbeat.Stop()
+		heartbeats = heartbeat.C
+	}
+
+	result := WaitResult{Cluster: clusterName, Namespace: namespace, Condition: condition}
+	var matchingSince time.Time
+	built, streaming := false, false
+	for {
+		rootTreeNode, _, status, err := buildTreeForRootWithin(client, "cluster", clusterName, namespace, options)
+		result.ElapsedSeconds = time.Since(start).Seconds()
+		switch {
+		case err != nil && (!built || status == http.StatusNotFound || status == http.StatusBadRequest):
+			// The cluster is gone or was never there, waiting longer will not help
+			if streaming {
+				result.Error = err.Error()
+				sendSSE(c, "done", result)
+			} else {
+				c.JSON(status, gin.H{"error": err.Error()})
+			}
+			return
+		case err != nil:
+			log.Printf("⚠️  Unable to rebuild tree of cluster %s while waiting: %v", clusterName, err)
+		default:
+			built = true
+			health := rollupHealth(rootTreeNode, nil)
+			version := treeVersion(rootTreeNode)
+			if health != condition {
+				matchingSince = time.Time{}
+			} else if matchingSince.IsZero() {
+				matchingSince = time.Now()
+			}
+			changed := health != result.Health || version != result.Version
+			result.Health, result.Version = health, version
+			result.Blocking = waitBlockers(rootTreeNode, condition)
+			result.Since = ""
+			if !matchingSince.IsZero() {
+				result.Since = matchingSince.UTC().Format(time.RFC3339)
+			}
+			result.Met = !matchingSince.IsZero() && time.Since(matchingSince) >= stableFor
+			if stream {
+				if !streaming {
+					prepareSSE(c)
+					streaming = true
+				}
+				if changed {
+					sendSSE(c, "health", result)
+				}
+			}
+			if result.Met {
+				log.Printf("Cluster %s is %s after %.0fs", clusterName, condition, result.ElapsedSeconds)
+				if stream {
+					sendSSE(c, "done", result)
+				} else {
+					c.JSON(http.StatusOK, result)
+				}
+				return
+			}
+		}
+
+	next:
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-heartbeats:
+				if streaming {
+					sendSSE(c, "heartbeat", gin.H{"time": time.Now().Format(time.RFC3339)})
+				}
+			case <-deadline.C:
+				result.ElapsedSeconds = time.Since(start).Seconds()
+				log.Printf("⚠️  Cluster %s is still %s, not %s, after %s", clusterName, result.Health, condition, timeout)
+				if streaming {
+					sendSSE(c, "done", result)
+				} else {
+					c.JSON(http.StatusRequestTimeout, result)
+				}
+				return
+			case <-ticker.C:
+				break next
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WarmupStatus reports the progress of the startup cache warm-up
+type WarmupStatus struct {
+	Ready     bool     `json:"ready"`
+	Phase     string   `json:"phase"`
+	Done      int      `json:"done"`
+	Total     int      `json:"total"`
+	StartedAt string   `json:"startedAt,omitempty"`
+	Duration  string   `json:"duration,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+type warmupTracker struct {
+	mu      sync.Mutex
+	started time.Time
+	status  WarmupStatus
+}
+
+var warmup = &warmupTracker{status: WarmupStatus{Phase: "pending"}}
+
+func (wt *warmupTracker) update(change func(status *WarmupStatus)) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	change(&wt.status)
+}
+
+func (wt *warmupTracker) snapshot() WarmupStatus {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	status := wt.status
+	status.Warnings = append([]string(nil), wt.status.Warnings...)
+	if !wt.started.IsZero() && !status.Ready {
+		status.Duration = time.Since(wt.started).Round(time.Millisecond).String()
+	}
+	return status
+}
+
+func (wt *warmupTracker) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	log.Printf("⚠️  Warm-up: %s", warning)
+	wt.update(func(status *WarmupStatus) { status.Warnings = append(status.Warnings, warning) })
+}
+
+// warmCaches primes discovery and the resource type detection of every KubeBlocks cluster in the
+// namespaces, so the first tree requests skip the fan-out of probing each type. All namespaces are
+// warmed when none are given. Failures are reported as warnings and do not keep the server unready.
+func warmCaches(client *K8sClient, namespaces []string) {
+	warmup.mu.Lock()
+	warmup.started = time.Now()
+	warmup.status.StartedAt = warmup.started.Format(time.RFC3339)
+	warmup.status.Phase = "discovery"
+	warmup.mu.Unlock()
+	log.Printf("🔥 Warming caches for namespaces %v", namespaces)
+
+	if discovered, _ := client.apiDiscovery.get(); len(discovered) == 0 {
+		warmup.warn("API discovery returned no resource types")
+	}
+
+	warmup.update(func(status *WarmupStatus) { status.Phase = "clusters" })
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	var clusters []unstructured.Unstructured
+	for _, namespace := range namespaces {
+		items, err := listInAllowedNamespaces(client, clusterGVR, namespace)
+		if err != nil {
+			warmup.warn("unable to list KubeBlocks clusters in namespace '%s': %v", namespace, err)
+			continue
+		}
+		clusters = append(clusters, items...)
+	}
+
+	warmup.update(func(status *WarmupStatus) {
+		status.Phase = "trees"
+		status.Total = len(clusters)
+	})
+	for _, cluster := range clusters {
+		// Same selector as the tree of the cluster
+		listOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", instanceLabel, cluster.GetName())}
+		builder := NewResourceTreeBuilder(client, cluster.GetNamespace(), listOptions)
+		builder.detectResourceTypes(builder.getSupportedResourceTypes())
+		warmup.update(func(status *WarmupStatus) { status.Done++ })
+	}
+
+	warmup.mu.Lock()
+	warmup.status.Ready = true
+	warmup.status.Phase = "done"
+	warmup.status.Duration = time.Since(warmup.started).Round(time.Millisecond).String()
+	warmup.mu.Unlock()
+	log.Printf("✓ Warmed caches of %d KubeBlocks clusters in %s", len(clusters), time.Since(warmup.started).Round(time.Millisecond))
+}
+
+// readinessCheck answers 503 until the cache warm-up has finished
+func readinessCheck(c *gin.Context) {
+	status := warmup.snapshot()
+	if !status.Ready {
+		c.JSON(http.StatusServiceUnavailable, status)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	watchReconnectAttempts = 5
+	watchReconnectBackoff  = 2 * time.Second
+)
+
+// isWatchExpired reports whether a watch error event means the resourceVersion is too old to resume from
+func isWatchExpired(result watch.Event) bool {
+	if result.Type != watch.Error {
+		return false
+	}
+	err := errors.FromObject(result.Object)
+	return errors.IsResourceExpired(err) || errors.IsGone(err)
+}
+
+// recordWatchReconnect counts a re-established watch of a stream, and whether it needed a relist
+func recordWatchReconnect(stream string, relisted bool) {
+	labels := map[string]string{"stream": stream}
+	metrics.AddCounter("kbviz_watch_reconnects_total", "Number of watches re-established after they ended", labels, 1)
+	if relisted {
+		metrics.AddCounter("kbviz_watch_resyncs_total", "Number of relists after a watch could not be resumed", labels, 1)
+	}
+}
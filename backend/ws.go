@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Topics a /api/ws connection can subscribe to
+const (
+	wsTopicTree   = "tree"
+	wsTopicEvents = "events"
+	wsTopicLogs   = "logs"
+)
+
+const (
+	// wsMaxSubscriptions bounds the subscriptions of one connection
+	wsMaxSubscriptions = 32
+	// wsSendBuffer is the number of messages queued for a connection before it is dropped as too slow
+	wsSendBuffer = 256
+	// wsReplayLimit bounds the messages of a topic replayed to a new subscriber
+	wsReplayLimit     = 500
+	wsMaxRequestBytes = 16 * 1024
+	wsMaxIDLength     = 64
+	wsWriteTimeout    = 10 * time.Second
+)
+
+// wsResetTypes are the message types that replace everything a topic sent before: a new tree,
+// or the resync marker preceding the current warnings. New subscribers are replayed the messages
+// since the last of them.
+var wsResetTypes = map[string]bool{"tree": true, "resync": true}
+
+// WSRequest is a message of the browser on /api/ws
+type WSRequest struct {
+	Op     string            `json:"op"` // subscribe or unsubscribe
+	ID     string            `json:"id"` // Chosen by the browser, unique among the subscriptions of the connection
+	Topic  string            `json:"topic,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// WSMessage is a message of the server on /api/ws. ID is the subscription the message belongs to,
+// empty for errors of the connection itself.
+type WSMessage struct {
+	ID   string      `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	// Pages of other origins must not subscribe with the cookies of the user
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || originAllowed(origin, r.Host)
+	},
+}
+
+// wsTopicState is a stream shared by every subscription with the same key: one producer, whose
+// messages are fanned out to all subscribers. The producer stops with its last subscriber.
+type wsTopicState struct {
+	key         string
+	cancel      context.CancelFunc
+	subscribers map[*wsSubscription]bool
+	backlog     []WSMessage
+}
+
+// wsSubscription is a subscription of a connection to a topic
+type wsSubscription struct {
+	conn  *wsConn
+	id    string
+	topic *wsTopicState
+}
+
+// wsHub is the subscription manager of /api/ws
+type wsHub struct {
+	mu     sync.Mutex
+	topics map[string]*wsTopicState
+}
+
+var wsTopics = &wsHub{topics: map[string]*wsTopicState{}}
+
+// subscribe adds the subscription to the topic of the key, starting its producer if it is the first
+// subscriber, and replays the messages a new subscriber needs to catch up
+func (hub *wsHub) subscribe(sub *wsSubscription, key string, produce func(ctx context.Context, emit func(string, interface{}))) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	topic := hub.topics[key]
+	if topic == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		topic = &wsTopicState{key: key, cancel: cancel, subscribers: map[*wsSubscription]bool{}}
+		hub.topics[key] = topic
+		log.Printf("Starting WebSocket topic %s", key)
+		go func() {
+			produce(ctx, func(messageType string, data interface{}) { hub.publish(topic, messageType, data) })
+			hub.end(topic)
+		}()
+	}
+	topic.subscribers[sub] = true
+	sub.topic = topic
+	for _, message := range topic.backlog {
+		message.ID = sub.id
+		sub.conn.send(message)
+	}
+	hub.recordSize()
+}
+
+// unsubscribe removes the subscription, stopping the producer of its topic with the last subscriber
+func (hub *wsHub) unsubscribe(sub *wsSubscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	topic := sub.topic
+	if topic == nil || !topic.subscribers[sub] {
+		return
+	}
+	delete(topic.subscribers, sub)
+	if len(topic.subscribers) == 0 && hub.topics[topic.key] == topic {
+		log.Printf("Stopping WebSocket topic %s, no subscribers left", topic.key)
+		topic.cancel()
+		delete(hub.topics, topic.key)
+	}
+	hub.recordSize()
+}
+
+// publish fans a message of the topic out to its subscribers. The data is encoded once for all of them.
+func (hub *wsHub) publish(topic *wsTopicState, messageType string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("⚠️  Unable to encode %s message of WebSocket topic %s: %v", messageType, topic.key, err)
+		return
+	}
+	message := WSMessage{Type: messageType, Data: json.RawMessage(encoded)}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if wsResetTypes[messageType] {
+		topic.backlog = topic.backlog[:0]
+	}
+	topic.backlog = append(topic.backlog, message)
+	if len(topic.backlog) > wsReplayLimit {
+		topic.backlog = append([]WSMessage{}, topic.backlog[len(topic.backlog)-wsReplayLimit:]...)
+	}
+	for sub := range topic.subscribers {
+		message.ID = sub.id
+		sub.conn.send(message)
+	}
+	metrics.AddCounter("kbviz_ws_messages_total", "Number of messages fanned out to WebSocket subscribers", map[string]string{"type": messageType}, float64(len(topic.subscribers)))
+}
+
+// end drops the subscriptions of a topic whose producer returned, e.g. after an end or error
+// message. The browser may subscribe again with the same ID.
+func (hub *wsHub) end(topic *wsTopicState) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for sub := range topic.subscribers {
+		sub.conn.forget(sub.id)
+	}
+	topic.subscribers = map[*wsSubscription]bool{}
+	if hub.topics[topic.key] == topic {
+		delete(hub.topics, topic.key)
+	}
+	topic.cancel()
+	hub.recordSize()
+}
+
+// recordSize exports the number of topics and subscriptions, the caller holds the lock
+func (hub *wsHub) recordSize() {
+	subscriptions := 0
+	for _, topic := range hub.topics {
+		subscriptions += len(topic.subscribers)
+	}
+	metrics.SetGauge("kbviz_ws_topics", "Number of WebSocket topics with a running producer", nil, float64(len(hub.topics)))
+	metrics.SetGauge("kbviz_ws_subscriptions", "Number of WebSocket subscriptions", nil, float64(subscriptions))
+}
+
+// wsConn is one /api/ws connection. Only its writer goroutine writes to the socket.
+type wsConn struct {
+	c             *gin.Context
+	conn          *websocket.Conn
+	outgoing      chan WSMessage
+	done          chan struct{}
+	closeOnce     sync.Once
+	mu            sync.Mutex
+	subscriptions map[string]*wsSubscription
+}
+
+// send queues a message, dropping the connection when the browser does not keep up
+func (wc *wsConn) send(message WSMessage) {
+	select {
+	case <-wc.done:
+	case wc.outgoing <- message:
+	default:
+		log.Printf("⚠️  Dropping WebSocket of %s, %d messages are queued", wc.c.ClientIP(), wsSendBuffer)
+		wc.close()
+	}
+}
+
+func (wc *wsConn) close() {
+	wc.closeOnce.Do(func() {
+		close(wc.done)
+		wc.conn.Close()
+	})
+}
+
+// forget removes a subscription whose topic ended
+func (wc *wsConn) forget(id string) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	delete(wc.subscriptions, id)
+}
+
+// writeLoop writes the queued messages and pings the browser, so proxies keep the connection open
+func (wc *wsConn) writeLoop() {
+	ticker := time.NewTicker(streamHeartbeatInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-wc.done:
+			return
+		case message := <-wc.outgoing:
+			wc.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := wc.conn.WriteJSON(message); err != nil {
+				wc.close()
+				return
+			}
+		case <-ticker.C:
+			if err := wc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				wc.close()
+				return
+			}
+		}
+	}
+}
+
+// handle serves one request of the browser
+func (wc *wsConn) handle(request WSRequest) {
+	fail := func(format string, args ...interface{}) {
+		wc.send(WSMessage{ID: request.ID, Type: "error", Data: gin.H{"error": fmt.Sprintf(format, args...)}})
+	}
+	if request.ID == "" || len(request.ID) > wsMaxIDLength {
+		fail("Invalid subscription ID, it must have 1 to %d characters", wsMaxIDLength)
+		return
+	}
+
+	wc.mu.Lock()
+	sub, exists := wc.subscriptions[request.ID]
+	count := len(wc.subscriptions)
+	wc.mu.Unlock()
+
+	switch request.Op {
+	case "unsubscribe":
+		if !exists {
+			fail("Unknown subscription: %s", request.ID)
+			return
+		}
+		wc.forget(request.ID)
+		wsTopics.unsubscribe(sub)
+		wc.send(WSMessage{ID: request.ID, Type: "unsubscribed"})
+	case "subscribe":
+		if exists {
+			fail("Subscription %s already exists", request.ID)
+			return
+		}
+		if count >= wsMaxSubscriptions {
+			fail("Too many subscriptions, at most %d are allowed per connection", wsMaxSubscriptions)
+			return
+		}
+		key, produce, err := wc.resolveTopic(request.Topic, request.Params)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		sub := &wsSubscription{conn: wc, id: request.ID}
+		wc.mu.Lock()
+		wc.subscriptions[request.ID] = sub
+		wc.mu.Unlock()
+		wc.send(WSMessage{ID: request.ID, Type: "subscribed", Data: gin.H{"topic": request.Topic}})
+		wsTopics.subscribe(sub, key, produce)
+	default:
+		fail("Unknown op %q, expected subscribe or unsubscribe", request.Op)
+	}
+}
+
+// resolveTopic checks a subscription against the namespace allowlist and the authorization webhook,
+// and returns the key shared by identical subscriptions with the producer of their messages
+func (wc *wsConn) resolveTopic(topic string, params map[string]string) (string, func(context.Context, func(string, interface{})), error) {
+	namespace, name := params["namespace"], params["name"]
+	if namespace == "" || name == "" {
+		return "", nil, fmt.Errorf("Parameters namespace and name are required")
+	}
+	if !appConfig.namespaceAllowed(namespace) {
+		return "", nil, fmt.Errorf("Namespace %s is not served by this instance", namespace)
+	}
+	// An uploaded kubeconfig selected with ?kubeconfigSession= on the upgrade request takes
+	// precedence, its client name keeps the streams of sessions apart
+	client, fromSession, err := kubeconfigSessionClient(wc.c)
+	if err != nil {
+		return "", nil, err
+	}
+	kubeCluster := defaultString(params["cluster"], defaultClusterName)
+	if fromSession {
+		kubeCluster = client.name
+	} else if client = clusterClients.clients[kubeCluster]; client == nil {
+		return "", nil, fmt.Errorf("Unknown cluster: %s", kubeCluster)
+	}
+	resourceType := "cluster"
+	if topic == wsTopicTree {
+		resourceType = params["type"]
+	}
+	if err := subscriptionAllowed(wc.c, resourceType, name, namespace); err != nil {
+		return "", nil, err
+	}
+
+	switch topic {
+	case wsTopicTree:
+		options := TreeOptions{Reveal: revealAllowed(wc.c), Language: requestLanguage(wc.c)}
+		key := fmt.Sprintf("%s|%s|%s|%s/%s|reveal=%t|%s", topic, kubeCluster, namespace, resourceType, name, options.Reveal, options.Language)
+		return key, func(ctx context.Context, emit func(string, interface{})) {
+			produceTreeTopic(ctx, client, resourceType, name, namespace, options, emit)
+		}, nil
+	case wsTopicEvents:
+		key := fmt.Sprintf("%s|%s|%s|%s", topic, kubeCluster, namespace, name)
+		return key, func(ctx context.Context, emit func(string, interface{})) {
+			stream, status, err := openWarningStream(ctx, client, name, namespace)
+			if err != nil {
+				emit("error", gin.H{"error": err.Error(), "status": status})
+				return
+			}
+			defer stream.stop()
+			stream.run(ctx, emit, false)
+		}, nil
+	case wsTopicLogs:
+		tailLines, err := parseTailLines(params["tailLines"])
+		if err != nil {
+			return "", nil, err
+		}
+		container := params["container"]
+		key := fmt.Sprintf("%s|%s|%s|%s|%s|%d", topic, kubeCluster, namespace, name, container, tailLines)
+		return key, func(ctx context.Context, emit func(string, interface{})) {
+			stream, status, err := openLogStream(client, name, namespace, container, tailLines)
+			if err != nil {
+				emit("error", gin.H{"error": err.Error(), "status": status})
+				return
+			}
+			stream.run(ctx, emit, false)
+		}, nil
+	}
+	return "", nil, fmt.Errorf("Unknown topic %q, expected %s, %s or %s", topic, wsTopicTree, wsTopicEvents, wsTopicLogs)
+}
+
+// subscriptionAllowed asks the authorization webhook whether the user may read the subscribed
+// resource, like it does for the resource of a tree request
+func subscriptionAllowed(c *gin.Context, resourceType, name, namespace string) error {
+	gvr, err := getGVRForResourceType(resourceType)
+	if err != nil {
+		return fmt.Errorf("Unknown resource type: %s", resourceType)
+	}
+	return resourceAccessAllowed(c, "get", gvr, name, namespace)
+}
+
+// produceTreeTopic emits the v2 tree of a root resource with its version, then again every time it
+// changes, like the long-poll of the tree endpoint. The topic ends when the root is gone.
+func produceTreeTopic(ctx context.Context, client *K8sClient, resourceType, name, namespace string, options TreeOptions, emit func(string, interface{})) {
+	ticker := time.NewTicker(longPollRefreshInterval)
+	defer ticker.Stop()
+
+	version := ""
+	for {
+		rootTreeNode, treeBuilder, status, err := buildTreeForRootWithin(client, resourceType, name, namespace, options)
+		switch {
+		case err != nil && (version == "" || status == http.StatusNotFound):
+			emit("error", gin.H{"error": err.Error(), "status": status})
+			return
+		case err != nil:
+			log.Printf("⚠️  Unable to rebuild tree of %s/%s for WebSocket subscribers: %v", resourceType, name, err)
+		default:
+			treeBuilder.RetainDeletedNodes(rootTreeNode, resourceType, name, true)
+			if current := treeVersion(rootTreeNode); current != version {
+				version = current
+				treeBuilder.DecorateTree(rootTreeNode)
+				tree := NewTreeV2(rootTreeNode, treeBuilder.Warnings())
+				setTreeV2Continuation(tree, treeBuilder)
+				emit("tree", gin.H{"version": version, "tree": tree})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveWebSocket multiplexes tree, event and log streams over one WebSocket, so the browser does
+// not need one connection per panel. Identical subscriptions of all connections share one stream.
+func serveWebSocket(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already answered the request
+		log.Printf("Unable to upgrade request from %s to a WebSocket: %v", c.ClientIP(), err)
+		return
+	}
+	log.Printf("WebSocket opened by %s", c.ClientIP())
+
+	wc := &wsConn{
+		c:             c,
+		conn:          conn,
+		outgoing:      make(chan WSMessage, wsSendBuffer),
+		done:          make(chan struct{}),
+		subscriptions: map[string]*wsSubscription{},
+	}
+	go wc.writeLoop()
+	defer func() {
+		wc.mu.Lock()
+		subscriptions := wc.subscriptions
+		wc.subscriptions = map[string]*wsSubscription{}
+		wc.mu.Unlock()
+		for _, sub := range subscriptions {
+			wsTopics.unsubscribe(sub)
+		}
+		wc.close()
+		log.Printf("WebSocket of %s closed, %d subscriptions dropped", c.ClientIP(), len(subscriptions))
+	}()
+
+	conn.SetReadLimit(wsMaxRequestBytes)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var request WSRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			wc.send(WSMessage{Type: "error", Data: gin.H{"error": fmt.Sprintf("Invalid message: %v", err)}})
+			continue
+		}
+		wc.handle(request)
+	}
+}
@@ -125,7 +125,7 @@ fi
 
 # Start backend with new resource tree support
 log_info "Starting backend server with enhanced tree structure..."
-go run main.go resource_tree.go &
+go run . &
 BACKEND_PID=$!
 
 # Wait for backend to start with retries
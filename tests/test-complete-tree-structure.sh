@@ -50,7 +50,7 @@ fi
 
 if ! curl -s http://localhost:8080/api/health > /dev/null; then
     log_error "Backend is not running. Please start:"
-    echo "   cd backend && go run main.go resource_tree.go"
+    echo "   cd backend && go run ."
     exit 1
 fi
 